@@ -0,0 +1,87 @@
+package s3fs
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// In S3 a key "foo" and keys below the prefix "foo/" can exist at the same
+// time. s3fs resolves such a name deterministically:
+//
+//   - Stat and OpenFile resolve it to the file "foo" (file wins).
+//   - Readdir, ReadDir and WalkDir list the directory "foo/" (dir wins).
+//   - Lstat checks both forms and returns an *AmbiguousPathError.
+//
+// With Config.StrictPaths, Stat and OpenFile also check both forms and
+// return an *AmbiguousPathError instead of picking the file.
+
+// Lstat returns file info for name like Stat, but checks both the file and
+// the directory form of name and returns an *AmbiguousPathError if both exist.
+// Symbolic links are not followed and have os.ModeSymlink set.
+// It always costs a HeadObject and a listing request.
+func (fs *FileSystem) Lstat(name string) (os.FileInfo, error) {
+	info, err := fs.lstat(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.withInode(name, info)
+}
+
+// lstat implements Lstat.
+func (fs *FileSystem) lstat(name string) (os.FileInfo, error) {
+	name = strings.TrimPrefix(name, "/")
+	key := strings.TrimSuffix(fs.objectKey(name), "/")
+
+	output, headErr := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if headErr != nil && httpStatus(headErr) != 404 {
+		return nil, fs.wrapError("Lstat", name, headErr)
+	}
+
+	isDir, err := fs.isDirectory(key + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	base := path.Base(strings.TrimSuffix(name, "/"))
+	switch {
+	case headErr == nil && isDir:
+		return nil, fs.wrapError("Lstat", name, &AmbiguousPathError{Path: name})
+	case headErr == nil:
+		return &fileInfo{
+			name:    base,
+			size:    aws.ToInt64(output.ContentLength),
+			modTime: aws.ToTime(output.LastModified),
+			link:    isSymlink(output.Metadata),
+			obj:     headObjectInfo(key, output),
+		}, nil
+	case isDir:
+		return &fileInfo{name: base, isDir: true}, nil
+	default:
+		return nil, fs.wrapError("Lstat", name, headErr)
+	}
+}
+
+// checkAmbiguous returns an *AmbiguousPathError for op if name exists both
+// as a file and as a directory. Only names without trailing slash can be
+// ambiguous; the caller has established that the file exists.
+func (fs *FileSystem) checkAmbiguous(op, name, key string) error {
+	if !fs.strictPaths || name == "" || strings.HasSuffix(name, "/") {
+		return nil
+	}
+
+	isDir, err := fs.isDirectory(key + "/")
+	if err != nil {
+		return err
+	}
+	if isDir {
+		return fs.wrapError(op, name, &AmbiguousPathError{Path: name})
+	}
+	return nil
+}
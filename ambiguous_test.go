@@ -0,0 +1,37 @@
+package s3fs
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestAmbiguousPathError(t *testing.T) {
+	fs := &FileSystem{pathErrors: true}
+	err := fs.wrapError("Lstat", "foo", &AmbiguousPathError{Path: "foo"})
+
+	var ambiguous *AmbiguousPathError
+	if !errors.As(err, &ambiguous) || ambiguous.Path != "foo" {
+		t.Errorf("errors.As(%v) did not find the AmbiguousPathError", err)
+	}
+	var pe *os.PathError
+	if !errors.As(err, &pe) || pe.Op != "lstat" {
+		t.Errorf("wrapped error = %v, want *os.PathError with op lstat", err)
+	}
+	if got := ambiguous.Error(); got != "s3fs: foo is both a file and a directory" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestCheckAmbiguous_NotStrict(t *testing.T) {
+	// Without StrictPaths no request is made, so the nil client is never used.
+	fs := &FileSystem{}
+	if err := fs.checkAmbiguous("Stat", "foo", "foo"); err != nil {
+		t.Errorf("checkAmbiguous() error = %v", err)
+	}
+
+	fs.strictPaths = true
+	if err := fs.checkAmbiguous("Stat", "foo/", "foo/"); err != nil {
+		t.Errorf("checkAmbiguous() for a directory name error = %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package s3fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ArchiveFormat selects the archive format of ArchivePrefix and
+// ExtractArchive.
+type ArchiveFormat int
+
+const (
+	ArchiveTar ArchiveFormat = iota // Uncompressed tar
+	ArchiveZip                      // Zip with deflate compression
+)
+
+// ArchivePrefix streams every file below the directory prefix into an
+// archive written to w, with names relative to prefix, in lexical order.
+// Objects are read one at a time and written straight into the archive, so
+// nothing is staged on local disk. Directory markers are not archived.
+func (fs *FileSystem) ArchivePrefix(prefix string, w io.Writer, format ArchiveFormat) error {
+	src := syncPrefix(prefix)
+	if format != ArchiveTar && format != ArchiveZip {
+		return fs.wrapError("ArchivePrefix", prefix, ErrInvalidArchiveFormat)
+	}
+
+	entries, err := fs.listRemote(src)
+	if err != nil {
+		return fs.wrapError("ArchivePrefix", prefix, err)
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	aw := newArchiveWriter(w, format)
+	for _, name := range names {
+		e := entries[name]
+		if err := fs.archiveObject(aw, name, e); err != nil {
+			return fs.wrapError("ArchivePrefix", src+name, err)
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return fs.wrapError("ArchivePrefix", prefix, err)
+	}
+	return nil
+}
+
+// archiveObject copies the object of e into the archive as name.
+func (fs *FileSystem) archiveObject(aw archiveWriter, name string, e syncEntry) error {
+	output, err := fs.client.GetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(e.key),
+	})
+	if err != nil {
+		return err
+	}
+	defer output.Body.Close()
+
+	ew, err := aw.Create(name, e.size, e.modTime)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(ew, output.Body)
+	return err
+}
+
+// ExtractArchive uploads the files of an archive read from r below the
+// directory prefix and returns the number of files uploaded. Entries are
+// streamed from tar archives; zip archives keep their index at the end, so
+// they are spooled to a temporary file first unless r is an *os.File.
+// Entries whose names would leave prefix fail with ErrUnsafeArchivePath.
+func (fs *FileSystem) ExtractArchive(r io.Reader, prefix string, format ArchiveFormat) (int, error) {
+	dst := syncPrefix(prefix)
+
+	var n int
+	var err error
+	switch format {
+	case ArchiveTar:
+		n, err = fs.extractTar(r, dst)
+	case ArchiveZip:
+		n, err = fs.extractZip(r, dst)
+	default:
+		err = ErrInvalidArchiveFormat
+	}
+	if err != nil {
+		return n, fs.wrapError("ExtractArchive", prefix, err)
+	}
+	return n, nil
+}
+
+func (fs *FileSystem) extractTar(r io.Reader, dst string) (int, error) {
+	tr := tar.NewReader(r)
+	n := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		name, err := archiveName(hdr.Name)
+		if err != nil {
+			return n, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			if err := fs.extractFile(dst+name, tr, hdr.Size); err != nil {
+				return n, err
+			}
+			n++
+		case tar.TypeDir:
+			if err := fs.MkdirAll(dst+name, 0o755); err != nil {
+				return n, err
+			}
+		}
+	}
+}
+
+func (fs *FileSystem) extractZip(r io.Reader, dst string) (int, error) {
+	file, ok := r.(*os.File)
+	if !ok {
+		tmp, err := os.CreateTemp("", "s3fs-archive-*.zip")
+		if err != nil {
+			return 0, err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		if _, err := io.Copy(tmp, r); err != nil {
+			return 0, err
+		}
+		file = tmp
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	zr, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, zf := range zr.File {
+		name, err := archiveName(zf.Name)
+		if err != nil {
+			return n, err
+		}
+		if zf.FileInfo().IsDir() {
+			if err := fs.MkdirAll(dst+name, 0o755); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return n, err
+		}
+		err = fs.extractFile(dst+name, rc, int64(zf.UncompressedSize64))
+		rc.Close()
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// extractFile uploads an archive entry of the given size as name.
+func (fs *FileSystem) extractFile(name string, r io.Reader, size int64) error {
+	key := fs.objectKey(name)
+	defer fs.stats.invalidate(key)
+
+	if err := fs.putReader(fs.ctx, name, r, size); err != nil {
+		return err
+	}
+	return fs.manifestPut(key, size, false)
+}
+
+// archiveName cleans the name of an archive entry and rejects names that
+// are absolute or escape the extraction prefix.
+func archiveName(name string) (string, error) {
+	name = strings.TrimPrefix(name, "./")
+	clean := path.Clean(name)
+	if path.IsAbs(name) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", ErrUnsafeArchivePath
+	}
+	if clean == "." {
+		return "", nil
+	}
+	return clean, nil
+}
+
+// archiveWriter writes the entries of a tar or zip archive.
+type archiveWriter interface {
+	Create(name string, size int64, modTime time.Time) (io.Writer, error)
+	Close() error
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) archiveWriter {
+	if format == ArchiveZip {
+		return zipWriter{zip.NewWriter(w)}
+	}
+	return tarWriter{tar.NewWriter(w)}
+}
+
+type tarWriter struct{ *tar.Writer }
+
+func (tw tarWriter) Create(name string, size int64, modTime time.Time) (io.Writer, error) {
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  modTime,
+	})
+	return tw.Writer, err
+}
+
+type zipWriter struct{ *zip.Writer }
+
+func (zw zipWriter) Create(name string, size int64, modTime time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+}
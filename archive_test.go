@@ -0,0 +1,106 @@
+package s3fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestArchiveName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"a.txt", "a.txt", false},
+		{"./dir/a.txt", "dir/a.txt", false},
+		{"dir/../b.txt", "b.txt", false},
+		{"dir/", "dir", false},
+		{"./", "", false},
+		{"../escape.txt", "", true},
+		{"dir/../../escape.txt", "", true},
+		{"/etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		got, err := archiveName(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("archiveName(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrUnsafeArchivePath) {
+			t.Errorf("archiveName(%q) error = %v, want ErrUnsafeArchivePath", tt.name, err)
+		}
+	}
+}
+
+func TestArchiveWriter(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	files := []struct{ name, data string }{
+		{"a.txt", "alpha"},
+		{"dir/b.txt", "bravo"},
+	}
+
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveZip} {
+		var buf bytes.Buffer
+		aw := newArchiveWriter(&buf, format)
+		for _, f := range files {
+			w, err := aw.Create(f.name, int64(len(f.data)), modTime)
+			if err != nil {
+				t.Fatalf("Create(%q) error = %v", f.name, err)
+			}
+			io.WriteString(w, f.data)
+		}
+		if err := aw.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		got := map[string]string{}
+		if format == ArchiveTar {
+			tr := tar.NewReader(&buf)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(tr)
+				got[hdr.Name] = string(data)
+				if !hdr.ModTime.Equal(modTime) {
+					t.Errorf("tar ModTime = %v, want %v", hdr.ModTime, modTime)
+				}
+			}
+		} else {
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, zf := range zr.File {
+				rc, _ := zf.Open()
+				data, _ := io.ReadAll(rc)
+				rc.Close()
+				got[zf.Name] = string(data)
+			}
+		}
+
+		for _, f := range files {
+			if got[f.name] != f.data {
+				t.Errorf("format %d: %q = %q, want %q", format, f.name, got[f.name], f.data)
+			}
+		}
+	}
+}
+
+func TestArchive_InvalidFormat(t *testing.T) {
+	fs := &FileSystem{}
+	if err := fs.ArchivePrefix("data", io.Discard, ArchiveFormat(9)); !errors.Is(err, ErrInvalidArchiveFormat) {
+		t.Errorf("ArchivePrefix() error = %v, want ErrInvalidArchiveFormat", err)
+	}
+	if _, err := fs.ExtractArchive(bytes.NewReader(nil), "data", ArchiveFormat(9)); !errors.Is(err, ErrInvalidArchiveFormat) {
+		t.Errorf("ExtractArchive() error = %v, want ErrInvalidArchiveFormat", err)
+	}
+}
@@ -0,0 +1,102 @@
+package s3fs
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// DefaultBreakerCooldown is the time the circuit breaker stays open when
+// Config.BreakerCooldown is not set.
+const DefaultBreakerCooldown = 30 * time.Second
+
+// retryer returns the retry strategy for the S3 client built by New, or nil
+// to keep the SDK default.
+func (cfg *Config) retryer() aws.Retryer {
+	if cfg.RetryMaxAttempts == 0 && cfg.RetryMaxBackoff == 0 && !cfg.RetryAdaptive {
+		return nil
+	}
+
+	standard := func(o *retry.StandardOptions) {
+		if cfg.RetryMaxAttempts > 0 {
+			o.MaxAttempts = cfg.RetryMaxAttempts
+		}
+		if cfg.RetryMaxBackoff > 0 {
+			o.MaxBackoff = cfg.RetryMaxBackoff
+		}
+	}
+	if cfg.RetryAdaptive {
+		return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+			o.StandardOptions = append(o.StandardOptions, standard)
+		})
+	}
+	return retry.NewStandard(standard)
+}
+
+// breaker is a circuit breaker that opens after a number of consecutive
+// requests failed because S3 was overloaded, failing further requests with
+// ErrCircuitOpen until the cooldown has passed. The first request after the
+// cooldown probes S3 again: success closes the breaker, another overload
+// failure opens it for a new cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns ErrCircuitOpen while the breaker is open. A nil breaker is
+// always closed.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// done records the result of a request.
+func (b *breaker) done(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !isOverloaded(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// isOverloaded reports whether err means that S3 is throttling requests or
+// failing with server errors.
+func isOverloaded(err error) bool {
+	if err == nil {
+		return false
+	}
+	if (&S3Error{Err: err}).IsThrottled() {
+		return true
+	}
+	return httpStatus(err) >= http.StatusInternalServerError
+}
@@ -0,0 +1,81 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// flakyClient fails HeadObject with err and counts the calls that reach it.
+type flakyClient struct {
+	Client
+	err   error
+	calls int
+}
+
+func (c *flakyClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func TestGuardedClient_Breaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	stub := &flakyClient{err: &apiError{"SlowDown", 503}}
+	c := &guardedClient{Client: stub, b: b}
+	head := func() error {
+		_, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{})
+		return err
+	}
+
+	head()
+	head()
+	if err := head(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("third call error = %v, want ErrCircuitOpen", err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("calls reaching S3 = %d, want 2", stub.calls)
+	}
+
+	// After the cooldown a probe is let through and reopens the breaker
+	now = now.Add(time.Minute)
+	if err := head(); errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("probe after cooldown error = %v", err)
+	}
+	if err := head(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("call after a failed probe error = %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	stub.err = nil
+	if err := head(); err != nil {
+		t.Errorf("successful probe error = %v", err)
+	}
+	stub.err = &apiError{"NoSuchKey", 404}
+	for i := 0; i < 3; i++ {
+		if err := head(); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("client errors opened the breaker")
+		}
+	}
+}
+
+func TestConfigRetryer(t *testing.T) {
+	if r := (&Config{}).retryer(); r != nil {
+		t.Errorf("retryer() without retry options = %T, want nil", r)
+	}
+	if r := (&Config{RetryMaxAttempts: 10}).retryer(); r == nil || r.MaxAttempts() != 10 {
+		t.Errorf("retryer() max attempts = %v, want 10", r)
+	}
+	if _, ok := (&Config{RetryAdaptive: true}).retryer().(*retry.AdaptiveMode); !ok {
+		t.Errorf("retryer() with RetryAdaptive is not adaptive")
+	}
+}
@@ -0,0 +1,142 @@
+package s3fs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ChecksumAlgorithm selects the S3 additional checksum used by
+// Config.ChecksumAlgorithm.
+type ChecksumAlgorithm string
+
+const (
+	ChecksumCRC32C ChecksumAlgorithm = "CRC32C"
+	ChecksumSHA256 ChecksumAlgorithm = "SHA256"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// newHash returns a hash computing the checksum.
+func (a ChecksumAlgorithm) newHash() hash.Hash {
+	if a == ChecksumSHA256 {
+		return sha256.New()
+	}
+	return crc32.New(crc32cTable)
+}
+
+// checksumClient attaches checksums to uploads and verifies the checksums of
+// downloads, see Config.ChecksumAlgorithm.
+type checksumClient struct {
+	Client
+	algorithm ChecksumAlgorithm
+}
+
+func (c *checksumClient) unwrap() Client { return c.Client }
+
+// checksum computes the base64 checksum of a request body. Seekable bodies
+// are hashed and rewound; for other bodies it returns "" and the SDK
+// computes the checksum while sending.
+func (c *checksumClient) checksum(body io.Reader) (string, error) {
+	rs, ok := body.(io.ReadSeeker)
+	if !ok {
+		return "", nil
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", nil
+	}
+
+	h := c.algorithm.newHash()
+	if _, err := io.Copy(h, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// attach sets the checksum fields of an upload.
+func (c *checksumClient) attach(body io.Reader, algorithm *types.ChecksumAlgorithm, crc32c, sha **string) error {
+	sum, err := c.checksum(body)
+	if err != nil {
+		return err
+	}
+	*algorithm = types.ChecksumAlgorithm(c.algorithm)
+	if sum == "" {
+		return nil
+	}
+	if c.algorithm == ChecksumSHA256 {
+		*sha = aws.String(sum)
+	} else {
+		*crc32c = aws.String(sum)
+	}
+	return nil
+}
+
+func (c *checksumClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	in := *params
+	if err := c.attach(in.Body, &in.ChecksumAlgorithm, &in.ChecksumCRC32C, &in.ChecksumSHA256); err != nil {
+		return nil, err
+	}
+	return c.Client.PutObject(ctx, &in, optFns...)
+}
+
+func (c *checksumClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	in := *params
+	if err := c.attach(in.Body, &in.ChecksumAlgorithm, &in.ChecksumCRC32C, &in.ChecksumSHA256); err != nil {
+		return nil, err
+	}
+	return c.Client.UploadPart(ctx, &in, optFns...)
+}
+
+func (c *checksumClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	in := *params
+	in.ChecksumAlgorithm = types.ChecksumAlgorithm(c.algorithm)
+	return c.Client.CreateMultipartUpload(ctx, &in, optFns...)
+}
+
+// GetObject requests the checksum of whole objects and verifies the body
+// against it. Ranged reads and objects uploaded in parts, whose checksums
+// are computed over the parts, are not verified.
+func (c *checksumClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if params.Range != nil || params.PartNumber != nil {
+		return c.Client.GetObject(ctx, params, optFns...)
+	}
+
+	in := *params
+	in.ChecksumMode = types.ChecksumModeEnabled
+	output, err := c.Client.GetObject(ctx, &in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, expected := ChecksumCRC32C, aws.ToString(output.ChecksumCRC32C)
+	if expected == "" {
+		algorithm, expected = ChecksumSHA256, aws.ToString(output.ChecksumSHA256)
+	}
+	if expected == "" || strings.Contains(expected, "-") {
+		return output, nil
+	}
+	digest, err := base64.StdEncoding.DecodeString(expected)
+	if err != nil {
+		return output, nil
+	}
+	output.Body = &verifyingReader{
+		body:     output.Body,
+		hash:     algorithm.newHash(),
+		expected: digest,
+		key:      aws.ToString(params.Key),
+		encode:   base64.StdEncoding.EncodeToString,
+	}
+	return output, nil
+}
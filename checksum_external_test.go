@@ -0,0 +1,33 @@
+package s3fs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_Checksums(t *testing.T) {
+	for _, algorithm := range []s3fs.ChecksumAlgorithm{s3fs.ChecksumCRC32C, s3fs.ChecksumSHA256} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: s3fstest.NewClient(), ChecksumAlgorithm: algorithm})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := fs.WriteFile("a.txt", []byte("hello"), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			writeFile(t, fs, "b.txt", "world")
+			for name, want := range map[string]string{"a.txt": "hello", "b.txt": "world"} {
+				if data, err := fs.ReadFile(name); err != nil || string(data) != want {
+					t.Errorf("ReadFile(%s) = %q, %v", name, data, err)
+				}
+			}
+		})
+	}
+
+	if _, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: s3fstest.NewClient(), ChecksumAlgorithm: "MD4"}); !errors.Is(err, s3fs.ErrInvalidChecksumAlgorithm) {
+		t.Errorf("New() with an unknown algorithm error = %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// checksumStub returns body with the given CRC32C from GetObject and
+// records the PutObject input.
+type checksumStub struct {
+	Client
+	body, crc string
+	put       *s3.PutObjectInput
+}
+
+func (c *checksumStub) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out := &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader([]byte(c.body)))}
+	if params.ChecksumMode == types.ChecksumModeEnabled {
+		out.ChecksumCRC32C = aws.String(c.crc)
+	}
+	return out, nil
+}
+
+func (c *checksumStub) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.put = params
+	return &s3.PutObjectOutput{}, nil
+}
+
+func crc32cBase64(s string) string {
+	h := crc32.New(crc32cTable)
+	h.Write([]byte(s))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func TestChecksumClient(t *testing.T) {
+	stub := &checksumStub{body: "hello", crc: crc32cBase64("hello")}
+	c := &checksumClient{Client: stub, algorithm: ChecksumCRC32C}
+	ctx := context.Background()
+
+	body := bytes.NewReader([]byte("data"))
+	if _, err := c.PutObject(ctx, &s3.PutObjectInput{Body: body}); err != nil {
+		t.Fatal(err)
+	}
+	if got := aws.ToString(stub.put.ChecksumCRC32C); got != crc32cBase64("data") {
+		t.Errorf("PutObject() checksum = %q, want %q", got, crc32cBase64("data"))
+	}
+	if body.Len() != 4 {
+		t.Errorf("PutObject() did not rewind the body")
+	}
+
+	out, err := c.GetObject(ctx, &s3.GetObjectInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(out.Body); err != nil || string(data) != "hello" {
+		t.Errorf("ReadAll() = %q, %v", data, err)
+	}
+
+	stub.body = "hellx"
+	out, _ = c.GetObject(ctx, &s3.GetObjectInput{})
+	_, err = io.ReadAll(out.Body)
+	var ce *ChecksumError
+	if !errors.As(err, &ce) || !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("ReadAll() of corrupted content error = %v, want *ChecksumError", err)
+	}
+
+	// Ranged reads cannot be verified
+	out, _ = c.GetObject(ctx, &s3.GetObjectInput{Range: aws.String("bytes=0-1")})
+	if _, err := io.ReadAll(out.Body); err != nil {
+		t.Errorf("ranged ReadAll() error = %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// shutdown is shared by a FileSystem and those derived from it with Sub and
+// WithContext, and tracks what Close must undo. A nil *shutdown tracks
+// nothing.
+type shutdown struct {
+	cancel context.CancelFunc // Cancels the context of New
+
+	mu      sync.Mutex
+	closed  bool
+	uploads map[*MultipartUpload]struct{} // Started and not yet completed or aborted
+}
+
+func newShutdown(cancel context.CancelFunc) *shutdown {
+	return &shutdown{cancel: cancel, uploads: make(map[*MultipartUpload]struct{})}
+}
+
+// track records a multipart upload for Close to abort.
+func (s *shutdown) track(mu *MultipartUpload) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.uploads[mu] = struct{}{}
+	}
+}
+
+// untrack forgets a completed or aborted multipart upload.
+func (s *shutdown) untrack(mu *MultipartUpload) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, mu)
+}
+
+// Ping checks that the bucket exists and can be listed, with HeadBucket and
+// a one-key listing of the filesystem's prefix under ctx, for liveness and
+// readiness checks. A failure is reported as a *ProbeError naming the failed
+// request and its likely cause, like the probe of Config.Probe.
+func (fs *FileSystem) Ping(ctx context.Context) error {
+	return fs.WithContext(ctx).probe(false)
+}
+
+// Close shuts down fs and every FileSystem derived from it with Sub and
+// WithContext: it saves the inode index with SyncInodes, cancels the
+// operations running under the context of New, aborts the multipart uploads
+// that were started and not completed, such as those of files open for
+// writing and of Writers, and clears the stat, directory and manifest
+// caches. Operations of FileSystems derived with WithContext run under their
+// own context and are not canceled, but their uploads are aborted. Close
+// returns the errors of the save and the aborts; later calls do nothing.
+func (fs *FileSystem) Close() error {
+	s := fs.shutdown
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	uploads := s.uploads
+	s.uploads = nil
+	s.mu.Unlock()
+
+	var errs []error
+	if err := fs.SyncInodes(); err != nil {
+		errs = append(errs, err)
+	}
+	s.cancel()
+	for mu := range uploads {
+		// The context of the upload may be the one just canceled
+		if err := mu.abort(context.Background()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	fs.stats.invalidatePrefix("")
+	fs.dirs.invalidatePrefix("")
+	fs.manifestCache.clear()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,71 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestFileSystem_PingClose(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, StatCacheTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	broken := &probeClient{Client: client, head: &s3fstest.Error{StatusCode: 403, Code: "AccessDenied", Message: "Access Denied"}}
+	other, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: broken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Ping(context.Background()); !errors.Is(err, s3fs.ErrProbeFailed) || !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Ping() of a denied bucket error = %v, want ErrProbeFailed and ErrPermission", err)
+	}
+
+	// A Writer with a started multipart upload, from a derived FileSystem
+	w := fs.Sub("dir").NewWriter("big.bin", &s3fs.PutOptions{PartSize: s3fs.MinPartSize})
+	if _, err := w.Write(make([]byte, s3fs.MinPartSize+1)); err != nil {
+		t.Fatal(err)
+	}
+	uploads, err := client.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{Bucket: aws.String("bucket")})
+	if err != nil || len(uploads.Uploads) != 1 {
+		t.Fatalf("ListMultipartUploads() = %v, %v; want one upload", uploads, err)
+	}
+
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	uploads, err = client.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{Bucket: aws.String("bucket")})
+	if err != nil || len(uploads.Uploads) != 0 {
+		t.Errorf("uploads after Close() = %v, %v; want none", uploads, err)
+	}
+	if _, err := fs.Stat("dir"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stat() after Close() error = %v, want context.Canceled", err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
+
+func TestFileSystem_PingSub(t *testing.T) {
+	c := &probeClient{Client: s3fstest.NewClient()}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Sub("dir").Ping(context.Background()); err != nil {
+		t.Fatalf("Sub().Ping() error = %v", err)
+	}
+	if len(c.lists) != 1 || c.lists[0] != "dir/" {
+		t.Errorf("Sub().Ping() listed prefixes %q, want dir/", c.lists)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/absfs/s3fs"
+)
+
+// runLs lists the direct entries of a directory, subdirectories first.
+func runLs(c *cli, args []string) error {
+	flags := flag.NewFlagSet("ls", flag.ContinueOnError)
+	long := flags.Bool("l", false, "show sizes and modification times")
+	args, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	dir, err := c.locateRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	files, dirs, err := dir.fs.ListDir(dir.name)
+	if err != nil {
+		return err
+	}
+	for _, name := range dirs {
+		if *long {
+			fmt.Fprintf(c.stdout, "%12s  %-19s  %s\n", "DIR", "", name)
+		} else {
+			fmt.Fprintln(c.stdout, name)
+		}
+	}
+	for _, f := range files {
+		if *long {
+			fmt.Fprintf(c.stdout, "%12d  %-19s  %s\n", f.Size, f.ModTime.Local().Format(time.DateTime), f.Key)
+		} else {
+			fmt.Fprintln(c.stdout, f.Key)
+		}
+	}
+	return nil
+}
+
+// runCp copies a file, or a directory with -r, between the local
+// filesystem and S3 or between buckets.
+func runCp(c *cli, args []string) error {
+	flags := flag.NewFlagSet("cp", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "copy directories recursively")
+	args, err := parse(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	src, dst, err := c.locatePair(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if *recursive {
+		return copyDir(src, dst)
+	}
+	return copyFile(src, dst)
+}
+
+// runMv moves a file between the local filesystem and S3 or between
+// buckets, or renames a file or directory within a bucket.
+func runMv(c *cli, args []string) error {
+	flags := flag.NewFlagSet("mv", flag.ContinueOnError)
+	args, err := parse(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	src, dst, err := c.locatePair(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	dst.name, dst.dir = target(src, dst), false
+
+	switch {
+	case src.fs == dst.fs:
+		return src.fs.Rename(src.name, dst.name)
+	case src.remote() && dst.remote():
+		return src.fs.MoveTo(dst.fs, src.name, dst.name)
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	if src.remote() {
+		return src.fs.Remove(src.name)
+	}
+	return os.Remove(src.name)
+}
+
+// runRm removes files, or directories and their contents with -r.
+func runRm(c *cli, args []string) error {
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "remove directories and their contents")
+	args, err := parse(flags, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		l, err := c.locateRemote(arg)
+		if err != nil {
+			return err
+		}
+		if *recursive {
+			err = l.fs.RemoveAll(l.name)
+		} else {
+			err = l.fs.Remove(l.name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runCat writes the contents of files to the standard output.
+func runCat(c *cli, args []string) error {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+	args, err := parse(flags, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		l, err := c.locateRemote(arg)
+		if err != nil {
+			return err
+		}
+		f, err := l.fs.Open(l.name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(c.stdout, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSync makes a remote directory match a local one or the reverse.
+func runSync(c *cli, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	opts := &s3fs.SyncOptions{}
+	flags.BoolVar(&opts.Delete, "delete", false, "delete files missing from the source")
+	flags.BoolVar(&opts.Checksum, "checksum", false, "compare MD5 checksums instead of modification times")
+	flags.BoolVar(&opts.DryRun, "dryrun", false, "show what would change without changing it")
+	flags.IntVar(&opts.Concurrency, "concurrency", 0, "parallel transfers (default 8)")
+	args, err := parse(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	src, dst, err := c.locatePair(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	var summary *s3fs.SyncSummary
+	switch {
+	case !src.remote():
+		summary, err = dst.fs.SyncUp(src.name, dst.name, opts)
+	case !dst.remote():
+		summary, err = src.fs.SyncDown(src.name, dst.name, opts)
+	default:
+		return fmt.Errorf("%w: sync needs one local and one s3:// path", errUsage)
+	}
+	if summary != nil {
+		for _, name := range summary.Transferred {
+			fmt.Fprintf(c.stdout, "copy: %s\n", name)
+		}
+		for _, name := range summary.Deleted {
+			fmt.Fprintf(c.stdout, "delete: %s\n", name)
+		}
+		fmt.Fprintf(c.stdout, "%d copied (%d bytes), %d deleted, %d up to date\n",
+			len(summary.Transferred), summary.Bytes, len(summary.Deleted), summary.Skipped)
+	}
+	return err
+}
+
+// runDu reports the storage used below a directory, by direct child.
+func runDu(c *cli, args []string) error {
+	flags := flag.NewFlagSet("du", flag.ContinueOnError)
+	human := flags.Bool("h", false, "print sizes in KiB, MiB and GiB")
+	args, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	dir, err := c.locateRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	usage, err := dir.fs.DiskUsage(dir.name)
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimSuffix(dir.name, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	for _, child := range usage.Children {
+		fmt.Fprintf(c.stdout, "%10s  %8d  %s\n", formatSize(child.Bytes, *human), child.Objects, prefix+child.Name)
+	}
+	fmt.Fprintf(c.stdout, "%10s  %8d  total\n", formatSize(usage.Bytes, *human), usage.Objects)
+	return nil
+}
+
+// runPresign prints a presigned URL for downloading or uploading a file.
+func runPresign(c *cli, args []string) error {
+	flags := flag.NewFlagSet("presign", flag.ContinueOnError)
+	put := flags.Bool("put", false, "presign an upload instead of a download")
+	expiry := flags.Duration("expiry", 15*time.Minute, "validity of the URL")
+	args, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	l, err := c.locateRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	var url string
+	if *put {
+		url, err = l.fs.PresignPut(l.name, *expiry)
+	} else {
+		url, err = l.fs.PresignGet(l.name, *expiry)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, url)
+	return nil
+}
+
+// locatePair locates the source and destination of a transfer, at least one
+// of which must be in S3.
+func (c *cli) locatePair(srcArg, dstArg string) (src, dst location, err error) {
+	if src, err = c.locate(srcArg); err != nil {
+		return src, dst, err
+	}
+	if dst, err = c.locate(dstArg); err != nil {
+		return src, dst, err
+	}
+	if !src.remote() && !dst.remote() {
+		return src, dst, fmt.Errorf("%w: %q and %q are both local", errUsage, srcArg, dstArg)
+	}
+	return src, dst, nil
+}
+
+// target returns the name a file copied from src is stored as in dst: dst
+// itself, or the base name of src inside it if dst is a directory.
+func target(src, dst location) string {
+	dir := dst.dir
+	if !dst.remote() {
+		info, err := os.Stat(dst.name)
+		dir = dir || (err == nil && info.IsDir())
+	}
+	if !dir {
+		return dst.name
+	}
+	base := path.Base(filepath.ToSlash(src.name))
+	if dst.remote() {
+		return dst.name + base
+	}
+	return filepath.Join(dst.name, base)
+}
+
+// copyFile copies the file src to dst.
+func copyFile(src, dst location) error {
+	name := target(src, dst)
+	switch {
+	case !src.remote():
+		return dst.fs.UploadFile(src.name, name, nil)
+	case !dst.remote():
+		return src.fs.DownloadFile(src.name, name, nil)
+	}
+	return src.fs.CopyTo(dst.fs, src.name, name)
+}
+
+// copyDir copies the files below the directory src to the same relative
+// names below dst.
+func copyDir(src, dst location) error {
+	var err error
+	switch {
+	case !src.remote():
+		_, err = dst.fs.UploadDir(src.name, dst.name, nil)
+	case !dst.remote():
+		_, err = src.fs.DownloadDir(src.name, dst.name, nil)
+	case src.fs == dst.fs:
+		_, err = src.fs.CopyAll(src.name, dst.name)
+	default:
+		// Keys are names here: the command uses no Sub filesystems or codecs.
+		prefix := strings.TrimSuffix(src.name, "/") + "/"
+		dstPrefix := strings.TrimSuffix(dst.name, "/") + "/"
+		src.fs.List(prefix)(func(obj s3fs.ObjectInfo, lerr error) bool {
+			if err = lerr; err != nil || strings.HasSuffix(obj.Key, "/") {
+				return err == nil
+			}
+			err = src.fs.CopyTo(dst.fs, obj.Key, dstPrefix+strings.TrimPrefix(obj.Key, prefix))
+			return err == nil
+		})
+	}
+	return err
+}
+
+// formatSize formats n bytes, with a binary unit if human is set.
+func formatSize(n int64, human bool) string {
+	if !human || n < 1024 {
+		return fmt.Sprint(n)
+	}
+	const units = "KMGTPE"
+	v := float64(n) / 1024
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f%ciB", v, units[i])
+}
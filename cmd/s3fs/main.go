@@ -0,0 +1,167 @@
+// Command s3fs runs common operations on S3 buckets with the s3fs package:
+//
+//	s3fs [flags] ls [-l] s3://bucket/dir
+//	s3fs [flags] cp [-r] src dst
+//	s3fs [flags] mv src dst
+//	s3fs [flags] rm [-r] s3://bucket/name
+//	s3fs [flags] cat s3://bucket/name...
+//	s3fs [flags] sync [-delete] [-checksum] [-dryrun] src dst
+//	s3fs [flags] du [-h] s3://bucket/dir
+//	s3fs [flags] presign [-put] [-expiry 15m] s3://bucket/name
+//
+// Remote paths are written as s3://bucket/name; any other path is local.
+// cp copies between local files and S3 and between buckets, with -r
+// copying directories. sync makes the destination directory match the
+// source, where exactly one side is local. mv and the S3 to S3 forms of cp
+// accept different buckets, streaming objects through the client when they
+// are not in the same bucket.
+//
+// Each subcommand is a few calls to the package API, see the run functions
+// below.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/absfs/s3fs"
+)
+
+// commands maps subcommand names to their run functions.
+var commands = map[string]func(c *cli, args []string) error{
+	"ls":      runLs,
+	"cp":      runCp,
+	"mv":      runMv,
+	"rm":      runRm,
+	"cat":     runCat,
+	"sync":    runSync,
+	"du":      runDu,
+	"presign": runPresign,
+}
+
+func main() {
+	var (
+		region    = flag.String("region", "", "AWS region (default from the environment)")
+		endpoint  = flag.String("endpoint", "", "S3 endpoint URL for S3-compatible services")
+		pathStyle = flag.Bool("path-style", false, "use path-style bucket addressing")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: s3fs [flags] ls|cp|mv|rm|cat|sync|du|presign [args]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := &cli{
+		stdout: os.Stdout,
+		open: func(bucket string) (*s3fs.FileSystem, error) {
+			return s3fs.New(&s3fs.Config{
+				Bucket:       bucket,
+				Region:       *region,
+				DetectRegion: *region == "",
+				Endpoint:     *endpoint,
+				UsePathStyle: *pathStyle,
+			})
+		},
+	}
+	if err := c.run(flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "s3fs: %v\n", err)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned for invalid command lines.
+var errUsage = errors.New("usage")
+
+// cli runs subcommands, opening one FileSystem per bucket.
+type cli struct {
+	stdout io.Writer
+	open   func(bucket string) (*s3fs.FileSystem, error)
+	fss    map[string]*s3fs.FileSystem
+}
+
+// run runs the subcommand named by args[0] with the remaining arguments.
+func (c *cli) run(args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+	}
+	return cmd(c, args[1:])
+}
+
+// fs returns the FileSystem of bucket, opening it on first use.
+func (c *cli) fs(bucket string) (*s3fs.FileSystem, error) {
+	if fs, ok := c.fss[bucket]; ok {
+		return fs, nil
+	}
+	fs, err := c.open(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if c.fss == nil {
+		c.fss = make(map[string]*s3fs.FileSystem)
+	}
+	c.fss[bucket] = fs
+	return fs, nil
+}
+
+// location is a command line path: an object name in a bucket, or a local
+// path if fs is nil.
+type location struct {
+	fs   *s3fs.FileSystem
+	name string // Object name for remote locations, local path otherwise
+	dir  bool   // Whether the path ends in a slash
+}
+
+// remote reports whether l is in S3.
+func (l location) remote() bool { return l.fs != nil }
+
+// locate parses an s3://bucket/name URL or a local path.
+func (c *cli) locate(arg string) (location, error) {
+	rest, ok := strings.CutPrefix(arg, "s3://")
+	if !ok {
+		return location{name: arg, dir: strings.HasSuffix(arg, "/")}, nil
+	}
+	bucket, name, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return location{}, fmt.Errorf("%w: no bucket in %q", errUsage, arg)
+	}
+	fs, err := c.fs(bucket)
+	if err != nil {
+		return location{}, err
+	}
+	return location{fs: fs, name: name, dir: name == "" || strings.HasSuffix(name, "/")}, nil
+}
+
+// locateRemote is locate for arguments that must be in S3.
+func (c *cli) locateRemote(arg string) (location, error) {
+	l, err := c.locate(arg)
+	if err == nil && !l.remote() {
+		err = fmt.Errorf("%w: %q is not an s3:// URL", errUsage, arg)
+	}
+	return l, err
+}
+
+// parse parses the flags of a subcommand and checks that it has between
+// min and max arguments, max < 0 meaning any number.
+func parse(flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", errUsage, flags.Name(), err)
+	}
+	n := flags.NArg()
+	if n < min || (max >= 0 && n > max) {
+		return nil, fmt.Errorf("%w: %s: wrong number of arguments", errUsage, flags.Name())
+	}
+	return flags.Args(), nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+// newTestCLI returns a cli opening in-memory buckets.
+func newTestCLI() *cli {
+	return &cli{
+		stdout: io.Discard,
+		open: func(bucket string) (*s3fs.FileSystem, error) {
+			return s3fstest.New(bucket), nil
+		},
+	}
+}
+
+func TestLocate(t *testing.T) {
+	c := newTestCLI()
+	tests := []struct {
+		arg, name string
+		remote    bool
+		dir       bool
+	}{
+		{"s3://bucket/dir/file.txt", "dir/file.txt", true, false},
+		{"s3://bucket/dir/", "dir/", true, true},
+		{"s3://bucket", "", true, true},
+		{"s3://bucket/", "", true, true},
+		{"local/file.txt", "local/file.txt", false, false},
+		{"local/", "local/", false, true},
+	}
+	for _, tt := range tests {
+		l, err := c.locate(tt.arg)
+		if err != nil {
+			t.Fatalf("locate(%q) error = %v", tt.arg, err)
+		}
+		if l.name != tt.name || l.remote() != tt.remote || l.dir != tt.dir {
+			t.Errorf("locate(%q) = %q, remote %v, dir %v; want %q, %v, %v", tt.arg, l.name, l.remote(), l.dir, tt.name, tt.remote, tt.dir)
+		}
+	}
+
+	a, _ := c.locate("s3://bucket/a")
+	b, _ := c.locate("s3://bucket/b")
+	if a.fs != b.fs {
+		t.Error("locate() opened a bucket twice")
+	}
+	if _, err := c.locate("s3:///name"); !errors.Is(err, errUsage) {
+		t.Errorf("locate() without a bucket error = %v, want errUsage", err)
+	}
+	if _, err := c.locateRemote("local/file.txt"); !errors.Is(err, errUsage) {
+		t.Errorf("locateRemote() of a local path error = %v, want errUsage", err)
+	}
+	if _, _, err := c.locatePair("a.txt", "b.txt"); !errors.Is(err, errUsage) {
+		t.Errorf("locatePair() of two local paths error = %v, want errUsage", err)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	c := newTestCLI()
+	dir := t.TempDir()
+	locate := func(arg string) location {
+		l, err := c.locate(arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	tests := []struct {
+		src, dst, want string
+	}{
+		{"s3://bucket/dir/a.txt", "s3://other/b.txt", "b.txt"},
+		{"s3://bucket/dir/a.txt", "s3://other/copies/", "copies/a.txt"},
+		{"s3://bucket/dir/a.txt", "s3://other", "a.txt"},
+		{filepath.Join(dir, "a.txt"), "s3://bucket/up/", "up/a.txt"},
+		{"s3://bucket/dir/a.txt", filepath.Join(dir, "b.txt"), filepath.Join(dir, "b.txt")},
+		{"s3://bucket/dir/a.txt", dir, filepath.Join(dir, "a.txt")},
+		{"s3://bucket/dir/a.txt", filepath.Join(dir, "new") + "/", filepath.Join(dir, "new", "a.txt")},
+	}
+	for _, tt := range tests {
+		if got := target(locate(tt.src), locate(tt.dst)); got != tt.want {
+			t.Errorf("target(%q, %q) = %q, want %q", tt.src, tt.dst, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		n     int64
+		human bool
+		want  string
+	}{
+		{1536, false, "1536"},
+		{0, true, "0"},
+		{1023, true, "1023"},
+		{1024, true, "1.0KiB"},
+		{1536, true, "1.5KiB"},
+		{5 << 20, true, "5.0MiB"},
+		{3 << 30, true, "3.0GiB"},
+		{1 << 62, true, "4.0EiB"},
+	}
+	for _, tt := range tests {
+		if got := formatSize(tt.n, tt.human); got != tt.want {
+			t.Errorf("formatSize(%d, %v) = %q, want %q", tt.n, tt.human, got, tt.want)
+		}
+	}
+}
+
+func TestRun_CopyMoveCat(t *testing.T) {
+	c := newTestCLI()
+	var out bytes.Buffer
+	c.stdout = &out
+	src := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"cp", src, "s3://bucket/dir/"},
+		{"mv", "s3://bucket/dir/a.txt", "s3://other/b.txt"},
+		{"cat", "s3://other/b.txt"},
+	} {
+		if err := c.run(args); err != nil {
+			t.Fatalf("run(%q) error = %v", args, err)
+		}
+	}
+	if out.String() != "hello" {
+		t.Errorf("cat printed %q, want the copied content", out.String())
+	}
+	fs, _ := c.fs("bucket")
+	if ok, err := fs.Exists("dir/a.txt"); ok || err != nil {
+		t.Errorf("Exists(dir/a.txt) after mv = %v, %v, want false", ok, err)
+	}
+}
+
+func TestRun_Unknown(t *testing.T) {
+	if err := newTestCLI().run([]string{"frobnicate"}); !errors.Is(err, errUsage) {
+		t.Errorf("run() of an unknown command error = %v, want errUsage", err)
+	}
+}
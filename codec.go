@@ -0,0 +1,213 @@
+package s3fs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// NameMetadataKey is the user metadata key under which a NameCodec stores
+// the sealed logical name of an object.
+const NameMetadataKey = "s3fs-name"
+
+// NameCodec maps logical file names to the keys stored in S3, so key names
+// don't have to reveal file names. The FileSystem API keeps using logical
+// names; listings recover them from the object metadata written on upload.
+type NameCodec interface {
+	// EncodeKey returns the S3 key for a name without leading slash. It must
+	// be deterministic and map each path element separately, keeping the
+	// slashes (including a trailing one) so prefixes still form directories.
+	EncodeKey(name string) string
+
+	// SealName returns the metadata value that stores name.
+	SealName(name string) (string, error)
+
+	// OpenName recovers the name stored by SealName.
+	OpenName(sealed string) (string, error)
+}
+
+// hmacCodec is the NameCodec returned by NewHMACCodec.
+type hmacCodec struct {
+	hashKey []byte
+	aead    cipher.AEAD
+}
+
+// keyEncoding encodes hashed path elements as lowercase, key-safe text.
+var keyEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// NewHMACCodec returns a NameCodec that replaces every path element with a
+// truncated HMAC-SHA256 of the element and keeps the full name encrypted with
+// AES-GCM in the object metadata. encKey must be 16, 24 or 32 bytes long.
+// Both keys must stay the same for the lifetime of the bucket.
+func NewHMACCodec(hashKey, encKey []byte) (NameCodec, error) {
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &hmacCodec{hashKey: hashKey, aead: aead}, nil
+}
+
+// EncodeKey implements NameCodec.
+func (c *hmacCodec) EncodeKey(name string) string {
+	elems := strings.Split(name, "/")
+	for i, elem := range elems {
+		if elem == "" {
+			continue
+		}
+		mac := hmac.New(sha256.New, c.hashKey)
+		mac.Write([]byte(elem))
+		elems[i] = keyEncoding.EncodeToString(mac.Sum(nil)[:20])
+	}
+	return strings.Join(elems, "/")
+}
+
+// SealName implements NameCodec.
+func (c *hmacCodec) SealName(name string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(name), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// OpenName implements NameCodec.
+func (c *hmacCodec) OpenName(sealed string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < c.aead.NonceSize() {
+		return "", errors.New("s3fs: sealed name too short")
+	}
+	nonce, data := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	name, err := c.aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+// nameTable remembers the logical names of encoded keys.
+type nameTable struct {
+	mu    sync.Mutex
+	names map[string]string
+}
+
+// objectKey returns the S3 key of a logical name.
+func (fs *FileSystem) objectKey(name string) string {
+	name = fs.root + strings.TrimPrefix(name, "/")
+	if fs.normalizer != nil {
+		name = fs.normalizer.String(name)
+		if key, ok := fs.aliasKey(name); ok {
+			return key
+		}
+	}
+	if fs.codec == nil {
+		return name
+	}
+
+	key := fs.codec.EncodeKey(name)
+	fs.rememberName(key, name)
+	return key
+}
+
+// rememberName records the logical name of an encoded key.
+func (fs *FileSystem) rememberName(key, name string) {
+	if fs.names == nil {
+		return
+	}
+	fs.names.mu.Lock()
+	defer fs.names.mu.Unlock()
+	if fs.names.names == nil {
+		fs.names.names = make(map[string]string)
+	}
+	fs.names.names[key] = name
+}
+
+// nameMetadata returns the object metadata that records the logical name of
+// an object written under key, or nil without a NameCodec.
+func (fs *FileSystem) nameMetadata(name string) (map[string]string, error) {
+	if fs.codec == nil {
+		return nil, nil
+	}
+	sealed, err := fs.codec.SealName(fs.root + strings.TrimPrefix(name, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{NameMetadataKey: sealed}, nil
+}
+
+// logicalName returns the logical name of a listed key, relative to the root
+// of a Sub filesystem, in the form of Config.Normalizer if set.
+func (fs *FileSystem) logicalName(key string) (string, error) {
+	name, err := fs.bucketName(key)
+	if err == nil {
+		name = fs.normalizeListed(key, name)
+	}
+	return strings.TrimPrefix(name, fs.root), err
+}
+
+// bucketName returns the name of a listed key relative to the bucket. Without
+// a NameCodec the key is the name; otherwise the name is decoded from the key
+// by a KeyDecoder, or taken from the table of known names or read from the
+// object metadata.
+func (fs *FileSystem) bucketName(key string) (string, error) {
+	if fs.codec == nil {
+		return key, nil
+	}
+	if d, ok := fs.codec.(KeyDecoder); ok {
+		return d.DecodeKey(key)
+	}
+
+	if fs.names != nil {
+		fs.names.mu.Lock()
+		name, ok := fs.names.names[key]
+		fs.names.mu.Unlock()
+		if ok {
+			return name, nil
+		}
+	}
+
+	output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", err
+	}
+	sealed, ok := output.Metadata[NameMetadataKey]
+	if !ok {
+		return "", errors.New("s3fs: object has no name metadata: " + key)
+	}
+	name, err := fs.codec.OpenName(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	// Record the parent directories as well, so listings of other objects
+	// below them resolve without further requests.
+	keys, names := strings.Split(key, "/"), strings.Split(name, "/")
+	if len(keys) == len(names) {
+		for i := 1; i < len(keys); i++ {
+			fs.rememberName(strings.Join(keys[:i], "/")+"/", strings.Join(names[:i], "/")+"/")
+		}
+	}
+	fs.rememberName(key, name)
+	return name, nil
+}
@@ -0,0 +1,76 @@
+package s3fs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHMACCodec(t *testing.T) {
+	codec, err := NewHMACCodec([]byte("hash key"), []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewHMACCodec() error = %v", err)
+	}
+
+	key := codec.EncodeKey("users/alice/cv.pdf")
+	if key != codec.EncodeKey("users/alice/cv.pdf") {
+		t.Errorf("EncodeKey() is not deterministic")
+	}
+	if strings.Contains(key, "alice") || strings.Count(key, "/") != 2 {
+		t.Errorf("EncodeKey() = %q, want three hashed elements", key)
+	}
+	if dir := codec.EncodeKey("users/alice/"); !strings.HasPrefix(key, dir) || !strings.HasSuffix(dir, "/") {
+		t.Errorf("EncodeKey() of the directory %q is not a prefix of %q", dir, key)
+	}
+
+	sealed, err := codec.SealName("users/alice/cv.pdf")
+	if err != nil {
+		t.Fatalf("SealName() error = %v", err)
+	}
+	if strings.Contains(sealed, "alice") {
+		t.Errorf("SealName() leaks the name: %q", sealed)
+	}
+	name, err := codec.OpenName(sealed)
+	if err != nil || name != "users/alice/cv.pdf" {
+		t.Errorf("OpenName() = %q, %v", name, err)
+	}
+
+	tampered := []byte(sealed)
+	if tampered[5] == 'A' {
+		tampered[5] = 'B'
+	} else {
+		tampered[5] = 'A'
+	}
+	if _, err := codec.OpenName(string(tampered)); err == nil {
+		t.Errorf("OpenName() accepted a tampered value")
+	}
+	if _, err := NewHMACCodec(nil, []byte("short")); err == nil {
+		t.Errorf("NewHMACCodec() accepted an invalid encryption key")
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	fs := &FileSystem{}
+	if got := fs.objectKey("/a/b.txt"); got != "a/b.txt" {
+		t.Errorf("objectKey() without codec = %q, want a/b.txt", got)
+	}
+
+	codec, _ := NewHMACCodec([]byte("k"), make([]byte, 32))
+	fs = &FileSystem{codec: codec, names: &nameTable{}}
+	key := fs.objectKey("/a/b.txt")
+	if key != codec.EncodeKey("a/b.txt") {
+		t.Errorf("objectKey() = %q, want the encoded key", key)
+	}
+
+	// Keys produced by objectKey resolve without a request.
+	if name, err := fs.logicalName(key); err != nil || name != "a/b.txt" {
+		t.Errorf("logicalName() = %q, %v", name, err)
+	}
+
+	metadata, err := fs.nameMetadata("/a/b.txt")
+	if err != nil || metadata[NameMetadataKey] == "" {
+		t.Fatalf("nameMetadata() = %v, %v", metadata, err)
+	}
+	if name, _ := codec.OpenName(metadata[NameMetadataKey]); name != "a/b.txt" {
+		t.Errorf("nameMetadata() sealed %q, want a/b.txt", name)
+	}
+}
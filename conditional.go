@@ -0,0 +1,87 @@
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// writeCondition holds the HTTP preconditions sent with an upload.
+type writeCondition struct {
+	ifMatch     string
+	ifNoneMatch string
+}
+
+// options returns the client options that add the precondition headers.
+func (c writeCondition) options() []func(*s3.Options) {
+	var opts []func(*s3.Options)
+	if c.ifMatch != "" {
+		opts = append(opts, s3.WithAPIOptions(smithyhttp.AddHeaderValue("If-Match", c.ifMatch)))
+	}
+	if c.ifNoneMatch != "" {
+		opts = append(opts, s3.WithAPIOptions(smithyhttp.AddHeaderValue("If-None-Match", c.ifNoneMatch)))
+	}
+	return opts
+}
+
+// preconditionError converts a failed precondition into ErrPreconditionFailed,
+// keeping the original error in the chain.
+func preconditionError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr smithy.APIError
+	if httpStatus(err) == 412 || (errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed") {
+		return fmt.Errorf("%w: %w", ErrPreconditionFailed, err)
+	}
+	return err
+}
+
+// WriteIfMatch makes Close upload the file only if the object's current ETag
+// is etag, so a writer that read an object can detect that another writer
+// replaced it in the meantime. A lost race makes Close fail with
+// ErrPreconditionFailed. The S3 service or S3-compatible store must support
+// conditional writes.
+func (f *File) WriteIfMatch(etag string) error {
+	if !f.writing {
+		return ErrWriteOnReadFile
+	}
+	f.cond.ifMatch = etag
+	return nil
+}
+
+// WriteIfNotExists makes Close upload the file only if no object exists under
+// its key yet. If one does, Close fails with ErrPreconditionFailed.
+func (f *File) WriteIfNotExists() error {
+	if !f.writing {
+		return ErrWriteOnReadFile
+	}
+	f.cond.ifNoneMatch = "*"
+	return nil
+}
+
+// ETag returns the ETag of the object uploaded by Close, or an empty string
+// if the file has not been uploaded.
+func (f *File) ETag() string {
+	return f.etag
+}
+
+// ETag returns the current ETag of the named object, for use with WriteIfMatch.
+func (fs *FileSystem) ETag(name string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+
+	output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.objectKey(name)),
+	})
+	if err != nil {
+		return "", fs.wrapError("ETag", name, err)
+	}
+	return aws.ToString(output.ETag), nil
+}
@@ -0,0 +1,53 @@
+package s3fs
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func TestWriteCondition_Options(t *testing.T) {
+	if n := len(writeCondition{}.options()); n != 0 {
+		t.Errorf("options() without conditions = %d, want 0", n)
+	}
+	if n := len(writeCondition{ifMatch: `"abc"`, ifNoneMatch: "*"}.options()); n != 2 {
+		t.Errorf("options() with both conditions = %d, want 2", n)
+	}
+}
+
+func TestFile_WriteIfMatch(t *testing.T) {
+	f := &File{writing: true}
+	if err := f.WriteIfMatch(`"abc"`); err != nil || f.cond.ifMatch != `"abc"` {
+		t.Errorf("WriteIfMatch() = %v, cond = %+v", err, f.cond)
+	}
+	if err := f.WriteIfNotExists(); err != nil || f.cond.ifNoneMatch != "*" {
+		t.Errorf("WriteIfNotExists() = %v, cond = %+v", err, f.cond)
+	}
+
+	r := &File{}
+	if err := r.WriteIfMatch(`"abc"`); err != ErrWriteOnReadFile {
+		t.Errorf("WriteIfMatch() on read file = %v, want ErrWriteOnReadFile", err)
+	}
+}
+
+func TestPreconditionError(t *testing.T) {
+	cause := &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusPreconditionFailed}},
+		Err:      errors.New("PreconditionFailed"),
+	}
+
+	err := preconditionError(cause)
+	if !errors.Is(err, ErrPreconditionFailed) || !errors.Is(err, cause) {
+		t.Errorf("preconditionError() = %v, want ErrPreconditionFailed wrapping the cause", err)
+	}
+
+	other := errors.New("boom")
+	if err := preconditionError(other); err != other {
+		t.Errorf("preconditionError() changed an unrelated error: %v", err)
+	}
+	if preconditionError(nil) != nil {
+		t.Errorf("preconditionError(nil) != nil")
+	}
+}
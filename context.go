@@ -4,12 +4,11 @@ import "context"
 
 // WithContext returns a new FileSystem that uses the given context for all operations.
 // This allows for cancellation and timeout control of S3 operations.
+// The returned FileSystem shares the client, configuration and caches of fs.
 func (fs *FileSystem) WithContext(ctx context.Context) *FileSystem {
-	return &FileSystem{
-		client: fs.client,
-		bucket: fs.bucket,
-		ctx:    ctx,
-	}
+	c := *fs
+	c.ctx = ctx
+	return &c
 }
 
 // Context returns the context used by the filesystem.
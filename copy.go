@@ -0,0 +1,433 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// MaxCopyObjectSize is the largest object a single CopyObject request can
+	// copy (5GB). Larger objects are copied with a multipart copy.
+	MaxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+	// CopyPartSize is the part size used for multipart copies (512MB).
+	CopyPartSize = 512 * 1024 * 1024
+
+	// DefaultCopyConcurrency is the number of objects CopyAll copies in parallel.
+	DefaultCopyConcurrency = 8
+)
+
+// CopyOptions overrides attributes of the copy made by RenameWithOptions.
+// Zero values keep the attribute of the source object.
+type CopyOptions struct {
+	ContentType string            // MIME type of the content
+	Metadata    map[string]string // User metadata replacing that of the source, if not nil
+	Tags        map[string]string // Tags replacing those of the source, if not nil
+
+	StorageClass         string // Storage class, such as "STANDARD_IA"
+	ServerSideEncryption string // Encryption algorithm, such as "AES256" or "aws:kms"
+	SSEKMSKeyID          string // KMS key for "aws:kms" encryption
+}
+
+// copyJob is one object copied by CopyAll.
+type copyJob struct {
+	srcKey, dstName string
+	size            int64
+}
+
+// CopyAll copies every object below the directory srcPrefix to the same
+// relative name below dstPrefix, using server-side copies so no data passes
+// through the client. Objects are copied in parallel; objects larger than
+// MaxCopyObjectSize are copied with a multipart copy. The copies keep the
+// metadata, tags, storage class and encryption settings of their sources,
+// which costs a HeadObject request per object. The prefixes must not
+// contain each other. It returns the number of objects copied.
+func (fs *FileSystem) CopyAll(srcPrefix, dstPrefix string) (int, error) {
+	src := strings.Trim(srcPrefix, "/") + "/"
+	dst := strings.Trim(dstPrefix, "/") + "/"
+	if src == "/" || dst == "/" {
+		return 0, fs.wrapError("CopyAll", srcPrefix, ErrRootPrefix)
+	}
+	if strings.HasPrefix(src, dst) || strings.HasPrefix(dst, src) {
+		return 0, fs.wrapError("CopyAll", srcPrefix, ErrPrefixOverlap)
+	}
+
+	jobs, err := fs.listCopyJobs(src, dst)
+	if err != nil {
+		return 0, fs.wrapError("CopyAll", srcPrefix, err)
+	}
+
+	copied, err := fs.copyJobs(jobs, nil)
+	for _, job := range jobs[:copied] {
+		key := fs.objectKey(job.dstName)
+		fs.stats.invalidate(key)
+		if merr := fs.manifestPut(key, job.size, strings.HasSuffix(key, "/")); merr != nil && err == nil {
+			err = merr
+		}
+	}
+	if err != nil {
+		return copied, fs.wrapError("CopyAll", srcPrefix, err)
+	}
+	return len(jobs), nil
+}
+
+// listCopyJobs lists the objects below the directory src (with trailing
+// slash) and maps each to the same relative name below dst.
+func (fs *FileSystem) listCopyJobs(src, dst string) ([]copyJob, error) {
+	var jobs []copyJob
+	var continuationToken *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(fs.objectKey(src)),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			if fs.isSystemKey(key) {
+				continue
+			}
+			name, err := fs.logicalName(key)
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, copyJob{
+				srcKey:  key,
+				dstName: dst + strings.TrimPrefix(name, src),
+				size:    aws.ToInt64(obj.Size),
+			})
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+	return jobs, nil
+}
+
+// copyJobs runs the copies with DefaultCopyConcurrency workers and stops at
+// the first error, reporting the bytes copied so far to progress (if not nil)
+// after each object. Jobs are reordered so that the first n returned were
+// copied successfully.
+func (fs *FileSystem) copyJobs(jobs []copyJob, progress ProgressFunc) (int, error) {
+	ctx, cancel := context.WithCancel(fs.ctx)
+	defer cancel()
+
+	work := make(chan int)
+	done := make([]bool, len(jobs))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	var mu sync.Mutex
+	var copied int64
+
+	for i := 0; i < DefaultCopyConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				job := jobs[i]
+				if err := fs.copyJob(ctx, job); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				done[i] = true
+				if progress != nil {
+					mu.Lock()
+					copied += job.size
+					progress(copied)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range jobs {
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	n := 0
+	for i := range jobs {
+		if done[i] {
+			jobs[n], jobs[i] = jobs[i], jobs[n]
+			n++
+		}
+	}
+	if firstErr == nil {
+		firstErr = fs.ctx.Err()
+	}
+	return n, firstErr
+}
+
+// copyJob copies one object of CopyAll or RenameDir with its attributes.
+func (fs *FileSystem) copyJob(ctx context.Context, job copyJob) error {
+	head, err := fs.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(job.srcKey),
+	})
+	if err != nil {
+		return err
+	}
+	metadata, err := fs.copyMetadata(job.dstName, head.Metadata)
+	if err != nil {
+		return err
+	}
+	return fs.copyKey(ctx, job.srcKey, fs.objectKey(job.dstName), head, metadata, nil)
+}
+
+// copyMetadata returns the metadata of a copy of an object with the given
+// metadata to the logical name dstName, which differs with a NameCodec.
+func (fs *FileSystem) copyMetadata(dstName string, metadata map[string]string) (map[string]string, error) {
+	names, err := fs.nameMetadata(dstName)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(metadata)+len(names))
+	for k, v := range metadata {
+		out[k] = v
+	}
+	for k, v := range names {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// copyKey copies the object src, described by head, to dst within the bucket
+// with the given metadata. CopyObject resets the storage class and
+// encryption of the copy to the bucket defaults, and a multipart copy also
+// drops the content headers and tags, so they are set from head, or from
+// opts where it sets them. opts.Metadata is left to the caller.
+func (fs *FileSystem) copyKey(ctx context.Context, src, dst string, head *s3.HeadObjectOutput, metadata map[string]string, opts *CopyOptions) error {
+	return fs.copyKeyIf(ctx, src, dst, head, metadata, opts, writeCondition{})
+}
+
+// copyKeyIf is copyKey with the write preconditions cond on dst.
+func (fs *FileSystem) copyKeyIf(ctx context.Context, src, dst string, head *s3.HeadObjectOutput, metadata map[string]string, opts *CopyOptions, cond writeCondition) error {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	contentType := head.ContentType
+	if opts.ContentType != "" {
+		contentType = aws.String(opts.ContentType)
+	}
+	storageClass := head.StorageClass
+	if opts.StorageClass != "" {
+		storageClass = types.StorageClass(opts.StorageClass)
+	}
+	sse, kmsKeyID := head.ServerSideEncryption, head.SSEKMSKeyId
+	if opts.ServerSideEncryption != "" {
+		sse, kmsKeyID = types.ServerSideEncryption(opts.ServerSideEncryption), nil
+	}
+	if opts.SSEKMSKeyID != "" {
+		kmsKeyID = aws.String(opts.SSEKMSKeyID)
+	}
+	var tagging *string
+	if opts.Tags != nil {
+		tagging = aws.String(encodeTags(opts.Tags))
+	}
+
+	size := aws.ToInt64(head.ContentLength)
+	if size <= MaxCopyObjectSize {
+		input := &s3.CopyObjectInput{
+			Bucket:               aws.String(fs.bucket),
+			CopySource:           aws.String(fs.copySource(src)),
+			Key:                  aws.String(dst),
+			ContentType:          contentType,
+			CacheControl:         head.CacheControl,
+			ContentEncoding:      head.ContentEncoding,
+			ContentDisposition:   head.ContentDisposition,
+			Expires:              head.Expires,
+			Metadata:             metadata,
+			MetadataDirective:    types.MetadataDirectiveReplace,
+			StorageClass:         storageClass,
+			ServerSideEncryption: sse,
+			SSEKMSKeyId:          kmsKeyID,
+		}
+		if tagging != nil {
+			input.Tagging = tagging
+			input.TaggingDirective = types.TaggingDirectiveReplace
+		}
+		_, err := fs.client.CopyObject(ctx, input, cond.options()...)
+		return err
+	}
+
+	if tagging == nil {
+		var err error
+		if tagging, err = fs.objectTagging(ctx, src); err != nil {
+			return err
+		}
+	}
+	output, err := fs.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(fs.bucket),
+		Key:                  aws.String(dst),
+		ContentType:          contentType,
+		CacheControl:         head.CacheControl,
+		ContentEncoding:      head.ContentEncoding,
+		ContentDisposition:   head.ContentDisposition,
+		Expires:              head.Expires,
+		Metadata:             metadata,
+		StorageClass:         storageClass,
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
+		Tagging:              tagging,
+	})
+	if err != nil {
+		return err
+	}
+	mu := &MultipartUpload{
+		fs:         fs.WithContext(ctx),
+		key:        dst,
+		uploadID:   aws.ToString(output.UploadId),
+		partNumber: 1,
+		partSize:   DefaultPartSize,
+		cond:       cond,
+	}
+	fs.shutdown.track(mu)
+	return mu.copyFrom(fs.copySource(src), size)
+}
+
+// objectTagging returns the tags of the object stored under key in the
+// URL-encoded form of the Tagging header, or nil if it has none.
+func (fs *FileSystem) objectTagging(ctx context.Context, key string) (*string, error) {
+	output, err := fs.client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.TagSet) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return aws.String(encodeTags(tags)), nil
+}
+
+// encodeTags returns tags in the URL-encoded form of the Tagging header.
+func encodeTags(tags map[string]string) string {
+	values := make(url.Values, len(tags))
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// copySource returns the CopySource value for key in the bucket, with the
+// key URL-encoded as S3 requires. Access point and Multi-Region Access Point
+// ARNs address objects as "<arn>/object/<key>".
+func (fs *FileSystem) copySource(key string) string {
+	if arn.IsARN(fs.bucket) {
+		return fs.bucket + "/object/" + escapeKey(key)
+	}
+	return fs.bucket + "/" + escapeKey(key)
+}
+
+// escapeKey URL-encodes each segment of key, keeping the slashes between
+// them. A plus sign is escaped too, as S3 would decode it to a space.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(segment), "+", "%2B")
+	}
+	return strings.Join(segments, "/")
+}
+
+// copyObject copies the object source (a CopySource value) of the given size
+// to the logical name dstName with a server-side copy, using a multipart copy
+// for objects larger than MaxCopyObjectSize.
+func (fs *FileSystem) copyObject(ctx context.Context, source, dstName string, size int64) error {
+	if size > MaxCopyObjectSize {
+		return fs.multipartCopy(ctx, source, dstName, size)
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(fs.bucket),
+		CopySource: aws.String(source),
+		Key:        aws.String(fs.objectKey(dstName)),
+	}
+	if fs.codec != nil {
+		metadata, err := fs.nameMetadata(dstName)
+		if err != nil {
+			return err
+		}
+		input.Metadata = metadata
+		input.MetadataDirective = types.MetadataDirectiveReplace
+	}
+	_, err := fs.client.CopyObject(ctx, input)
+	return err
+}
+
+// multipartCopy copies objects larger than MaxCopyObjectSize in parts of
+// CopyPartSize with UploadPartCopy.
+func (fs *FileSystem) multipartCopy(ctx context.Context, source, dstName string, size int64) error {
+	mu, err := fs.WithContext(ctx).NewMultipartUpload(dstName)
+	if err != nil {
+		return err
+	}
+	return mu.copyFrom(source, size)
+}
+
+// copyFrom copies the object source (a CopySource value) of the given size
+// in parts of CopyPartSize and completes the upload, or aborts it on failure.
+func (mu *MultipartUpload) copyFrom(source string, size int64) error {
+	for off := int64(0); off < size; off += CopyPartSize {
+		end := off + CopyPartSize
+		if end > size {
+			end = size
+		}
+		if err := mu.uploadPartCopy(source, off, end-1); err != nil {
+			mu.Abort()
+			return err
+		}
+	}
+	return mu.Complete()
+}
+
+// uploadPartCopy adds the bytes first to last of the object source (a
+// CopySource value) as the next part of the upload.
+func (mu *MultipartUpload) uploadPartCopy(source string, first, last int64) error {
+	output, err := mu.fs.client.UploadPartCopy(mu.fs.ctx, &s3.UploadPartCopyInput{
+		Bucket:          aws.String(mu.fs.bucket),
+		Key:             aws.String(mu.key),
+		UploadId:        aws.String(mu.uploadID),
+		PartNumber:      aws.Int32(mu.partNumber),
+		CopySource:      aws.String(source),
+		CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", first, last)),
+	})
+	if err != nil {
+		return mu.fs.wrapError("UploadPartCopy", mu.key, err)
+	}
+
+	mu.parts = append(mu.parts, types.CompletedPart{
+		ETag:       output.CopyPartResult.ETag,
+		PartNumber: aws.Int32(mu.partNumber),
+	})
+	mu.partNumber++
+	return nil
+}
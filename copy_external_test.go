@@ -0,0 +1,27 @@
+package s3fs_test
+
+import (
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestRename_EscapedKeys(t *testing.T) {
+	codec, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: s3fstest.NewClient(), NameCodec: s3fs.NewPercentCodec()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fs := range []*s3fs.FileSystem{s3fstest.New("bucket"), codec} {
+		writeFile(t, fs, "my file 100%+1.txt", "content")
+		if err := fs.Rename("my file 100%+1.txt", "b.txt"); err != nil {
+			t.Fatalf("Rename() error = %v", err)
+		}
+		if got := readFile(t, fs, "b.txt"); got != "content" {
+			t.Errorf("b.txt after Rename = %q, want the renamed content", got)
+		}
+		if ok, err := fs.Exists("my file 100%+1.txt"); ok || err != nil {
+			t.Errorf("Exists() of the old name = %v, %v, want false", ok, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestCopyAll_InvalidPrefixes(t *testing.T) {
+	fs := &FileSystem{ctx: context.Background()}
+
+	tests := []struct {
+		src, dst string
+		want     error
+	}{
+		{"", "backup", ErrRootPrefix},
+		{"data", "/", ErrRootPrefix},
+		{"data", "data/backup", ErrPrefixOverlap},
+		{"data/set/", "data", ErrPrefixOverlap},
+		{"data", "/data/", ErrPrefixOverlap},
+	}
+	for _, tt := range tests {
+		if _, err := fs.CopyAll(tt.src, tt.dst); !errors.Is(err, tt.want) {
+			t.Errorf("CopyAll(%q, %q) error = %v, want %v", tt.src, tt.dst, err, tt.want)
+		}
+	}
+}
+
+func TestCopyJobs_Empty(t *testing.T) {
+	fs := &FileSystem{ctx: context.Background()}
+	if n, err := fs.copyJobs(nil, nil); n != 0 || err != nil {
+		t.Errorf("copyJobs(nil) = %d, %v", n, err)
+	}
+}
+
+func TestCopySource(t *testing.T) {
+	tests := []struct {
+		bucket, key, want string
+	}{
+		{"bucket", "dir/file.bin", "bucket/dir/file.bin"},
+		{"bucket", "my dir/100%+1?#.txt", "bucket/my%20dir/100%25%2B1%3F%23.txt"},
+		{"bucket", "dir/ünïcode.txt", "bucket/dir/%C3%BCn%C3%AFcode.txt"},
+		{"arn:aws:s3:us-west-2:123456789012:accesspoint/my-ap", "dir/file.txt", "arn:aws:s3:us-west-2:123456789012:accesspoint/my-ap/object/dir/file.txt"},
+		{"arn:aws:s3::123456789012:accesspoint/mfzwi23gnjvgw.mrap", "file.txt", "arn:aws:s3::123456789012:accesspoint/mfzwi23gnjvgw.mrap/object/file.txt"},
+		{"arn:aws:s3:us-west-2:123456789012:accesspoint/my-ap", "my dir/100%.txt", "arn:aws:s3:us-west-2:123456789012:accesspoint/my-ap/object/my%20dir/100%25.txt"},
+	}
+	for _, tt := range tests {
+		fs := &FileSystem{bucket: tt.bucket}
+		if got := fs.copySource(tt.key); got != tt.want {
+			t.Errorf("copySource(%q) in %s = %q, want %q", tt.key, tt.bucket, got, tt.want)
+		}
+	}
+}
+
+// multipartCopyStub records the CreateMultipartUpload request of a copy and
+// fails it.
+type multipartCopyStub struct {
+	Client
+	create *s3.CreateMultipartUploadInput
+}
+
+func (c *multipartCopyStub) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return &s3.GetObjectTaggingOutput{TagSet: []types.Tag{{Key: aws.String("team"), Value: aws.String("a b")}}}, nil
+}
+
+func (c *multipartCopyStub) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	c.create = params
+	return nil, errors.New("stop")
+}
+
+func TestCopyKey_MultipartKeepsAttributes(t *testing.T) {
+	client := &multipartCopyStub{}
+	fs := &FileSystem{bucket: "bucket", client: client, ctx: context.Background()}
+	head := &s3.HeadObjectOutput{
+		ContentLength:        aws.Int64(MaxCopyObjectSize + 1),
+		ContentType:          aws.String("video/mp4"),
+		StorageClass:         types.StorageClassStandardIa,
+		ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+		SSEKMSKeyId:          aws.String("key-1"),
+	}
+
+	fs.copyKey(fs.ctx, "src", "dst", head, nil, nil)
+	in := client.create
+	if in == nil {
+		t.Fatal("copyKey() did not start a multipart upload")
+	}
+	if aws.ToString(in.ContentType) != "video/mp4" || in.StorageClass != types.StorageClassStandardIa ||
+		in.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(in.SSEKMSKeyId) != "key-1" {
+		t.Errorf("CreateMultipartUpload() = %+v, want the attributes of the source", in)
+	}
+	if aws.ToString(in.Tagging) != "team=a+b" {
+		t.Errorf("CreateMultipartUpload() Tagging = %q, want team=a+b", aws.ToString(in.Tagging))
+	}
+
+	fs.copyKey(fs.ctx, "src", "dst", head, nil, &CopyOptions{StorageClass: "GLACIER", ServerSideEncryption: "AES256"})
+	if in := client.create; in.StorageClass != types.StorageClassGlacier || in.ServerSideEncryption != types.ServerSideEncryptionAes256 || in.SSEKMSKeyId != nil {
+		t.Errorf("CreateMultipartUpload() with options = %+v", in)
+	}
+}
@@ -0,0 +1,67 @@
+package s3fs
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// CopyTo copies the named file of fs to dstName in dst, which may use
+// another bucket, region or endpoint, or be fs itself. Like Mirror, the
+// object is copied server-side when both FileSystems share a client, and so
+// credentials and region, and streamed through the client otherwise, without
+// staging it locally. A refused server-side copy also falls back to
+// streaming.
+func (fs *FileSystem) CopyTo(dst *FileSystem, srcName, dstName string) error {
+	srcName = strings.TrimPrefix(srcName, "/")
+	dstName = strings.TrimPrefix(dstName, "/")
+	if err := fs.copyTo(dst, srcName, dstName); err != nil {
+		return fs.wrapError("CopyTo", srcName, err)
+	}
+	return nil
+}
+
+// MoveTo moves the named file of fs to dstName in dst with CopyTo, then
+// removes the original. If the original cannot be removed, the copy is
+// deleted again so the file is not left in both places.
+func (fs *FileSystem) MoveTo(dst *FileSystem, srcName, dstName string) error {
+	srcName = strings.TrimPrefix(srcName, "/")
+	dstName = strings.TrimPrefix(dstName, "/")
+	if err := fs.copyTo(dst, srcName, dstName); err != nil {
+		return fs.wrapError("MoveTo", srcName, err)
+	}
+	if err := fs.Remove(srcName); err != nil {
+		key := dst.objectKey(dstName)
+		dst.client.DeleteObject(dst.ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(dst.bucket),
+			Key:    aws.String(key),
+		})
+		dst.stats.invalidate(key)
+		dst.manifestDelete(key)
+		return fs.wrapError("MoveTo", srcName, err)
+	}
+	return nil
+}
+
+// copyTo implements CopyTo.
+func (fs *FileSystem) copyTo(dst *FileSystem, srcName, dstName string) error {
+	key := fs.objectKey(srcName)
+	sameClient := fs.baseClient() == dst.baseClient()
+	if sameClient && fs.bucket == dst.bucket && key == dst.objectKey(dstName) {
+		return ErrSameObject
+	}
+
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if httpStatus(err) == 404 {
+			return ErrNotExist
+		}
+		return err
+	}
+	m := &mirror{src: fs, dst: dst, serverSide: sameClient}
+	return m.transfer(fs.ctx, copyJob{srcKey: key, dstName: dstName, size: aws.ToInt64(head.ContentLength)})
+}
@@ -0,0 +1,65 @@
+package s3fs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_CopyTo(t *testing.T) {
+	client := s3fstest.NewClient()
+	src, err := s3fs.New(&s3fs.Config{Bucket: "src", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := s3fs.New(&s3fs.Config{Bucket: "dst", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := s3fstest.New("other")
+	if err := src.WriteFile("a/file.txt", []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A shared client copies server-side
+	if err := src.CopyTo(shared, "a/file.txt", "b/copy.txt"); err != nil {
+		t.Fatalf("CopyTo() error = %v", err)
+	}
+	if got, ok := client.Object("dst", "b/copy.txt"); !ok || string(got) != "content" {
+		t.Errorf("copy = %q, %v; want content", got, ok)
+	}
+	if n := shared.Stats().Operations["CopyObject"].Requests; n != 1 {
+		t.Errorf("CopyObject requests = %d, want 1", n)
+	}
+
+	// Another client gets the content streamed
+	if err := src.CopyTo(other, "/a/file.txt", "/copy.txt"); err != nil {
+		t.Fatalf("CopyTo() to another client error = %v", err)
+	}
+	if got, err := other.ReadFile("copy.txt"); err != nil || string(got) != "content" {
+		t.Errorf("streamed copy = %q, %v; want content", got, err)
+	}
+	if n := other.Stats().Operations["CopyObject"].Requests; n != 0 {
+		t.Errorf("CopyObject requests of streaming copy = %d, want 0", n)
+	}
+
+	if err := src.MoveTo(other, "a/file.txt", "moved.txt"); err != nil {
+		t.Fatalf("MoveTo() error = %v", err)
+	}
+	if _, err := src.Stat("a/file.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() of the moved file error = %v, want not exist", err)
+	}
+	if got, err := other.ReadFile("moved.txt"); err != nil || string(got) != "content" {
+		t.Errorf("moved file = %q, %v; want content", got, err)
+	}
+
+	if err := src.CopyTo(other, "a/file.txt", "x.txt"); !errors.Is(err, s3fs.ErrNotExist) {
+		t.Errorf("CopyTo() of a missing file error = %v, want ErrNotExist", err)
+	}
+	if err := shared.CopyTo(shared, "b/copy.txt", "b/copy.txt"); !errors.Is(err, s3fs.ErrSameObject) {
+		t.Errorf("CopyTo() onto itself error = %v, want ErrSameObject", err)
+	}
+}
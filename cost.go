@@ -0,0 +1,205 @@
+package s3fs
+
+import (
+	"context"
+	"sync"
+)
+
+// RequestClass groups S3 operations by the way S3 bills their requests.
+type RequestClass int
+
+const (
+	ClassFree RequestClass = iota // DELETE and abort requests
+	ClassA                        // PUT, COPY, POST and LIST requests
+	ClassB                        // GET, HEAD and all other requests
+)
+
+// requestClasses lists the operations not in ClassB.
+var requestClasses = map[string]RequestClass{
+	"PutObject":                       ClassA,
+	"CopyObject":                      ClassA,
+	"ListObjectsV2":                   ClassA,
+	"ListObjectVersions":              ClassA,
+	"RestoreObject":                   ClassA,
+	"PutObjectRetention":              ClassA,
+	"PutObjectLegalHold":              ClassA,
+	"CreateMultipartUpload":           ClassA,
+	"UploadPart":                      ClassA,
+	"UploadPartCopy":                  ClassA,
+	"CompleteMultipartUpload":         ClassA,
+	"ListMultipartUploads":            ClassA,
+	"PutBucketLifecycleConfiguration": ClassA,
+	"DeleteObject":                    ClassFree,
+	"DeleteObjects":                   ClassFree,
+	"AbortMultipartUpload":            ClassFree,
+	"DeleteBucketLifecycle":           ClassFree,
+}
+
+// OperationClass returns the request class of an S3 API operation, such as
+// "ListObjectsV2", as named in Stats.
+func OperationClass(op string) RequestClass {
+	if class, ok := requestClasses[op]; ok {
+		return class
+	}
+	return ClassB
+}
+
+// Pricing holds the prices Costs and Budget estimate with, see
+// Config.Pricing.
+type Pricing struct {
+	ClassA      float64 // Dollars per 1000 class A requests
+	ClassB      float64 // Dollars per 1000 class B requests
+	TransferOut float64 // Dollars per GiB downloaded
+}
+
+// DefaultPricing is the price list of S3 Standard in us-east-1, with data
+// transfer to the internet.
+var DefaultPricing = Pricing{
+	ClassA:      0.005,
+	ClassB:      0.0004,
+	TransferOut: 0.09,
+}
+
+// request returns the price of one request of class.
+func (p Pricing) request(class RequestClass) float64 {
+	switch class {
+	case ClassA:
+		return p.ClassA / 1000
+	case ClassB:
+		return p.ClassB / 1000
+	}
+	return 0
+}
+
+// transfer returns the price of downloading n bytes.
+func (p Pricing) transfer(n int64) float64 {
+	return float64(n) / (1 << 30) * p.TransferOut
+}
+
+// Costs is an estimate of what the requests of a FileSystem cost, returned
+// by FileSystem.Costs.
+type Costs struct {
+	ClassA          int64   // Class A requests
+	ClassB          int64   // Class B requests
+	Free            int64   // Requests S3 does not bill
+	BytesDownloaded int64   // Response bodies read by callers
+	Dollars         float64 // Estimated price of the requests and downloads
+}
+
+// Costs estimates what the requests in s cost with prices p.
+func (s Stats) Costs(p Pricing) Costs {
+	c := Costs{BytesDownloaded: s.BytesDownloaded}
+	for op, o := range s.Operations {
+		class := OperationClass(op)
+		switch class {
+		case ClassA:
+			c.ClassA += o.Requests
+		case ClassB:
+			c.ClassB += o.Requests
+		default:
+			c.Free += o.Requests
+		}
+		c.Dollars += float64(o.Requests) * p.request(class)
+	}
+	c.Dollars += p.transfer(s.BytesDownloaded)
+	return c
+}
+
+// Costs returns the requests the filesystem made by class and their
+// estimated price, with the prices of Config.Pricing.
+func (fs *FileSystem) Costs() Costs {
+	return fs.Stats().Costs(fs.pricing)
+}
+
+// Budget limits the estimated price of the requests made through a
+// FileSystem returned by WithBudget, so a single operation such as RemoveAll
+// on the wrong prefix cannot run up an unexpected bill. Downloads are
+// charged by the size S3 reports when a GetObject response arrives. A Budget
+// may be shared by several filesystems and goroutines; it must not be
+// copied after first use.
+type Budget struct {
+	// Limit is the price in dollars the requests may cost.
+	Limit float64
+
+	// OnExceeded, if set, is called once when the spending first exceeds
+	// Limit, and requests keep being made. Otherwise requests that would
+	// exceed Limit fail with ErrBudgetExceeded without being sent.
+	OnExceeded func(spent float64)
+
+	// Pricing overrides the prices of the FileSystem (Config.Pricing).
+	Pricing *Pricing
+
+	mu       sync.Mutex
+	spent    float64
+	exceeded bool
+}
+
+// Spent returns the estimated price of the requests charged to b so far.
+func (b *Budget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+// charge adds price to the spending. Unless force is set it fails if the
+// budget aborts and the price would exceed the limit; OnExceeded is called
+// when the limit is exceeded first.
+func (b *Budget) charge(price float64, force bool) error {
+	b.mu.Lock()
+	if !force && b.OnExceeded == nil && b.spent+price > b.Limit {
+		b.mu.Unlock()
+		return ErrBudgetExceeded
+	}
+	b.spent += price
+	warn := b.OnExceeded != nil && !b.exceeded && b.spent > b.Limit
+	if warn {
+		b.exceeded = true
+	}
+	spent := b.spent
+	b.mu.Unlock()
+
+	if warn {
+		b.OnExceeded(spent)
+	}
+	return nil
+}
+
+// budgetKey is the context key of the budget of WithBudget.
+type budgetKey struct{}
+
+// budgetScope is a Budget with the prices it charges.
+type budgetScope struct {
+	b       *Budget
+	pricing Pricing
+}
+
+// WithBudget returns a shallow copy of fs whose requests are charged to b.
+// Like WithContext, the budget applies to the requests the copy makes; it is
+// carried in its context, so operations that fan out over goroutines are
+// charged in full.
+func (fs *FileSystem) WithBudget(b *Budget) *FileSystem {
+	pricing := fs.pricing
+	if b.Pricing != nil {
+		pricing = *b.Pricing
+	}
+	return fs.WithContext(context.WithValue(fs.ctx, budgetKey{}, &budgetScope{b: b, pricing: pricing}))
+}
+
+// chargeRequest charges a request of op to the budget of ctx, if any.
+func chargeRequest(ctx context.Context, op string) error {
+	s, ok := ctx.Value(budgetKey{}).(*budgetScope)
+	if !ok {
+		return nil
+	}
+	return s.b.charge(s.pricing.request(OperationClass(op)), false)
+}
+
+// chargeTransfer charges the download of n bytes to the budget of ctx, if
+// any. The response has arrived, so the charge cannot be refused.
+func chargeTransfer(ctx context.Context, n int64) {
+	s, ok := ctx.Value(budgetKey{}).(*budgetScope)
+	if !ok || n <= 0 {
+		return
+	}
+	s.b.charge(s.pricing.transfer(n), true)
+}
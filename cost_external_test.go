@@ -0,0 +1,59 @@
+package s3fs_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_WithBudget(t *testing.T) {
+	fs, err := s3fs.New(&s3fs.Config{
+		Bucket:  "bucket",
+		Client:  s3fstest.NewClient(),
+		Pricing: &s3fs.Pricing{ClassA: 1000, ClassB: 1000}, // A dollar a request
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		writeFile(t, fs, fmt.Sprintf("logs/%d.txt", i), "x")
+	}
+	before := fs.Stats().Requests()
+
+	b := &s3fs.Budget{Limit: 3}
+	limited := fs.WithBudget(b)
+	var failed error
+	for i := 0; i < 5 && failed == nil; i++ {
+		_, failed = limited.ReadFile(fmt.Sprintf("logs/%d.txt", i))
+	}
+	if !errors.Is(failed, s3fs.ErrBudgetExceeded) {
+		t.Errorf("reads over the budget = %v, want ErrBudgetExceeded", failed)
+	}
+	if spent := b.Spent(); spent > 3 {
+		t.Errorf("Spent() = %g, want at most the limit", spent)
+	}
+	if n := fs.Stats().Requests() - before; n > 3 {
+		t.Errorf("requests = %d, want none over the budget", n)
+	}
+	if _, err := fs.ReadFile("logs/0.txt"); err != nil {
+		t.Errorf("ReadFile() without the budget = %v", err)
+	}
+
+	exceeded := 0
+	warned := fs.WithBudget(&s3fs.Budget{Limit: 1, OnExceeded: func(float64) { exceeded++ }})
+	for i := 0; i < 5; i++ {
+		if _, err := warned.ReadFile(fmt.Sprintf("logs/%d.txt", i)); err != nil {
+			t.Fatalf("ReadFile() with OnExceeded = %v", err)
+		}
+	}
+	if exceeded != 1 {
+		t.Errorf("OnExceeded calls = %d, want 1", exceeded)
+	}
+
+	if c := fs.Costs(); c.ClassA < 5 || c.Dollars < float64(c.ClassA+c.ClassB) {
+		t.Errorf("Costs() = %+v", c)
+	}
+}
@@ -0,0 +1,52 @@
+package s3fs
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestStats_Costs(t *testing.T) {
+	s := Stats{
+		Operations: map[string]OperationStats{
+			"PutObject":     {Requests: 1000},
+			"ListObjectsV2": {Requests: 1000},
+			"GetObject":     {Requests: 2000},
+			"DeleteObject":  {Requests: 500},
+		},
+		BytesDownloaded: 1 << 30,
+	}
+	c := s.Costs(DefaultPricing)
+	if c.ClassA != 2000 || c.ClassB != 2000 || c.Free != 500 {
+		t.Errorf("Costs() = %+v, want 2000 class A, 2000 class B, 500 free", c)
+	}
+	if want := 0.01 + 0.0008 + 0.09; math.Abs(c.Dollars-want) > 1e-9 {
+		t.Errorf("Costs().Dollars = %g, want %g", c.Dollars, want)
+	}
+}
+
+func TestBudget_Charge(t *testing.T) {
+	b := &Budget{Limit: 2}
+	for i := 0; i < 2; i++ {
+		if err := b.charge(1, false); err != nil {
+			t.Fatalf("charge() within the limit = %v", err)
+		}
+	}
+	if err := b.charge(1, false); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("charge() over the limit = %v, want ErrBudgetExceeded", err)
+	}
+	if err := b.charge(1, true); err != nil || b.Spent() != 3 {
+		t.Errorf("forced charge() = %v, spent %g, want 3", err, b.Spent())
+	}
+
+	var warnings []float64
+	b = &Budget{Limit: 1, OnExceeded: func(spent float64) { warnings = append(warnings, spent) }}
+	for i := 0; i < 3; i++ {
+		if err := b.charge(1, false); err != nil {
+			t.Fatalf("charge() with OnExceeded = %v", err)
+		}
+	}
+	if len(warnings) != 1 || warnings[0] != 2 {
+		t.Errorf("OnExceeded calls = %v, want one at 2", warnings)
+	}
+}
@@ -0,0 +1,61 @@
+package s3fs
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultRoleSessionName is the session name of assumed roles when
+// Config.RoleSessionName is not set.
+const DefaultRoleSessionName = "s3fs"
+
+// loadOptions returns the options of the default config loading.
+func (cfg *Config) loadOptions() []func(*config.LoadOptions) error {
+	opts := []func(*config.LoadOptions) error{config.WithRegion(cfg.Region)}
+	if cfg.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(cfg.Profile))
+	}
+	if len(cfg.SharedConfigFiles) > 0 {
+		opts = append(opts, config.WithSharedConfigFiles(cfg.SharedConfigFiles))
+	}
+	if len(cfg.SharedCredentialsFiles) > 0 {
+		opts = append(opts, config.WithSharedCredentialsFiles(cfg.SharedCredentialsFiles))
+	}
+	return opts
+}
+
+// applyCredentials sets the credentials of the client New creates from
+// Credentials, RoleARN, ExternalID, RoleSessionName and
+// WebIdentityTokenFile.
+func (cfg *Config) applyCredentials(awsConfig *aws.Config) {
+	if cfg.Credentials != nil {
+		awsConfig.Credentials = cfg.Credentials
+	}
+	if cfg.RoleARN == "" {
+		return
+	}
+
+	sessionName := cfg.RoleSessionName
+	if sessionName == "" {
+		sessionName = DefaultRoleSessionName
+	}
+	// The STS client signs with the credentials set so far
+	client := sts.NewFromConfig(*awsConfig)
+
+	var provider aws.CredentialsProvider
+	if cfg.WebIdentityTokenFile != "" {
+		provider = stscreds.NewWebIdentityRoleProvider(client, cfg.RoleARN, stscreds.IdentityTokenFile(cfg.WebIdentityTokenFile), func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = sessionName
+		})
+	} else {
+		provider = stscreds.NewAssumeRoleProvider(client, cfg.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if cfg.ExternalID != "" {
+				o.ExternalID = aws.String(cfg.ExternalID)
+			}
+		})
+	}
+	awsConfig.Credentials = aws.NewCredentialsCache(provider)
+}
@@ -0,0 +1,50 @@
+package s3fs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestConfigApplyCredentials(t *testing.T) {
+	static := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "tenant"}, nil
+	})
+
+	var awsConfig aws.Config
+	(&Config{}).applyCredentials(&awsConfig)
+	if awsConfig.Credentials != nil {
+		t.Errorf("applyCredentials() without options set %T, want the default chain", awsConfig.Credentials)
+	}
+
+	(&Config{Credentials: static}).applyCredentials(&awsConfig)
+	if creds, err := awsConfig.Credentials.Retrieve(context.Background()); err != nil || creds.AccessKeyID != "tenant" {
+		t.Errorf("applyCredentials() with Credentials = %v, %v", creds, err)
+	}
+
+	for _, cfg := range []*Config{
+		{Credentials: static, RoleARN: "arn:aws:iam::123456789012:role/tenant", ExternalID: "id"},
+		{RoleARN: "arn:aws:iam::123456789012:role/tenant", WebIdentityTokenFile: "/var/run/token"},
+	} {
+		awsConfig := aws.Config{Region: "us-east-1"}
+		cfg.applyCredentials(&awsConfig)
+		if _, ok := awsConfig.Credentials.(*aws.CredentialsCache); !ok {
+			t.Errorf("applyCredentials() with RoleARN set %T, want a *aws.CredentialsCache", awsConfig.Credentials)
+		}
+	}
+}
+
+func TestConfigLoadOptions(t *testing.T) {
+	if n := len((&Config{Region: "us-east-1"}).loadOptions()); n != 1 {
+		t.Errorf("loadOptions() = %d options, want 1", n)
+	}
+	cfg := &Config{
+		Profile:                "dev",
+		SharedConfigFiles:      []string{"/etc/aws/config"},
+		SharedCredentialsFiles: []string{"/etc/aws/credentials"},
+	}
+	if n := len(cfg.loadOptions()); n != 4 {
+		t.Errorf("loadOptions() with profile and files = %d options, want 4", n)
+	}
+}
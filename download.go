@@ -0,0 +1,116 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DefaultDownloadConcurrency is the default number of ranges Download fetches in parallel.
+const DefaultDownloadConcurrency = 5
+
+// Download fetches the named object into w, splitting it into ranges of
+// Config.DownloadPartSize bytes that are fetched concurrently by up to
+// Config.DownloadConcurrency workers. All ranges are pinned to the ETag seen
+// when the download starts, so a concurrent overwrite fails the download
+// instead of producing mixed content. It returns the number of bytes written.
+func (fs *FileSystem) Download(name string, w io.WriterAt) (int64, error) {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, fs.wrapError("Download", name, err)
+	}
+	size := aws.ToInt64(head.ContentLength)
+	if size == 0 {
+		return 0, nil
+	}
+
+	partSize := fs.downloadPartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	workers := fs.downloadConcurrency
+	if workers <= 0 {
+		workers = DefaultDownloadConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(fs.ctx)
+	defer cancel()
+
+	offsets := make(chan int64)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, partSize)
+			for off := range offsets {
+				n := partSize
+				if off+n > size {
+					n = size - off
+				}
+				if err := fs.downloadRange(ctx, key, head.ETag, off, buf[:n], w); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+feed:
+	for off := int64(0); off < size; off += partSize {
+		select {
+		case offsets <- off:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(offsets)
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, fs.wrapError("Download", name, firstErr)
+	}
+	if err := fs.ctx.Err(); err != nil {
+		return 0, fs.wrapError("Download", name, err)
+	}
+	return size, nil
+}
+
+// downloadRange fetches len(buf) bytes at off and writes them to w at the same offset.
+func (fs *FileSystem) downloadRange(ctx context.Context, key string, etag *string, off int64, buf []byte, w io.WriterAt) error {
+	output, err := fs.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(fs.bucket),
+		Key:     aws.String(key),
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", off, off+int64(len(buf))-1)),
+		IfMatch: etag,
+	})
+	if err != nil {
+		return err
+	}
+	defer output.Body.Close()
+
+	if _, err := io.ReadFull(output.Body, buf); err != nil {
+		return err
+	}
+	_, err = w.WriteAt(buf, off)
+	return err
+}
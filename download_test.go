@@ -0,0 +1,53 @@
+package s3fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_Download(t *testing.T) {
+	newFS := func(codec s3fs.NameCodec) *s3fs.FileSystem {
+		fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: s3fstest.NewClient(), DownloadPartSize: 1000, NameCodec: codec})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+	content := strings.Repeat("0123456789", 250)
+
+	tests := []struct {
+		name string
+		fs   *s3fs.FileSystem
+		file string
+	}{
+		{"root", newFS(nil), "data.bin"},
+		{"sub", newFS(nil).Sub("dir"), "sub/data.bin"},
+		{"codec", newFS(s3fs.NewPercentCodec()), "my dir/data 100%.bin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeFile(t, tt.fs, tt.file, content)
+
+			out, err := os.Create(filepath.Join(t.TempDir(), "out.bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			n, err := tt.fs.Download(tt.file, out)
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			if n != int64(len(content)) {
+				t.Errorf("Download() = %d, want %d", n, len(content))
+			}
+			if data, err := os.ReadFile(out.Name()); err != nil || string(data) != content {
+				t.Errorf("downloaded %d bytes, %v; want the written content", len(data), err)
+			}
+		})
+	}
+}
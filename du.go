@@ -0,0 +1,82 @@
+package s3fs
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Usage is the storage used below a directory, as reported by DiskUsage.
+type Usage struct {
+	Bytes    int64        // Total size of the objects
+	Objects  int64        // Number of objects, including directory markers
+	Children []ChildUsage // Usage of each immediate child, sorted by name
+}
+
+// ChildUsage is the storage used by a file or directory directly inside the
+// directory passed to DiskUsage.
+type ChildUsage struct {
+	Name    string // Base name, with a trailing slash for directories
+	Bytes   int64  // Size of the file, or total size below the directory
+	Objects int64  // Number of objects
+}
+
+// DiskUsage reports the total size and number of objects below the
+// directory prefix ("" for the whole filesystem), broken down by immediate
+// child, like du -s dir/*. The tree is listed with up to
+// DefaultListConcurrency directory listings in parallel; objects below the
+// system prefix are not counted.
+func (fs *FileSystem) DiskUsage(prefix string) (*Usage, error) {
+	prefix = syncPrefix(prefix)
+
+	var mu sync.Mutex
+	usage := &Usage{}
+	children := make(map[string]*ChildUsage)
+	err := fs.listParallel(fs.objectKey(prefix), 0, func(obj types.Object) error {
+		key := aws.ToString(obj.Key)
+		if fs.isSystemKey(key) {
+			return nil
+		}
+		name, err := fs.logicalName(key)
+		if err != nil {
+			return err
+		}
+		size := aws.ToInt64(obj.Size)
+
+		mu.Lock()
+		defer mu.Unlock()
+		usage.Bytes += size
+		usage.Objects++
+
+		rel := strings.TrimPrefix(name, prefix)
+		if rel == "" {
+			return nil // marker of the directory itself
+		}
+		child := rel
+		if i := strings.IndexByte(rel, '/'); i >= 0 {
+			child = rel[:i+1]
+		}
+		c := children[child]
+		if c == nil {
+			c = &ChildUsage{Name: child}
+			children[child] = c
+		}
+		c.Bytes += size
+		c.Objects++
+		return nil
+	})
+	if err != nil {
+		return nil, fs.wrapError("DiskUsage", prefix, err)
+	}
+
+	for _, c := range children {
+		usage.Children = append(usage.Children, *c)
+	}
+	sort.Slice(usage.Children, func(i, j int) bool {
+		return usage.Children[i].Name < usage.Children[j].Name
+	})
+	return usage, nil
+}
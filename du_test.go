@@ -0,0 +1,41 @@
+package s3fs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_DiskUsage(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "top.txt", "12345")
+	writeFile(t, fs, "data/a.bin", strings.Repeat("x", 100))
+	writeFile(t, fs, "data/sub/b.bin", strings.Repeat("x", 20))
+	writeFile(t, fs, "data/sub/deeper/c.bin", strings.Repeat("x", 3))
+	writeFile(t, fs, "other/d.bin", "d")
+	if err := fs.Mkdir("empty", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := fs.DiskUsage("data")
+	if err != nil {
+		t.Fatalf("DiskUsage() error = %v", err)
+	}
+	if usage.Bytes != 123 || usage.Objects != 3 {
+		t.Errorf("DiskUsage(data) = %d bytes, %d objects; want 123, 3", usage.Bytes, usage.Objects)
+	}
+	want := []s3fs.ChildUsage{{Name: "a.bin", Bytes: 100, Objects: 1}, {Name: "sub/", Bytes: 23, Objects: 2}}
+	if len(usage.Children) != len(want) || usage.Children[0] != want[0] || usage.Children[1] != want[1] {
+		t.Errorf("DiskUsage(data).Children = %+v, want %+v", usage.Children, want)
+	}
+
+	usage, err = fs.DiskUsage("")
+	if err != nil {
+		t.Fatalf("DiskUsage() error = %v", err)
+	}
+	if usage.Bytes != 129 || usage.Objects != 6 || len(usage.Children) != 4 {
+		t.Errorf("DiskUsage(\"\") = %+v, want 129 bytes in 6 objects and 4 children", usage)
+	}
+}
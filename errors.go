@@ -3,6 +3,12 @@ package s3fs
 import (
 	"errors"
 	"fmt"
+	iofs "io/fs"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/smithy-go"
 )
 
 // Common errors returned by s3fs operations.
@@ -18,8 +24,177 @@ var (
 
 	// ErrReadOnWriteFile is returned when attempting to read from a write-only file.
 	ErrReadOnWriteFile = errors.New("s3fs: cannot read from write-only file")
+
+	// ErrPackClosed is returned when using a PackWriter after Close.
+	ErrPackClosed = errors.New("s3fs: pack writer is closed")
+
+	// ErrChecksumMismatch is returned when downloaded content does not match
+	// its expected checksum.
+	ErrChecksumMismatch = errors.New("s3fs: checksum mismatch")
+
+	// ErrRootPrefix is returned when a bulk operation would affect the whole bucket.
+	ErrRootPrefix = errors.New("s3fs: operation not allowed on the bucket root")
+
+	// ErrPrefixOverlap is returned when the source and destination of a
+	// prefix operation contain each other.
+	ErrPrefixOverlap = errors.New("s3fs: source and destination prefixes overlap")
+
+	// ErrIncompleteCopy is returned when a copied object is missing or has
+	// the wrong size when the copy is verified.
+	ErrIncompleteCopy = errors.New("s3fs: copy incomplete")
+
+	// ErrPreconditionFailed is returned when a conditional write is rejected
+	// because the object changed (If-Match) or already exists (If-None-Match).
+	ErrPreconditionFailed = errors.New("s3fs: precondition failed")
+
+	// ErrInvalidArchiveFormat is returned for an unknown ArchiveFormat.
+	ErrInvalidArchiveFormat = errors.New("s3fs: invalid archive format")
+
+	// ErrUnsafeArchivePath is returned by ExtractArchive for entries with
+	// absolute names or names that escape the destination prefix.
+	ErrUnsafeArchivePath = errors.New("s3fs: archive entry escapes the destination")
+
+	// ErrPresignUnsupported is returned by PresignGet and PresignPut when
+	// Config.Client is not an *s3.Client.
+	ErrPresignUnsupported = errors.New("s3fs: presigning requires an *s3.Client")
+
+	// ErrNotMounted is returned by MountTable for names outside every mount.
+	ErrNotMounted = errors.New("s3fs: no filesystem mounted at path")
+
+	// ErrSymlinkLoop is returned when resolving a name follows too many
+	// symbolic links.
+	ErrSymlinkLoop = errors.New("s3fs: too many levels of symbolic links")
+
+	// ErrObjectArchived matches errors for reads of objects in the GLACIER
+	// or DEEP_ARCHIVE storage classes that have not been restored.
+	ErrObjectArchived = errors.New("s3fs: object is archived and must be restored")
+
+	// ErrInvalidChecksumAlgorithm is returned by New for an unknown
+	// Config.ChecksumAlgorithm.
+	ErrInvalidChecksumAlgorithm = errors.New("s3fs: invalid checksum algorithm")
+
+	// ErrCircuitOpen is returned without contacting S3 while the circuit
+	// breaker is open, see Config.BreakerThreshold.
+	ErrCircuitOpen = errors.New("s3fs: circuit breaker open after repeated S3 failures")
+
+	// ErrTxnDone is returned by Commit for a transaction that was already
+	// committed.
+	ErrTxnDone = errors.New("s3fs: transaction already committed")
+
+	// ErrLocked is returned by TryLock when another holder has the lock.
+	ErrLocked = errors.New("s3fs: locked")
+
+	// ErrLockLost is returned by Unlock when the lock expired or was taken
+	// over by another holder before it was released.
+	ErrLockLost = errors.New("s3fs: lock lost")
+
+	// ErrNotDeleted is returned by Undelete for objects that exist.
+	ErrNotDeleted = errors.New("s3fs: object is not deleted")
+
+	// ErrTrashDisabled is returned by the trash operations when
+	// Config.Trash is not set.
+	ErrTrashDisabled = errors.New("s3fs: trash is disabled")
+
+	// ErrInodesDisabled is returned by Inode when Config.Inodes is not set.
+	ErrInodesDisabled = errors.New("s3fs: inode numbers are disabled")
+
+	// ErrNoQueue is returned by Watch without a Queue.
+	ErrNoQueue = errors.New("s3fs: watch needs a queue")
+
+	// ErrInvalidRestore is returned by Restore for a non-positive number of days.
+	ErrInvalidRestore = errors.New("s3fs: restore days must be positive")
+
+	// ErrUnsupportedFlags is matched by the *FlagError OpenFile returns for
+	// flag combinations s3fs cannot implement.
+	ErrUnsupportedFlags = errors.New("s3fs: unsupported open flags")
+
+	// ErrWriterClosed is returned by a Writer after Close or CloseWithError.
+	ErrWriterClosed = errors.New("s3fs: writer is closed")
+
+	// ErrInvalidCABundle is returned by New if Config.CABundle contains no
+	// PEM encoded certificate.
+	ErrInvalidCABundle = errors.New("s3fs: no certificates in CA bundle")
+
+	// ErrSameObject is returned by CopyTo and MoveTo when the source and
+	// destination name the same object.
+	ErrSameObject = errors.New("s3fs: source and destination are the same object")
+
+	// ErrProbeFailed is matched by the *ProbeError New returns when the
+	// checks of Config.Probe fail.
+	ErrProbeFailed = errors.New("s3fs: bucket probe failed")
+
+	// ErrInvalidKey is matched by the *KeyError returned for names whose
+	// object key S3 does not accept, see ValidateKey.
+	ErrInvalidKey = errors.New("s3fs: invalid object key")
+
+	// ErrBudgetExceeded is returned for requests that would exceed the
+	// Budget of a FileSystem returned by WithBudget.
+	ErrBudgetExceeded = errors.New("s3fs: request budget exceeded")
 )
 
+// AmbiguousPathError is returned by Lstat, and by Stat and OpenFile with
+// Config.StrictPaths, when both a file "name" and a directory "name/" exist.
+type AmbiguousPathError struct {
+	Path string
+}
+
+// Error implements the error interface.
+func (e *AmbiguousPathError) Error() string {
+	return fmt.Sprintf("s3fs: %s is both a file and a directory", e.Path)
+}
+
+// FlagError is returned by OpenFile for a combination of os.O_* flags that
+// s3fs does not support. It matches ErrUnsupportedFlags.
+type FlagError struct {
+	Flag   int    // Flags passed to OpenFile
+	Reason string // Unsupported combination, such as "O_RDWR without O_TRUNC"
+}
+
+// Error implements the error interface.
+func (e *FlagError) Error() string {
+	return fmt.Sprintf("s3fs: unsupported open flags %#x: %s", e.Flag, e.Reason)
+}
+
+// Is reports whether target is ErrUnsupportedFlags.
+func (e *FlagError) Is(target error) bool {
+	return target == ErrUnsupportedFlags
+}
+
+// KeyError is returned for an object key that breaks the constraints of S3
+// keys, see ValidateKey. It matches ErrInvalidKey.
+type KeyError struct {
+	Key    string // Object key
+	Reason string // Broken constraint, such as "control character U+0001"
+}
+
+// Error implements the error interface.
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("s3fs: invalid object key %q: %s", e.Key, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidKey.
+func (e *KeyError) Is(target error) bool {
+	return target == ErrInvalidKey
+}
+
+// ChecksumError is returned when downloaded content does not match the
+// checksum S3 reported for it. It matches ErrChecksumMismatch.
+type ChecksumError struct {
+	Key      string // Object key
+	Expected string // Checksum reported by S3 or the mirror, or of uploaded content
+	Actual   string // Checksum of the received content, or the ETag S3 stored
+}
+
+// Error implements the error interface.
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("s3fs: checksum mismatch for %s: expected %s, got %s", e.Key, e.Expected, e.Actual)
+}
+
+// Is reports whether target is ErrChecksumMismatch.
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
 // S3Error wraps S3 operation errors with additional context.
 type S3Error struct {
 	Op   string // Operation that failed (e.g., "GetObject", "PutObject")
@@ -37,6 +212,66 @@ func (e *S3Error) Unwrap() error {
 	return e.Err
 }
 
+// Is reports whether the error matches target. Besides the errors in the
+// chain, an S3Error matches fs.ErrNotExist for missing objects and buckets,
+// fs.ErrPermission for denied requests and ErrObjectArchived for reads of
+// archived objects, so callers can test errors without knowing the S3 error
+// codes.
+func (e *S3Error) Is(target error) bool {
+	switch target {
+	case iofs.ErrNotExist:
+		return e.IsNotExist()
+	case iofs.ErrPermission:
+		return e.IsAccessDenied()
+	case ErrObjectArchived:
+		return e.IsArchived()
+	}
+	return false
+}
+
+// IsArchived reports whether the operation failed because the object is in
+// an archive storage class and must be restored first, see Restore.
+func (e *S3Error) IsArchived() bool {
+	return errorCode(e.Err) == "InvalidObjectState"
+}
+
+// IsNotExist reports whether the operation failed because the object or
+// bucket does not exist.
+func (e *S3Error) IsNotExist() bool {
+	if errors.Is(e.Err, ErrNotExist) {
+		return true
+	}
+	switch errorCode(e.Err) {
+	case "NoSuchKey", "NotFound", "NoSuchBucket", "NoSuchVersion":
+		return true
+	}
+	return httpStatus(e.Err) == http.StatusNotFound
+}
+
+// IsAccessDenied reports whether S3 refused the operation because the
+// credentials lack permission.
+func (e *S3Error) IsAccessDenied() bool {
+	switch errorCode(e.Err) {
+	case "AccessDenied":
+		return true
+	case "InvalidObjectState":
+		// Also a 403, but not about permissions
+		return false
+	}
+	return httpStatus(e.Err) == http.StatusForbidden
+}
+
+// IsThrottled reports whether S3 rejected the operation because the request
+// rate was too high. Throttled operations can be retried after a delay.
+func (e *S3Error) IsThrottled() bool {
+	switch errorCode(e.Err) {
+	case "SlowDown", "Throttling", "ThrottlingException", "RequestLimitExceeded",
+		"TooManyRequests", "TooManyRequestsException":
+		return true
+	}
+	return httpStatus(e.Err) == http.StatusTooManyRequests
+}
+
 // wrapError wraps an error with S3Error context.
 func wrapError(op, path string, err error) error {
 	if err == nil {
@@ -48,3 +283,59 @@ func wrapError(op, path string, err error) error {
 		Err:  err,
 	}
 }
+
+// httpStatus returns the HTTP status code of the response that caused err,
+// or 0 if err did not come from an HTTP response.
+func httpStatus(err error) int {
+	var re interface{ HTTPStatusCode() int }
+	if errors.As(err, &re) {
+		return re.HTTPStatusCode()
+	}
+	return 0
+}
+
+// errorCode returns the S3 error code of err, such as "NoSuchKey", or ""
+// if err is not an S3 API error.
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+// pathErrorOps maps s3fs operation names to the lowercase names used by the
+// os package in *os.PathError.
+var pathErrorOps = map[string]string{
+	"OpenFile": "open",
+	"Stat":     "stat",
+	"Mkdir":    "mkdir",
+	"Remove":   "remove",
+	"Rename":   "rename",
+	"Read":     "read",
+	"ReadAt":   "read",
+	"Close":    "close",
+	"Readdir":  "readdirent",
+	"Truncate": "truncate",
+}
+
+// wrapError wraps err with S3Error context. When Config.PathErrors is set the
+// result is additionally wrapped in an *os.PathError, matching the errors
+// returned by the os package and expected by the absfs conformance tests.
+func (fs *FileSystem) wrapError(op, path string, err error) error {
+	if err == nil || fs == nil || !fs.pathErrors {
+		return wrapError(op, path, err)
+	}
+
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		return err
+	}
+	err = wrapError(op, path, err)
+
+	osOp, ok := pathErrorOps[op]
+	if !ok {
+		osOp = strings.ToLower(op)
+	}
+	return &os.PathError{Op: osOp, Path: path, Err: err}
+}
@@ -0,0 +1,94 @@
+package s3fs
+
+import (
+	"errors"
+	iofs "io/fs"
+	"os"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestWrapError_PathErrors(t *testing.T) {
+	cause := errors.New("boom")
+
+	err := (&FileSystem{}).wrapError("Stat", "a.txt", cause)
+	var s3err *S3Error
+	if !errors.As(err, &s3err) {
+		t.Fatalf("wrapError() = %T, want *S3Error", err)
+	}
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		t.Errorf("wrapError() without PathErrors returned *os.PathError")
+	}
+
+	fs := &FileSystem{pathErrors: true}
+	err = fs.wrapError("Stat", "a.txt", cause)
+	if !errors.As(err, &pe) {
+		t.Fatalf("wrapError() = %T, want *os.PathError", err)
+	}
+	if pe.Op != "stat" || pe.Path != "a.txt" {
+		t.Errorf("PathError = %q %q, want stat a.txt", pe.Op, pe.Path)
+	}
+	if !errors.As(err, &s3err) || !errors.Is(err, cause) {
+		t.Errorf("PathError does not wrap the S3Error and cause")
+	}
+
+	if again := fs.wrapError("MkdirAll", "a", err); again != err {
+		t.Errorf("wrapError() re-wrapped an existing *os.PathError")
+	}
+	if err := fs.wrapError("Stat", "a.txt", nil); err != nil {
+		t.Errorf("wrapError(nil) = %v, want nil", err)
+	}
+}
+
+// apiError is a minimal smithy.APIError carrying an HTTP status code.
+type apiError struct {
+	code   string
+	status int
+}
+
+func (e *apiError) Error() string                 { return e.code }
+func (e *apiError) ErrorCode() string             { return e.code }
+func (e *apiError) ErrorMessage() string          { return e.code }
+func (e *apiError) ErrorFault() smithy.ErrorFault { return smithy.FaultClient }
+func (e *apiError) HTTPStatusCode() int           { return e.status }
+
+func TestS3Error_Classification(t *testing.T) {
+	tests := []struct {
+		name       string
+		cause      error
+		notExist   bool
+		permission bool
+		throttled  bool
+	}{
+		{"NoSuchKey", &apiError{"NoSuchKey", 404}, true, false, false},
+		{"bare 404", &apiError{"", 404}, true, false, false},
+		{"sentinel", ErrNotExist, true, false, false},
+		{"AccessDenied", &apiError{"AccessDenied", 403}, false, true, false},
+		{"InvalidObjectState", &apiError{"InvalidObjectState", 403}, false, false, false},
+		{"SlowDown", &apiError{"SlowDown", 503}, false, false, true},
+		{"429", &apiError{"", 429}, false, false, true},
+		{"other", errors.New("boom"), false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapError("Stat", "a.txt", tt.cause)
+			if got := errors.Is(err, iofs.ErrNotExist); got != tt.notExist {
+				t.Errorf("errors.Is(err, fs.ErrNotExist) = %v, want %v", got, tt.notExist)
+			}
+			if got := errors.Is(err, iofs.ErrPermission); got != tt.permission {
+				t.Errorf("errors.Is(err, fs.ErrPermission) = %v, want %v", got, tt.permission)
+			}
+			if got := err.(*S3Error).IsThrottled(); got != tt.throttled {
+				t.Errorf("IsThrottled() = %v, want %v", got, tt.throttled)
+			}
+		})
+	}
+
+	pathErr := (&FileSystem{pathErrors: true}).wrapError("Stat", "a.txt", &apiError{"NoSuchKey", 404})
+	if !errors.Is(pathErr, os.ErrNotExist) {
+		t.Errorf("errors.Is() through *os.PathError = false, want true")
+	}
+}
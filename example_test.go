@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/absfs/s3fs"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -52,7 +53,7 @@ func ExampleFileSystem_OpenFile() {
 	})
 
 	// Open a file for writing
-	f, err := fs.OpenFile("path/to/file.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := fs.OpenFile("path/to/file.txt", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -255,3 +256,52 @@ func ExampleFileSystem_NewMultipartUpload() {
 		log.Fatal(err)
 	}
 }
+
+func ExampleFileSystem_PresignGet() {
+	fs, _ := s3fs.New(&s3fs.Config{
+		Bucket: "my-bucket",
+		Region: "us-east-1",
+	})
+
+	// Hand out a download link that is valid for 15 minutes
+	url, err := fs.PresignGet("reports/2024.pdf", 15*time.Minute)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(url)
+}
+
+func ExampleFileSystem_Download() {
+	fs, _ := s3fs.New(&s3fs.Config{
+		Bucket:              "my-bucket",
+		Region:              "us-east-1",
+		DownloadConcurrency: 8,
+	})
+
+	out, err := os.Create("dataset.bin")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer out.Close()
+
+	// Fetch the object in parallel ranges straight into the local file
+	n, err := fs.Download("datasets/large.bin", out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Downloaded %d bytes\n", n)
+}
+
+func ExampleFileSystem_CleanupMultipartUploads() {
+	fs, _ := s3fs.New(&s3fs.Config{
+		Bucket: "my-bucket",
+		Region: "us-east-1",
+	})
+
+	// Abort uploads that were abandoned more than a day ago
+	n, err := fs.CleanupMultipartUploads(24 * time.Hour)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Aborted %d stale uploads\n", n)
+}
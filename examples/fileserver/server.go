@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/absfs/s3fs"
+)
+
+// server exposes a FileSystem over HTTP.
+type server struct {
+	fs  *s3fs.FileSystem
+	mux *http.ServeMux
+}
+
+// entry is one item of a directory listing.
+type entry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Dir     bool      `json:"dir"`
+	ModTime time.Time `json:"modTime"`
+}
+
+func newServer(fs *s3fs.FileSystem) *server {
+	s := &server{fs: fs, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/files/", s.handleFiles)
+	s.mux.HandleFunc("/presign/", s.handlePresign)
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/files/")
+	fs := s.fs.WithContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.get(fs, w, r, name)
+	case http.MethodPut:
+		s.put(fs, w, r, name)
+	case http.MethodDelete:
+		if err := fs.Remove(name); err != nil {
+			httpError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// get serves a file with http.ServeContent, which handles Range and
+// conditional requests by seeking the s3fs file, or lists a directory.
+func (s *server) get(fs *s3fs.FileSystem, w http.ResponseWriter, r *http.Request, name string) {
+	if name == "" || strings.HasSuffix(name, "/") {
+		s.list(fs, w, name)
+		return
+	}
+
+	info, err := fs.Stat(name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if info.IsDir() {
+		s.list(fs, w, name)
+		return
+	}
+
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	defer f.Close()
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+func (s *server) list(fs *s3fs.FileSystem, w http.ResponseWriter, name string) {
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	defer f.Close()
+
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	entries := make([]entry, len(infos))
+	for i, info := range infos {
+		entries[i] = entry{
+			Name:    strings.TrimSuffix(info.Name(), "/"),
+			Size:    info.Size(),
+			Dir:     info.IsDir(),
+			ModTime: info.ModTime(),
+		}
+	}
+	writeJSON(w, entries)
+}
+
+// put uploads the request body, logging progress every 10MB.
+func (s *server) put(fs *s3fs.FileSystem, w http.ResponseWriter, r *http.Request, name string) {
+	f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	var logged int64
+	n, err := s3fs.UploadContext(r.Context(), f, r.Body, func(transferred int64) {
+		if transferred-logged >= 10<<20 {
+			log.Printf("upload %s: %d bytes", name, transferred)
+			logged = transferred
+		}
+	})
+	if err != nil {
+		f.Close()
+		httpError(w, err)
+		return
+	}
+	if err := f.Close(); err != nil {
+		httpError(w, err)
+		return
+	}
+	log.Printf("upload %s: done, %d bytes", name, n)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *server) handlePresign(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/presign/")
+	fs := s.fs.WithContext(r.Context())
+
+	expiry := 15 * time.Minute
+	if v := r.URL.Query().Get("expiry"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, "invalid expiry", http.StatusBadRequest)
+			return
+		}
+		expiry = d
+	}
+
+	var url string
+	var err error
+	switch strings.ToUpper(r.URL.Query().Get("method")) {
+	case "", http.MethodGet:
+		url, err = fs.PresignGet(name, expiry)
+	case http.MethodPut:
+		url, err = fs.PresignPut(name, expiry)
+	default:
+		http.Error(w, "method must be GET or PUT", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	writeJSON(w, map[string]string{"url": url})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print(err)
+	}
+}
+
+// httpError maps s3fs errors to HTTP status codes.
+func httpError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, s3fs.ErrNotExist):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// runSmoke starts srv on a random local port and exercises every endpoint
+// against a unique prefix in the bucket, cleaning up afterwards.
+func runSmoke(srv *server) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	go http.Serve(ln, srv)
+
+	base := "http://" + ln.Addr().String()
+	dir := fmt.Sprintf("s3fs-smoke-%d", time.Now().UnixNano())
+	name := dir + "/hello.txt"
+	content := "hello from the s3fs smoke test"
+	defer srv.fs.RemoveAll(dir)
+
+	steps := []struct {
+		desc string
+		run  func() error
+	}{
+		{"upload", func() error {
+			return expect(do(http.MethodPut, base+"/files/"+name, strings.NewReader(content), nil), http.StatusCreated, nil)
+		}},
+		{"list", func() error {
+			var entries []entry
+			if err := expect(do(http.MethodGet, base+"/files/"+dir+"/", nil, nil), http.StatusOK, &entries); err != nil {
+				return err
+			}
+			if len(entries) != 1 || entries[0].Name != "hello.txt" || entries[0].Size != int64(len(content)) {
+				return fmt.Errorf("listing = %+v", entries)
+			}
+			return nil
+		}},
+		{"read", func() error {
+			return expectBody(do(http.MethodGet, base+"/files/"+name, nil, nil), http.StatusOK, content)
+		}},
+		{"ranged read", func() error {
+			header := http.Header{"Range": {"bytes=6-9"}}
+			return expectBody(do(http.MethodGet, base+"/files/"+name, nil, header), http.StatusPartialContent, content[6:10])
+		}},
+		{"presigned read", func() error {
+			var out map[string]string
+			if err := expect(do(http.MethodGet, base+"/presign/"+name, nil, nil), http.StatusOK, &out); err != nil {
+				return err
+			}
+			return expectBody(do(http.MethodGet, out["url"], nil, nil), http.StatusOK, content)
+		}},
+		{"delete", func() error {
+			return expect(do(http.MethodDelete, base+"/files/"+name, nil, nil), http.StatusNoContent, nil)
+		}},
+		{"read deleted", func() error {
+			return expect(do(http.MethodGet, base+"/files/"+name, nil, nil), http.StatusNotFound, nil)
+		}},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			return fmt.Errorf("%s: %w", step.desc, err)
+		}
+	}
+	return nil
+}
+
+type response struct {
+	*http.Response
+	err error
+}
+
+func do(method, url string, body io.Reader, header http.Header) response {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return response{err: err}
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	return response{resp, err}
+}
+
+// expect checks the status of resp and decodes its JSON body into v, if
+// not nil.
+func expect(resp response, status int, v interface{}) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("status %d, want %d: %s", resp.StatusCode, status, bytes.TrimSpace(body))
+	}
+	if v != nil {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+	return nil
+}
+
+// expectBody checks the status and the exact body of resp.
+func expectBody(resp response, status int, want string) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != status {
+		return fmt.Errorf("status %d, want %d: %s", resp.StatusCode, status, bytes.TrimSpace(body))
+	}
+	if string(body) != want {
+		return fmt.Errorf("body = %q, want %q", body, want)
+	}
+	return nil
+}
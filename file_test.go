@@ -1,6 +1,8 @@
 package s3fs
 
 import (
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -154,12 +156,14 @@ func TestFile_Seek(t *testing.T) {
 	}{
 		{"SeekStart", 10, 0, 10, false},
 		{"SeekCurrent", 5, 1, 5, false},
-		{"SeekEnd", 0, 2, 0, true},
+		{"SeekEnd", -5, 2, 15, false},
+		{"Negative", -1, 0, 0, true},
+		{"BadWhence", 0, 3, 0, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := &File{offset: 0}
+			f := &File{offset: 0, writing: true, buffer: make([]byte, 20)}
 			got, err := f.Seek(tt.offset, tt.whence)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Seek() error = %v, wantErr %v", err, tt.wantErr)
@@ -172,9 +176,46 @@ func TestFile_Seek(t *testing.T) {
 	}
 }
 
+func TestFile_SeekResetsBody(t *testing.T) {
+	f := &File{packed: &packEntry{Size: 100}, body: io.NopCloser(strings.NewReader("data"))}
+
+	if _, err := f.Seek(0, io.SeekCurrent); err != nil || f.body == nil {
+		t.Fatalf("Seek() to the current offset should keep the body, err = %v", err)
+	}
+
+	off, err := f.Seek(-10, io.SeekEnd)
+	if err != nil || off != 90 {
+		t.Fatalf("Seek(-10, SeekEnd) = %d, %v; want 90", off, err)
+	}
+	if f.body != nil {
+		t.Errorf("Seek() to a new offset should discard the body")
+	}
+
+	f.offset = 100
+	if n, err := f.Read(make([]byte, 4)); n != 0 || err != io.EOF {
+		t.Errorf("Read() past the end = %d, %v; want 0, io.EOF", n, err)
+	}
+}
+
 func TestFile_Sync(t *testing.T) {
 	f := &File{}
 	if err := f.Sync(); err != nil {
 		t.Errorf("Sync() error = %v", err)
 	}
 }
+
+func TestFileSystem_OpenVersion(t *testing.T) {
+	fs := &FileSystem{}
+	f, err := fs.OpenVersion("/docs/report.txt", "v1")
+	if err != nil {
+		t.Fatalf("OpenVersion() error = %v", err)
+	}
+
+	file := f.(*File)
+	if file.key != "docs/report.txt" || file.version != "v1" {
+		t.Errorf("OpenVersion() key = %q version = %q", file.key, file.version)
+	}
+	if _, err := file.Write([]byte("x")); err != ErrWriteOnReadFile {
+		t.Errorf("Write() on version error = %v, want ErrWriteOnReadFile", err)
+	}
+}
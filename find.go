@@ -0,0 +1,192 @@
+package s3fs
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// DefaultListConcurrency is the number of directories listed in parallel by
+// Find and DiskUsage when no concurrency is given.
+const DefaultListConcurrency = 8
+
+// FindOptions selects the files returned by Find. Zero values do not filter.
+type FindOptions struct {
+	MinSize int64 // Minimum size in bytes
+	MaxSize int64 // Maximum size in bytes
+
+	ModifiedAfter  time.Time // Only files modified after this time
+	ModifiedBefore time.Time // Only files modified before this time
+
+	// Name, if set, must match the base name of the file.
+	Name *regexp.Regexp
+
+	// StorageClasses, if set, lists the storage classes to include, such as
+	// "STANDARD" or "GLACIER".
+	StorageClasses []string
+
+	// Concurrency is the number of directories listed in parallel (default
+	// DefaultListConcurrency).
+	Concurrency int
+}
+
+// FindResult is a file found by Find.
+type FindResult struct {
+	Name string // Name of the file
+	ObjectInfo
+}
+
+// match reports whether a file with the base name and listed attributes info
+// passes the filters.
+func (o *FindOptions) match(base string, info *ObjectInfo) bool {
+	if info.Size < o.MinSize || o.MaxSize > 0 && info.Size > o.MaxSize {
+		return false
+	}
+	if !o.ModifiedAfter.IsZero() && !info.ModTime.After(o.ModifiedAfter) {
+		return false
+	}
+	if !o.ModifiedBefore.IsZero() && !info.ModTime.Before(o.ModifiedBefore) {
+		return false
+	}
+	if o.Name != nil && !o.Name.MatchString(base) {
+		return false
+	}
+	if len(o.StorageClasses) > 0 {
+		class := info.StorageClass
+		if class == "" {
+			class = string(types.StorageClassStandard)
+		}
+		found := false
+		for _, c := range o.StorageClasses {
+			found = found || strings.EqualFold(c, class)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the files below the directory root that pass the filters of
+// opts, sorted by name. The attributes are those of the listing, so finding
+// files costs no request per file. Directories are listed separately, up to
+// opts.Concurrency at a time, which speeds up wide trees.
+func (fs *FileSystem) Find(root string, opts FindOptions) ([]FindResult, error) {
+	root = syncPrefix(root)
+
+	var mu sync.Mutex
+	var results []FindResult
+	err := fs.listParallel(fs.objectKey(root), opts.Concurrency, func(obj types.Object) error {
+		key := aws.ToString(obj.Key)
+		if strings.HasSuffix(key, "/") || fs.isSystemKey(key) {
+			return nil
+		}
+		name, err := fs.logicalName(key)
+		if err != nil {
+			return err
+		}
+		info := listObjectInfo(obj)
+		if !opts.match(path.Base(name), info) {
+			return nil
+		}
+
+		mu.Lock()
+		results = append(results, FindResult{Name: name, ObjectInfo: *info})
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, fs.wrapError("Find", root, err)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+// listParallel calls fn for every object below the key prefix. Each directory
+// is listed with a delimiter, up to concurrency (default
+// DefaultListConcurrency) directories at a time, and fn is called from
+// several goroutines. System prefixes are skipped, and the first error stops
+// the listing.
+func (fs *FileSystem) listParallel(prefix string, concurrency int, fn func(types.Object) error) error {
+	if concurrency <= 0 {
+		concurrency = DefaultListConcurrency
+	}
+	ctx, cancel := context.WithCancel(fs.ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		slots    = make(chan struct{}, concurrency)
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	var list func(dir string)
+	list = func(dir string) {
+		defer wg.Done()
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		var subdirs []string
+		var continuationToken *string
+		for {
+			output, err := fs.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+				Bucket:            aws.String(fs.bucket),
+				Prefix:            aws.String(dir),
+				Delimiter:         aws.String("/"),
+				ContinuationToken: continuationToken,
+			})
+			if err != nil {
+				fail(err)
+				break
+			}
+			for _, obj := range output.Contents {
+				if err := fn(obj); err != nil {
+					fail(err)
+					break
+				}
+			}
+			for _, p := range output.CommonPrefixes {
+				if sub := aws.ToString(p.Prefix); !fs.isSystemKey(sub) {
+					subdirs = append(subdirs, sub)
+				}
+			}
+			if !aws.ToBool(output.IsTruncated) || ctx.Err() != nil {
+				break
+			}
+			continuationToken = output.NextContinuationToken
+		}
+		<-slots
+
+		// Subdirectories wait for a slot of their own
+		for _, sub := range subdirs {
+			wg.Add(1)
+			go list(sub)
+		}
+	}
+
+	wg.Add(1)
+	go list(prefix)
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,67 @@
+package s3fs_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestFileSystem_Find(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "small.txt", "x")
+	writeFile(t, fs, "logs/2024/app.log", strings.Repeat("x", 100))
+	writeFile(t, fs, "logs/2024/db.log", strings.Repeat("x", 10))
+	writeFile(t, fs, "logs/readme.md", strings.Repeat("x", 50))
+	if _, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:       aws.String("bucket"),
+		Key:          aws.String("logs/2023/old.log"),
+		Body:         strings.NewReader("archived"),
+		StorageClass: types.StorageClassGlacier,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	find := func(root string, opts s3fs.FindOptions) string {
+		t.Helper()
+		results, err := fs.Find(root, opts)
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		var names []string
+		for _, r := range results {
+			names = append(names, r.Name)
+		}
+		return strings.Join(names, " ")
+	}
+
+	if got := find("", s3fs.FindOptions{}); got != "logs/2023/old.log logs/2024/app.log logs/2024/db.log logs/readme.md small.txt" {
+		t.Errorf("Find() = %s", got)
+	}
+	if got := find("logs", s3fs.FindOptions{Name: regexp.MustCompile(`\.log$`), MinSize: 10, Concurrency: 2}); got != "logs/2024/app.log logs/2024/db.log" {
+		t.Errorf("Find(logs, *.log >= 10) = %s", got)
+	}
+	if got := find("", s3fs.FindOptions{MaxSize: 49, MinSize: 2}); got != "logs/2023/old.log logs/2024/db.log" {
+		t.Errorf("Find(2..49 bytes) = %s", got)
+	}
+	if got := find("", s3fs.FindOptions{StorageClasses: []string{"GLACIER"}}); got != "logs/2023/old.log" {
+		t.Errorf("Find(GLACIER) = %s", got)
+	}
+	if got := find("", s3fs.FindOptions{ModifiedBefore: time.Now().Add(-time.Hour)}); got != "" {
+		t.Errorf("Find(modified an hour ago) = %s", got)
+	}
+	if got := find("", s3fs.FindOptions{ModifiedAfter: time.Now().Add(-time.Hour), Name: regexp.MustCompile(`^small`)}); got != "small.txt" {
+		t.Errorf("Find(modified in the last hour) = %s", got)
+	}
+}
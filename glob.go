@@ -0,0 +1,123 @@
+package s3fs
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Glob returns the names of all files and directories matching pattern, like
+// filepath.Glob: the syntax of each path element is that of path.Match, and
+// an element "**" additionally matches any number of directories, including
+// none ("logs/**/*.gz"). Directory names have no trailing slash, and the
+// names are sorted. Only the subtree below the literal leading directories of
+// the pattern is listed. The only possible error is path.ErrBadPattern, or an
+// error listing the bucket.
+func (fs *FileSystem) Glob(pattern string) ([]string, error) {
+	pattern = strings.Trim(pattern, "/")
+	elems := strings.Split(pattern, "/")
+	for _, elem := range elems {
+		if _, err := path.Match(elem, ""); err != nil {
+			return nil, fs.wrapError("Glob", pattern, err)
+		}
+	}
+
+	// Without wildcards there is at most one match
+	literal := 0
+	for literal < len(elems) && !hasMeta(elems[literal]) {
+		literal++
+	}
+	if literal == len(elems) {
+		if ok, err := fs.Exists(pattern); err != nil || !ok {
+			return nil, err
+		}
+		return []string{pattern}, nil
+	}
+	prefix := ""
+	if literal > 0 {
+		prefix = strings.Join(elems[:literal], "/") + "/"
+	}
+
+	matches := make(map[string]bool)
+	consider := func(name string) {
+		if !matches[name] && matchElems(elems, strings.Split(name, "/")) {
+			matches[name] = true
+		}
+	}
+
+	var continuationToken *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(fs.objectKey(prefix)),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, fs.wrapError("Glob", pattern, err)
+		}
+
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			if fs.isSystemKey(key) {
+				continue
+			}
+			name, err := fs.logicalName(key)
+			if err != nil {
+				return nil, fs.wrapError("Glob", key, err)
+			}
+
+			// The object itself and the directories below the prefix
+			// that lead to it
+			name = strings.TrimSuffix(name, "/")
+			if name != "" {
+				consider(name)
+			}
+			for dir := path.Dir(name); len(dir) >= len(prefix) && dir != "."; dir = path.Dir(dir) {
+				consider(dir)
+			}
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+
+	names := make([]string, 0, len(matches))
+	for name := range matches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// hasMeta reports whether a path element contains path.Match wildcards.
+func hasMeta(elem string) bool {
+	return strings.ContainsAny(elem, `*?[\`)
+}
+
+// matchElems reports whether the elements of a name match the elements of a
+// Glob pattern, where "**" matches any number of elements.
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,44 @@
+package s3fs_test
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_Glob(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	for _, name := range []string{"a.go", "b.txt", "src/main.go", "src/util/util.go", "src/util/util_test.go", "docs/readme.md"} {
+		writeFile(t, fs, name, "x")
+	}
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"*.go", "a.go"},
+		{"src/*", "src/main.go src/util"},
+		{"**/*.go", "a.go src/main.go src/util/util.go src/util/util_test.go"},
+		{"src/**/*_test.go", "src/util/util_test.go"},
+		{"*/util", "src/util"},
+		{"docs/readme.md", "docs/readme.md"},
+		{"docs/missing.md", ""},
+	}
+	for _, tt := range tests {
+		names, err := fs.Glob(tt.pattern)
+		if err != nil {
+			t.Errorf("Glob(%q) error = %v", tt.pattern, err)
+			continue
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("Glob(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+
+	if _, err := fs.Glob("src/[a"); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("Glob() of a bad pattern error = %v, want ErrBadPattern", err)
+	}
+}
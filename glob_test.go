@@ -0,0 +1,32 @@
+package s3fs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchElems(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "a.go", true},
+		{"*.go", "dir/a.go", false},
+		{"dir/*.go", "dir/a.go", true},
+		{"**/*.go", "a.go", true},
+		{"**/*.go", "x/y/a.go", true},
+		{"x/**/a.go", "x/a.go", true},
+		{"x/**/a.go", "x/y/z/a.go", true},
+		{"x/**/a.go", "y/a.go", false},
+		{"x/**", "x/y/z", true},
+		{"x/**", "x", true},
+		{"**", "any/thing", true},
+		{"x/?/[ab].txt", "x/1/b.txt", true},
+		{"x/?/[ab].txt", "x/12/b.txt", false},
+	}
+	for _, tt := range tests {
+		if got := matchElems(strings.Split(tt.pattern, "/"), strings.Split(tt.name, "/")); got != tt.want {
+			t.Errorf("matchElems(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// guardedClient passes every call to Client through the circuit breaker and
+// the request limiter, either of which may be nil. See
+// Config.BreakerThreshold, Config.MaxConcurrentRequests and
+// Config.MaxRequestsPerSecond.
+type guardedClient struct {
+	Client
+	b *breaker
+	l *limiter
+}
+
+func (c *guardedClient) unwrap() Client { return c.Client }
+
+// guarded calls fn unless the breaker is open, within the limits of the
+// limiter, and records its result in the breaker.
+func guarded[T any](ctx context.Context, c *guardedClient, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := c.b.allow(); err != nil {
+		return zero, err
+	}
+	release, err := c.l.acquire(ctx)
+	if err != nil {
+		return zero, err
+	}
+	defer release()
+
+	out, err := fn()
+	c.b.done(err)
+	return out, err
+}
+
+// releasingBody releases a concurrency slot when the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// GetObject holds its concurrency slot until the body is closed, since the
+// connection stays in use while the body is read.
+func (c *guardedClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if err := c.b.allow(); err != nil {
+		return nil, err
+	}
+	release, err := c.l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.Client.GetObject(ctx, params, optFns...)
+	c.b.done(err)
+	if err != nil || out.Body == nil {
+		release()
+		return out, err
+	}
+	out.Body = &releasingBody{ReadCloser: out.Body, release: release}
+	return out, nil
+}
+
+func (c *guardedClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return guarded(ctx, c, func() (*s3.HeadObjectOutput, error) { return c.Client.HeadObject(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return guarded(ctx, c, func() (*s3.PutObjectOutput, error) { return c.Client.PutObject(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return guarded(ctx, c, func() (*s3.CopyObjectOutput, error) { return c.Client.CopyObject(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return guarded(ctx, c, func() (*s3.DeleteObjectOutput, error) { return c.Client.DeleteObject(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return guarded(ctx, c, func() (*s3.DeleteObjectsOutput, error) { return c.Client.DeleteObjects(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return guarded(ctx, c, func() (*s3.ListObjectsV2Output, error) { return c.Client.ListObjectsV2(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return guarded(ctx, c, func() (*s3.ListObjectVersionsOutput, error) {
+		return c.Client.ListObjectVersions(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	return guarded(ctx, c, func() (*s3.RestoreObjectOutput, error) { return c.Client.RestoreObject(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return guarded(ctx, c, func() (*s3.GetObjectTaggingOutput, error) { return c.Client.GetObjectTagging(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	return guarded(ctx, c, func() (*s3.PutObjectRetentionOutput, error) {
+		return c.Client.PutObjectRetention(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	return guarded(ctx, c, func() (*s3.PutObjectLegalHoldOutput, error) {
+		return c.Client.PutObjectLegalHold(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return guarded(ctx, c, func() (*s3.CreateMultipartUploadOutput, error) {
+		return c.Client.CreateMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return guarded(ctx, c, func() (*s3.UploadPartOutput, error) { return c.Client.UploadPart(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return guarded(ctx, c, func() (*s3.UploadPartCopyOutput, error) { return c.Client.UploadPartCopy(ctx, params, optFns...) })
+}
+
+func (c *guardedClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return guarded(ctx, c, func() (*s3.CompleteMultipartUploadOutput, error) {
+		return c.Client.CompleteMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return guarded(ctx, c, func() (*s3.AbortMultipartUploadOutput, error) {
+		return c.Client.AbortMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	return guarded(ctx, c, func() (*s3.ListMultipartUploadsOutput, error) {
+		return c.Client.ListMultipartUploads(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	return guarded(ctx, c, func() (*s3.GetBucketLifecycleConfigurationOutput, error) {
+		return c.Client.GetBucketLifecycleConfiguration(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	return guarded(ctx, c, func() (*s3.PutBucketLifecycleConfigurationOutput, error) {
+		return c.Client.PutBucketLifecycleConfiguration(ctx, params, optFns...)
+	})
+}
+
+func (c *guardedClient) DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	return guarded(ctx, c, func() (*s3.DeleteBucketLifecycleOutput, error) {
+		return c.Client.DeleteBucketLifecycle(ctx, params, optFns...)
+	})
+}
@@ -1,17 +1,27 @@
 package s3fs
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	iofs "io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // MkdirAll creates a directory path and all parent directories if they don't exist.
 // It's similar to os.MkdirAll but for S3. Since S3 doesn't have real directories,
-// this creates zero-byte marker objects for each directory level.
+// this creates zero-byte marker objects for each directory level. Directories
+// known to exist are remembered for Config.DirCacheTTL, so repeated calls for
+// paths in the same tree skip the per-level existence checks.
 func (fs *FileSystem) MkdirAll(name string, perm os.FileMode) error {
 	name = strings.TrimPrefix(name, "/")
 	if name == "" || name == "." {
@@ -23,17 +33,38 @@ func (fs *FileSystem) MkdirAll(name string, perm os.FileMode) error {
 		name += "/"
 	}
 
+	// Without marker objects there is nothing to create for the parents
+	if fs.implicitDirs {
+		if _, ok := fs.dirs.get(fs.objectKey(name)); ok {
+			return nil
+		}
+		err := fs.Mkdir(name, perm)
+		if errors.Is(err, os.ErrExist) && fs.strictSemantics {
+			if info, serr := fs.Stat(name); serr == nil && info.IsDir() {
+				return nil
+			}
+		}
+		return err
+	}
+
 	// Create all parent directories
 	parts := strings.Split(strings.TrimSuffix(name, "/"), "/")
 	for i := range parts {
 		dir := strings.Join(parts[:i+1], "/") + "/"
 
+		// Skip directories recently seen or created
+		key := fs.objectKey(dir)
+		if _, ok := fs.dirs.get(key); ok {
+			continue
+		}
+
 		// Check if directory already exists
 		exists, err := fs.Exists(dir)
 		if err != nil {
 			return err
 		}
 		if exists {
+			fs.dirs.put(key, nil)
 			continue
 		}
 
@@ -51,35 +82,48 @@ func (fs *FileSystem) MkdirAll(name string, perm os.FileMode) error {
 // with the directory as a prefix.
 func (fs *FileSystem) RemoveAll(name string) error {
 	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
 
 	// Check if it's a directory
-	if !strings.HasSuffix(name, "/") {
+	if !strings.HasSuffix(key, "/") {
 		// Try as directory first
-		dirName := name + "/"
-		isDir, err := fs.isDirectory(dirName)
+		dirKey := key + "/"
+		isDir, err := fs.isDirectory(dirKey)
 		if err == nil && isDir {
-			name = dirName
+			key = dirKey
 		}
 	}
 
 	// If it's a directory, delete all objects with this prefix
-	if strings.HasSuffix(name, "/") {
-		return fs.removePrefix(name)
+	var err error
+	if strings.HasSuffix(key, "/") {
+		fs.dirs.invalidatePrefix(key)
+		err = fs.removePrefix(key)
+	} else {
+		// Otherwise, just remove the single file
+		err = fs.remove(name)
 	}
-
-	// Otherwise, just remove the single file
-	return fs.Remove(name)
+	fs.packs.forget(name)
+	if err == nil {
+		fs.forgetInode(name)
+	}
+	return err
 }
 
-// Exists checks if a file or directory exists in S3.
+// Exists checks if a file or directory exists in S3. Directories exist as
+// marker objects or, like trees uploaded by other tools, as prefixes of
+// other keys. Errors other than a missing name, such as denied requests, are
+// returned rather than reported as a missing name.
 func (fs *FileSystem) Exists(name string) (bool, error) {
 	_, err := fs.Stat(name)
-	if err != nil {
-		// Check if it's a "not found" error
-		// In S3, we consider the object doesn't exist if HeadObject fails
+	var ambiguous *AmbiguousPathError
+	switch {
+	case err == nil, errors.As(err, &ambiguous):
+		return true, nil
+	case errors.Is(err, iofs.ErrNotExist):
 		return false, nil
 	}
-	return true, nil
+	return false, err
 }
 
 // isDirectory checks if a path is a directory (has objects with it as prefix).
@@ -94,7 +138,7 @@ func (fs *FileSystem) isDirectory(name string) (bool, error) {
 		MaxKeys: aws.Int32(1),
 	})
 	if err != nil {
-		return false, wrapError("isDirectory", name, err)
+		return false, fs.wrapError("isDirectory", name, err)
 	}
 
 	return len(output.Contents) > 0, nil
@@ -111,12 +155,19 @@ func (fs *FileSystem) removePrefix(prefix string) error {
 			ContinuationToken: continuationToken,
 		})
 		if err != nil {
-			return wrapError("removePrefix", prefix, err)
+			return fs.wrapError("removePrefix", prefix, err)
 		}
 
 		// Delete all objects in this batch
 		for _, obj := range output.Contents {
-			if err := fs.Remove(aws.ToString(obj.Key)); err != nil {
+			key := aws.ToString(obj.Key)
+			remove := fs.removeKey
+			if fs.trash != "" && !fs.isSystemKey(key) {
+				remove = fs.trashKey
+			} else if fs.isTrashKey(key) {
+				continue // only EmptyTrash deletes trashed objects
+			}
+			if err := remove(key, key); err != nil {
 				return err
 			}
 		}
@@ -132,60 +183,77 @@ func (fs *FileSystem) removePrefix(prefix string) error {
 }
 
 // Walk walks the file tree rooted at root, calling fn for each file or directory.
-// This is similar to filepath.Walk but for S3.
+// This is similar to filepath.Walk but for S3. The hierarchy is built from the
+// object keys, so directories are visited even when no marker object exists for
+// them, always before their children, and the entries of each directory are
+// visited in lexical order. Directory paths are passed to fn with a trailing
+// slash, matching the form of directory marker keys. As with filepath.Walk, fn
+// may return filepath.SkipDir to skip a directory (or the remaining entries of
+// a file's directory) and filepath.SkipAll to stop the walk. The whole tree
+// below root is still listed; use WalkDir to avoid listing skipped directories.
+// If root does not exist, fn is called with root, a nil FileInfo and the error,
+// as filepath.Walk does.
 func (fs *FileSystem) Walk(root string, fn func(path string, info os.FileInfo, err error) error) error {
-	root = strings.TrimPrefix(root, "/")
+	err := fs.walk(strings.TrimPrefix(root, "/"), fn, nil)
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walk implements Walk. visited is passed on to followLinks.
+func (fs *FileSystem) walk(root string, fn filepath.WalkFunc, visited map[string]bool) error {
+	if fs.followSymlinks {
+		fn = fs.followLinks(fn, visited)
+	}
 
 	// Ensure root has trailing slash if it's meant to be a directory
+	given := root
+	var statErr error
 	if root != "" && !strings.HasSuffix(root, "/") {
 		// Check if it's a file or directory
 		info, err := fs.Stat(root)
-		if err == nil {
-			// It's a file, call fn and return
-			if !info.IsDir() {
-				return fn(root, info, nil)
-			}
-			root += "/"
-		} else {
-			root += "/"
+		var ambiguous *AmbiguousPathError
+		switch {
+		case err == nil && !info.IsDir():
+			return fn(root, info, nil)
+		case errors.Is(err, iofs.ErrNotExist):
+			statErr = err // Unless the listing finds a directory without a marker
+		case err != nil && !errors.As(err, &ambiguous):
+			return fn(root, nil, err)
 		}
+		root += "/"
 	}
 
+	tree := newWalkNode(root)
+	found := false
 	var continuationToken *string
-	visited := make(map[string]bool)
 
 	for {
 		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
 			Bucket:            aws.String(fs.bucket),
-			Prefix:            aws.String(root),
+			Prefix:            aws.String(fs.objectKey(root)),
 			ContinuationToken: continuationToken,
 		})
 		if err != nil {
-			return fn(root, nil, wrapError("Walk", root, err))
+			return fn(root, nil, fs.wrapError("Walk", root, err))
 		}
 
-		// Process each object
+		// Add each object to the tree
 		for _, obj := range output.Contents {
 			key := aws.ToString(obj.Key)
-
-			// Skip if already visited
-			if visited[key] {
+			if fs.isSystemKey(key) {
 				continue
 			}
-			visited[key] = true
-
-			// Create file info
-			info := &fileInfo{
-				name:    path.Base(key),
-				size:    *obj.Size,
-				modTime: *obj.LastModified,
-				isDir:   strings.HasSuffix(key, "/"),
+			name, err := fs.logicalName(key)
+			if err != nil {
+				return fn(key, nil, fs.wrapError("Walk", key, err))
 			}
-
-			// Call the walk function
-			if err := fn(key, info, nil); err != nil {
-				return err
+			if name == "" {
+				continue // marker of a Sub root
 			}
+			tree.insert(name, fs.listedInfo(path.Base(name), strings.HasSuffix(name, "/"), obj))
+			found = true
 		}
 
 		// Check if there are more objects
@@ -194,6 +262,126 @@ func (fs *FileSystem) Walk(root string, fn func(path string, info os.FileInfo, e
 		}
 		continuationToken = output.NextContinuationToken
 	}
+	for name, e := range fs.packs.below(root) {
+		tree.insert(name, &fileInfo{name: path.Base(name), size: e.Size, modTime: e.ModTime})
+		found = true
+	}
+	if statErr != nil && !found {
+		// Like filepath.Walk, report a missing root to fn
+		return fn(given, nil, statErr)
+	}
+
+	if root == "" {
+		return tree.walkChildren(fn)
+	}
+	return tree.walk(fn)
+}
+
+// listedInfo returns the FileInfo of a listed object under the given name,
+// dir telling whether the object is a directory marker. Markers are reported
+// as plain directories, without the size and object details of the marker,
+// unless Config.ShowDirMarkers is set.
+func (fs *FileSystem) listedInfo(name string, dir bool, obj types.Object) *fileInfo {
+	if dir && !fs.showDirMarkers {
+		return &fileInfo{name: name, isDir: true}
+	}
+	return &fileInfo{
+		name:    name,
+		size:    *obj.Size,
+		modTime: *obj.LastModified,
+		isDir:   dir,
+		obj:     listObjectInfo(obj),
+	}
+}
+
+// walkNode is a file or directory in the hierarchy Walk builds from keys.
+type walkNode struct {
+	key      string
+	info     *fileInfo
+	children map[string]*walkNode
+}
+
+func newWalkNode(key string) *walkNode {
+	n := &walkNode{key: key}
+	if strings.HasSuffix(key, "/") || key == "" {
+		n.children = make(map[string]*walkNode)
+		n.info = &fileInfo{name: path.Base(key), isDir: true}
+	}
+	return n
+}
+
+// insert adds the object key below n, synthesizing intermediate directories.
+func (n *walkNode) insert(key string, info *fileInfo) {
+	rel := strings.TrimPrefix(key, n.key)
+	if rel == "" {
+		// The marker object of this directory
+		n.info = info
+		return
+	}
+
+	parts := strings.SplitAfter(rel, "/")
+	if parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+
+	node := n
+	for _, part := range parts {
+		child, ok := node.children[part]
+		if !ok {
+			child = newWalkNode(node.key + part)
+			node.children[part] = child
+		}
+		node = child
+	}
+	node.info = info
+}
 
+// walk calls fn for n and then for its descendants. A filepath.SkipDir
+// returned for a directory skips its descendants and is not passed on.
+func (n *walkNode) walk(fn func(path string, info os.FileInfo, err error) error) error {
+	if err := fn(n.key, n.info, nil); err != nil {
+		if err == filepath.SkipDir && n.info.IsDir() {
+			return nil
+		}
+		return err
+	}
+	return n.walkChildren(fn)
+}
+
+// walkChildren walks the children of n in lexical order of their names.
+func (n *walkNode) walkChildren(fn func(path string, info os.FileInfo, err error) error) error {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	// A file and a directory of the same name compare equal without their
+	// slashes; the file comes first
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := strings.TrimSuffix(names[i], "/"), strings.TrimSuffix(names[j], "/")
+		if a != b {
+			return a < b
+		}
+		return len(names[i]) < len(names[j])
+	})
+
+	for _, name := range names {
+		if err := n.children[name].walk(fn); err != nil {
+			if err == filepath.SkipDir {
+				// Returned for a file: skip the rest of this directory
+				return nil
+			}
+			return err
+		}
+	}
 	return nil
 }
+
+// randomID returns 64 random bits in hex, for the identifiers of locks,
+// temp keys and transactions.
+func randomID() string {
+	var random [8]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		panic(fmt.Sprintf("s3fs: reading random bytes: %v", err))
+	}
+	return hex.EncodeToString(random[:])
+}
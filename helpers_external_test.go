@@ -0,0 +1,116 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// deniedHeadClient fails every HeadObject with AccessDenied.
+type deniedHeadClient struct {
+	*s3fstest.Client
+}
+
+func (c *deniedHeadClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, &s3fstest.Error{StatusCode: 403, Code: "AccessDenied", Message: "Access Denied"}
+}
+
+func TestFileSystem_ImplicitDirs(t *testing.T) {
+	client := s3fstest.NewClient()
+	// Uploaded by another tool, without directory markers
+	if _, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("photos/2024/img.jpg"),
+		Body:   strings.NewReader("jpeg"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"photos", "photos/", "photos/2024"} {
+		info, err := fs.Stat(name)
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat(%q) = %v, %v, want a directory", name, info, err)
+		}
+		if ok, err := fs.Exists(name); !ok || err != nil {
+			t.Errorf("Exists(%q) = %v, %v, want true", name, ok, err)
+		}
+	}
+	if ok, err := fs.Exists("photos/2023"); ok || err != nil {
+		t.Errorf("Exists(missing) = %v, %v, want false, nil", ok, err)
+	}
+
+	f, err := fs.Open("photos")
+	if err != nil {
+		t.Fatalf("Open() of an implicit directory = %v", err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || !info.IsDir() {
+		t.Errorf("File.Stat() = %v, %v, want a directory", info, err)
+	}
+	names, err := f.Readdirnames(-1)
+	if err != nil || len(names) != 1 || names[0] != "2024" {
+		t.Errorf("Readdirnames() = %q, %v", names, err)
+	}
+
+	denied, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: &deniedHeadClient{Client: client}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := denied.Exists("photos/2024/img.jpg"); ok || !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Exists() with denied requests = %v, %v, want ErrPermission", ok, err)
+	}
+}
+
+func TestFileSystem_WalkMissingRoot(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	var calls int
+	err := fs.Walk("missing", func(path string, info os.FileInfo, err error) error {
+		calls++
+		if path != "missing" || info != nil || !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("fn(%q, %v, %v), want the missing root with ErrNotExist", path, info, err)
+		}
+		return nil
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("Walk() = %v after %d calls, want nil after 1", err, calls)
+	}
+
+	// A directory without a marker object is still walked
+	writeFile(t, fs, "implicit/a.txt", "a")
+	var paths []string
+	err = fs.Walk("implicit", func(path string, info os.FileInfo, err error) error {
+		paths = append(paths, path)
+		return err
+	})
+	if err != nil || strings.Join(paths, " ") != "implicit/ implicit/a.txt" {
+		t.Errorf("Walk() of a directory without marker = %v, %v", paths, err)
+	}
+}
+
+func TestFileSystem_WalkFileAndDirSameName(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "dir/a", "file")
+	writeFile(t, fs, "dir/a/b.txt", "b")
+	writeFile(t, fs, "dir/a.txt", "a")
+	for i := 0; i < 10; i++ {
+		var paths []string
+		err := fs.Walk("dir", func(path string, info os.FileInfo, err error) error {
+			paths = append(paths, path)
+			return err
+		})
+		if got := strings.Join(paths, " "); err != nil || got != "dir/ dir/a dir/a/ dir/a/b.txt dir/a.txt" {
+			t.Fatalf("Walk() = %s, %v", got, err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package s3fs
 
 import (
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -69,3 +72,89 @@ func TestMkdirAllParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestWalkNode_Order(t *testing.T) {
+	tree := newWalkNode("root/")
+	for _, key := range []string{
+		"root/",
+		"root/b.txt",
+		"root/b/c.txt",
+		"root/a/deep/file.txt",
+		"root/a.txt",
+	} {
+		tree.insert(key, &fileInfo{name: path.Base(key), isDir: strings.HasSuffix(key, "/")})
+	}
+
+	var visited []string
+	err := tree.walk(func(p string, info os.FileInfo, err error) error {
+		visited = append(visited, p)
+		if info == nil {
+			t.Errorf("nil info for %q", p)
+		} else if info.IsDir() != strings.HasSuffix(p, "/") {
+			t.Errorf("IsDir() = %v for %q", info.IsDir(), p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk() error = %v", err)
+	}
+
+	want := []string{
+		"root/",
+		"root/a/",
+		"root/a/deep/",
+		"root/a/deep/file.txt",
+		"root/a.txt",
+		"root/b/",
+		"root/b/c.txt",
+		"root/b.txt",
+	}
+	if strings.Join(visited, ",") != strings.Join(want, ",") {
+		t.Errorf("visited = %v, want %v", visited, want)
+	}
+}
+
+func TestWalkNode_Skip(t *testing.T) {
+	tree := newWalkNode("root/")
+	for _, key := range []string{
+		"root/a/x.txt",
+		"root/a/y.txt",
+		"root/b/skip.txt",
+		"root/c/1.txt",
+		"root/c/2.txt",
+		"root/d.txt",
+	} {
+		tree.insert(key, &fileInfo{name: path.Base(key)})
+	}
+
+	var visited []string
+	err := tree.walk(func(p string, info os.FileInfo, err error) error {
+		visited = append(visited, p)
+		switch p {
+		case "root/b/":
+			return filepath.SkipDir
+		case "root/c/1.txt":
+			return filepath.SkipDir
+		case "root/d.txt":
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != filepath.SkipAll {
+		t.Fatalf("walk() error = %v, want filepath.SkipAll", err)
+	}
+
+	want := []string{
+		"root/",
+		"root/a/",
+		"root/a/x.txt",
+		"root/a/y.txt",
+		"root/b/",
+		"root/c/",
+		"root/c/1.txt",
+		"root/d.txt",
+	}
+	if strings.Join(visited, ",") != strings.Join(want, ",") {
+		t.Errorf("visited = %v, want %v", visited, want)
+	}
+}
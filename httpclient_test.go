@@ -0,0 +1,49 @@
+package s3fs
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigHTTPClient(t *testing.T) {
+	if c, err := (&Config{}).httpClient(); c != nil || err != nil {
+		t.Errorf("httpClient() without options = %v, %v; want nil", c, err)
+	}
+	custom := &http.Client{}
+	if c, _ := (&Config{HTTPClient: custom, ProxyURL: "http://proxy:3128"}).httpClient(); c != custom {
+		t.Errorf("httpClient() did not return Config.HTTPClient")
+	}
+
+	c, err := (&Config{ProxyURL: "http://proxy:3128"}).httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "https://bucket.s3.amazonaws.com/", nil)
+	if proxy, err := c.Transport.(*http.Transport).Proxy(req); err != nil || proxy.String() != "http://proxy:3128" {
+		t.Errorf("proxy = %v, %v; want http://proxy:3128", proxy, err)
+	}
+
+	if _, err := (&Config{CABundle: []byte("not a certificate")}).httpClient(); !errors.Is(err, ErrInvalidCABundle) {
+		t.Errorf("httpClient() with an invalid CA bundle error = %v, want ErrInvalidCABundle", err)
+	}
+
+	// A private endpoint with a certificate that is only trusted through the bundle
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	if _, err := http.DefaultClient.Get(server.URL); err == nil {
+		t.Fatal("GET with the default client succeeded, want an unknown authority error")
+	}
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	c, err = (&Config{CABundle: bundle}).httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET with the CA bundle error = %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,191 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// HTTPFileSystem returns an http.FileSystem serving fs, for use with
+// http.FileServer. Files are read with Range requests from the offset
+// http.ServeContent seeks to, so partial requests only download the bytes
+// asked for. Directories are listed with a delimiter listing and exist
+// whenever any key is stored below them.
+func (fs *FileSystem) HTTPFileSystem() http.FileSystem {
+	return httpFS{fs}
+}
+
+type httpFS struct {
+	fs *FileSystem
+}
+
+// Open opens the named file or directory.
+func (h httpFS) Open(name string) (http.File, error) {
+	rel := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if rel == "" {
+		return h.openDir(name, "")
+	}
+
+	info, err := h.fs.Stat(rel)
+	switch {
+	case err == nil && !info.IsDir():
+		f, err := h.fs.OpenFile(rel, os.O_RDONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		return &httpFile{File: f.(*File), info: info}, nil
+	case err == nil:
+		return h.openDir(name, rel+"/")
+	case !isNotFound(err):
+		return nil, err
+	}
+
+	isDir, err := h.fs.isDirectory(h.fs.objectKey(rel + "/"))
+	if err != nil {
+		return nil, err
+	}
+	if !isDir {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return h.openDir(name, rel+"/")
+}
+
+func (h httpFS) openDir(name, dir string) (http.File, error) {
+	return &httpFile{
+		File: &File{fs: h.fs, name: dir, key: h.fs.objectKey(dir)},
+		info: &fileInfo{name: path.Base("/" + dir), isDir: true},
+	}, nil
+}
+
+// httpFile is an http.File backed by a File. Its info is taken when it is
+// opened, so Stat and Seek relative to the end need no further requests.
+type httpFile struct {
+	*File
+	info os.FileInfo
+
+	entries []os.FileInfo // Directory entries, listed on first Readdir
+	listed  bool
+}
+
+// Stat returns the info recorded when the file was opened.
+func (f *httpFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+// Seek sets the offset for the next Read, resolving io.SeekEnd against the
+// size recorded when the file was opened.
+func (f *httpFile) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		return f.File.Seek(f.info.Size()+offset, io.SeekStart)
+	}
+	return f.File.Seek(offset, whence)
+}
+
+// Readdir returns the direct entries of a directory with their base names,
+// following the contract of os.File.Readdir.
+func (f *httpFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.info.IsDir() {
+		return nil, &os.PathError{Op: "readdirent", Path: f.name, Err: errors.New("not a directory")}
+	}
+	if !f.listed {
+		entries, err := f.fs.listDir(f.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			info, _ := e.Info()
+			f.entries = append(f.entries, info)
+		}
+		f.listed = true
+	}
+
+	if count <= 0 {
+		infos := f.entries
+		f.entries = nil
+		if infos == nil {
+			infos = []os.FileInfo{}
+		}
+		return infos, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.entries) {
+		count = len(f.entries)
+	}
+	infos := f.entries[:count]
+	f.entries = f.entries[count:]
+	return infos, nil
+}
+
+// Handler returns an http.Handler serving the files of fs for GET and HEAD
+// requests. Files are served with http.ServeContent and the object's ETag,
+// Last-Modified and Content-Type, so Range, If-Range, If-None-Match and
+// If-Modified-Since requests are answered without downloading more than
+// needed; the object is looked up with a single HeadObject request.
+// Directories are served like http.FileServer does.
+func (fs *FileSystem) Handler() http.Handler {
+	return &httpHandler{fs: fs, files: http.FileServer(fs.HTTPFileSystem())}
+}
+
+type httpHandler struct {
+	fs    *FileSystem
+	files http.Handler
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rel := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if rel == "" || strings.HasSuffix(r.URL.Path, "/") || path.Base(rel) == "index.html" {
+		h.files.ServeHTTP(w, r)
+		return
+	}
+
+	fs := h.fs.WithContext(r.Context())
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.objectKey(rel)),
+	})
+	if isNotFound(err) {
+		// Directories without marker objects, redirects and 404s
+		h.files.ServeHTTP(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	info := &fileInfo{
+		name:    path.Base(rel),
+		size:    aws.ToInt64(head.ContentLength),
+		modTime: aws.ToTime(head.LastModified),
+		obj:     headObjectInfo(fs.objectKey(rel), head),
+	}
+	if etag := aws.ToString(head.ETag); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if ct := aws.ToString(head.ContentType); ct != "" && ct != "binary/octet-stream" {
+		w.Header().Set("Content-Type", ct)
+	}
+
+	f := &httpFile{File: &File{fs: fs, name: rel, key: fs.objectKey(rel)}, info: info}
+	defer f.Close()
+	http.ServeContent(w, r, rel, info.modTime, f)
+}
+
+// isNotFound reports whether err means that an object does not exist.
+func isNotFound(err error) bool {
+	return err != nil && (errors.Is(err, ErrNotExist) || errors.Is(err, os.ErrNotExist) || httpStatus(err) == http.StatusNotFound)
+}
@@ -0,0 +1,72 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestHTTPFile_Seek(t *testing.T) {
+	f := &httpFile{File: &File{}, info: &fileInfo{size: 100}}
+
+	off, err := f.Seek(-10, io.SeekEnd)
+	if err != nil || off != 90 {
+		t.Fatalf("Seek(-10, SeekEnd) = %d, %v; want 90", off, err)
+	}
+	if off, _ := f.Seek(5, io.SeekCurrent); off != 95 {
+		t.Errorf("Seek(5, SeekCurrent) = %d, want 95", off)
+	}
+	if info, err := f.Stat(); err != nil || info.Size() != 100 {
+		t.Errorf("Stat() = %v, %v", info, err)
+	}
+}
+
+func TestHTTPFile_Readdir(t *testing.T) {
+	f := &httpFile{
+		File:    &File{name: "docs/"},
+		info:    &fileInfo{name: "docs", isDir: true},
+		entries: []os.FileInfo{&fileInfo{name: "a"}, &fileInfo{name: "b"}, &fileInfo{name: "c"}},
+		listed:  true,
+	}
+
+	infos, err := f.Readdir(2)
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("Readdir(2) = %d entries, %v", len(infos), err)
+	}
+	infos, err = f.Readdir(2)
+	if err != nil || len(infos) != 1 || infos[0].Name() != "c" {
+		t.Fatalf("second Readdir(2) = %d entries, %v", len(infos), err)
+	}
+	if _, err := f.Readdir(2); err != io.EOF {
+		t.Errorf("Readdir(2) after exhaustion error = %v, want io.EOF", err)
+	}
+	if infos, err := f.Readdir(-1); err != nil || len(infos) != 0 {
+		t.Errorf("Readdir(-1) after exhaustion = %v, %v", infos, err)
+	}
+
+	file := &httpFile{File: &File{}, info: &fileInfo{name: "a.txt"}}
+	if _, err := file.Readdir(-1); err == nil {
+		t.Errorf("Readdir() on a file should fail")
+	}
+}
+
+func TestHandler_Method(t *testing.T) {
+	h := (&FileSystem{}).Handler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/a.txt", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") == "" {
+		t.Errorf("POST status = %d, Allow = %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if isNotFound(nil) || isNotFound(errors.New("boom")) {
+		t.Errorf("isNotFound() = true for unrelated errors")
+	}
+	if !isNotFound(wrapError("Stat", "x", ErrNotExist)) || !isNotFound(os.ErrNotExist) {
+		t.Errorf("isNotFound() = false for not-exist errors")
+	}
+}
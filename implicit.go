@@ -0,0 +1,65 @@
+package s3fs
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// checkImplicitDir validates a Mkdir call when Config.ImplicitDirs is set.
+// No marker object is written; the call only fails if a file already exists
+// under the directory's name.
+func (fs *FileSystem) checkImplicitDir(dir, key string) error {
+	if key == "/" {
+		return nil
+	}
+
+	_, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(strings.TrimSuffix(key, "/")),
+	})
+	if err == nil {
+		return fs.wrapError("Mkdir", dir, os.ErrExist)
+	}
+	if httpStatus(err) != 404 {
+		return fs.wrapError("Mkdir", dir, err)
+	}
+
+	fs.dirs.put(key, nil)
+	return nil
+}
+
+// statImplicitDir reports name, stored under key, as a directory if any key
+// exists below it. notFound is the error returned by the preceding
+// HeadObject, if any, and is returned when the prefix is empty as well.
+func (fs *FileSystem) statImplicitDir(name, key string, notFound error) (os.FileInfo, error) {
+	dir := strings.TrimSuffix(key, "/")
+	if dir == "" {
+		return &fileInfo{name: "/", isDir: true}, nil
+	}
+	info := &fileInfo{name: path.Base(strings.TrimSuffix(name, "/")), isDir: true}
+	if info.obj = fs.statDirMarker(dir + "/"); info.obj != nil {
+		return info, nil
+	}
+
+	if _, ok := fs.dirs.get(dir + "/"); ok {
+		return info, nil
+	}
+
+	isDir, err := fs.isDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !isDir {
+		if notFound == nil {
+			notFound = ErrNotExist
+		}
+		return nil, fs.wrapError("Stat", name, notFound)
+	}
+
+	fs.dirs.put(dir+"/", nil)
+	return info, nil
+}
@@ -0,0 +1,36 @@
+package s3fs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatImplicitDir_Cached(t *testing.T) {
+	fs := &FileSystem{dirs: newStatCache(time.Minute, 0)}
+
+	info, err := fs.statImplicitDir("", "", nil)
+	if err != nil || !info.IsDir() || info.Name() != "/" {
+		t.Errorf("statImplicitDir(\"\") = %v, %v; want root directory", info, err)
+	}
+
+	fs.dirs.put("a/b/", nil)
+	for _, name := range []string{"a/b", "a/b/"} {
+		info, err := fs.statImplicitDir(name, name, nil)
+		if err != nil {
+			t.Fatalf("statImplicitDir(%q) error = %v", name, err)
+		}
+		if !info.IsDir() || info.Name() != "b" {
+			t.Errorf("statImplicitDir(%q) = %q dir=%v, want \"b\" dir=true", name, info.Name(), info.IsDir())
+		}
+	}
+}
+
+func TestMkdirAll_ImplicitDirsCached(t *testing.T) {
+	fs := &FileSystem{implicitDirs: true, dirs: newStatCache(time.Minute, 0)}
+	fs.dirs.put("a/b/", nil)
+
+	// A cached directory needs no request, so the nil client is never used.
+	if err := fs.MkdirAll("/a/b", 0755); err != nil {
+		t.Errorf("MkdirAll() error = %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package s3fs
+
+import (
+	"sync"
+	"time"
+)
+
+// manifestCache keeps loaded directory manifests for a fixed TTL.
+// A nil *manifestCache is valid and caches nothing.
+type manifestCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]manifestCacheEntry
+}
+
+type manifestCacheEntry struct {
+	m       *manifest
+	expires time.Time
+}
+
+// newManifestCache returns a cache for the given TTL, or nil if ttl is zero.
+func newManifestCache(ttl time.Duration) *manifestCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &manifestCache{ttl: ttl, entries: make(map[string]manifestCacheEntry)}
+}
+
+// get returns a copy of the cached manifest of dir, or nil.
+func (c *manifestCache) get(dir string) *manifest {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := manifestKey(dir)
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	return e.m.clone()
+}
+
+// put caches a copy of m as the manifest of dir.
+func (c *manifestCache) put(dir string, m *manifest) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[manifestKey(dir)] = manifestCacheEntry{m: m.clone(), expires: time.Now().Add(c.ttl)}
+}
+
+// invalidate drops the cached manifest of dir.
+func (c *manifestCache) invalidate(dir string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, manifestKey(dir))
+}
+
+// clear drops every cached manifest.
+func (c *manifestCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// clone returns a copy of m that can be modified independently. Inline data
+// is shared, as it is never modified in place.
+func (m *manifest) clone() *manifest {
+	c := &manifest{Entries: make(map[string]manifestEntry, len(m.Entries)), etag: m.etag}
+	for name, e := range m.Entries {
+		c.Entries[name] = e
+	}
+	return c
+}
+
+// manifestPutInline records name in its parent directory's manifest together
+// with its content.
+func (fs *FileSystem) manifestPutInline(name string, data []byte) error {
+	data = append([]byte(nil), data...)
+	return fs.updateManifest(name, func(m *manifest, base string) {
+		m.Entries[base] = manifestEntry{Size: int64(len(data)), ModTime: time.Now(), Data: data}
+	})
+}
+
+// inlineData returns the content of name stored in its directory manifest,
+// if inlining is enabled and the manifest has it.
+func (fs *FileSystem) inlineData(name string) ([]byte, bool) {
+	if !fs.manifests || fs.inlineThreshold <= 0 {
+		return nil, false
+	}
+
+	dir, base := splitManifestPath(name)
+	if base == "" {
+		return nil, false
+	}
+	m, err := fs.loadManifest(dir)
+	if err != nil || m == nil {
+		return nil, false // Read the object instead
+	}
+	e, ok := m.Entries[base]
+	if !ok || e.Data == nil || int64(len(e.Data)) != e.Size {
+		return nil, false
+	}
+	return e.Data, true
+}
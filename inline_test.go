@@ -0,0 +1,68 @@
+package s3fs
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestManifestCache(t *testing.T) {
+	var nilCache *manifestCache
+	nilCache.put("a", &manifest{})
+	if nilCache.get("a") != nil {
+		t.Errorf("nil cache returned a manifest")
+	}
+
+	c := newManifestCache(time.Minute)
+	m := &manifest{Entries: map[string]manifestEntry{"x": {Size: 1}}}
+	c.put("/a/", m)
+
+	// The cache keeps its own copy
+	m.Entries["y"] = manifestEntry{}
+	got := c.get("a")
+	if got == nil || len(got.Entries) != 1 {
+		t.Fatalf("get() = %+v, want the manifest as put", got)
+	}
+	got.Entries["z"] = manifestEntry{}
+	if len(c.get("a").Entries) != 1 {
+		t.Errorf("modifying a returned manifest changed the cache")
+	}
+
+	c.invalidate("a")
+	if c.get("a") != nil {
+		t.Errorf("get() after invalidate() returned a manifest")
+	}
+
+	c = newManifestCache(time.Nanosecond)
+	c.put("a", m)
+	time.Sleep(time.Millisecond)
+	if c.get("a") != nil {
+		t.Errorf("get() returned an expired manifest")
+	}
+}
+
+func TestFile_InlineRead(t *testing.T) {
+	fs := &FileSystem{
+		manifests:       true,
+		inlineThreshold: 1024,
+		manifestCache:   newManifestCache(time.Minute),
+	}
+	fs.manifestCache.put("conf", &manifest{Entries: map[string]manifestEntry{
+		"flags.json": {Size: 11, Data: []byte(`{"on":true}`)},
+	}})
+
+	// Served from the cached manifest, so the nil client is never used.
+	f := &File{fs: fs, name: "conf/flags.json", key: "conf/flags.json"}
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != `{"on":true}` {
+		t.Errorf("ReadAll() = %q, %v", data, err)
+	}
+
+	buf := make([]byte, 4)
+	if n, err := f.ReadAt(buf, 1); n != 4 || err != nil || string(buf) != `"on"` {
+		t.Errorf("ReadAt() = %d, %v, %q", n, err, buf)
+	}
+	if n, err := f.ReadAt(buf, 9); n != 2 || err != io.EOF {
+		t.Errorf("ReadAt() at the end = %d, %v; want 2, io.EOF", n, err)
+	}
+}
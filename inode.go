@@ -0,0 +1,276 @@
+package s3fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// RootInode is the inode number of the bucket root, as FUSE expects.
+const RootInode = 1
+
+// inodeIndexName is the name of the inode index below the system prefix.
+const inodeIndexName = "inodes.json"
+
+// inodeIndex is the JSON document that maps keys to inode numbers. Keys are
+// object keys without a trailing slash, so a directory has the same number
+// whether or not it has a marker object.
+type inodeIndex struct {
+	Next   uint64            `json:"next"`
+	Inodes map[string]uint64 `json:"inodes"`
+}
+
+// assign returns the number of key, assigning the next free one if it has
+// none.
+func (x *inodeIndex) assign(key string) uint64 {
+	if key == "" {
+		return RootInode
+	}
+	if ino, ok := x.Inodes[key]; ok {
+		return ino
+	}
+	x.Next = max(x.Next, RootInode+1)
+	ino := x.Next
+	x.Next++
+	x.Inodes[key] = ino
+	return ino
+}
+
+// move gives the numbers of key and the keys below it to the same names
+// below newKey.
+func (x *inodeIndex) move(key, newKey string) {
+	moved := make(map[string]uint64)
+	for k, ino := range x.Inodes {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(x.Inodes, k)
+			moved[newKey+k[len(key):]] = ino
+		}
+	}
+	for k, ino := range moved {
+		x.Inodes[k] = ino
+	}
+}
+
+// forget drops the numbers of key and the keys below it. Numbers are not
+// reused.
+func (x *inodeIndex) forget(key string) {
+	for k := range x.Inodes {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(x.Inodes, k)
+		}
+	}
+}
+
+// inodeTable is the inode index of a FileSystem, shared with the
+// filesystems derived from it. The index is read on first use; changes are
+// kept in a journal until SyncInodes saves them, so they can be replayed
+// onto an index another process saved in the meantime.
+type inodeTable struct {
+	mu      sync.Mutex
+	loaded  bool
+	etag    string // ETag of the saved index, empty if there is none
+	index   inodeIndex
+	journal []func(*inodeIndex)
+}
+
+// newInodeTable returns an empty table, or nil if enabled is false.
+func newInodeTable(enabled bool) *inodeTable {
+	if !enabled {
+		return nil
+	}
+	return &inodeTable{}
+}
+
+// apply records a change and makes it to the index if it is loaded.
+func (t *inodeTable) apply(change func(*inodeIndex)) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.journal = append(t.journal, change)
+	if t.loaded {
+		change(&t.index)
+	}
+}
+
+// inodeKey returns the key the index stores the number of name under.
+func (fs *FileSystem) inodeKey(name string) string {
+	return strings.Trim(fs.objectKey(strings.TrimPrefix(name, "/")), "/")
+}
+
+// Inode returns the inode number of the named file or directory, assigning
+// one if the name has none yet, see Config.Inodes. The number stays the
+// same across Rename and RenameDir and is not reused after Remove or
+// RemoveAll. It does not check that name exists. Numbers assigned or moved
+// are saved by SyncInodes and Close.
+func (fs *FileSystem) Inode(name string) (uint64, error) {
+	t := fs.inodes
+	if t == nil {
+		return 0, fs.wrapError("Inode", name, ErrInodesDisabled)
+	}
+	key := fs.inodeKey(name)
+	if key == "" {
+		return RootInode, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := fs.loadInodes(); err != nil {
+		return 0, fs.wrapError("Inode", name, err)
+	}
+	if ino, ok := t.index.Inodes[key]; ok {
+		return ino, nil
+	}
+	change := func(x *inodeIndex) { x.assign(key) }
+	t.journal = append(t.journal, change)
+	return t.index.assign(key), nil
+}
+
+// SyncInodes saves the inode numbers assigned, moved and dropped since the
+// index was last saved. The index is written conditionally; if another
+// process saved it in the meantime, the changes are replayed onto its
+// version, and numbers this process assigned since the last save may
+// change. The S3 service or S3-compatible store must support conditional
+// writes.
+func (fs *FileSystem) SyncInodes() error {
+	t := fs.inodes
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.journal) == 0 {
+		return nil
+	}
+	for {
+		if err := fs.loadInodes(); err != nil {
+			return fs.wrapError("SyncInodes", inodeIndexName, err)
+		}
+		err := fs.saveInodes()
+		if err == nil {
+			t.journal = nil
+			return nil
+		}
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return fs.wrapError("SyncInodes", inodeIndexName, err)
+		}
+		t.loaded = false // Saved by someone else: replay onto theirs
+	}
+}
+
+// loadInodes reads the saved index, if it is not loaded, and replays the
+// journal onto it. The caller holds fs.inodes.mu.
+func (fs *FileSystem) loadInodes() error {
+	t := fs.inodes
+	if t.loaded {
+		return nil
+	}
+	index := inodeIndex{Inodes: make(map[string]uint64)}
+	etag := ""
+	output, err := fs.client.GetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.systemKey(inodeIndexName)),
+	})
+	switch {
+	case err == nil:
+		data, err := io.ReadAll(output.Body)
+		output.Body.Close()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &index); err != nil {
+			return err
+		}
+		if index.Inodes == nil {
+			index.Inodes = make(map[string]uint64)
+		}
+		etag = aws.ToString(output.ETag)
+	case httpStatus(err) != 404:
+		return err
+	}
+
+	for _, change := range t.journal {
+		change(&index)
+	}
+	t.index, t.etag, t.loaded = index, etag, true
+	return nil
+}
+
+// saveInodes writes the loaded index if the saved one is still the one it
+// was loaded from. The caller holds fs.inodes.mu.
+func (fs *FileSystem) saveInodes() error {
+	t := fs.inodes
+	data, err := json.Marshal(&t.index)
+	if err != nil {
+		return err
+	}
+	cond := writeCondition{ifMatch: t.etag}
+	if t.etag == "" {
+		cond = writeCondition{ifNoneMatch: "*"}
+	}
+	output, err := fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(fs.bucket),
+		Key:           aws.String(fs.systemKey(inodeIndexName)),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String("application/json"),
+	}, cond.options()...)
+	if err != nil {
+		return preconditionError(err)
+	}
+	t.etag = aws.ToString(output.ETag)
+	return nil
+}
+
+// moveInode records that the object or directory stored under name moved to
+// newName.
+func (fs *FileSystem) moveInode(name, newName string) {
+	if fs.inodes == nil {
+		return
+	}
+	key, newKey := fs.inodeKey(name), fs.inodeKey(newName)
+	if key == "" || key == newKey {
+		return
+	}
+	fs.inodes.apply(func(x *inodeIndex) { x.move(key, newKey) })
+}
+
+// forgetInode records that the object or directory stored under name was
+// removed.
+func (fs *FileSystem) forgetInode(name string) {
+	if fs.inodes == nil {
+		return
+	}
+	if key := fs.inodeKey(name); key != "" {
+		fs.inodes.apply(func(x *inodeIndex) { x.forget(key) })
+	}
+}
+
+// withInode returns a copy of info whose Sys reports the inode number of
+// name in ObjectInfo.Inode, if Config.Inodes is set.
+func (fs *FileSystem) withInode(name string, info os.FileInfo) (os.FileInfo, error) {
+	fi, ok := info.(*fileInfo)
+	if fs.inodes == nil || !ok {
+		return info, nil
+	}
+	ino, err := fs.Inode(name)
+	if err != nil {
+		return nil, err
+	}
+	obj := ObjectInfo{Key: fs.objectKey(strings.TrimPrefix(name, "/"))}
+	if fi.obj != nil {
+		obj = *fi.obj
+	}
+	obj.Inode = ino
+	c := *fi
+	c.obj = &obj
+	return &c, nil
+}
@@ -0,0 +1,94 @@
+package s3fs_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_Inodes(t *testing.T) {
+	if _, err := s3fstest.New("bucket").Inode("a"); !errors.Is(err, s3fs.ErrInodesDisabled) {
+		t.Errorf("Inode() without Inodes error = %v, want ErrInodesDisabled", err)
+	}
+
+	client := s3fstest.NewClient()
+	open := func() *s3fs.FileSystem {
+		fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, Inodes: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+	ino := func(fs *s3fs.FileSystem, name string) uint64 {
+		info, err := fs.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q) error = %v", name, err)
+		}
+		obj, ok := info.Sys().(*s3fs.ObjectInfo)
+		if !ok || obj.Inode == 0 {
+			t.Fatalf("Stat(%q).Sys() = %v, want an inode number", name, info.Sys())
+		}
+		return obj.Inode
+	}
+
+	fs := open()
+	writeFile(t, fs, "dir/a.txt", "a")
+	writeFile(t, fs, "dir/sub/b.txt", "b")
+	a, b, dir := ino(fs, "dir/a.txt"), ino(fs, "dir/sub/b.txt"), ino(fs, "dir")
+	if a == b || a == dir || ino(fs, "dir/") != dir || ino(fs, "/dir/a.txt") != a {
+		t.Errorf("inodes a = %d, b = %d, dir = %d", a, b, dir)
+	}
+	if n, err := fs.Sub("dir").Inode("a.txt"); err != nil || n != a {
+		t.Errorf("Sub().Inode() = %d, %v, want %d", n, err, a)
+	}
+	if n, err := fs.Inode("/"); err != nil || n != s3fs.RootInode {
+		t.Errorf("Inode(\"/\") = %d, %v, want RootInode", n, err)
+	}
+
+	if err := fs.Rename("dir/a.txt", "dir/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Rename("dir/sub", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if ino(fs, "dir/c.txt") != a || ino(fs, "moved/b.txt") != b {
+		t.Errorf("inodes changed by Rename")
+	}
+	if err := fs.Remove("dir/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "dir/c.txt", "c")
+	if n := ino(fs, "dir/c.txt"); n == a {
+		t.Errorf("inode %d reused after Remove", n)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Another process sees the saved numbers, and changes made concurrently
+	// are merged when saved
+	fs1, fs2 := open(), open()
+	if ino(fs1, "moved/b.txt") != b || ino(fs2, "dir") != dir {
+		t.Errorf("inodes not saved")
+	}
+	writeFile(t, fs1, "one", "1")
+	writeFile(t, fs2, "two", "2")
+	one, two := ino(fs1, "one"), ino(fs2, "two")
+	if err := fs1.SyncInodes(); err != nil {
+		t.Fatalf("SyncInodes() error = %v", err)
+	}
+	if err := fs2.SyncInodes(); err != nil {
+		t.Fatalf("SyncInodes() after concurrent save error = %v", err)
+	}
+	fs3 := open()
+	if ino(fs3, "one") != one || ino(fs3, "two") == one || ino(fs3, "two") != ino(fs2, "two") {
+		t.Errorf("merged inodes one = %d, two = %d (was %d)", ino(fs3, "one"), ino(fs3, "two"), two)
+	}
+
+	if _, dirs, err := fs3.ListDir(""); err != nil || strings.Join(dirs, " ") != "dir/ moved/" {
+		t.Errorf("ListDir() = %q, %v, want the index hidden", dirs, err)
+	}
+}
@@ -0,0 +1,31 @@
+package s3fs
+
+import "testing"
+
+func TestInodeIndex(t *testing.T) {
+	x := inodeIndex{Inodes: make(map[string]uint64)}
+	if ino := x.assign(""); ino != RootInode {
+		t.Errorf("assign(\"\") = %d, want %d", ino, RootInode)
+	}
+	a, b, c := x.assign("a"), x.assign("d/b"), x.assign("d/e/c")
+	if a != 2 || b != 3 || c != 4 || x.assign("a") != a {
+		t.Errorf("assign() = %d, %d, %d, want 2, 3, 4", a, b, c)
+	}
+
+	x.move("d", "f")
+	if x.Inodes["f/b"] != b || x.Inodes["f/e/c"] != c || len(x.Inodes) != 3 {
+		t.Errorf("move() = %v", x.Inodes)
+	}
+	x.move("a", "a/x")
+	if x.Inodes["a/x"] != a {
+		t.Errorf("move() into itself = %v", x.Inodes)
+	}
+
+	x.forget("f")
+	if len(x.Inodes) != 1 {
+		t.Errorf("forget() = %v", x.Inodes)
+	}
+	if ino := x.assign("f/b"); ino != 5 {
+		t.Errorf("assign() after forget = %d, want 5", ino)
+	}
+}
@@ -0,0 +1,187 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MaxKeyLength is the longest object key S3 accepts, in bytes of UTF-8.
+const MaxKeyLength = 1024
+
+// ValidateKey checks key against the constraints of S3 object keys and
+// returns a *KeyError for the first one it breaks: keys must be valid UTF-8
+// of at most MaxKeyLength bytes, without control characters, and without
+// empty, "." or ".." path elements, which many S3 tools normalize away so
+// the object cannot be reached by its name. A trailing slash, as in
+// directory markers, is allowed.
+func ValidateKey(key string) error {
+	if err := checkKeyLength(key); err != nil {
+		return err
+	}
+	if !utf8.ValidString(key) {
+		return &KeyError{Key: key, Reason: "not valid UTF-8"}
+	}
+	for _, r := range key {
+		if r < 0x20 || r == 0x7f {
+			return &KeyError{Key: key, Reason: fmt.Sprintf("control character %U", r)}
+		}
+	}
+
+	elems := strings.Split(strings.TrimSuffix(key, "/"), "/")
+	for _, elem := range elems {
+		switch elem {
+		case "":
+			return &KeyError{Key: key, Reason: "empty path element"}
+		case ".", "..":
+			return &KeyError{Key: key, Reason: fmt.Sprintf("%q path element", elem)}
+		}
+	}
+	return nil
+}
+
+// checkKeyLength returns a *KeyError if key is longer than MaxKeyLength.
+func checkKeyLength(key string) error {
+	if len(key) > MaxKeyLength {
+		return &KeyError{Key: key, Reason: fmt.Sprintf("%d bytes, longer than %d", len(key), MaxKeyLength)}
+	}
+	return nil
+}
+
+// KeyDecoder is implemented by a NameCodec whose keys can be decoded back to
+// names without reading object metadata, such as NewPercentCodec. Listings
+// then cost no HeadObject requests.
+type KeyDecoder interface {
+	// DecodeKey returns the name of a key returned by EncodeKey.
+	DecodeKey(key string) (string, error)
+}
+
+// percentCodec is the NameCodec returned by NewPercentCodec.
+type percentCodec struct{}
+
+// NewPercentCodec returns a NameCodec that percent-encodes every byte of a
+// name outside the characters S3 documents as safe for keys (ASCII letters,
+// digits and !-_.*'()), as well as "." and ".." path elements. Any local
+// file name can then be stored and round-tripped, and keys pass
+// ValidateKey unless they grow too long. It implements KeyDecoder, so the
+// names are recovered from the keys alone.
+func NewPercentCodec() NameCodec {
+	return percentCodec{}
+}
+
+// isSafeKeyByte reports whether c is stored unencoded by the percent codec.
+func isSafeKeyByte(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!-_.*'()", c) >= 0
+}
+
+// EncodeKey implements NameCodec.
+func (percentCodec) EncodeKey(name string) string {
+	elems := strings.Split(name, "/")
+	for i, elem := range elems {
+		if elem == "." || elem == ".." {
+			elems[i] = strings.Repeat("%2E", len(elem))
+			continue
+		}
+
+		var b strings.Builder
+		for j := 0; j < len(elem); j++ {
+			c := elem[j]
+			if isSafeKeyByte(c) {
+				b.WriteByte(c)
+			} else {
+				fmt.Fprintf(&b, "%%%02X", c)
+			}
+		}
+		elems[i] = b.String()
+	}
+	return strings.Join(elems, "/")
+}
+
+// DecodeKey implements KeyDecoder.
+func (percentCodec) DecodeKey(key string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+2 >= len(key) || unhex(key[i+1]) < 0 || unhex(key[i+2]) < 0 {
+			return "", &KeyError{Key: key, Reason: "invalid percent-encoding"}
+		}
+		b.WriteByte(byte(unhex(key[i+1])<<4 | unhex(key[i+2])))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// SealName implements NameCodec. The encoded key is stored, since metadata
+// values must be ASCII.
+func (c percentCodec) SealName(name string) (string, error) {
+	return c.EncodeKey(name), nil
+}
+
+// OpenName implements NameCodec.
+func (c percentCodec) OpenName(sealed string) (string, error) {
+	return c.DecodeKey(sealed)
+}
+
+// unhex returns the value of the hexadecimal digit c, or -1.
+func unhex(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10)
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 10)
+	}
+	return -1
+}
+
+// keyCheckClient rejects requests that would create objects under keys
+// ValidateKey refuses, see Config.ValidateKeys. Without strict it only
+// checks the length, which S3 enforces anyway, to fail with a clearer error.
+type keyCheckClient struct {
+	Client
+	strict bool
+}
+
+func (c *keyCheckClient) unwrap() Client { return c.Client }
+
+// check validates a key of a request.
+func (c *keyCheckClient) check(key *string) error {
+	if !c.strict {
+		return checkKeyLength(aws.ToString(key))
+	}
+	return ValidateKey(aws.ToString(key))
+}
+
+func (c *keyCheckClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := c.check(params.Key); err != nil {
+		return nil, err
+	}
+	return c.Client.PutObject(ctx, params, optFns...)
+}
+
+func (c *keyCheckClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if err := c.check(params.Key); err != nil {
+		return nil, err
+	}
+	return c.Client.CopyObject(ctx, params, optFns...)
+}
+
+func (c *keyCheckClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	if err := c.check(params.Key); err != nil {
+		return nil, err
+	}
+	return c.Client.CreateMultipartUpload(ctx, params, optFns...)
+}
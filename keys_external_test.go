@@ -0,0 +1,62 @@
+package s3fs_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_KeyValidation(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, ValidateKeys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("bad\x01name", []byte("x"), 0o644); !errors.Is(err, s3fs.ErrInvalidKey) {
+		t.Errorf("WriteFile() of a control character = %v, want ErrInvalidKey", err)
+	}
+	if err := fs.Mkdir("a//b", 0o755); !errors.Is(err, s3fs.ErrInvalidKey) {
+		t.Errorf("Mkdir() of an empty element = %v, want ErrInvalidKey", err)
+	}
+	if n := fs.Stats().Operations["PutObject"].Requests; n != 0 {
+		t.Errorf("PutObject requests = %d, want none for invalid keys", n)
+	}
+
+	// Without ValidateKeys only the length is checked
+	fs, err = s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile(strings.Repeat("a", s3fs.MaxKeyLength+1), nil, 0o644); !errors.Is(err, s3fs.ErrInvalidKey) {
+		t.Errorf("WriteFile() of a long key = %v, want ErrInvalidKey", err)
+	}
+
+	// The percent codec stores any name under a valid key
+	fs, err = s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, ValidateKeys: true, NameCodec: s3fs.NewPercentCodec()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "dir/bad\x01name [1].txt"
+	if err := fs.WriteFile(name, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	heads := fs.Stats().Operations["HeadObject"].Requests
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "bad\x01name [1].txt" {
+		t.Errorf("Readdirnames() = %q, want [%q]", names, "bad\x01name [1].txt")
+	}
+	if n := fs.Stats().Operations["HeadObject"].Requests - heads; n != 0 {
+		t.Errorf("HeadObject requests = %d, want names decoded from keys", n)
+	}
+}
@@ -0,0 +1,64 @@
+package s3fs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key string
+		ok  bool
+	}{
+		{"a/b.txt", true},
+		{"dir/", true},
+		{"ü nicode (1).txt", true},
+		{strings.Repeat("a", MaxKeyLength), true},
+		{strings.Repeat("a", MaxKeyLength+1), false},
+		{"bad\x01name", false},
+		{"bad\x7fname", false},
+		{"bad\xffname", false},
+		{"a//b", false},
+		{"a/./b", false},
+		{"../b", false},
+	}
+	for _, tt := range tests {
+		err := ValidateKey(tt.key)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateKey(%q) = %v, want ok %v", tt.key, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ValidateKey(%q) = %v, want ErrInvalidKey", tt.key, err)
+		}
+	}
+}
+
+func TestPercentCodec(t *testing.T) {
+	codec := NewPercentCodec()
+	decoder := codec.(KeyDecoder)
+	for _, name := range []string{
+		"plain/name.txt",
+		"dir/",
+		"50% off [final] #2.txt",
+		"ü/\x01\\/../.",
+	} {
+		key := codec.EncodeKey(name)
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("EncodeKey(%q) = %q, not a valid key: %v", name, key, err)
+		}
+		if got, err := decoder.DecodeKey(key); err != nil || got != name {
+			t.Errorf("DecodeKey(%q) = %q, %v, want %q", key, got, err, name)
+		}
+		sealed, _ := codec.SealName(name)
+		if got, err := codec.OpenName(sealed); err != nil || got != name {
+			t.Errorf("OpenName(SealName(%q)) = %q, %v", name, got, err)
+		}
+	}
+	if key := codec.EncodeKey("plain/name.txt"); key != "plain/name.txt" {
+		t.Errorf("EncodeKey() = %q, want safe names unchanged", key)
+	}
+	if _, err := decoder.DecodeKey("bad%2"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("DecodeKey() of a truncated escape = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,144 @@
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// removeAllRulePrefix prefixes the IDs of lifecycle rules installed by RemoveAllAsync.
+const removeAllRulePrefix = "s3fs-removeall-"
+
+// RemoveAllHandle tracks a lifecycle-assisted RemoveAllAsync operation.
+type RemoveAllHandle struct {
+	fs     *FileSystem
+	prefix string
+	ruleID string
+}
+
+// RemoveAllAsync deletes every object under the directory prefix by installing
+// a bucket lifecycle rule that expires them, instead of issuing one delete
+// request per object. S3 applies lifecycle rules asynchronously, typically
+// within a day or two; poll the returned handle's Done method to find out when
+// the prefix is empty and to remove the rule again.
+//
+// The bucket lifecycle configuration is read, extended and written back, so
+// concurrent changes to it by other tools may be lost. On versioned buckets the
+// rule only creates delete markers; noncurrent versions are not expired.
+func (fs *FileSystem) RemoveAllAsync(prefix string) (*RemoveAllHandle, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return nil, fs.wrapError("RemoveAllAsync", prefix, ErrRootPrefix)
+	}
+	prefix += "/"
+
+	h := &RemoveAllHandle{
+		fs:     fs,
+		prefix: fs.objectKey(prefix),
+		ruleID: fmt.Sprintf("%s%d", removeAllRulePrefix, time.Now().UnixNano()),
+	}
+
+	rules, err := fs.lifecycleRules()
+	if err != nil {
+		return nil, fs.wrapError("RemoveAllAsync", prefix, err)
+	}
+	rules = append(rules, types.LifecycleRule{
+		ID:         aws.String(h.ruleID),
+		Status:     types.ExpirationStatusEnabled,
+		Filter:     &types.LifecycleRuleFilterMemberPrefix{Value: h.prefix},
+		Expiration: &types.LifecycleExpiration{Days: aws.Int32(1)},
+	})
+	if err := fs.putLifecycleRules(rules); err != nil {
+		return nil, fs.wrapError("RemoveAllAsync", prefix, err)
+	}
+	return h, nil
+}
+
+// Prefix returns the prefix being removed.
+func (h *RemoveAllHandle) Prefix() string {
+	return h.prefix
+}
+
+// RuleID returns the ID of the lifecycle rule installed for the operation.
+func (h *RemoveAllHandle) RuleID() string {
+	return h.ruleID
+}
+
+// Done reports whether all objects under the prefix have expired. Once they
+// have, the lifecycle rule is removed from the bucket.
+func (h *RemoveAllHandle) Done() (bool, error) {
+	output, err := h.fs.client.ListObjectsV2(h.fs.ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(h.fs.bucket),
+		Prefix:  aws.String(h.prefix),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return false, h.fs.wrapError("RemoveAllAsync", h.prefix, err)
+	}
+	if len(output.Contents) > 0 {
+		return false, nil
+	}
+	return true, h.Cancel()
+}
+
+// Cancel removes the lifecycle rule. Objects that already expired stay deleted.
+func (h *RemoveAllHandle) Cancel() error {
+	rules, err := h.fs.lifecycleRules()
+	if err != nil {
+		return h.fs.wrapError("RemoveAllAsync", h.prefix, err)
+	}
+
+	kept := rules[:0]
+	for _, rule := range rules {
+		if aws.ToString(rule.ID) != h.ruleID {
+			kept = append(kept, rule)
+		}
+	}
+	if len(kept) == len(rules) {
+		return nil
+	}
+
+	if err := h.fs.putLifecycleRules(kept); err != nil {
+		return h.fs.wrapError("RemoveAllAsync", h.prefix, err)
+	}
+	return nil
+}
+
+// lifecycleRules returns the bucket's lifecycle rules, or none if the bucket
+// has no lifecycle configuration.
+func (fs *FileSystem) lifecycleRules() ([]types.LifecycleRule, error) {
+	output, err := fs.client.GetBucketLifecycleConfiguration(fs.ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(fs.bucket),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchLifecycleConfiguration" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return output.Rules, nil
+}
+
+// putLifecycleRules replaces the bucket's lifecycle rules, deleting the
+// configuration entirely when rules is empty.
+func (fs *FileSystem) putLifecycleRules(rules []types.LifecycleRule) error {
+	if len(rules) == 0 {
+		_, err := fs.client.DeleteBucketLifecycle(fs.ctx, &s3.DeleteBucketLifecycleInput{
+			Bucket: aws.String(fs.bucket),
+		})
+		return err
+	}
+
+	_, err := fs.client.PutBucketLifecycleConfiguration(fs.ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(fs.bucket),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{Rules: rules},
+	})
+	return err
+}
@@ -0,0 +1,15 @@
+package s3fs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRemoveAllAsync_RootPrefix(t *testing.T) {
+	fs := &FileSystem{}
+	for _, prefix := range []string{"", "/", "//"} {
+		if _, err := fs.RemoveAllAsync(prefix); !errors.Is(err, ErrRootPrefix) {
+			t.Errorf("RemoveAllAsync(%q) error = %v, want ErrRootPrefix", prefix, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package s3fs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter caps the number of S3 requests in flight and paces requests to a
+// maximum rate, see Config.MaxConcurrentRequests and
+// Config.MaxRequestsPerSecond.
+type limiter struct {
+	slots chan struct{} // nil for no concurrency cap
+
+	interval time.Duration // Time between requests, 0 for no rate limit
+	mu       sync.Mutex
+	next     time.Time // Earliest start of the next request
+}
+
+// newLimiter returns a limiter, or nil if neither limit is set.
+func newLimiter(concurrency int, perSecond float64) *limiter {
+	if concurrency <= 0 && perSecond <= 0 {
+		return nil
+	}
+	l := &limiter{}
+	if concurrency > 0 {
+		l.slots = make(chan struct{}, concurrency)
+	}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return l
+}
+
+// acquire waits until a request may start and returns the function that
+// ends it. It fails with the context's error if ctx is done first. A nil
+// limiter lets every request start immediately.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.interval > 0 {
+		l.mu.Lock()
+		now := time.Now()
+		if l.next.Before(now) {
+			l.next = now
+		}
+		wait := l.next.Sub(now)
+		l.next = l.next.Add(l.interval)
+		l.mu.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
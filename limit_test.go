@@ -0,0 +1,80 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestLimiter_Concurrency(t *testing.T) {
+	l := newLimiter(2, 0)
+	ctx := context.Background()
+
+	r1, _ := l.acquire(ctx)
+	if _, err := l.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire() beyond the cap error = %v, want DeadlineExceeded", err)
+	}
+
+	r1()
+	if _, err := l.acquire(ctx); err != nil {
+		t.Errorf("acquire() after release error = %v", err)
+	}
+}
+
+func TestLimiter_Rate(t *testing.T) {
+	l := newLimiter(0, 100)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("4 requests at 100/s took %v, want at least 30ms", elapsed)
+	}
+
+	if newLimiter(0, 0) != nil {
+		t.Errorf("newLimiter() without limits is not nil")
+	}
+}
+
+// bodyClient returns a body from GetObject.
+type bodyClient struct{ Client }
+
+func (bodyClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("data"))}, nil
+}
+
+func TestGuardedClient_GetObjectHoldsSlot(t *testing.T) {
+	c := &guardedClient{Client: bodyClient{}, l: newLimiter(1, 0)}
+	ctx := context.Background()
+
+	out, err := c.GetObject(ctx, &s3.GetObjectInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetObject(short, &s3.GetObjectInput{}); err == nil {
+		t.Fatalf("GetObject() while a body is open succeeded, want it to wait")
+	}
+
+	out.Body.Close()
+	out.Body.Close() // Releases only once
+	if _, err := c.GetObject(ctx, &s3.GetObjectInput{}); err != nil {
+		t.Errorf("GetObject() after Close error = %v", err)
+	}
+}
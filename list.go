@@ -0,0 +1,60 @@
+//go:build go1.23
+
+package s3fs
+
+import (
+	"iter"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// List returns an iterator over the objects below the directory prefix (""
+// for all), in key order, fetching one page of up to 1000 keys at a time, so
+// memory use does not grow with the number of objects and breaking out of
+// the loop stops the listing:
+//
+//	for obj, err := range fs.List("logs") {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(obj.Key, obj.Size)
+//	}
+//
+// Key is the object key in the bucket, which differs from the name for
+// filesystems created with Sub or a NameCodec. Objects below the system
+// prefix are skipped. A listing error is yielded once and ends the
+// iteration.
+func (fs *FileSystem) List(prefix string) iter.Seq2[ObjectInfo, error] {
+	prefix = syncPrefix(strings.TrimPrefix(prefix, "/"))
+
+	return func(yield func(ObjectInfo, error) bool) {
+		var continuationToken *string
+		for {
+			output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+				Bucket:            aws.String(fs.bucket),
+				Prefix:            aws.String(fs.objectKey(prefix)),
+				ContinuationToken: continuationToken,
+			})
+			if err != nil {
+				yield(ObjectInfo{}, fs.wrapError("List", prefix, err))
+				return
+			}
+
+			for _, obj := range output.Contents {
+				if fs.isSystemKey(aws.ToString(obj.Key)) {
+					continue
+				}
+				if !yield(*listObjectInfo(obj), nil) {
+					return
+				}
+			}
+
+			if !aws.ToBool(output.IsTruncated) {
+				return
+			}
+			continuationToken = output.NextContinuationToken
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//go:build go1.23
+
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// pagingClient serves pages of keys "k0".."kN-1" and counts the requests.
+type pagingClient struct {
+	Client
+	keys, pageSize, requests int
+	fail                     bool
+}
+
+func (c *pagingClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.requests++
+	if c.fail {
+		return nil, errors.New("boom")
+	}
+	start := 0
+	if params.ContinuationToken != nil {
+		fmt.Sscan(*params.ContinuationToken, &start)
+	}
+	output := &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)}
+	for i := start; i < c.keys && i < start+c.pageSize; i++ {
+		output.Contents = append(output.Contents, types.Object{Key: aws.String(fmt.Sprintf("k%d", i)), Size: aws.Int64(int64(i))})
+	}
+	if next := start + c.pageSize; next < c.keys {
+		output.IsTruncated = aws.Bool(true)
+		output.NextContinuationToken = aws.String(fmt.Sprint(next))
+	}
+	return output, nil
+}
+
+func TestList(t *testing.T) {
+	client := &pagingClient{keys: 25, pageSize: 10}
+	fs := &FileSystem{bucket: "bucket", client: client, ctx: context.Background()}
+
+	n := 0
+	for obj, err := range fs.List("") {
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if want := fmt.Sprintf("k%d", n); obj.Key != want {
+			t.Errorf("List() object %d = %s, want %s", n, obj.Key, want)
+		}
+		n++
+	}
+	if n != 25 || client.requests != 3 {
+		t.Errorf("List() = %d objects in %d requests, want 25 in 3", n, client.requests)
+	}
+
+	// Breaking early stops fetching pages
+	client.requests = 0
+	for obj := range fs.List("") {
+		if obj.Key == "k5" {
+			break
+		}
+	}
+	if client.requests != 1 {
+		t.Errorf("List() with break made %d requests, want 1", client.requests)
+	}
+
+	client.fail = true
+	for _, err := range fs.List("") {
+		if err == nil {
+			t.Errorf("List() yielded an object from a failing listing")
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package s3fs
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ListDir returns the direct entries of the directory prefix ("" for the
+// root) from a delimiter listing, reading all pages: the objects directly
+// below it and the names of its subdirectories, taken from the common
+// prefixes of the listing. Both are in key order.
+//
+// As in List, the Key of a file is the object key in the bucket. Directory
+// names are relative to fs and end in a slash, so they can be passed back
+// to ListDir. The marker object of prefix itself and entries below the
+// system prefix are left out.
+func (fs *FileSystem) ListDir(prefix string) (files []ObjectInfo, dirs []string, err error) {
+	prefix = syncPrefix(strings.TrimPrefix(prefix, "/"))
+	key := fs.objectKey(prefix)
+
+	var token *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(key),
+			Delimiter:         aws.String("/"),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			return nil, nil, fs.wrapError("ListDir", prefix, err)
+		}
+
+		for _, obj := range output.Contents {
+			objKey := aws.ToString(obj.Key)
+			if objKey == key || fs.isSystemKey(objKey) {
+				continue
+			}
+			files = append(files, *listObjectInfo(obj))
+		}
+		for _, cp := range output.CommonPrefixes {
+			dirKey := aws.ToString(cp.Prefix)
+			if fs.isSystemKey(dirKey) {
+				continue
+			}
+			name, err := fs.logicalName(dirKey)
+			if err != nil {
+				return nil, nil, fs.wrapError("ListDir", dirKey, err)
+			}
+			dirs = append(dirs, name)
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			return files, dirs, nil
+		}
+		token = output.NextContinuationToken
+	}
+}
@@ -0,0 +1,41 @@
+package s3fs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_ListDir(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	for _, name := range []string{"dir/a", "dir/b", "dir/c/x", "dir/c/y/z", "other"} {
+		writeFile(t, fs, name, name)
+	}
+	if err := fs.Mkdir("dir/e", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("dir", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, dirs, err := fs.Sub("dir").ListDir("/")
+	if err != nil {
+		t.Fatalf("ListDir() error = %v", err)
+	}
+	var keys []string
+	for _, f := range files {
+		keys = append(keys, f.Key)
+	}
+	if got := strings.Join(keys, " "); got != "dir/a dir/b" {
+		t.Errorf("ListDir() files = %q, want dir/a dir/b", got)
+	}
+	if got := strings.Join(dirs, " "); got != "c/ e/" {
+		t.Errorf("ListDir() dirs = %q, want c/ e/", got)
+	}
+
+	files, dirs, err = fs.ListDir("dir/c")
+	if err != nil || len(files) != 1 || files[0].Key != "dir/c/x" || len(dirs) != 1 || dirs[0] != "dir/c/y/" {
+		t.Errorf("ListDir(\"dir/c\") = %v, %q, %v", files, dirs, err)
+	}
+}
@@ -0,0 +1,90 @@
+package s3fs
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MaxListPageSize is the largest number of entries S3 returns in one listing
+// page, and the page size ListPage uses when max is zero.
+const MaxListPageSize = 1000
+
+// ListPage returns one page of the direct entries of the directory prefix (""
+// for the root) and the continuation token of the next page, or "" after the
+// last page. Pass "" as token for the first page and the returned token for
+// the following ones; tokens are opaque and can be handed to a client, so a
+// web UI can page through a huge directory without listing it from the
+// start on each request.
+//
+// A page holds at most max (default and limit MaxListPageSize) entries,
+// files and directories in name order; directories come from the common
+// prefixes of a delimiter listing and report IsDir. Entry names are base
+// names. Entries below the system prefix are left out, so a page can be
+// shorter than max, even empty, while the token is not "".
+func (fs *FileSystem) ListPage(prefix, token string, max int) ([]os.FileInfo, string, error) {
+	prefix = syncPrefix(strings.TrimPrefix(prefix, "/"))
+	if max <= 0 || max > MaxListPageSize {
+		max = MaxListPageSize
+	}
+	key := fs.objectKey(prefix)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(fs.bucket),
+		Prefix:    aws.String(key),
+		Delimiter: aws.String("/"),
+		MaxKeys:   aws.Int32(int32(max)),
+	}
+	if token != "" {
+		input.ContinuationToken = aws.String(token)
+	}
+	output, err := fs.client.ListObjectsV2(fs.ctx, input)
+	if err != nil {
+		return nil, "", fs.wrapError("ListPage", prefix, err)
+	}
+
+	infos := make([]os.FileInfo, 0, len(output.Contents)+len(output.CommonPrefixes))
+	for _, obj := range output.Contents {
+		objKey := aws.ToString(obj.Key)
+		if objKey == key || fs.isSystemKey(objKey) {
+			continue
+		}
+		name, err := fs.logicalName(objKey)
+		if err != nil {
+			return nil, "", fs.wrapError("ListPage", objKey, err)
+		}
+		infos = append(infos, &fileInfo{
+			name:    path.Base(name),
+			size:    aws.ToInt64(obj.Size),
+			modTime: aws.ToTime(obj.LastModified),
+			obj:     listObjectInfo(obj),
+		})
+	}
+	for _, cp := range output.CommonPrefixes {
+		dirKey := aws.ToString(cp.Prefix)
+		if fs.isSystemKey(dirKey) {
+			continue
+		}
+		name, err := fs.logicalName(dirKey)
+		if err != nil {
+			return nil, "", fs.wrapError("ListPage", dirKey, err)
+		}
+		infos = append(infos, &fileInfo{
+			name:  path.Base(strings.TrimSuffix(name, "/")),
+			isDir: true,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	next := ""
+	if aws.ToBool(output.IsTruncated) {
+		next = aws.ToString(output.NextContinuationToken)
+	}
+	return infos, next, nil
+}
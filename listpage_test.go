@@ -0,0 +1,49 @@
+package s3fs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_ListPage(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	for _, name := range []string{"dir/a", "dir/b", "dir/c/x", "dir/c/y", "dir/d", "other"} {
+		writeFile(t, fs, name, name)
+	}
+
+	var names []string
+	token := ""
+	pages := 0
+	for {
+		infos, next, err := fs.ListPage("dir", token, 2)
+		if err != nil {
+			t.Fatalf("ListPage() error = %v", err)
+		}
+		if len(infos) > 2 {
+			t.Errorf("ListPage() returned %d entries, want at most 2", len(infos))
+		}
+		for _, info := range infos {
+			name := info.Name()
+			if info.IsDir() {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	if got := strings.Join(names, " "); got != "a b c/ d" || pages != 2 {
+		t.Errorf("ListPage() = %q in %d pages, want \"a b c/ d\" in 2", got, pages)
+	}
+
+	// A token can be reused, as by a client going back
+	again, _, err := fs.ListPage("dir", token, 2)
+	if err != nil || len(again) != 2 || again[0].Name() != "c" {
+		t.Errorf("ListPage() with a reused token = %v, %v", again, err)
+	}
+}
@@ -0,0 +1,272 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ModTimeMetadataKey is the metadata key holding the modification time of
+// the local file an object was uploaded from in RFC 3339 format. It is set
+// and read by UploadFile and DownloadFile with TransferOptions.PreserveModTime.
+const ModTimeMetadataKey = "s3fs-mtime"
+
+// TransferOptions controls UploadFile and DownloadFile. A nil
+// *TransferOptions uses the defaults.
+type TransferOptions struct {
+	// PreserveModTime stores the modification time of uploaded files in the
+	// ModTimeMetadataKey metadata, and sets the modification time of
+	// downloaded files to it, or to the last modification time of objects
+	// uploaded without it.
+	PreserveModTime bool
+
+	Put         *PutOptions  // Attributes of uploaded objects, may be nil
+	Concurrency int          // Parts or ranges transferred in parallel (default DefaultDownloadConcurrency)
+	Progress    ProgressFunc // Called with the bytes transferred so far after each part or range
+}
+
+// UploadFile uploads the local file at localPath as the named file. Files
+// larger than the part size are uploaded with a multipart upload whose parts
+// are read from the file and sent in parallel, so they are never held in
+// memory; smaller files take a single PutObject request. opts may be nil.
+func (fs *FileSystem) UploadFile(localPath, name string, opts *TransferOptions) error {
+	name = strings.TrimPrefix(name, "/")
+	if opts == nil {
+		opts = &TransferOptions{}
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return &os.PathError{Op: "UploadFile", Path: localPath, Err: syscall.EISDIR}
+	}
+
+	put := &PutOptions{}
+	if opts.Put != nil {
+		*put = *opts.Put
+	}
+	if opts.PreserveModTime {
+		metadata := make(map[string]string, len(put.Metadata)+1)
+		for k, v := range put.Metadata {
+			metadata[k] = v
+		}
+		metadata[ModTimeMetadataKey] = info.ModTime().UTC().Format(time.RFC3339Nano)
+		put.Metadata = metadata
+	}
+	partSize := int64(DefaultPartSize)
+	if put.PartSize > 0 {
+		partSize = max(put.PartSize, MinPartSize)
+	}
+
+	key := fs.objectKey(name)
+	defer fs.stats.invalidate(key)
+	size := info.Size()
+	if size <= partSize {
+		input, err := fs.putInput(name, put)
+		if err != nil {
+			return fs.wrapError("UploadFile", name, err)
+		}
+		input.Body = file
+		input.ContentLength = aws.Int64(size)
+		if _, err := fs.client.PutObject(fs.ctx, input); err != nil {
+			return fs.wrapError("UploadFile", name, err)
+		}
+		if opts.Progress != nil {
+			opts.Progress(size)
+		}
+		fs.packs.forget(name)
+		return fs.manifestPut(key, size, false)
+	}
+
+	mu, err := fs.newMultipartUpload(name, put)
+	if err != nil {
+		return fs.wrapError("UploadFile", name, err)
+	}
+	if err := mu.uploadParallel(file, size, opts.concurrency(), newProgress(opts.Progress)); err != nil {
+		mu.Abort()
+		return fs.wrapError("UploadFile", name, err)
+	}
+	if err := mu.Complete(); err != nil {
+		mu.Abort()
+		return err
+	}
+	fs.packs.forget(name)
+	return fs.manifestPut(key, size, false)
+}
+
+// DownloadFile downloads the named file to localPath, creating missing parent
+// directories. The object is fetched with Download, in ranges of
+// Config.DownloadPartSize bytes fetched in parallel, into a temporary file
+// in the same directory that replaces localPath when complete, so localPath
+// never holds partial content. opts may be nil.
+func (fs *FileSystem) DownloadFile(name, localPath string, opts *TransferOptions) error {
+	name = strings.TrimPrefix(name, "/")
+	if opts == nil {
+		opts = &TransferOptions{}
+	}
+
+	var modTime time.Time
+	if opts.PreserveModTime {
+		info, err := fs.StatExtended(name)
+		if err != nil {
+			return err
+		}
+		modTime = info.ModTime
+		if t, err := time.Parse(time.RFC3339Nano, info.Metadata[ModTimeMetadataKey]); err == nil {
+			modTime = t
+		}
+	}
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+path.Base(filepath.ToSlash(localPath))+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	c := *fs
+	c.downloadConcurrency = opts.concurrency()
+	w := &progressWriterAt{w: tmp, progress: newProgress(opts.Progress)}
+	if _, err := c.Download(name, w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if opts.PreserveModTime {
+		if err := os.Chtimes(tmp.Name(), modTime, modTime); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), localPath)
+}
+
+func (opts *TransferOptions) concurrency() int {
+	if opts.Concurrency > 0 {
+		return opts.Concurrency
+	}
+	return DefaultDownloadConcurrency
+}
+
+// progress reports the bytes of parts transferred in parallel to a
+// ProgressFunc, one call at a time.
+type progress struct {
+	mu    sync.Mutex
+	total int64
+	fn    ProgressFunc
+}
+
+// newProgress returns a progress calling fn, or nil if fn is nil.
+func newProgress(fn ProgressFunc) *progress {
+	if fn == nil {
+		return nil
+	}
+	return &progress{fn: fn}
+}
+
+// add records n more bytes. A nil progress ignores them.
+func (p *progress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += n
+	p.fn(p.total)
+}
+
+// progressWriterAt reports the bytes written through it.
+type progressWriterAt struct {
+	w        io.WriterAt
+	progress *progress
+}
+
+func (w *progressWriterAt) WriteAt(b []byte, off int64) (int, error) {
+	n, err := w.w.WriteAt(b, off)
+	w.progress.add(int64(n))
+	return n, err
+}
+
+// uploadParallel uploads size bytes of r as the parts of the upload, with up
+// to workers parts in flight. Parts are sent straight from r.
+func (mu *MultipartUpload) uploadParallel(r io.ReaderAt, size int64, workers int, p *progress) error {
+	count := int((size + mu.partSize - 1) / mu.partSize)
+	parts := make([]types.CompletedPart, count)
+
+	ctx, cancel := context.WithCancel(mu.fs.ctx)
+	defer cancel()
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				off := int64(i) * mu.partSize
+				n := min(mu.partSize, size-off)
+				output, err := mu.fs.client.UploadPart(ctx, &s3.UploadPartInput{
+					Bucket:        aws.String(mu.fs.bucket),
+					Key:           aws.String(mu.key),
+					UploadId:      aws.String(mu.uploadID),
+					PartNumber:    aws.Int32(int32(i + 1)),
+					Body:          io.NewSectionReader(r, off, n),
+					ContentLength: aws.Int64(n),
+				})
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				parts[i] = types.CompletedPart{ETag: output.ETag, PartNumber: aws.Int32(int32(i + 1))}
+				p.add(n)
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < count; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := mu.fs.ctx.Err(); err != nil {
+		return err
+	}
+	mu.parts = parts
+	mu.partNumber = int32(count + 1)
+	return nil
+}
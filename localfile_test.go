@@ -0,0 +1,110 @@
+package s3fs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_UploadDownloadFile(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	dir := t.TempDir()
+	large := strings.Repeat("0123456789", s3fs.MinPartSize/10*2+100)
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, content := range []string{"small", large} {
+		local := filepath.Join(dir, "src.txt")
+		if err := os.WriteFile(local, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(local, modTime, modTime)
+
+		var uploaded int64
+		opts := &s3fs.TransferOptions{
+			PreserveModTime: true,
+			Put:             &s3fs.PutOptions{ContentType: "text/plain", PartSize: s3fs.MinPartSize},
+			Concurrency:     2,
+			Progress:        func(n int64) { uploaded = n },
+		}
+		if err := fs.UploadFile(local, "remote/file.txt", opts); err != nil {
+			t.Fatalf("UploadFile() error = %v", err)
+		}
+		if uploaded != int64(len(content)) {
+			t.Errorf("UploadFile() progress = %d, want %d", uploaded, len(content))
+		}
+		info, err := fs.StatExtended("remote/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.ContentType != "text/plain" || info.Metadata[s3fs.ModTimeMetadataKey] != "2020-01-02T03:04:05Z" {
+			t.Errorf("uploaded content type %q, metadata %v", info.ContentType, info.Metadata)
+		}
+
+		var downloaded int64
+		opts.Progress = func(n int64) { downloaded = n }
+		dst := filepath.Join(dir, "a", "b", "dst.txt")
+		if err := fs.DownloadFile("remote/file.txt", dst, opts); err != nil {
+			t.Fatalf("DownloadFile() error = %v", err)
+		}
+		data, err := os.ReadFile(dst)
+		if err != nil || string(data) != content {
+			t.Errorf("downloaded %d bytes, %v; want %d bytes", len(data), err, len(content))
+		}
+		if downloaded != int64(len(content)) {
+			t.Errorf("DownloadFile() progress = %d, want %d", downloaded, len(content))
+		}
+		if fi, err := os.Stat(dst); err != nil || !fi.ModTime().Equal(modTime) {
+			t.Errorf("downloaded file modified %v, want %v", fi.ModTime(), modTime)
+		}
+	}
+
+	if err := fs.DownloadFile("missing.txt", filepath.Join(dir, "missing.txt"), nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("DownloadFile(missing) error = %v, want ErrNotExist", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.txt")); !os.IsNotExist(err) {
+		t.Errorf("DownloadFile(missing) created a local file")
+	}
+	if err := fs.UploadFile(dir, "dir", nil); !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("UploadFile(dir) error = %v, want EISDIR", err)
+	}
+}
+
+func TestFileSystem_UploadDownloadFileSub(t *testing.T) {
+	fs := s3fstest.New("bucket").Sub("dir")
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.UploadFile(src, "sub/file.txt", nil); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	dst := filepath.Join(dir, "dst.txt")
+	if err := fs.DownloadFile("sub/file.txt", dst, nil); err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "content" {
+		t.Errorf("downloaded %q, %v; want the uploaded content", data, err)
+	}
+}
+
+func TestFileSystem_UploadFilePacked(t *testing.T) {
+	fs := newPackedFS(t)
+	src := filepath.Join(t.TempDir(), "src.txt")
+	if err := os.WriteFile(src, []byte("uploaded"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.UploadFile(src, "docs/a.txt", nil); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if got := readFile(t, fs, "docs/a.txt"); got != "uploaded" {
+		t.Errorf("read after UploadFile = %q, want the uploaded content", got)
+	}
+}
@@ -0,0 +1,261 @@
+package s3fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DefaultLockTTL is the lock lifetime used when Lock or TryLock is called
+// with a ttl of zero.
+const DefaultLockTTL = 30 * time.Second
+
+// FileLock is an advisory lock on a name, held until Unlock or until the
+// holder stops renewing it. Locks are stored as lock objects below the system
+// prefix; only code that takes the lock is serialized, other writes of the
+// name are not affected.
+type FileLock struct {
+	fs    *FileSystem
+	name  string
+	key   string // Key of the lock object
+	owner string
+	ttl   time.Duration
+
+	mu   sync.Mutex
+	etag string // ETag of the lock object as last written by this holder
+
+	stop chan struct{}
+	lost chan struct{}
+	done chan struct{}
+}
+
+// lockRecord is the content of a lock object.
+type lockRecord struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// lockKey returns the key of the lock object for name.
+func (fs *FileSystem) lockKey(name string) string {
+	return fs.systemKey("locks", fs.objectKey(name)+".lock")
+}
+
+// lockOwner returns an identifier for a new lock holder.
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), randomID())
+}
+
+// TryLock takes the advisory lock on name without waiting. It fails with
+// ErrLocked if another holder has the lock and it has not expired. The lock
+// expires ttl (default DefaultLockTTL) after it was last renewed; it is
+// renewed in the background every ttl/3 until Unlock, so a crashed holder
+// releases it after at most ttl. The lock is taken with a conditional
+// PutObject (If-None-Match), and an expired lock is taken over with one
+// conditioned on its ETag (If-Match), so the S3 service or S3-compatible
+// store must support conditional writes.
+func (fs *FileSystem) TryLock(name string, ttl time.Duration) (*FileLock, error) {
+	name = strings.TrimPrefix(name, "/")
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+
+	l := &FileLock{
+		fs:    fs,
+		name:  name,
+		key:   fs.lockKey(name),
+		owner: lockOwner(),
+		ttl:   ttl,
+		stop:  make(chan struct{}),
+		lost:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if err := l.acquire(); err != nil {
+		return nil, fs.wrapError("Lock", name, err)
+	}
+	go l.renew()
+	return l, nil
+}
+
+// Lock takes the advisory lock on name like TryLock, waiting for the current
+// holder to release it or for it to expire. It gives up when the context of
+// the FileSystem (see WithContext) is done.
+func (fs *FileSystem) Lock(name string, ttl time.Duration) (*FileLock, error) {
+	delay := 50 * time.Millisecond
+	for {
+		l, err := fs.TryLock(name, ttl)
+		if !errors.Is(err, ErrLocked) {
+			return l, err
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-fs.ctx.Done():
+			return nil, fs.wrapError("Lock", name, fs.ctx.Err())
+		}
+		if delay < time.Second {
+			delay *= 2
+		}
+	}
+}
+
+// acquire creates the lock object, or replaces it if it has expired.
+func (l *FileLock) acquire() error {
+	err := l.put(writeCondition{ifNoneMatch: "*"})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		return err
+	}
+
+	// Held by someone: take it over if the holder stopped renewing it
+	current, etag, err := l.read()
+	if err != nil {
+		if httpStatus(err) == 404 {
+			return l.put(writeCondition{ifNoneMatch: "*"}) // released meanwhile
+		}
+		return err
+	}
+	if time.Now().Before(current.Expires) {
+		return ErrLocked
+	}
+	if err := l.put(writeCondition{ifMatch: etag}); err != nil {
+		if errors.Is(err, ErrPreconditionFailed) || httpStatus(err) == 404 {
+			return ErrLocked // another waiter was faster
+		}
+		return err
+	}
+	return nil
+}
+
+// put writes the lock object with a new expiry under cond.
+func (l *FileLock) put(cond writeCondition) error {
+	data, err := json.Marshal(lockRecord{Owner: l.owner, Expires: time.Now().Add(l.ttl)})
+	if err != nil {
+		return err
+	}
+	output, err := l.fs.client.PutObject(l.fs.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(l.fs.bucket),
+		Key:           aws.String(l.key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String("application/json"),
+	}, cond.options()...)
+	if err != nil {
+		return preconditionError(err)
+	}
+
+	l.mu.Lock()
+	l.etag = aws.ToString(output.ETag)
+	l.mu.Unlock()
+	return nil
+}
+
+// read returns the current lock object and its ETag.
+func (l *FileLock) read() (*lockRecord, string, error) {
+	output, err := l.fs.client.GetObject(l.fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(l.fs.bucket),
+		Key:    aws.String(l.key),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	var record lockRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, "", err
+	}
+	return &record, aws.ToString(output.ETag), nil
+}
+
+// renew extends the lock every ttl/3 until Unlock. It closes lost when the
+// lock was taken over, or expired because it could not be renewed in time.
+func (l *FileLock) renew() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	expires := time.Now().Add(l.ttl)
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-l.fs.ctx.Done():
+			close(l.lost)
+			return
+		case <-ticker.C:
+		}
+
+		l.mu.Lock()
+		etag := l.etag
+		l.mu.Unlock()
+
+		err := l.put(writeCondition{ifMatch: etag})
+		switch {
+		case err == nil:
+			expires = time.Now().Add(l.ttl)
+		case errors.Is(err, ErrPreconditionFailed) || httpStatus(err) == 404 || !time.Now().Before(expires):
+			close(l.lost)
+			return
+		}
+	}
+}
+
+// Name returns the name the lock was taken on.
+func (l *FileLock) Name() string {
+	return l.name
+}
+
+// Lost returns a channel that is closed when the lock is lost: because it
+// could not be renewed before it expired, was taken over by another holder
+// after expiring, or the context of the FileSystem is done. Work protected
+// by the lock should stop when it is closed.
+func (l *FileLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Unlock releases the lock by deleting the lock object, unless another
+// holder has taken it over. It fails with ErrLockLost if the lock was lost
+// before Unlock.
+func (l *FileLock) Unlock() error {
+	select {
+	case <-l.done:
+	default:
+		close(l.stop)
+		<-l.done
+	}
+	select {
+	case <-l.lost:
+		return l.fs.wrapError("Unlock", l.name, ErrLockLost)
+	default:
+	}
+
+	l.mu.Lock()
+	etag := l.etag
+	l.mu.Unlock()
+
+	_, err := l.fs.client.DeleteObject(l.fs.ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(l.fs.bucket),
+		Key:    aws.String(l.key),
+	}, writeCondition{ifMatch: etag}.options()...)
+	if err := preconditionError(err); err != nil {
+		if errors.Is(err, ErrPreconditionFailed) || httpStatus(err) == 404 {
+			return l.fs.wrapError("Unlock", l.name, ErrLockLost)
+		}
+		return l.fs.wrapError("Unlock", l.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestFileSystem_Lock(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := fs.TryLock("a.txt", 60*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	// Renewals keep the lock alive beyond its ttl
+	time.Sleep(200 * time.Millisecond)
+	if _, err := fs.TryLock("a.txt", time.Second); !errors.Is(err, s3fs.ErrLocked) {
+		t.Fatalf("TryLock() of a held lock error = %v, want ErrLocked", err)
+	}
+	if other, err := fs.TryLock("b.txt", time.Second); err != nil {
+		t.Errorf("TryLock() of another name error = %v", err)
+	} else {
+		other.Unlock()
+	}
+
+	// Lock waits for the holder to release the lock
+	acquired := make(chan *s3fs.FileLock)
+	go func() {
+		l2, err := fs.Lock("a.txt", time.Second)
+		if err != nil {
+			t.Errorf("Lock() error = %v", err)
+		}
+		acquired <- l2
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := l.Unlock(); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	l2 := <-acquired
+	if l2 == nil {
+		t.FailNow()
+	}
+	if err := l2.Unlock(); err != nil {
+		t.Errorf("Unlock() error = %v", err)
+	}
+
+	// A lock left behind by a crashed holder is taken over once expired
+	expired := `{"owner":"crashed","expires":"2000-01-01T00:00:00Z"}`
+	if _, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String(".s3fs/locks/c.txt.lock"),
+		Body:   strings.NewReader(expired),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	l3, err := fs.TryLock("c.txt", time.Second)
+	if err != nil {
+		t.Fatalf("TryLock() of an expired lock error = %v", err)
+	}
+	if err := l3.Unlock(); err != nil {
+		t.Errorf("Unlock() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	held, _ := fs.TryLock("d.txt", time.Second)
+	if _, err := fs.WithContext(ctx).Lock("d.txt", time.Second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock() with an expiring context error = %v, want DeadlineExceeded", err)
+	}
+	held.Unlock()
+}
@@ -0,0 +1,269 @@
+package s3fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ManifestName is the base name of the per-directory manifest objects
+// maintained when Config.Manifests is enabled.
+const ManifestName = ".s3fs-manifest"
+
+// manifestEntry describes a single child of a directory in its manifest.
+type manifestEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"dir,omitempty"`
+	Data    []byte    `json:"data,omitempty"` // Inline content, see Config.InlineThreshold
+}
+
+// manifest is the JSON document stored in a directory's manifest object.
+type manifest struct {
+	Entries map[string]manifestEntry `json:"entries"`
+
+	etag string // ETag of the manifest object it was loaded from or saved as
+}
+
+// manifestKey returns the key of the manifest object for dir.
+func manifestKey(dir string) string {
+	dir = strings.Trim(dir, "/")
+	if dir == "" || dir == "." {
+		return ManifestName
+	}
+	return dir + "/" + ManifestName
+}
+
+// isManifestKey reports whether key is a manifest object.
+func isManifestKey(key string) bool {
+	return path.Base(key) == ManifestName
+}
+
+// splitManifestPath splits name into its parent directory and base name.
+func splitManifestPath(name string) (dir, base string) {
+	name = strings.Trim(name, "/")
+	dir, base = path.Split(name)
+	return dir, base
+}
+
+// loadManifest fetches the manifest of dir. It returns nil without error
+// if dir has no manifest, so callers fall back to listing.
+func (fs *FileSystem) loadManifest(dir string) (*manifest, error) {
+	if m := fs.manifestCache.get(dir); m != nil {
+		return m, nil
+	}
+
+	output, err := fs.client.GetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(manifestKey(dir)),
+	})
+	if err != nil {
+		if httpStatus(err) == 404 {
+			return nil, nil
+		}
+		return nil, fs.wrapError("loadManifest", dir, err)
+	}
+	defer output.Body.Close()
+
+	var m manifest
+	if err := json.NewDecoder(output.Body).Decode(&m); err != nil {
+		return nil, fs.wrapError("loadManifest", dir, fmt.Errorf("invalid manifest: %w", err))
+	}
+	if m.Entries == nil {
+		m.Entries = make(map[string]manifestEntry)
+	}
+	m.etag = aws.ToString(output.ETag)
+	fs.manifestCache.put(dir, &m)
+	return &m, nil
+}
+
+// saveManifest uploads m as the manifest of dir. If m was loaded, the
+// upload is conditioned on the manifest object still having the ETag it was
+// loaded with, and fails with ErrPreconditionFailed if another writer
+// changed it in the meantime.
+func (fs *FileSystem) saveManifest(dir string, m *manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fs.wrapError("saveManifest", dir, err)
+	}
+
+	output, err := fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(manifestKey(dir)),
+		Body:   bytes.NewReader(data),
+	}, writeCondition{ifMatch: m.etag}.options()...)
+	if err != nil {
+		fs.manifestCache.invalidate(dir)
+		return fs.wrapError("saveManifest", dir, preconditionError(err))
+	}
+	m.etag = aws.ToString(output.ETag)
+	fs.manifestCache.put(dir, m)
+	return nil
+}
+
+// updateManifest applies fn to the manifest of name's parent directory.
+// Only existing manifests are updated; directories without a manifest are
+// left alone until they are created with Mkdir or repaired with Rebuild.
+// The manifest is saved conditionally; if another writer saved it in the
+// meantime, it is loaded again and fn reapplied.
+func (fs *FileSystem) updateManifest(name string, fn func(m *manifest, base string)) error {
+	if !fs.manifests {
+		return nil
+	}
+
+	dir, base := splitManifestPath(name)
+	if base == "" {
+		return nil
+	}
+
+	for {
+		m, err := fs.loadManifest(dir)
+		if err != nil || m == nil {
+			return err
+		}
+		fn(m, base)
+		err = fs.saveManifest(dir, m)
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return err
+		}
+	}
+}
+
+// manifestPut records name in its parent directory's manifest.
+func (fs *FileSystem) manifestPut(name string, size int64, isDir bool) error {
+	return fs.updateManifest(name, func(m *manifest, base string) {
+		m.Entries[base] = manifestEntry{Size: size, ModTime: time.Now(), IsDir: isDir}
+	})
+}
+
+// manifestDelete removes name from its parent directory's manifest.
+func (fs *FileSystem) manifestDelete(name string) error {
+	return fs.updateManifest(name, func(m *manifest, base string) {
+		delete(m.Entries, base)
+	})
+}
+
+// manifestStat answers Stat from the parent directory's manifest.
+// The boolean result is false when no manifest is available.
+func (fs *FileSystem) manifestStat(name string) (os.FileInfo, bool, error) {
+	dir, base := splitManifestPath(name)
+	if base == "" {
+		return nil, false, nil
+	}
+
+	m, err := fs.loadManifest(dir)
+	if err != nil {
+		return nil, true, err
+	}
+	if m == nil {
+		return nil, false, nil
+	}
+
+	e, ok := m.Entries[base]
+	if !ok {
+		return nil, true, fs.wrapError("Stat", name, ErrNotExist)
+	}
+	return &fileInfo{name: base, size: e.Size, modTime: e.ModTime, isDir: e.IsDir}, true, nil
+}
+
+// manifestReaddir answers Readdir from dir's manifest.
+// The result is nil when no manifest is available.
+func (fs *FileSystem) manifestReaddir(dir string) ([]os.FileInfo, error) {
+	m, err := fs.loadManifest(dir)
+	if err != nil || m == nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(m.Entries))
+	for name := range m.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]os.FileInfo, 0, len(names))
+	for _, name := range names {
+		e := m.Entries[name]
+		infos = append(infos, &fileInfo{name: name, size: e.Size, modTime: e.ModTime, isDir: e.IsDir})
+	}
+	return infos, nil
+}
+
+// Rebuild regenerates the manifest of the directory prefix from a listing.
+// Use it to create a manifest for an existing directory or to repair one that
+// drifted because objects were changed without going through s3fs.
+func (fs *FileSystem) Rebuild(prefix string) error {
+	prefix = strings.Trim(prefix, "/")
+	listPrefix := ""
+	if prefix != "" {
+		listPrefix = prefix + "/"
+	}
+	listPrefix = fs.objectKey(listPrefix)
+
+	m := &manifest{Entries: make(map[string]manifestEntry)}
+	var continuationToken *string
+
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(listPrefix),
+			Delimiter:         aws.String("/"),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return fs.wrapError("Rebuild", prefix, err)
+		}
+
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			if key == listPrefix || fs.isSystemKey(key) {
+				continue
+			}
+			m.Entries[strings.TrimPrefix(key, listPrefix)] = manifestEntry{
+				Size:    aws.ToInt64(obj.Size),
+				ModTime: aws.ToTime(obj.LastModified),
+			}
+		}
+		for _, cp := range output.CommonPrefixes {
+			if fs.isSystemKey(aws.ToString(cp.Prefix)) {
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(cp.Prefix), listPrefix), "/")
+			m.Entries[name] = manifestEntry{IsDir: true}
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+
+	return fs.saveManifest(listPrefix, m)
+}
+
+// manifestMove moves oldpath's manifest entry to newpath's parent manifest.
+func (fs *FileSystem) manifestMove(oldpath, newpath string) error {
+	if !fs.manifests {
+		return nil
+	}
+
+	var entry manifestEntry
+	if err := fs.updateManifest(oldpath, func(m *manifest, base string) {
+		entry = m.Entries[base]
+		delete(m.Entries, base)
+	}); err != nil {
+		return err
+	}
+	return fs.updateManifest(newpath, func(m *manifest, base string) {
+		entry.ModTime = time.Now()
+		m.Entries[base] = entry
+	})
+}
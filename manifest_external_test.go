@@ -0,0 +1,94 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestManifest_ConcurrentUpdates(t *testing.T) {
+	client := s3fstest.NewClient()
+	open := func() *s3fs.FileSystem {
+		t.Helper()
+		fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, Manifests: true, StatCacheTTL: time.Minute})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+	fs1, fs2 := open(), open()
+	if err := fs1.Mkdir("dir", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// fs2 caches the manifest before fs1 changes it
+	if _, err := fs2.Stat("dir/missing.txt"); err == nil {
+		t.Fatal("Stat() of a missing file succeeded")
+	}
+	writeFile(t, fs1, "dir/a.txt", "a")
+	writeFile(t, fs2, "dir/b.txt", "b")
+
+	f, err := open().Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names, " "); got != "a.txt b.txt" {
+		t.Errorf("Readdirnames() = %s, want both files in the manifest", got)
+	}
+}
+
+// failingManifestClient fails reads of manifest objects while fail is set.
+type failingManifestClient struct {
+	*s3fstest.Client
+	fail bool
+}
+
+var errManifestRead = errors.New("manifest read failed")
+
+func (c *failingManifestClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if c.fail && strings.HasSuffix(aws.ToString(params.Key), s3fs.ManifestName) {
+		return nil, errManifestRead
+	}
+	return c.Client.GetObject(ctx, params, optFns...)
+}
+
+func TestManifest_LoadError(t *testing.T) {
+	client := &failingManifestClient{Client: s3fstest.NewClient()}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, Manifests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing manifest falls back to the objects
+	writeFile(t, fs, "plain/a.txt", "a")
+	if info, err := fs.Stat("plain/a.txt"); err != nil || info.Size() != 1 {
+		t.Errorf("Stat() without a manifest = %v, %v", info, err)
+	}
+
+	_, err = client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/" + s3fs.ManifestName),
+		Body:   strings.NewReader(`{"entries":{}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.fail = true
+	if _, err := fs.Stat("dir/a.txt"); !errors.Is(err, errManifestRead) {
+		t.Errorf("Stat() error = %v, want the manifest read error", err)
+	}
+	if err := fs.WriteFile("dir/a.txt", []byte("a"), 0o644); !errors.Is(err, errManifestRead) {
+		t.Errorf("WriteFile() error = %v, want the manifest read error", err)
+	}
+}
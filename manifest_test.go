@@ -0,0 +1,64 @@
+package s3fs
+
+import "testing"
+
+func TestManifestKey(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"", ManifestName},
+		{"/", ManifestName},
+		{".", ManifestName},
+		{"a/b", "a/b/" + ManifestName},
+		{"/a/b/", "a/b/" + ManifestName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := manifestKey(tt.dir); got != tt.want {
+				t.Errorf("manifestKey(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsManifestKey(t *testing.T) {
+	if !isManifestKey("a/b/" + ManifestName) {
+		t.Errorf("isManifestKey() = false for manifest key")
+	}
+	if isManifestKey("a/b/file.txt") {
+		t.Errorf("isManifestKey() = true for regular key")
+	}
+}
+
+func TestSplitManifestPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantDir  string
+		wantBase string
+	}{
+		{"file.txt", "", "file.txt"},
+		{"/a/b/file.txt", "a/b/", "file.txt"},
+		{"a/b/", "a/", "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, base := splitManifestPath(tt.name)
+			if dir != tt.wantDir || base != tt.wantBase {
+				t.Errorf("splitManifestPath(%q) = %q, %q, want %q, %q", tt.name, dir, base, tt.wantDir, tt.wantBase)
+			}
+		})
+	}
+}
+
+func TestManifestDisabled(t *testing.T) {
+	fs := &FileSystem{}
+	if err := fs.manifestPut("a/b.txt", 1, false); err != nil {
+		t.Errorf("manifestPut() with manifests disabled error = %v", err)
+	}
+	if err := fs.manifestMove("a/b.txt", "a/c.txt"); err != nil {
+		t.Errorf("manifestMove() with manifests disabled error = %v", err)
+	}
+}
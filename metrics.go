@@ -0,0 +1,396 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// LatencyBuckets are the upper bounds of the latency histograms in Stats.
+var LatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Stats is a snapshot of the requests a FileSystem made, returned by
+// FileSystem.Stats. Filesystems derived with WithContext and Sub share the
+// counters of their parent.
+type Stats struct {
+	Operations      map[string]OperationStats // Per S3 API operation, such as "GetObject"
+	Retries         int64                     // Attempts repeated by the SDK retryer
+	BytesUploaded   int64                     // Request bodies of PutObject and UploadPart
+	BytesDownloaded int64                     // Response bodies of GetObject read by callers
+}
+
+// OperationStats counts the requests of one S3 API operation.
+type OperationStats struct {
+	Requests int64         // Completed calls, including failed ones
+	Errors   int64         // Calls that returned an error
+	Latency  time.Duration // Total time spent in calls
+
+	// Buckets[i] is the number of calls that took at most LatencyBuckets[i].
+	Buckets []int64
+}
+
+// Requests returns the total number of requests in s.
+func (s Stats) Requests() int64 {
+	var n int64
+	for _, op := range s.Operations {
+		n += op.Requests
+	}
+	return n
+}
+
+// Stats returns a snapshot of the request counters of the filesystem.
+func (fs *FileSystem) Stats() Stats {
+	if fs.metrics == nil {
+		return Stats{Operations: map[string]OperationStats{}}
+	}
+	return fs.metrics.snapshot()
+}
+
+// WritePrometheus writes the counters of the filesystem to w in the
+// Prometheus text exposition format, so that they can be served from a
+// /metrics handler without depending on the Prometheus client library.
+func (fs *FileSystem) WritePrometheus(w io.Writer) error {
+	s := fs.Stats()
+	bucket := fs.bucket
+
+	ops := make([]string, 0, len(s.Operations))
+	for op := range s.Operations {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	ew := &errWriter{w: w}
+	ew.printf("# HELP s3fs_requests_total S3 requests by operation.\n# TYPE s3fs_requests_total counter\n")
+	for _, op := range ops {
+		ew.printf("s3fs_requests_total{bucket=%q,operation=%q} %d\n", bucket, op, s.Operations[op].Requests)
+	}
+	ew.printf("# HELP s3fs_errors_total Failed S3 requests by operation.\n# TYPE s3fs_errors_total counter\n")
+	for _, op := range ops {
+		ew.printf("s3fs_errors_total{bucket=%q,operation=%q} %d\n", bucket, op, s.Operations[op].Errors)
+	}
+	ew.printf("# HELP s3fs_request_duration_seconds S3 request latency by operation.\n# TYPE s3fs_request_duration_seconds histogram\n")
+	for _, op := range ops {
+		o := s.Operations[op]
+		for i, le := range LatencyBuckets {
+			ew.printf("s3fs_request_duration_seconds_bucket{bucket=%q,operation=%q,le=\"%g\"} %d\n", bucket, op, le.Seconds(), o.Buckets[i])
+		}
+		ew.printf("s3fs_request_duration_seconds_bucket{bucket=%q,operation=%q,le=\"+Inf\"} %d\n", bucket, op, o.Requests)
+		ew.printf("s3fs_request_duration_seconds_sum{bucket=%q,operation=%q} %g\n", bucket, op, o.Latency.Seconds())
+		ew.printf("s3fs_request_duration_seconds_count{bucket=%q,operation=%q} %d\n", bucket, op, o.Requests)
+	}
+	ew.printf("# HELP s3fs_retries_total S3 request attempts repeated by the retryer.\n# TYPE s3fs_retries_total counter\n")
+	ew.printf("s3fs_retries_total{bucket=%q} %d\n", bucket, s.Retries)
+	ew.printf("# HELP s3fs_uploaded_bytes_total Bytes sent in request bodies.\n# TYPE s3fs_uploaded_bytes_total counter\n")
+	ew.printf("s3fs_uploaded_bytes_total{bucket=%q} %d\n", bucket, s.BytesUploaded)
+	ew.printf("# HELP s3fs_downloaded_bytes_total Bytes read from response bodies.\n# TYPE s3fs_downloaded_bytes_total counter\n")
+	ew.printf("s3fs_downloaded_bytes_total{bucket=%q} %d\n", bucket, s.BytesDownloaded)
+	return ew.err
+}
+
+// errWriter keeps the first error of a sequence of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
+
+// metrics holds the counters behind Stats.
+type metrics struct {
+	mu  sync.Mutex
+	ops map[string]*OperationStats
+
+	retries    atomic.Int64
+	uploaded   atomic.Int64
+	downloaded atomic.Int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{ops: make(map[string]*OperationStats)}
+}
+
+// observe records a call of op that started at start.
+func (m *metrics) observe(op string, start time.Time, err error) {
+	d := time.Since(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.ops[op]
+	if !ok {
+		o = &OperationStats{Buckets: make([]int64, len(LatencyBuckets))}
+		m.ops[op] = o
+	}
+	o.Requests++
+	if err != nil {
+		o.Errors++
+	}
+	o.Latency += d
+	for i, le := range LatencyBuckets {
+		if d <= le {
+			o.Buckets[i]++
+		}
+	}
+}
+
+func (m *metrics) snapshot() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := Stats{
+		Operations:      make(map[string]OperationStats, len(m.ops)),
+		Retries:         m.retries.Load(),
+		BytesUploaded:   m.uploaded.Load(),
+		BytesDownloaded: m.downloaded.Load(),
+	}
+	for op, o := range m.ops {
+		c := *o
+		c.Buckets = append([]int64(nil), o.Buckets...)
+		s.Operations[op] = c
+	}
+	return s
+}
+
+// countAttempts returns optFns with an option that counts the attempts the
+// SDK makes for a call in *attempts. Clients other than *s3.Client ignore
+// it, so retries are only counted for real S3 clients.
+func countAttempts(optFns []func(*s3.Options), attempts *int32) []func(*s3.Options) {
+	count := func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			// After the retry middleware, so it runs once per attempt
+			return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("s3fsCountAttempts",
+				func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+					atomic.AddInt32(attempts, 1)
+					return next.HandleFinalize(ctx, in)
+				}), middleware.After)
+		})
+	}
+	return append(optFns[:len(optFns):len(optFns)], count)
+}
+
+// record calls fn for op and records the call in m. The call is charged to
+// the Budget of ctx, if any, and not made if the budget refuses it.
+func record[T any](ctx context.Context, m *metrics, op string, optFns []func(*s3.Options), fn func(optFns []func(*s3.Options)) (T, error)) (T, error) {
+	if err := chargeRequest(ctx, op); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	var attempts int32
+	start := time.Now()
+	out, err := fn(countAttempts(optFns, &attempts))
+	m.observe(op, start, err)
+	if attempts > 1 {
+		m.retries.Add(int64(attempts - 1))
+	}
+	return out, err
+}
+
+// bodySize returns the size of a request body: its ContentLength if set,
+// otherwise the length of in-memory readers, or 0 if it is unknown.
+func bodySize(contentLength *int64, body io.Reader) int64 {
+	if contentLength != nil {
+		return aws.ToInt64(contentLength)
+	}
+	if r, ok := body.(interface{ Len() int }); ok {
+		return int64(r.Len())
+	}
+	return 0
+}
+
+// countingBody counts the bytes read from a response body.
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+// metricsClient records every call to Client in metrics.
+type metricsClient struct {
+	Client
+	m *metrics
+}
+
+func (c *metricsClient) unwrap() Client { return c.Client }
+
+// baseClient returns the client the filesystem was created with, without
+// the wrappers New adds around it.
+func (fs *FileSystem) baseClient() Client {
+	c := fs.client
+	for {
+		w, ok := c.(interface{ unwrap() Client })
+		if !ok {
+			return c
+		}
+		c = w.unwrap()
+	}
+}
+
+func (c *metricsClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := record(ctx, c.m, "GetObject", optFns, func(optFns []func(*s3.Options)) (*s3.GetObjectOutput, error) {
+		return c.Client.GetObject(ctx, params, optFns...)
+	})
+	if err == nil && out.Body != nil {
+		out.Body = &countingBody{ReadCloser: out.Body, n: &c.m.downloaded}
+		chargeTransfer(ctx, aws.ToInt64(out.ContentLength))
+	}
+	return out, err
+}
+
+func (c *metricsClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return record(ctx, c.m, "HeadObject", optFns, func(optFns []func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+		return c.Client.HeadObject(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	size := bodySize(params.ContentLength, params.Body)
+	out, err := record(ctx, c.m, "PutObject", optFns, func(optFns []func(*s3.Options)) (*s3.PutObjectOutput, error) {
+		return c.Client.PutObject(ctx, params, optFns...)
+	})
+	if err == nil {
+		c.m.uploaded.Add(size)
+	}
+	return out, err
+}
+
+func (c *metricsClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return record(ctx, c.m, "CopyObject", optFns, func(optFns []func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+		return c.Client.CopyObject(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return record(ctx, c.m, "DeleteObject", optFns, func(optFns []func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+		return c.Client.DeleteObject(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return record(ctx, c.m, "DeleteObjects", optFns, func(optFns []func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+		return c.Client.DeleteObjects(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return record(ctx, c.m, "ListObjectsV2", optFns, func(optFns []func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+		return c.Client.ListObjectsV2(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return record(ctx, c.m, "ListObjectVersions", optFns, func(optFns []func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+		return c.Client.ListObjectVersions(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	return record(ctx, c.m, "RestoreObject", optFns, func(optFns []func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+		return c.Client.RestoreObject(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return record(ctx, c.m, "GetObjectTagging", optFns, func(optFns []func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+		return c.Client.GetObjectTagging(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	return record(ctx, c.m, "PutObjectRetention", optFns, func(optFns []func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+		return c.Client.PutObjectRetention(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	return record(ctx, c.m, "PutObjectLegalHold", optFns, func(optFns []func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+		return c.Client.PutObjectLegalHold(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return record(ctx, c.m, "CreateMultipartUpload", optFns, func(optFns []func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+		return c.Client.CreateMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	size := bodySize(params.ContentLength, params.Body)
+	out, err := record(ctx, c.m, "UploadPart", optFns, func(optFns []func(*s3.Options)) (*s3.UploadPartOutput, error) {
+		return c.Client.UploadPart(ctx, params, optFns...)
+	})
+	if err == nil {
+		c.m.uploaded.Add(size)
+	}
+	return out, err
+}
+
+func (c *metricsClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return record(ctx, c.m, "UploadPartCopy", optFns, func(optFns []func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+		return c.Client.UploadPartCopy(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return record(ctx, c.m, "CompleteMultipartUpload", optFns, func(optFns []func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+		return c.Client.CompleteMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return record(ctx, c.m, "AbortMultipartUpload", optFns, func(optFns []func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+		return c.Client.AbortMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	return record(ctx, c.m, "ListMultipartUploads", optFns, func(optFns []func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+		return c.Client.ListMultipartUploads(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	return record(ctx, c.m, "GetBucketLifecycleConfiguration", optFns, func(optFns []func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+		return c.Client.GetBucketLifecycleConfiguration(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	return record(ctx, c.m, "PutBucketLifecycleConfiguration", optFns, func(optFns []func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+		return c.Client.PutBucketLifecycleConfiguration(ctx, params, optFns...)
+	})
+}
+
+func (c *metricsClient) DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	return record(ctx, c.m, "DeleteBucketLifecycle", optFns, func(optFns []func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+		return c.Client.DeleteBucketLifecycle(ctx, params, optFns...)
+	})
+}
@@ -0,0 +1,54 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_Stats(t *testing.T) {
+	fs := s3fstest.New("bucket")
+
+	if err := fs.WriteFile("a.txt", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	fs.ReadFile("missing.txt")
+
+	s := fs.Stats()
+	if put := s.Operations["PutObject"]; put.Requests != 1 || put.Errors != 0 {
+		t.Errorf("PutObject stats = %+v, want 1 request", put)
+	}
+	get := s.Operations["GetObject"]
+	if get.Requests != 2 || get.Errors != 1 {
+		t.Errorf("GetObject stats = %+v, want 2 requests and 1 error", get)
+	}
+	if n := get.Buckets[len(get.Buckets)-1]; n != 2 {
+		t.Errorf("GetObject latency histogram = %v, want both requests below the last bucket", get.Buckets)
+	}
+	if s.BytesUploaded != 5 || s.BytesDownloaded != 5 {
+		t.Errorf("bytes uploaded, downloaded = %d, %d; want 5, 5", s.BytesUploaded, s.BytesDownloaded)
+	}
+	if s.Requests() != 3 {
+		t.Errorf("Requests() = %d, want 3", s.Requests())
+	}
+
+	var buf bytes.Buffer
+	if err := fs.WritePrometheus(&buf); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+	for _, want := range []string{
+		`s3fs_requests_total{bucket="bucket",operation="GetObject"} 2`,
+		`s3fs_errors_total{bucket="bucket",operation="GetObject"} 1`,
+		`s3fs_request_duration_seconds_count{bucket="bucket",operation="PutObject"} 1`,
+		`s3fs_uploaded_bytes_total{bucket="bucket"} 5`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WritePrometheus() output is missing %s", want)
+		}
+	}
+}
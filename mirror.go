@@ -0,0 +1,111 @@
+package s3fs
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// mirrorGet fetches key from the first configured HTTP mirror that serves it.
+// When the object's MD5 can be derived from its S3 ETag, the returned body
+// verifies the downloaded content and fails with ErrChecksumMismatch at EOF
+// if it differs.
+func (fs *FileSystem) mirrorGet(key string) (io.ReadCloser, error) {
+	expected := fs.mirrorChecksum(key)
+
+	var lastErr error
+	for _, base := range fs.mirrors {
+		req, err := http.NewRequestWithContext(fs.ctx, http.MethodGet, mirrorURL(base, key), nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		resp, err := fs.mirrorClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("mirror %s: %s", base, resp.Status)
+			continue
+		}
+
+		digest, err := hex.DecodeString(expected)
+		if expected == "" || err != nil {
+			return resp.Body, nil
+		}
+		return &verifyingReader{
+			body:     resp.Body,
+			hash:     md5.New(),
+			expected: digest,
+			key:      key,
+			encode:   hex.EncodeToString,
+		}, nil
+	}
+
+	if lastErr == nil {
+		lastErr = ErrNotExist
+	}
+	return nil, lastErr
+}
+
+// mirrorChecksum returns the hex MD5 of key as reported by S3, or "" if it
+// is unavailable. Multipart ETags are not MD5 digests and are ignored.
+func (fs *FileSystem) mirrorChecksum(key string) string {
+	output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return ""
+	}
+
+	return etagMD5(aws.ToString(output.ETag))
+}
+
+// mirrorURL joins a mirror base URL and an object key, escaping each path
+// segment of the key.
+func mirrorURL(base, key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.Join(segments, "/")
+}
+
+// verifyingReader hashes everything read through it and compares the digest
+// with the expected value when the underlying body reaches EOF, failing with
+// a *ChecksumError if they differ.
+type verifyingReader struct {
+	body     io.ReadCloser
+	hash     hash.Hash
+	expected []byte
+	key      string
+	encode   func([]byte) string // Encoding of digests in errors
+}
+
+func (r *verifyingReader) Read(b []byte) (int, error) {
+	n, err := r.body.Read(b)
+	r.hash.Write(b[:n])
+	if err == io.EOF {
+		if actual := r.hash.Sum(nil); !bytes.Equal(actual, r.expected) {
+			return n, &ChecksumError{Key: r.key, Expected: r.encode(r.expected), Actual: r.encode(actual)}
+		}
+	}
+	return n, err
+}
+
+func (r *verifyingReader) Close() error {
+	return r.body.Close()
+}
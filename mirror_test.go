@@ -0,0 +1,50 @@
+package s3fs
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMirrorURL(t *testing.T) {
+	tests := []struct {
+		base string
+		key  string
+		want string
+	}{
+		{"https://mirror.example.com", "a/b.txt", "https://mirror.example.com/a/b.txt"},
+		{"https://mirror.example.com/data/", "a/b c.txt", "https://mirror.example.com/data/a/b%20c.txt"},
+		{"http://m", "x?y#z", "http://m/x%3Fy%23z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := mirrorURL(tt.base, tt.key); got != tt.want {
+				t.Errorf("mirrorURL(%q, %q) = %q, want %q", tt.base, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyingReader(t *testing.T) {
+	sum := md5.Sum([]byte("hello"))
+	good := sum[:]
+
+	r := &verifyingReader{body: io.NopCloser(strings.NewReader("hello")), hash: md5.New(), expected: good, encode: hex.EncodeToString}
+	if data, err := io.ReadAll(r); err != nil || string(data) != "hello" {
+		t.Errorf("ReadAll() = %q, %v", data, err)
+	}
+
+	r = &verifyingReader{body: io.NopCloser(strings.NewReader("hellx")), hash: md5.New(), expected: good, key: "k", encode: hex.EncodeToString}
+	_, err := io.ReadAll(r)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("ReadAll() error = %v, want ErrChecksumMismatch", err)
+	}
+	var ce *ChecksumError
+	if !errors.As(err, &ce) || ce.Key != "k" || ce.Expected != hex.EncodeToString(good) {
+		t.Errorf("ReadAll() error = %#v, want a *ChecksumError for k", err)
+	}
+}
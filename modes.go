@@ -0,0 +1,172 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+
+	"github.com/absfs/absfs"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// OpenMode is a combination of os.O_* flags with well defined S3 semantics.
+// Use it with OpenMode.Flag or the helpers OpenRead, CreateWrite and Append
+// instead of hand-building flag combinations that s3fs interprets loosely.
+type OpenMode int
+
+const (
+	// ModeRead opens an existing object and streams its content from S3.
+	ModeRead OpenMode = OpenMode(os.O_RDONLY)
+
+	// ModeWrite creates or replaces an object. Data is buffered in memory and
+	// uploaded in a single request on Close.
+	ModeWrite OpenMode = OpenMode(os.O_WRONLY | os.O_CREATE | os.O_TRUNC)
+
+	// ModeAppend downloads the existing object (if any) into the write buffer
+	// and positions writes after it. The whole object is re-uploaded on Close,
+	// so appending is only suitable for small objects.
+	ModeAppend OpenMode = OpenMode(os.O_WRONLY | os.O_CREATE | os.O_APPEND)
+)
+
+// accessModes masks the access mode of os.O_* flags.
+const accessModes = os.O_RDONLY | os.O_WRONLY | os.O_RDWR
+
+// checkFlag returns a *FlagError if OpenFile cannot honor flag. Reads stream
+// the object and writes replace it on Close, so a file is either read or
+// written: O_RDWR is only supported with O_TRUNC, where there is nothing to
+// read, and the flags that modify writing only with a write mode.
+func checkFlag(flag int) error {
+	switch flag & accessModes {
+	case os.O_RDONLY:
+		if flag&(os.O_CREATE|os.O_TRUNC|os.O_APPEND|os.O_EXCL) != 0 {
+			return &FlagError{Flag: flag, Reason: "O_CREATE, O_TRUNC, O_APPEND or O_EXCL without a write mode"}
+		}
+	case os.O_WRONLY:
+	case os.O_RDWR:
+		if flag&os.O_APPEND != 0 {
+			return &FlagError{Flag: flag, Reason: "O_RDWR with O_APPEND"}
+		}
+		if flag&os.O_TRUNC == 0 {
+			return &FlagError{Flag: flag, Reason: "O_RDWR without O_TRUNC"}
+		}
+	default:
+		return &FlagError{Flag: flag, Reason: "invalid access mode"}
+	}
+	if flag&os.O_EXCL != 0 && flag&os.O_CREATE == 0 {
+		return &FlagError{Flag: flag, Reason: "O_EXCL without O_CREATE"}
+	}
+	if flag&os.O_TRUNC != 0 && flag&os.O_APPEND != 0 {
+		return &FlagError{Flag: flag, Reason: "O_TRUNC with O_APPEND"}
+	}
+	return nil
+}
+
+// Flag returns the os.O_* flags for the mode.
+func (m OpenMode) Flag() int {
+	return int(m)
+}
+
+// OpenRead opens the named object for reading.
+func (fs *FileSystem) OpenRead(name string) (absfs.File, error) {
+	return fs.OpenFile(name, ModeRead.Flag(), 0)
+}
+
+// CreateWrite creates or truncates the named object and opens it for writing.
+func (fs *FileSystem) CreateWrite(name string) (absfs.File, error) {
+	return fs.OpenFile(name, ModeWrite.Flag(), 0644)
+}
+
+// Append opens the named object for appending, creating it if necessary.
+func (fs *FileSystem) Append(name string) (absfs.File, error) {
+	return fs.OpenFile(name, ModeAppend.Flag(), 0644)
+}
+
+// Open opens the named file for reading, like os.Open.
+func (fs *FileSystem) Open(name string) (absfs.File, error) {
+	return fs.OpenRead(name)
+}
+
+// Create creates or truncates the named file for writing, like os.Create.
+// Nothing is uploaded until the file is closed.
+func (fs *FileSystem) Create(name string) (absfs.File, error) {
+	return fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// Truncate changes the size of the named file, like os.Truncate. S3 cannot
+// modify objects in place, so the object is downloaded and re-uploaded with
+// the new size; growing it pads with zero bytes.
+func (fs *FileSystem) Truncate(name string, size int64) error {
+	if size < 0 {
+		return fs.wrapError("Truncate", name, os.ErrInvalid)
+	}
+	data, err := fs.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if size <= int64(len(data)) {
+		data = data[:size]
+	} else {
+		data = append(data, make([]byte, size-int64(len(data)))...)
+	}
+	return fs.WriteFile(name, data, 0)
+}
+
+// openWrite prepares f, opened for writing with flag, following the os
+// semantics of the flags: without O_CREATE the file must exist, with O_EXCL
+// it must not (which the upload on Close checks again with If-None-Match),
+// and without O_TRUNC its content is kept, with writes starting at its end
+// for O_APPEND and at the beginning otherwise.
+func (f *File) openWrite(flag int) error {
+	exists := false
+	if flag&(os.O_CREATE|os.O_EXCL|os.O_TRUNC) != os.O_CREATE|os.O_TRUNC {
+		info, err := f.fs.Stat(f.name)
+		if err != nil && !errors.Is(err, iofs.ErrNotExist) {
+			return err
+		}
+		exists = err == nil && !info.IsDir()
+	}
+
+	switch {
+	case flag&os.O_EXCL != 0 && exists:
+		return f.fs.wrapError("OpenFile", f.name, os.ErrExist)
+	case flag&os.O_CREATE == 0 && !exists:
+		return f.fs.wrapError("OpenFile", f.name, ErrNotExist)
+	}
+	if flag&os.O_EXCL != 0 {
+		f.cond.ifNoneMatch = "*"
+	}
+	if flag&os.O_TRUNC != 0 || !exists {
+		return nil
+	}
+	if err := f.loadContent(); err != nil {
+		return err
+	}
+	if flag&os.O_APPEND == 0 {
+		f.offset = 0
+	}
+	return nil
+}
+
+// loadContent fills f's write buffer with the current content of its object
+// and positions f at its end.
+func (f *File) loadContent() error {
+
+	output, err := f.fs.client.GetObject(f.fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.fs.bucket),
+		Key:    aws.String(f.key),
+	})
+	if err != nil {
+		return f.fs.wrapError("OpenFile", f.name, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return f.fs.wrapError("OpenFile", f.name, err)
+	}
+	f.buffer = data
+	f.offset = int64(len(data))
+	return nil
+}
@@ -0,0 +1,156 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestFileSystem_CreateOpenTruncate(t *testing.T) {
+	fs := s3fstest.New("bucket")
+
+	f, err := fs.Create("c.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	f.Write([]byte("0123456789"))
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if err := fs.Truncate("c.txt", 4); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	f, err = fs.Open("c.txt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "0123" {
+		t.Errorf("after Truncate(4) content = %q, want 0123", data)
+	}
+
+	if err := fs.Truncate("c.txt", 6); err != nil {
+		t.Fatalf("Truncate() to grow error = %v", err)
+	}
+	if data, _ := fs.ReadFile("c.txt"); string(data) != "0123\x00\x00" {
+		t.Errorf("after Truncate(6) content = %q", data)
+	}
+	if err := fs.Truncate("missing.txt", 0); err == nil {
+		t.Errorf("Truncate() of a missing file should fail")
+	}
+}
+
+// headCountingClient counts HeadObject requests.
+type headCountingClient struct {
+	*s3fstest.Client
+	heads int
+}
+
+func (c *headCountingClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.heads++
+	return c.Client.HeadObject(ctx, params, optFns...)
+}
+
+func TestFileSystem_OpenChecksExistence(t *testing.T) {
+	client := &headCountingClient{Client: s3fstest.NewClient()}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "file.txt", "0123456789")
+
+	if _, err := fs.Open("missing.txt"); !errors.Is(err, os.ErrNotExist) || !errors.Is(err, s3fs.ErrNotExist) {
+		t.Errorf("Open(missing) error = %v, want ErrNotExist", err)
+	}
+
+	f, err := fs.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	client.heads = 0
+	if pos, err := f.Seek(-4, io.SeekEnd); err != nil || pos != 6 {
+		t.Errorf("Seek(-4, SeekEnd) = %d, %v; want 6", pos, err)
+	}
+	if client.heads != 0 {
+		t.Errorf("Seek(SeekEnd) made %d HeadObject requests after Open, want 0", client.heads)
+	}
+
+	lazy, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, LazyOpen: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.heads = 0
+	f, err = lazy.Open("missing.txt")
+	if err != nil {
+		t.Fatalf("Open(missing) with LazyOpen error = %v", err)
+	}
+	if client.heads != 0 {
+		t.Errorf("Open() with LazyOpen made %d HeadObject requests, want 0", client.heads)
+	}
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Read() of a missing file error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestFileSystem_OpenFileFlags(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "a.txt", "0123456789")
+
+	writeWith := func(name string, flag int, data string) error {
+		t.Helper()
+		f, err := fs.OpenFile(name, flag, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		return f.Close()
+	}
+	content := func(name string) string {
+		t.Helper()
+		data, err := fs.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// Without O_TRUNC the rest of the file is kept
+	if err := writeWith("a.txt", os.O_WRONLY, "ab"); err != nil {
+		t.Fatalf("OpenFile(O_WRONLY) error = %v", err)
+	}
+	if got := content("a.txt"); got != "ab23456789" {
+		t.Errorf("O_WRONLY write = %q, want ab23456789", got)
+	}
+	if err := writeWith("a.txt", os.O_WRONLY|os.O_TRUNC, "xy"); err != nil {
+		t.Fatal(err)
+	}
+	if got := content("a.txt"); got != "xy" {
+		t.Errorf("O_WRONLY|O_TRUNC write = %q, want xy", got)
+	}
+
+	if err := writeWith("missing.txt", os.O_WRONLY, "x"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenFile(missing, O_WRONLY) error = %v, want ErrNotExist", err)
+	}
+	if err := writeWith("a.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, "x"); !errors.Is(err, os.ErrExist) {
+		t.Errorf("OpenFile(existing, O_EXCL) error = %v, want ErrExist", err)
+	}
+	if err := writeWith("new.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, "new"); err != nil {
+		t.Errorf("OpenFile(new, O_EXCL) error = %v", err)
+	}
+
+	var flagErr *s3fs.FlagError
+	if _, err := fs.OpenFile("a.txt", os.O_RDWR|os.O_APPEND, 0); !errors.As(err, &flagErr) || !errors.Is(err, s3fs.ErrUnsupportedFlags) {
+		t.Errorf("OpenFile(O_RDWR|O_APPEND) error = %v, want a *FlagError", err)
+	}
+}
@@ -0,0 +1,80 @@
+package s3fs
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestOpenModeFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		mode OpenMode
+		want int
+	}{
+		{"read", ModeRead, os.O_RDONLY},
+		{"write", ModeWrite, os.O_WRONLY | os.O_CREATE | os.O_TRUNC},
+		{"append", ModeAppend, os.O_WRONLY | os.O_CREATE | os.O_APPEND},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mode.Flag(); got != tt.want {
+				t.Errorf("Flag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFlag(t *testing.T) {
+	tests := []struct {
+		flag int
+		ok   bool
+	}{
+		{os.O_RDONLY, true},
+		{os.O_WRONLY, true},
+		{os.O_WRONLY | os.O_CREATE | os.O_EXCL, true},
+		{os.O_WRONLY | os.O_APPEND, true},
+		{os.O_RDWR | os.O_CREATE | os.O_TRUNC, true},
+		{os.O_RDONLY | os.O_CREATE, false},
+		{os.O_RDONLY | os.O_TRUNC, false},
+		{os.O_RDWR, false},
+		{os.O_RDWR | os.O_APPEND | os.O_CREATE, false},
+		{os.O_WRONLY | os.O_EXCL, false},
+		{os.O_WRONLY | os.O_TRUNC | os.O_APPEND, false},
+		{os.O_WRONLY | os.O_RDWR, false},
+	}
+	for _, tt := range tests {
+		err := checkFlag(tt.flag)
+		if tt.ok != (err == nil) {
+			t.Errorf("checkFlag(%#x) = %v, want ok %v", tt.flag, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, ErrUnsupportedFlags) {
+			t.Errorf("checkFlag(%#x) error does not match ErrUnsupportedFlags", tt.flag)
+		}
+	}
+}
+
+func TestCreateWrite(t *testing.T) {
+	fs := &FileSystem{}
+	f, err := fs.CreateWrite("/out.txt")
+	if err != nil {
+		t.Fatalf("CreateWrite() error = %v", err)
+	}
+
+	file := f.(*File)
+	if !file.writing || file.key != "out.txt" {
+		t.Errorf("CreateWrite() writing = %v key = %q", file.writing, file.key)
+	}
+}
+
+func TestOpenRead(t *testing.T) {
+	fs := &FileSystem{lazyOpen: true} // no client to check existence with
+	f, err := fs.OpenRead("in.txt")
+	if err != nil {
+		t.Fatalf("OpenRead() error = %v", err)
+	}
+	if f.(*File).writing {
+		t.Errorf("OpenRead() returned a file in write mode")
+	}
+}
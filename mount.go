@@ -0,0 +1,257 @@
+package s3fs
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// MountTable is an absfs.Filer that maps path prefixes to FileSystems, so
+// several buckets (or regions, endpoints, or prefixes of a bucket via Sub)
+// appear as a single tree:
+//
+//	mt := s3fs.NewMountTable()
+//	mt.Mount("/logs", logsFS)
+//	mt.Mount("/assets", assetsFS)
+//
+// Each name is served by the mount with the longest matching prefix. Parents
+// of mount points ("/" above) are reported as directories by Stat. Rename
+// within a mount is delegated to the FileSystem; across mounts the files are
+// streamed to the destination and then deleted from the source, so the move
+// is neither atomic nor server-side.
+type MountTable struct {
+	mu     sync.RWMutex
+	mounts []mountPoint // Sorted by descending prefix length
+}
+
+type mountPoint struct {
+	prefix string // Without leading or trailing slash; "" mounts the root
+	fs     *FileSystem
+}
+
+var _ absfs.Filer = (*MountTable)(nil)
+
+// NewMountTable returns an empty MountTable.
+func NewMountTable() *MountTable {
+	return &MountTable{}
+}
+
+// Mount serves the names below prefix from fs. It fails with os.ErrExist if
+// prefix is already mounted.
+func (mt *MountTable) Mount(prefix string, fs *FileSystem) error {
+	prefix = mountPrefix(prefix)
+
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	for _, m := range mt.mounts {
+		if m.prefix == prefix {
+			return &os.PathError{Op: "mount", Path: "/" + prefix, Err: os.ErrExist}
+		}
+	}
+	mt.mounts = append(mt.mounts, mountPoint{prefix: prefix, fs: fs})
+	sort.SliceStable(mt.mounts, func(i, j int) bool {
+		return len(mt.mounts[i].prefix) > len(mt.mounts[j].prefix)
+	})
+	return nil
+}
+
+// Unmount removes the mount at prefix. It fails with ErrNotMounted if
+// nothing is mounted there.
+func (mt *MountTable) Unmount(prefix string) error {
+	prefix = mountPrefix(prefix)
+
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	for i, m := range mt.mounts {
+		if m.prefix == prefix {
+			mt.mounts = append(mt.mounts[:i], mt.mounts[i+1:]...)
+			return nil
+		}
+	}
+	return &os.PathError{Op: "unmount", Path: "/" + prefix, Err: ErrNotMounted}
+}
+
+// mountPrefix normalizes a mount point or a name to a clean path without
+// leading or trailing slash.
+func mountPrefix(name string) string {
+	return strings.Trim(path.Clean("/"+name), "/")
+}
+
+// resolve returns the FileSystem serving name and the name relative to it.
+func (mt *MountTable) resolve(op, name string) (*FileSystem, string, error) {
+	clean := mountPrefix(name)
+	dir := strings.HasSuffix(name, "/") && clean != ""
+
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+	for _, m := range mt.mounts {
+		var rel string
+		switch {
+		case m.prefix == "":
+			rel = clean
+		case clean == m.prefix:
+			rel = ""
+		case strings.HasPrefix(clean, m.prefix+"/"):
+			rel = strings.TrimPrefix(clean, m.prefix+"/")
+		default:
+			continue
+		}
+		if dir && rel != "" {
+			rel += "/"
+		}
+		return m.fs, rel, nil
+	}
+	return nil, "", &os.PathError{Op: op, Path: name, Err: ErrNotMounted}
+}
+
+// isMountParent reports whether name is a strict parent of a mount point.
+func (mt *MountTable) isMountParent(name string) bool {
+	clean := mountPrefix(name)
+
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+	for _, m := range mt.mounts {
+		if m.prefix != "" && (clean == "" || strings.HasPrefix(m.prefix, clean+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenFile opens name in the FileSystem it is mounted from.
+func (mt *MountTable) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	fs, rel, err := mt.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.OpenFile(rel, flag, perm)
+}
+
+// Mkdir creates a directory in the FileSystem name is mounted from.
+func (mt *MountTable) Mkdir(name string, perm os.FileMode) error {
+	fs, rel, err := mt.resolve("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return fs.Mkdir(rel, perm)
+}
+
+// Remove removes a file from the FileSystem name is mounted from.
+func (mt *MountTable) Remove(name string) error {
+	fs, rel, err := mt.resolve("remove", name)
+	if err != nil {
+		return err
+	}
+	return fs.Remove(rel)
+}
+
+// Rename moves oldpath to newpath. Within a mount it calls the FileSystem's
+// Rename; across mounts it copies every file through the client and then
+// deletes the source.
+func (mt *MountTable) Rename(oldpath, newpath string) error {
+	src, oldRel, err := mt.resolve("rename", oldpath)
+	if err != nil {
+		return err
+	}
+	dst, newRel, err := mt.resolve("rename", newpath)
+	if err != nil {
+		return err
+	}
+	if src == dst {
+		return src.Rename(oldRel, newRel)
+	}
+
+	info, err := src.Stat(oldRel)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if err := moveAcross(src, oldRel, dst, newRel, info.Size()); err != nil {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+		}
+		return nil
+	}
+
+	oldDir := strings.TrimSuffix(oldRel, "/") + "/"
+	newDir := strings.TrimSuffix(newRel, "/") + "/"
+	entries, err := src.listRemote(oldDir)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	for name, e := range entries {
+		if err := moveAcross(src, oldDir+name, dst, newDir+name, e.size); err != nil {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+		}
+	}
+	return src.RemoveAll(oldDir)
+}
+
+// moveAcross streams the file srcName of src to dstName in dst and removes
+// the source once the upload succeeded.
+func moveAcross(src *FileSystem, srcName string, dst *FileSystem, dstName string, size int64) error {
+	f, err := src.OpenFile(srcName, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	key := dst.objectKey(dstName)
+	err = dst.putReader(dst.ctx, dstName, f, size)
+	dst.stats.invalidate(key)
+	if err != nil {
+		return err
+	}
+	if err := dst.manifestPut(key, size, false); err != nil {
+		return err
+	}
+	return src.Remove(srcName)
+}
+
+// Stat returns file info for name. Parents of mount points that do not
+// exist in a mounted FileSystem are reported as directories.
+func (mt *MountTable) Stat(name string) (os.FileInfo, error) {
+	fs, rel, err := mt.resolve("stat", name)
+	if err == nil {
+		info, serr := fs.Stat(rel)
+		if serr == nil {
+			return info, nil
+		}
+		err = serr
+	}
+	if mt.isMountParent(name) {
+		return &fileInfo{name: path.Base("/" + mountPrefix(name)), isDir: true}, nil
+	}
+	return nil, err
+}
+
+// Chmod is delegated to the FileSystem name is mounted from.
+func (mt *MountTable) Chmod(name string, mode os.FileMode) error {
+	fs, rel, err := mt.resolve("chmod", name)
+	if err != nil {
+		return err
+	}
+	return fs.Chmod(rel, mode)
+}
+
+// Chtimes is delegated to the FileSystem name is mounted from.
+func (mt *MountTable) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	fs, rel, err := mt.resolve("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return fs.Chtimes(rel, atime, mtime)
+}
+
+// Chown is delegated to the FileSystem name is mounted from.
+func (mt *MountTable) Chown(name string, uid, gid int) error {
+	fs, rel, err := mt.resolve("chown", name)
+	if err != nil {
+		return err
+	}
+	return fs.Chown(rel, uid, gid)
+}
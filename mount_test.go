@@ -0,0 +1,68 @@
+package s3fs
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestMountTable_Resolve(t *testing.T) {
+	root := &FileSystem{bucket: "root"}
+	logs := &FileSystem{bucket: "logs"}
+	archive := &FileSystem{bucket: "archive"}
+
+	mt := NewMountTable()
+	for prefix, fs := range map[string]*FileSystem{"/": root, "/logs": logs, "logs/archive/": archive} {
+		if err := mt.Mount(prefix, fs); err != nil {
+			t.Fatalf("Mount(%q) error = %v", prefix, err)
+		}
+	}
+	if err := mt.Mount("/logs/", root); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Mount() twice error = %v, want os.ErrExist", err)
+	}
+
+	tests := []struct {
+		name   string
+		wantFS *FileSystem
+		rel    string
+	}{
+		{"/a.txt", root, "a.txt"},
+		{"/logs", logs, ""},
+		{"/logs/2024/app.log", logs, "2024/app.log"},
+		{"/logs/2024/", logs, "2024/"},
+		{"/logsx/a", root, "logsx/a"},
+		{"/logs/archive/old.log", archive, "old.log"},
+		{"/logs/../logs/x", logs, "x"},
+	}
+	for _, tt := range tests {
+		fs, rel, err := mt.resolve("stat", tt.name)
+		if err != nil || fs != tt.wantFS || rel != tt.rel {
+			t.Errorf("resolve(%q) = %s, %q, %v; want %s, %q", tt.name, fs.bucket, rel, err, tt.wantFS.bucket, tt.rel)
+		}
+	}
+
+	if err := mt.Unmount("/"); err != nil {
+		t.Fatalf("Unmount() error = %v", err)
+	}
+	if _, _, err := mt.resolve("stat", "/a.txt"); !errors.Is(err, ErrNotMounted) {
+		t.Errorf("resolve() outside mounts error = %v, want ErrNotMounted", err)
+	}
+	if err := mt.Unmount("/"); !errors.Is(err, ErrNotMounted) {
+		t.Errorf("Unmount() twice error = %v, want ErrNotMounted", err)
+	}
+}
+
+func TestMountTable_StatMountParent(t *testing.T) {
+	mt := NewMountTable()
+	mt.Mount("/data/assets", &FileSystem{})
+
+	for _, name := range []string{"/", "/data", "/data/"} {
+		info, err := mt.Stat(name)
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat(%q) = %v, %v; want a directory", name, info, err)
+		}
+	}
+	if _, err := mt.Stat("/other"); !errors.Is(err, ErrNotMounted) {
+		t.Errorf("Stat() outside mounts error = %v, want ErrNotMounted", err)
+	}
+}
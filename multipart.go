@@ -2,7 +2,9 @@ package s3fs
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -18,6 +20,8 @@ const (
 )
 
 // MultipartUpload handles large file uploads to S3 using multipart upload.
+// It implements io.Writer and io.ReaderFrom: written data is buffered until a
+// full part is available, and Complete uploads whatever remains as the last part.
 type MultipartUpload struct {
 	fs         *FileSystem
 	key        string
@@ -25,35 +29,67 @@ type MultipartUpload struct {
 	partNumber int32
 	parts      []types.CompletedPart
 	partSize   int64
+	pending    []byte
+	cond       writeCondition
+	etag       string
 }
 
 // NewMultipartUpload creates a new multipart upload session.
 func (fs *FileSystem) NewMultipartUpload(key string) (*MultipartUpload, error) {
-	key = trimPrefix(key)
+	mu, err := fs.newMultipartUpload(trimPrefix(key), nil)
+	if err != nil {
+		return nil, fs.wrapError("NewMultipartUpload", key, err)
+	}
+	return mu, nil
+}
 
+// newMultipartUpload starts a multipart upload of the named file with the
+// attributes of opts, which may be nil.
+func (fs *FileSystem) newMultipartUpload(name string, opts *PutOptions) (*MultipartUpload, error) {
+	return fs.newMultipartUploadTo(fs.objectKey(name), name, opts)
+}
+
+// newMultipartUploadTo starts a multipart upload under key with the
+// attributes of the named file, for content staged before it is moved to
+// name.
+func (fs *FileSystem) newMultipartUploadTo(key, name string, opts *PutOptions) (*MultipartUpload, error) {
+	input, err := fs.putInput(name, opts)
+	if err != nil {
+		return nil, err
+	}
 	output, err := fs.client.CreateMultipartUpload(fs.ctx, &s3.CreateMultipartUploadInput{
-		Bucket: aws.String(fs.bucket),
-		Key:    aws.String(key),
+		Bucket:       aws.String(fs.bucket),
+		Key:          aws.String(key),
+		ContentType:  input.ContentType,
+		Metadata:     input.Metadata,
+		StorageClass: input.StorageClass,
+		Tagging:      input.Tagging,
 	})
 	if err != nil {
-		return nil, wrapError("NewMultipartUpload", key, err)
+		return nil, err
 	}
 
-	return &MultipartUpload{
+	partSize := int64(DefaultPartSize)
+	if opts != nil && opts.PartSize > 0 {
+		partSize = max(opts.PartSize, MinPartSize)
+	}
+	mu := &MultipartUpload{
 		fs:         fs,
 		key:        key,
 		uploadID:   *output.UploadId,
 		partNumber: 1,
 		parts:      make([]types.CompletedPart, 0),
-		partSize:   DefaultPartSize,
-	}, nil
+		partSize:   partSize,
+	}
+	fs.shutdown.track(mu)
+	return mu, nil
 }
 
 // SetPartSize sets the size of each part for the multipart upload.
 // The part size must be at least MinPartSize (5MB).
 func (mu *MultipartUpload) SetPartSize(size int64) error {
 	if size < MinPartSize {
-		return wrapError("SetPartSize", mu.key, ErrInvalidSeek)
+		return mu.fs.wrapError("SetPartSize", mu.key, ErrInvalidSeek)
 	}
 	mu.partSize = size
 	return nil
@@ -69,7 +105,7 @@ func (mu *MultipartUpload) UploadPart(data []byte) error {
 		Body:       bytes.NewReader(data),
 	})
 	if err != nil {
-		return wrapError("UploadPart", mu.key, err)
+		return mu.fs.wrapError("UploadPart", mu.key, err)
 	}
 
 	mu.parts = append(mu.parts, types.CompletedPart{
@@ -88,7 +124,7 @@ func (mu *MultipartUpload) UploadFromReader(r io.Reader) error {
 	for {
 		n, err := io.ReadFull(r, buf)
 		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-			return wrapError("UploadFromReader", mu.key, err)
+			return mu.fs.wrapError("UploadFromReader", mu.key, err)
 		}
 
 		if n == 0 {
@@ -108,37 +144,155 @@ func (mu *MultipartUpload) UploadFromReader(r io.Reader) error {
 	return nil
 }
 
+// Write buffers p and uploads a part each time the buffer reaches the part size.
+// If a part upload fails the data stays buffered and the error is returned.
+func (mu *MultipartUpload) Write(p []byte) (int, error) {
+	mu.pending = append(mu.pending, p...)
+	if err := mu.flushFull(); err != nil {
+		return len(p), err
+	}
+	return len(p), nil
+}
+
+// ReadFrom reads from r until EOF, uploading a part each time a full part has
+// been read. It returns the number of bytes read from r.
+func (mu *MultipartUpload) ReadFrom(r io.Reader) (int64, error) {
+	if err := mu.flushFull(); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for {
+		if int64(cap(mu.pending)) < mu.partSize {
+			buf := make([]byte, len(mu.pending), mu.partSize)
+			copy(buf, mu.pending)
+			mu.pending = buf
+		}
+
+		n, err := io.ReadFull(r, mu.pending[len(mu.pending):mu.partSize])
+		mu.pending = mu.pending[:len(mu.pending)+n]
+		total += int64(n)
+
+		if int64(len(mu.pending)) == mu.partSize {
+			if uerr := mu.UploadPart(mu.pending); uerr != nil {
+				return total, uerr
+			}
+			mu.pending = mu.pending[:0]
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, mu.fs.wrapError("ReadFrom", mu.key, err)
+		}
+	}
+}
+
+// flushFull uploads full parts from the write buffer.
+func (mu *MultipartUpload) flushFull() error {
+	for int64(len(mu.pending)) >= mu.partSize {
+		if err := mu.UploadPart(mu.pending[:mu.partSize]); err != nil {
+			return err
+		}
+		mu.pending = append(mu.pending[:0], mu.pending[mu.partSize:]...)
+	}
+	return nil
+}
+
 // Complete completes the multipart upload.
+// Data buffered by Write or ReadFrom is uploaded as the final part first.
 func (mu *MultipartUpload) Complete() error {
-	_, err := mu.fs.client.CompleteMultipartUpload(mu.fs.ctx, &s3.CompleteMultipartUploadInput{
+	if len(mu.pending) > 0 || len(mu.parts) == 0 {
+		if err := mu.UploadPart(mu.pending); err != nil {
+			return err
+		}
+		mu.pending = nil
+	}
+
+	output, err := mu.fs.client.CompleteMultipartUpload(mu.fs.ctx, &s3.CompleteMultipartUploadInput{
 		Bucket:   aws.String(mu.fs.bucket),
 		Key:      aws.String(mu.key),
 		UploadId: aws.String(mu.uploadID),
 		MultipartUpload: &types.CompletedMultipartUpload{
 			Parts: mu.parts,
 		},
-	})
+	}, mu.cond.options()...)
 	if err != nil {
-		return wrapError("Complete", mu.key, err)
+		return mu.fs.wrapError("Complete", mu.key, preconditionError(err))
 	}
+	mu.etag = aws.ToString(output.ETag)
+	mu.fs.shutdown.untrack(mu)
 
 	return nil
 }
 
 // Abort aborts the multipart upload and deletes all uploaded parts.
 func (mu *MultipartUpload) Abort() error {
-	_, err := mu.fs.client.AbortMultipartUpload(mu.fs.ctx, &s3.AbortMultipartUploadInput{
+	return mu.abort(mu.fs.ctx)
+}
+
+// abort implements Abort under ctx.
+func (mu *MultipartUpload) abort(ctx context.Context) error {
+	mu.fs.shutdown.untrack(mu)
+	_, err := mu.fs.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
 		Bucket:   aws.String(mu.fs.bucket),
 		Key:      aws.String(mu.key),
 		UploadId: aws.String(mu.uploadID),
 	})
 	if err != nil {
-		return wrapError("Abort", mu.key, err)
+		return mu.fs.wrapError("Abort", mu.key, err)
 	}
 
 	return nil
 }
 
+// CleanupMultipartUploads aborts incomplete multipart uploads in the bucket that
+// were initiated more than olderThan ago. Abandoned uploads are invisible in
+// listings but their parts are billed as storage until aborted. It returns the
+// number of uploads that were aborted.
+func (fs *FileSystem) CleanupMultipartUploads(olderThan time.Duration) (int, error) {
+	cutoff := time.Now().Add(-olderThan)
+	aborted := 0
+
+	var keyMarker, uploadIDMarker *string
+	for {
+		output, err := fs.client.ListMultipartUploads(fs.ctx, &s3.ListMultipartUploadsInput{
+			Bucket:         aws.String(fs.bucket),
+			KeyMarker:      keyMarker,
+			UploadIdMarker: uploadIDMarker,
+		})
+		if err != nil {
+			return aborted, fs.wrapError("CleanupMultipartUploads", "", err)
+		}
+
+		for _, upload := range output.Uploads {
+			if !aws.ToTime(upload.Initiated).Before(cutoff) {
+				continue
+			}
+
+			key := aws.ToString(upload.Key)
+			_, err := fs.client.AbortMultipartUpload(fs.ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(fs.bucket),
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			})
+			if err != nil {
+				return aborted, fs.wrapError("CleanupMultipartUploads", key, err)
+			}
+			aborted++
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		keyMarker = output.NextKeyMarker
+		uploadIDMarker = output.NextUploadIdMarker
+	}
+
+	return aborted, nil
+}
+
 // trimPrefix is a helper function to remove leading slashes.
 func trimPrefix(s string) string {
 	if len(s) > 0 && s[0] == '/' {
@@ -0,0 +1,33 @@
+package s3fs
+
+import (
+	"io"
+	"testing"
+)
+
+func TestMultipartUpload_Interfaces(t *testing.T) {
+	var _ io.Writer = (*MultipartUpload)(nil)
+	var _ io.ReaderFrom = (*MultipartUpload)(nil)
+}
+
+func TestMultipartUpload_WriteBuffers(t *testing.T) {
+	mu := &MultipartUpload{partSize: MinPartSize}
+
+	n, err := mu.Write([]byte("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("Write() = %d, %v", n, err)
+	}
+	if string(mu.pending) != "hello" || len(mu.parts) != 0 {
+		t.Errorf("pending = %q, parts = %d; want buffered data and no parts", mu.pending, len(mu.parts))
+	}
+}
+
+func TestMultipartUpload_SetPartSize(t *testing.T) {
+	mu := &MultipartUpload{partSize: DefaultPartSize}
+	if err := mu.SetPartSize(MinPartSize - 1); err == nil {
+		t.Errorf("SetPartSize() below minimum should fail")
+	}
+	if err := mu.SetPartSize(MinPartSize); err != nil || mu.partSize != MinPartSize {
+		t.Errorf("SetPartSize(MinPartSize) = %v, partSize = %d", err, mu.partSize)
+	}
+}
@@ -0,0 +1,123 @@
+package s3fs
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Normalizer maps names to one Unicode normalization form, see
+// Config.Normalizer. The forms of golang.org/x/text/unicode/norm, such as
+// norm.NFC and norm.NFD, implement it.
+type Normalizer interface {
+	String(s string) string
+}
+
+// aliasTable maps normalized names, relative to the bucket, to the keys of
+// listed objects stored in another normalization form.
+type aliasTable struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+func (t *aliasTable) lookup(name string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key, ok := t.keys[name]
+	return key, ok
+}
+
+func (t *aliasTable) put(name, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.keys == nil {
+		t.keys = make(map[string]string)
+	}
+	t.keys[name] = key
+}
+
+// normalize returns name in the form of Config.Normalizer, or unchanged
+// without one.
+func (fs *FileSystem) normalize(name string) string {
+	if fs.normalizer == nil {
+		return name
+	}
+	return fs.normalizer.String(name)
+}
+
+// normalizeListed returns the normalized form of the name of a listed key,
+// relative to the bucket. If the key is stored in another form it is
+// recorded, so the normalized name keeps referring to the stored object.
+func (fs *FileSystem) normalizeListed(key, name string) string {
+	if fs.normalizer == nil {
+		return name
+	}
+	n := fs.normalizer.String(name)
+	if n != name {
+		fs.aliases.put(n, key)
+	}
+	return n
+}
+
+// aliasKey returns the key of an object listed under another normalization
+// form of the normalized name, relative to the bucket. The key of a
+// directory also serves its name without trailing slash.
+func (fs *FileSystem) aliasKey(name string) (string, bool) {
+	if key, ok := fs.aliases.lookup(name); ok {
+		return key, true
+	}
+	if key, ok := fs.aliases.lookup(name + "/"); ok {
+		return strings.TrimSuffix(key, "/"), true
+	}
+	return "", false
+}
+
+// findAlias lists the parent directory of name for an object stored under
+// another normalization form of name, and reports whether one was found.
+func (fs *FileSystem) findAlias(name string) bool {
+	dir := path.Dir(strings.TrimSuffix(name, "/"))
+	if dir == "." {
+		dir = ""
+	} else {
+		dir += "/"
+	}
+	want := fs.normalize(fs.root + name)
+
+	prefix := fs.objectKey(dir)
+	var token *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(prefix),
+			Delimiter:         aws.String("/"),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			return false
+		}
+
+		keys := make([]string, 0, len(output.Contents)+len(output.CommonPrefixes))
+		for _, obj := range output.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+		for _, p := range output.CommonPrefixes {
+			keys = append(keys, aws.ToString(p.Prefix))
+		}
+		for _, key := range keys {
+			if !fs.isSystemKey(key) {
+				fs.logicalName(key) // records keys in another form
+			}
+		}
+		if _, ok := fs.aliasKey(want); ok {
+			return true
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			return false
+		}
+		token = output.NextContinuationToken
+	}
+}
@@ -0,0 +1,71 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// composer is a Normalizer that composes "e" and a combining acute accent,
+// standing in for norm.NFC.
+type composer struct{}
+
+func (composer) String(s string) string { return strings.ReplaceAll(s, "e\u0301", "\u00e9") }
+
+func TestFileSystem_Normalizer(t *testing.T) {
+	client := s3fstest.NewClient()
+	// Written by a tool that stores decomposed names
+	if _, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("docs/cafe\u0301.txt"),
+		Body:   strings.NewReader("old"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, Normalizer: composer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("docs/caf\u00e9.txt"); err != nil {
+		t.Fatalf("Stat() of the composed name = %v", err)
+	}
+	if data, err := fs.ReadFile("docs/cafe\u0301.txt"); err != nil || string(data) != "old" {
+		t.Errorf("ReadFile() of the decomposed name = %q, %v", data, err)
+	}
+
+	if err := fs.WriteFile("docs/caf\u00e9.txt", []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.Open("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "caf\u00e9.txt" {
+		t.Errorf("Readdirnames() = %q, want the composed name once", names)
+	}
+	if data, err := fs.ReadFile("docs/caf\u00e9.txt"); err != nil || string(data) != "new" {
+		t.Errorf("ReadFile() = %q, %v, want the stored object overwritten", data, err)
+	}
+
+	// Without a Normalizer the forms are different names
+	plain, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plain.Stat("docs/caf\u00e9.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() without Normalizer = %v, want ErrNotExist", err)
+	}
+}
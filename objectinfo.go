@@ -0,0 +1,88 @@
+package s3fs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ObjectInfo holds the S3 attributes of an object. The Sys method of the
+// os.FileInfo values returned by Stat, Lstat, Readdir and Walk returns an
+// *ObjectInfo for objects; it returns nil for directories without marker
+// objects and for files answered from manifests or packs.
+//
+// Listings do not carry encryption details, content types or metadata, so
+// those fields, like the Object Lock settings, are only set by StatExtended
+// and by a Stat that was not answered from the stat cache.
+type ObjectInfo struct {
+	Key                  string            // Object key in the bucket
+	Size                 int64             // Size in bytes
+	ModTime              time.Time         // Last modification time
+	ETag                 string            // Entity tag, including quotes
+	StorageClass         string            // Storage class, such as "STANDARD" or "GLACIER"
+	VersionID            string            // Version ID in versioned buckets
+	ServerSideEncryption string            // Encryption algorithm, such as "AES256" or "aws:kms"
+	SSEKMSKeyID          string            // KMS key used for "aws:kms" encryption
+	ContentType          string            // MIME type of the content
+	Metadata             map[string]string // User metadata, without the x-amz-meta- prefix
+
+	RetentionMode RetentionMode // Object Lock retention mode, if the object has a retention period
+	RetainUntil   time.Time     // End of the retention period
+	LegalHold     bool          // Whether an Object Lock legal hold is placed on the object
+
+	Inode uint64 // Stable inode number, set by Stat and Lstat if Config.Inodes is set
+}
+
+// StatExtended returns the S3 attributes of the named object. Unlike Stat it
+// always asks S3, so the result includes metadata and encryption details.
+func (fs *FileSystem) StatExtended(name string) (*ObjectInfo, error) {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+
+	output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fs.wrapError("StatExtended", name, err)
+	}
+	return headObjectInfo(key, output), nil
+}
+
+// headObjectInfo converts a HeadObject response for key.
+func headObjectInfo(key string, output *s3.HeadObjectOutput) *ObjectInfo {
+	storageClass := string(output.StorageClass)
+	if storageClass == "" {
+		// HeadObject omits the header for the default class
+		storageClass = string(types.StorageClassStandard)
+	}
+	return &ObjectInfo{
+		Key:                  key,
+		Size:                 aws.ToInt64(output.ContentLength),
+		ModTime:              aws.ToTime(output.LastModified),
+		ETag:                 aws.ToString(output.ETag),
+		StorageClass:         storageClass,
+		VersionID:            aws.ToString(output.VersionId),
+		ServerSideEncryption: string(output.ServerSideEncryption),
+		SSEKMSKeyID:          aws.ToString(output.SSEKMSKeyId),
+		ContentType:          aws.ToString(output.ContentType),
+		Metadata:             output.Metadata,
+		RetentionMode:        RetentionMode(output.ObjectLockMode),
+		RetainUntil:          aws.ToTime(output.ObjectLockRetainUntilDate),
+		LegalHold:            output.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn,
+	}
+}
+
+// listObjectInfo converts an object of a ListObjectsV2 response.
+func listObjectInfo(obj types.Object) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          aws.ToString(obj.Key),
+		Size:         aws.ToInt64(obj.Size),
+		ModTime:      aws.ToTime(obj.LastModified),
+		ETag:         aws.ToString(obj.ETag),
+		StorageClass: string(obj.StorageClass),
+	}
+}
@@ -0,0 +1,51 @@
+package s3fs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_StatExtended(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "dir/a.txt", "hello")
+
+	info, err := fs.Stat("dir/a.txt")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	obj, ok := info.Sys().(*s3fs.ObjectInfo)
+	if !ok {
+		t.Fatalf("Sys() = %T, want *s3fs.ObjectInfo", info.Sys())
+	}
+	if obj.Key != "dir/a.txt" || obj.ETag == "" || obj.StorageClass != "STANDARD" {
+		t.Errorf("Sys() = %+v", obj)
+	}
+
+	ext, err := fs.StatExtended("dir/a.txt")
+	if err != nil {
+		t.Fatalf("StatExtended() error = %v", err)
+	}
+	if ext.Size != 5 || ext.ETag != obj.ETag {
+		t.Errorf("StatExtended() = %+v, want size 5 and ETag %s", ext, obj.ETag)
+	}
+	if _, err := fs.StatExtended("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("StatExtended() of a missing object error = %v, want fs.ErrNotExist", err)
+	}
+
+	d, err := fs.Open("dir")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer d.Close()
+	infos, err := d.Readdir(-1)
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("Readdir() = %d entries, %v", len(infos), err)
+	}
+	if obj, ok := infos[0].Sys().(*s3fs.ObjectInfo); !ok || obj.StorageClass != "STANDARD" {
+		t.Errorf("Readdir() Sys() = %#v", infos[0].Sys())
+	}
+}
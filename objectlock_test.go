@@ -0,0 +1,64 @@
+package s3fs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_ObjectLock(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	if err := fs.WriteFile("record.txt", []byte("audit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := fs.SetRetention("record.txt", s3fs.RetentionGovernance, until); err != nil {
+		t.Fatalf("SetRetention() error = %v", err)
+	}
+	if err := fs.SetLegalHold("/record.txt", true); err != nil {
+		t.Fatalf("SetLegalHold() error = %v", err)
+	}
+	info, err := fs.StatExtended("record.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.RetentionMode != s3fs.RetentionGovernance || !info.RetainUntil.Equal(until) || !info.LegalHold {
+		t.Errorf("StatExtended() lock = %q, %v, %v; want GOVERNANCE, %v, true", info.RetentionMode, info.RetainUntil, info.LegalHold, until)
+	}
+	if err := fs.Remove("record.txt"); err == nil {
+		t.Error("Remove() of a locked file succeeded")
+	}
+
+	// Removing the governance retention leaves the legal hold
+	if err := fs.SetRetention("record.txt", "", time.Time{}); err != nil {
+		t.Fatalf("SetRetention() removing the retention error = %v", err)
+	}
+	if err := fs.Remove("record.txt"); err == nil {
+		t.Error("Remove() of a file on legal hold succeeded")
+	}
+	if err := fs.SetLegalHold("record.txt", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("record.txt"); err != nil {
+		t.Errorf("Remove() of an unlocked file error = %v", err)
+	}
+
+	if err := fs.WriteFile("compliance.txt", []byte("audit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.SetRetention("compliance.txt", s3fs.RetentionCompliance, until); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.SetRetention("compliance.txt", s3fs.RetentionCompliance, until.Add(-time.Minute)); err == nil {
+		t.Error("SetRetention() shortening a compliance retention succeeded")
+	}
+	if err := fs.SetRetention("compliance.txt", "", time.Time{}); err == nil {
+		t.Error("SetRetention() removing a compliance retention succeeded")
+	}
+	if err := fs.SetRetention("compliance.txt", s3fs.RetentionCompliance, until.Add(time.Hour)); err != nil {
+		t.Errorf("SetRetention() extending a compliance retention error = %v", err)
+	}
+}
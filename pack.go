@@ -0,0 +1,252 @@
+package s3fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// PackIndexSuffix is appended to a pack object's key to form the key of its index.
+const PackIndexSuffix = ".idx"
+
+// packEntry locates a single file inside a pack object.
+type packEntry struct {
+	Pack    string    `json:"-"`
+	Offset  int64     `json:"offset"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// packIndex is the JSON document stored next to each pack object.
+type packIndex struct {
+	Entries map[string]packEntry `json:"entries"`
+}
+
+// packTable holds the entries of all packs loaded into a FileSystem.
+type packTable struct {
+	mu      sync.RWMutex
+	entries map[string]packEntry
+}
+
+func newPackTable() *packTable {
+	return &packTable{entries: make(map[string]packEntry)}
+}
+
+// lookup returns the pack entry for name, if any.
+func (t *packTable) lookup(name string) (packEntry, bool) {
+	if t == nil {
+		return packEntry{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	e, ok := t.entries[name]
+	return e, ok
+}
+
+// add registers all entries of the pack stored under key.
+func (t *packTable) add(key string, idx *packIndex) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, e := range idx.Entries {
+		e.Pack = key
+		t.entries[name] = e
+	}
+}
+
+// remove forgets every entry that belongs to the pack stored under key.
+func (t *packTable) remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, e := range t.entries {
+		if e.Pack == key {
+			delete(t.entries, name)
+		}
+	}
+}
+
+// forget drops the entry of name and, if name is a directory, the entries
+// below it, "" being the root.
+func (t *packTable) forget(name string) {
+	if t == nil {
+		return
+	}
+	name = strings.Trim(name, "/")
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for n := range t.entries {
+		if name == "" || n == name || strings.HasPrefix(n, name+"/") {
+			delete(t.entries, n)
+		}
+	}
+}
+
+// below returns the entries below the directory dir, "" being the root.
+func (t *packTable) below(dir string) map[string]packEntry {
+	if t == nil {
+		return nil
+	}
+	prefix := strings.Trim(dir, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var entries map[string]packEntry
+	for name, e := range t.entries {
+		if strings.HasPrefix(name, prefix) {
+			if entries == nil {
+				entries = make(map[string]packEntry)
+			}
+			entries[name] = e
+		}
+	}
+	return entries
+}
+
+// packedInfos returns the direct entries of the directory dir that come
+// from loaded packs: the packed files in it and the directories their names
+// imply, with base names.
+func (fs *FileSystem) packedInfos(dir string) []os.FileInfo {
+	prefix := strings.Trim(dir, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	var infos []os.FileInfo
+	dirs := make(map[string]bool)
+	for name, e := range fs.packs.below(dir) {
+		rest := strings.TrimPrefix(name, prefix)
+		if sub, _, ok := strings.Cut(rest, "/"); ok {
+			if !dirs[sub] {
+				dirs[sub] = true
+				infos = append(infos, &fileInfo{name: sub, isDir: true})
+			}
+			continue
+		}
+		infos = append(infos, &fileInfo{name: rest, size: e.Size, modTime: e.ModTime})
+	}
+	return infos
+}
+
+// PackWriter aggregates many small files into a single pack object.
+// Files are appended to an in-memory buffer; Close uploads the pack object
+// together with a JSON index (stored under the pack key plus PackIndexSuffix)
+// and loads the pack into the FileSystem so the files can be read by path.
+type PackWriter struct {
+	fs      *FileSystem
+	key     string
+	buf     bytes.Buffer
+	index   packIndex
+	closed  bool
+	modTime time.Time
+}
+
+// NewPackWriter creates a PackWriter that will store its pack object under key.
+func (fs *FileSystem) NewPackWriter(key string) *PackWriter {
+	return &PackWriter{
+		fs:      fs,
+		key:     strings.TrimPrefix(key, "/"),
+		index:   packIndex{Entries: make(map[string]packEntry)},
+		modTime: time.Now(),
+	}
+}
+
+// Add appends a file to the pack. Adding the same name twice replaces the
+// earlier entry in the index (the earlier bytes remain in the pack).
+func (pw *PackWriter) Add(name string, data []byte) error {
+	if pw.closed {
+		return ErrPackClosed
+	}
+	name = strings.TrimPrefix(name, "/")
+
+	pw.index.Entries[name] = packEntry{
+		Offset:  int64(pw.buf.Len()),
+		Size:    int64(len(data)),
+		ModTime: pw.modTime,
+	}
+	pw.buf.Write(data)
+	return nil
+}
+
+// Len returns the number of files added to the pack.
+func (pw *PackWriter) Len() int {
+	return len(pw.index.Entries)
+}
+
+// Close uploads the pack object and its index, then loads the pack.
+func (pw *PackWriter) Close() error {
+	if pw.closed {
+		return ErrPackClosed
+	}
+	pw.closed = true
+
+	idx, err := json.Marshal(&pw.index)
+	if err != nil {
+		return pw.fs.wrapError("PackWriter.Close", pw.key, err)
+	}
+
+	_, err = pw.fs.client.PutObject(pw.fs.ctx, &s3.PutObjectInput{
+		Bucket: aws.String(pw.fs.bucket),
+		Key:    aws.String(pw.key),
+		Body:   bytes.NewReader(pw.buf.Bytes()),
+	})
+	if err != nil {
+		return pw.fs.wrapError("PackWriter.Close", pw.key, err)
+	}
+
+	_, err = pw.fs.client.PutObject(pw.fs.ctx, &s3.PutObjectInput{
+		Bucket: aws.String(pw.fs.bucket),
+		Key:    aws.String(pw.key + PackIndexSuffix),
+		Body:   bytes.NewReader(idx),
+	})
+	if err != nil {
+		return pw.fs.wrapError("PackWriter.Close", pw.key, err)
+	}
+
+	pw.fs.packs.add(pw.key, &pw.index)
+	return nil
+}
+
+// LoadPack reads the index of the pack stored under key and makes its files
+// available through OpenFile, Stat, Readdir and Walk. Writing or removing a
+// packed file drops it from the loaded packs. Reads of packed files are served with
+// range requests against the pack object. Files from later loaded packs take
+// precedence over earlier ones and over standalone objects with the same name.
+func (fs *FileSystem) LoadPack(key string) error {
+	key = strings.TrimPrefix(key, "/")
+
+	output, err := fs.client.GetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key + PackIndexSuffix),
+	})
+	if err != nil {
+		return fs.wrapError("LoadPack", key, err)
+	}
+	defer output.Body.Close()
+
+	var idx packIndex
+	if err := json.NewDecoder(output.Body).Decode(&idx); err != nil {
+		return fs.wrapError("LoadPack", key, fmt.Errorf("invalid pack index: %w", err))
+	}
+
+	fs.packs.add(key, &idx)
+	return nil
+}
+
+// UnloadPack forgets the files of the pack stored under key. The pack object
+// itself is left untouched.
+func (fs *FileSystem) UnloadPack(key string) {
+	fs.packs.remove(strings.TrimPrefix(key, "/"))
+}
+
+// packRange returns the HTTP Range header for n bytes of a packed file
+// starting at off, relative to the start of the file.
+func packRange(e *packEntry, off, n int64) string {
+	return fmt.Sprintf("bytes=%d-%d", e.Offset+off, e.Offset+off+n-1)
+}
@@ -0,0 +1,117 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+// newPackedFS returns a filesystem with a loaded pack of docs/a.txt and
+// docs/sub/b.txt next to the standalone object docs/c.txt.
+func newPackedFS(t *testing.T) *s3fs.FileSystem {
+	t.Helper()
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "docs/c.txt", "standalone")
+	pw := fs.NewPackWriter("packs/0001.pack")
+	if err := pw.Add("docs/a.txt", []byte("alpha")); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Add("docs/sub/b.txt", []byte("beta")); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func TestPack_Listing(t *testing.T) {
+	fs := newPackedFS(t)
+
+	f, err := fs.Open("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+		if info.Name() == "a.txt" && info.Size() != 5 {
+			t.Errorf("Readdir() size of a.txt = %d, want 5", info.Size())
+		}
+		if info.Name() == "sub" && !info.IsDir() {
+			t.Errorf("Readdir() entry sub is not a directory")
+		}
+	}
+	if got := strings.Join(names, " "); got != "a.txt c.txt sub" {
+		t.Errorf("Readdir() = %s, want a.txt c.txt sub", got)
+	}
+
+	var walked []string
+	err = fs.Walk("docs", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(walked)
+	if got := strings.Join(walked, " "); got != "docs/ docs/a.txt docs/c.txt docs/sub/ docs/sub/b.txt" {
+		t.Errorf("Walk() = %s", got)
+	}
+}
+
+func TestPack_WriteAndRemove(t *testing.T) {
+	fs := newPackedFS(t)
+
+	writeFile(t, fs, "docs/a.txt", "rewritten")
+	if got := readFile(t, fs, "docs/a.txt"); got != "rewritten" {
+		t.Errorf("read after write = %q, want the written content", got)
+	}
+	if info, err := fs.Stat("docs/a.txt"); err != nil || info.Size() != 9 {
+		t.Errorf("Stat() after write = %v, %v, want the written object", info, err)
+	}
+
+	if err := fs.Remove("docs/sub/b.txt"); err != nil {
+		t.Fatalf("Remove() of a packed file error = %v", err)
+	}
+	if _, err := fs.Stat("docs/sub/b.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() after Remove error = %v, want ErrNotExist", err)
+	}
+	if _, err := fs.Open("docs/sub/b.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open() after Remove error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestPack_ReadAtEmpty(t *testing.T) {
+	fs := newPackedFS(t)
+	f, err := fs.Open("docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gets := fs.Stats().Operations["GetObject"].Requests
+	if n, err := f.ReadAt(nil, 2); n != 0 || err != nil {
+		t.Errorf("ReadAt() of no bytes = %d, %v, want 0, nil", n, err)
+	}
+	if n := fs.Stats().Operations["GetObject"].Requests - gets; n != 0 {
+		t.Errorf("GetObject requests = %d, want none for an empty read", n)
+	}
+	if n, err := f.ReadAt(make([]byte, 1), 5); n != 0 || err != io.EOF {
+		t.Errorf("ReadAt() at the end = %d, %v, want 0, io.EOF", n, err)
+	}
+}
@@ -0,0 +1,70 @@
+package s3fs
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPackWriter_Add(t *testing.T) {
+	fs := &FileSystem{packs: newPackTable()}
+	pw := fs.NewPackWriter("/packs/0001.pack")
+
+	if err := pw.Add("/a.txt", []byte("hello")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := pw.Add("b.txt", []byte("world!")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if pw.key != "packs/0001.pack" {
+		t.Errorf("key = %q, want packs/0001.pack", pw.key)
+	}
+	if pw.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", pw.Len())
+	}
+
+	b := pw.index.Entries["b.txt"]
+	if b.Offset != 5 || b.Size != 6 {
+		t.Errorf("b.txt entry = %+v, want offset 5 size 6", b)
+	}
+	if got := packRange(&b, 2, 3); got != "bytes=7-9" {
+		t.Errorf("packRange() = %q, want bytes=7-9", got)
+	}
+}
+
+func TestPackWriter_Closed(t *testing.T) {
+	pw := (&FileSystem{packs: newPackTable()}).NewPackWriter("p")
+	pw.closed = true
+
+	if err := pw.Add("a", nil); err != ErrPackClosed {
+		t.Errorf("Add() after Close error = %v, want ErrPackClosed", err)
+	}
+	if err := pw.Close(); err != ErrPackClosed {
+		t.Errorf("Close() twice error = %v, want ErrPackClosed", err)
+	}
+}
+
+func TestPackTable(t *testing.T) {
+	var idx packIndex
+	if err := json.Unmarshal([]byte(`{"entries":{"a.txt":{"offset":0,"size":3}}}`), &idx); err != nil {
+		t.Fatal(err)
+	}
+
+	table := newPackTable()
+	table.add("p1", &idx)
+
+	e, ok := table.lookup("a.txt")
+	if !ok || e.Pack != "p1" || e.Size != 3 {
+		t.Errorf("lookup() = %+v, %v", e, ok)
+	}
+
+	table.remove("p1")
+	if _, ok := table.lookup("a.txt"); ok {
+		t.Errorf("lookup() after remove should fail")
+	}
+
+	var nilTable *packTable
+	if _, ok := nilTable.lookup("a.txt"); ok {
+		t.Errorf("lookup() on nil table should fail")
+	}
+}
@@ -0,0 +1,199 @@
+package s3fs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/absfs/absfs"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// ModeMetadataKey is the user metadata key that holds the permission
+	// bits set with Chmod, in octal as by chmod(1), for example "0640".
+	ModeMetadataKey = "s3fs-mode"
+
+	// UIDMetadataKey and GIDMetadataKey are the user metadata keys that hold
+	// the numeric owner and group set with Chown.
+	UIDMetadataKey = "s3fs-uid"
+	GIDMetadataKey = "s3fs-gid"
+)
+
+// Chmod sets the permission bits of the named file or directory when
+// Config.POSIXMetadata is enabled, and otherwise returns ErrNotImplemented.
+// The mode is stored in the object metadata under ModeMetadataKey and
+// reported by the Mode of Stat results. Setting it copies the object onto
+// itself, which updates its modification time; a directory without a marker
+// object gets one. Writing the file again discards the mode.
+func (fs *FileSystem) Chmod(name string, mode os.FileMode) error {
+	if !fs.posixMetadata {
+		return absfs.ErrNotImplemented
+	}
+	return fs.setPOSIXMetadata("Chmod", name, map[string]string{
+		ModeMetadataKey: formatMode(mode),
+	})
+}
+
+// Chown sets the numeric owner and group of the named file or directory when
+// Config.POSIXMetadata is enabled, and otherwise returns ErrNotImplemented.
+// Like os.Chown, an id of -1 leaves it unchanged. The ids are stored in the
+// object metadata like the mode of Chmod and reported by ObjectInfo.Owner.
+func (fs *FileSystem) Chown(name string, uid, gid int) error {
+	if !fs.posixMetadata {
+		return absfs.ErrNotImplemented
+	}
+	updates := make(map[string]string, 2)
+	if uid >= 0 {
+		updates[UIDMetadataKey] = strconv.Itoa(uid)
+	}
+	if gid >= 0 {
+		updates[GIDMetadataKey] = strconv.Itoa(gid)
+	}
+	if len(updates) == 0 {
+		_, err := fs.Stat(name)
+		return err
+	}
+	return fs.setPOSIXMetadata("Chown", name, updates)
+}
+
+// setPOSIXMetadata merges updates into the metadata of the object, or the
+// directory marker, stored for name.
+func (fs *FileSystem) setPOSIXMetadata(op, name string, updates map[string]string) error {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil && httpStatus(err) == 404 && !strings.HasSuffix(key, "/") {
+		key += "/"
+		head, err = fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(fs.bucket),
+			Key:    aws.String(key),
+		})
+	}
+	defer fs.stats.invalidate(strings.TrimSuffix(key, "/"))
+	defer fs.stats.invalidate(key)
+
+	if err != nil {
+		if httpStatus(err) != 404 {
+			return fs.wrapError(op, name, err)
+		}
+		return fs.chmodImplicitDir(op, name, key, updates)
+	}
+
+	metadata := make(map[string]string, len(head.Metadata)+len(updates))
+	for k, v := range head.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range updates {
+		metadata[k] = v
+	}
+	if err := fs.copyKey(fs.ctx, key, key, head, metadata, nil); err != nil {
+		return fs.wrapError(op, name, err)
+	}
+	return nil
+}
+
+// chmodImplicitDir creates a marker object carrying metadata for a directory
+// that exists only as a prefix of other keys.
+func (fs *FileSystem) chmodImplicitDir(op, name, key string, updates map[string]string) error {
+	isDir, err := fs.isDirectory(strings.TrimSuffix(key, "/"))
+	if err != nil {
+		return fs.wrapError(op, name, err)
+	}
+	if !isDir {
+		return fs.wrapError(op, name, ErrNotExist)
+	}
+
+	dirName := strings.TrimSuffix(name, "/") + "/"
+	metadata, err := fs.nameMetadata(dirName)
+	if err != nil {
+		return fs.wrapError(op, name, err)
+	}
+	if metadata == nil {
+		metadata = make(map[string]string, len(updates))
+	}
+	for k, v := range updates {
+		metadata[k] = v
+	}
+	if _, err := fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:   aws.String(fs.bucket),
+		Key:      aws.String(key),
+		Body:     strings.NewReader(""),
+		Metadata: metadata,
+	}); err != nil {
+		return fs.wrapError(op, name, err)
+	}
+	return nil
+}
+
+// statDirMarker returns the attributes of the marker object of the directory
+// stored under the prefix dir, or nil if it has none. Only directories with
+// POSIX metadata need them, so it asks S3 only if Config.POSIXMetadata is
+// enabled.
+func (fs *FileSystem) statDirMarker(dir string) *ObjectInfo {
+	if !fs.posixMetadata {
+		return nil
+	}
+	output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(dir),
+	})
+	if err != nil {
+		return nil
+	}
+	return headObjectInfo(dir, output)
+}
+
+// formatMode returns the permission bits of mode in the octal notation of
+// chmod(1), including the setuid, setgid and sticky bits.
+func formatMode(mode os.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return "0" + strconv.FormatUint(uint64(bits), 8)
+}
+
+// parseMode parses the permission bits written by formatMode.
+func parseMode(s string) (os.FileMode, bool) {
+	bits, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || bits > 07777 {
+		return 0, false
+	}
+	mode := os.FileMode(bits & 0777)
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, true
+}
+
+// Owner returns the numeric owner and group stored by Chown in the metadata
+// of the object, -1 for an id that was never set. ok is false if neither
+// was set.
+func (o *ObjectInfo) Owner() (uid, gid int, ok bool) {
+	uid, gid = -1, -1
+	if id, err := strconv.Atoi(o.Metadata[UIDMetadataKey]); err == nil {
+		uid = id
+	}
+	if id, err := strconv.Atoi(o.Metadata[GIDMetadataKey]); err == nil {
+		gid = id
+	}
+	return uid, gid, uid >= 0 || gid >= 0
+}
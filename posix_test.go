@@ -0,0 +1,58 @@
+package s3fs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_POSIXMetadata(t *testing.T) {
+	if err := s3fstest.New("bucket").Chmod("a", 0600); err == nil {
+		t.Errorf("Chmod() without POSIXMetadata succeeded")
+	}
+
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: s3fstest.NewClient(), POSIXMetadata: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "a.txt", "data")
+	writeFile(t, fs, "implicit/b.txt", "b")
+
+	if err := fs.Chmod("a.txt", 0600|os.ModeSetuid); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	if err := fs.Chown("a.txt", 1000, 100); err != nil {
+		t.Fatalf("Chown() error = %v", err)
+	}
+	if err := fs.Chown("a.txt", -1, 200); err != nil {
+		t.Fatalf("Chown() error = %v", err)
+	}
+	info, err := fs.Stat("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != 0600|os.ModeSetuid {
+		t.Errorf("Mode() = %v, want %v", info.Mode(), 0600|os.ModeSetuid)
+	}
+	if uid, gid, ok := info.Sys().(*s3fs.ObjectInfo).Owner(); !ok || uid != 1000 || gid != 200 {
+		t.Errorf("Owner() = %d, %d, %v; want 1000, 200, true", uid, gid, ok)
+	}
+	if data, err := fs.ReadFile("a.txt"); err != nil || string(data) != "data" {
+		t.Errorf("ReadFile() = %q, %v after Chmod", data, err)
+	}
+
+	// A directory without a marker gets one
+	if err := fs.Chmod("implicit", 0700); err != nil {
+		t.Fatalf("Chmod(dir) error = %v", err)
+	}
+	if info, err := fs.Stat("implicit"); err != nil || info.Mode() != os.ModeDir|0700 {
+		t.Errorf("Stat(dir) = %v, %v; want mode %v", info, err, os.ModeDir|0700)
+	}
+
+	if err := fs.Chmod("missing", 0600); !errors.Is(err, s3fs.ErrNotExist) {
+		t.Errorf("Chmod(missing) error = %v, want ErrNotExist", err)
+	}
+}
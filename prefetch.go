@@ -0,0 +1,144 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// prefetcher is the body of a read mode file with Config.Prefetch set. It
+// fetches the object in ranges of Config.DownloadPartSize bytes, keeping up
+// to Config.Prefetch ranges after the current one in flight. All ranges
+// after the first are pinned to the ETag of the first response.
+type prefetcher struct {
+	fs     *FileSystem
+	ctx    context.Context
+	cancel context.CancelFunc
+	input  s3.GetObjectInput // Request template, the Range is set per request
+	chunk  int64
+	depth  int
+	size   int64 // Object size
+	next   int64 // Offset of the next range to request
+
+	pending []chan prefetchResult // Requested ranges in order
+	cur     []byte
+}
+
+type prefetchResult struct {
+	data []byte
+	err  error
+}
+
+// prefetchBody fetches the range at the current offset and starts fetching
+// the following ones in the background.
+func (f *File) prefetchBody(input *s3.GetObjectInput) (io.ReadCloser, error) {
+	chunk := f.fs.downloadPartSize
+	if chunk <= 0 {
+		chunk = DefaultPartSize
+	}
+
+	ctx, cancel := context.WithCancel(f.fs.ctx)
+	p := &prefetcher{
+		fs:     f.fs,
+		ctx:    ctx,
+		cancel: cancel,
+		input:  *input,
+		chunk:  chunk,
+		depth:  f.fs.prefetch,
+		next:   f.offset,
+	}
+
+	in := p.input
+	in.Range = aws.String(fmt.Sprintf("bytes=%d-%d", p.next, p.next+chunk-1))
+	output, err := f.fs.client.GetObject(ctx, &in)
+	if err != nil {
+		cancel()
+		return nil, preconditionError(err)
+	}
+	size, ok := contentRangeSize(aws.ToString(output.ContentRange))
+	if !ok {
+		// Not a ranged response, so the body is the whole object
+		cancel()
+		f.bodyETag = aws.ToString(output.ETag)
+		return output.Body, nil
+	}
+	f.bodyETag = aws.ToString(output.ETag)
+	p.size = size
+	p.input.IfMatch = output.ETag
+	p.next += chunk
+	p.fill()
+
+	p.cur, err = io.ReadAll(output.Body)
+	output.Body.Close()
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// fill requests ranges until depth ranges are in flight or the end of the
+// object is reached.
+func (p *prefetcher) fill() {
+	for len(p.pending) < p.depth && p.next < p.size {
+		ch := make(chan prefetchResult, 1)
+		go p.fetch(p.next, ch)
+		p.pending = append(p.pending, ch)
+		p.next += p.chunk
+	}
+}
+
+func (p *prefetcher) fetch(off int64, ch chan<- prefetchResult) {
+	in := p.input
+	in.Range = aws.String(fmt.Sprintf("bytes=%d-%d", off, off+p.chunk-1))
+	output, err := p.fs.client.GetObject(p.ctx, &in)
+	if err != nil {
+		ch <- prefetchResult{err: preconditionError(err)}
+		return
+	}
+	defer output.Body.Close()
+	data, err := io.ReadAll(output.Body)
+	ch <- prefetchResult{data: data, err: err}
+}
+
+func (p *prefetcher) Read(b []byte) (int, error) {
+	for len(p.cur) == 0 {
+		if len(p.pending) == 0 {
+			return 0, io.EOF
+		}
+		r := <-p.pending[0]
+		p.pending = p.pending[1:]
+		if r.err != nil {
+			return 0, r.err
+		}
+		p.cur = r.data
+		p.fill()
+	}
+	n := copy(b, p.cur)
+	p.cur = p.cur[n:]
+	return n, nil
+}
+
+// Close cancels the ranges in flight.
+func (p *prefetcher) Close() error {
+	p.cancel()
+	p.pending = nil
+	p.cur = nil
+	return nil
+}
+
+// contentRangeSize returns the complete length of a "bytes first-last/size"
+// Content-Range header.
+func contentRangeSize(header string) (int64, bool) {
+	_, size, ok := strings.Cut(header, "/")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,69 @@
+package s3fs_test
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// rangeClient records the ranges of GetObject requests.
+type rangeClient struct {
+	*s3fstest.Client
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (c *rangeClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.mu.Lock()
+	c.ranges = append(c.ranges, aws.ToString(params.Range))
+	c.mu.Unlock()
+	return c.Client.GetObject(ctx, params, optFns...)
+}
+
+func TestFileSystem_Prefetch(t *testing.T) {
+	client := &rangeClient{Client: s3fstest.NewClient()}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, Prefetch: 2, DownloadPartSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "0123456789abcdefghij"
+	writeFile(t, fs, "file.txt", content)
+
+	f, err := fs.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != content {
+		t.Fatalf("ReadAll() = %q, %v; want %q", data, err, content)
+	}
+	sort.Strings(client.ranges)
+	want := []string{"bytes=0-3", "bytes=12-15", "bytes=16-19", "bytes=4-7", "bytes=8-11"}
+	if strings.Join(client.ranges, ",") != strings.Join(want, ",") {
+		t.Errorf("GetObject ranges = %q, want %q", client.ranges, want)
+	}
+
+	// Seeking starts prefetching at the new offset
+	if _, err := f.Seek(14, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err = io.ReadAll(f)
+	if err != nil || string(data) != content[14:] {
+		t.Errorf("ReadAll() after Seek(14) = %q, %v; want %q", data, err, content[14:])
+	}
+	if _, err := f.Seek(100, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := f.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Read() after the end = %d, %v; want EOF", n, err)
+	}
+}
@@ -0,0 +1,47 @@
+package s3fs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// PresignGet returns a URL that allows an unauthenticated client to download
+// the named object with a plain HTTP GET until expiry has elapsed.
+func (fs *FileSystem) PresignGet(name string, expiry time.Duration) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+
+	presigner, err := fs.presignClient()
+	if err != nil {
+		return "", fs.wrapError("PresignGet", name, err)
+	}
+	req, err := presigner.PresignGetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.objectKey(name)),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fs.wrapError("PresignGet", name, err)
+	}
+	return req.URL, nil
+}
+
+// PresignPut returns a URL that allows an unauthenticated client to upload
+// the named object with a plain HTTP PUT until expiry has elapsed.
+func (fs *FileSystem) PresignPut(name string, expiry time.Duration) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+
+	presigner, err := fs.presignClient()
+	if err != nil {
+		return "", fs.wrapError("PresignPut", name, err)
+	}
+	req, err := presigner.PresignPutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.objectKey(name)),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fs.wrapError("PresignPut", name, err)
+	}
+	return req.URL, nil
+}
@@ -0,0 +1,80 @@
+package s3fs
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Prime lists every object below the directory prefix and stores the
+// results in the stat cache, and the directories seen in the directory
+// cache, so that a following burst of Stat, Exists and MkdirAll calls below
+// prefix is answered without a request per file. It returns the number of
+// objects cached. Priming needs Config.StatCacheTTL; without it Prime does
+// nothing and returns 0.
+func (fs *FileSystem) Prime(prefix string) (int, error) {
+	if fs.stats == nil || fs.stats.ttl <= 0 {
+		return 0, nil
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	listPrefix := fs.objectKey("")
+	if prefix != "" {
+		listPrefix = fs.objectKey(prefix + "/")
+	}
+
+	primed := 0
+	var continuationToken *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(listPrefix),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return primed, fs.wrapError("Prime", prefix, err)
+		}
+
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			if fs.isSystemKey(key) {
+				continue
+			}
+			name, err := fs.logicalName(key)
+			if err != nil {
+				return primed, fs.wrapError("Prime", key, err)
+			}
+
+			fs.stats.put(key, &fileInfo{
+				name:    path.Base(name),
+				size:    aws.ToInt64(obj.Size),
+				modTime: aws.ToTime(obj.LastModified),
+				isDir:   strings.HasSuffix(key, "/"),
+				obj:     listObjectInfo(obj),
+			})
+			primeDirs(fs.dirs, key)
+			primed++
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+
+	return primed, nil
+}
+
+// primeDirs records every directory above key in the directory cache.
+func primeDirs(dirs *statCache, key string) {
+	for i := strings.Index(key, "/"); i >= 0; {
+		dirs.put(key[:i+1], nil)
+		next := strings.Index(key[i+1:], "/")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+}
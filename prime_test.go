@@ -0,0 +1,29 @@
+package s3fs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrime_Disabled(t *testing.T) {
+	// Without a stat cache Prime returns before using the nil client.
+	fs := &FileSystem{}
+	if n, err := fs.Prime("templates"); n != 0 || err != nil {
+		t.Errorf("Prime() = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestPrimeDirs(t *testing.T) {
+	dirs := newStatCache(time.Minute, 0)
+	primeDirs(dirs, "a/b/c/file.txt")
+	primeDirs(dirs, "top.txt")
+
+	for _, dir := range []string{"a/", "a/b/", "a/b/c/"} {
+		if _, ok := dirs.get(dir); !ok {
+			t.Errorf("directory %q not cached", dir)
+		}
+	}
+	if dirs.len() != 3 {
+		t.Errorf("len() = %d, want 3", dirs.len())
+	}
+}
@@ -0,0 +1,120 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// bucketHeader is implemented by *s3.Client and the s3fstest fake.
+type bucketHeader interface {
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+}
+
+// probe checks that the bucket exists and can be listed and, if write is
+// set, that an object can be written and deleted, for Config.Probe.
+func (fs *FileSystem) probe(write bool) error {
+	if c, ok := fs.baseClient().(bucketHeader); ok {
+		if _, err := c.HeadBucket(fs.ctx, &s3.HeadBucketInput{Bucket: aws.String(fs.bucket)}); err != nil {
+			return fs.probeError("HeadBucket", err)
+		}
+	}
+	_, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(fs.bucket),
+		Prefix:  aws.String(fs.objectKey("")),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return fs.probeError("ListObjectsV2", err)
+	}
+	if !write {
+		return nil
+	}
+
+	// A temp key under the system prefix, so it stays within the filesystem
+	// and CleanupTempKeys removes it if the delete fails
+	key := fs.objectKey(NewTempKey(fs.reservedPrefix()))
+	_, err = fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(fs.bucket),
+		Key:           aws.String(key),
+		Body:          strings.NewReader(""),
+		ContentLength: aws.Int64(0),
+	})
+	if err != nil {
+		return fs.probeError("PutObject", err)
+	}
+	_, err = fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fs.probeError("DeleteObject", err)
+	}
+	return nil
+}
+
+// probeError returns the *ProbeError of the failed request op.
+func (fs *FileSystem) probeError(op string, err error) error {
+	return &ProbeError{Bucket: fs.bucket, Op: op, Hint: probeHint(op, err), Err: err}
+}
+
+// probeHint returns the likely cause of a failed probe request, or "".
+func probeHint(op string, err error) string {
+	var permission string
+	switch op {
+	case "PutObject":
+		permission = "s3:PutObject"
+	case "DeleteObject":
+		permission = "s3:DeleteObject"
+	default:
+		permission = "s3:ListBucket"
+	}
+
+	switch code := errorCode(err); {
+	case code == "NoSuchBucket" || httpStatus(err) == http.StatusNotFound:
+		return "the bucket does not exist"
+	case code == "PermanentRedirect" || code == "AuthorizationHeaderMalformed" || httpStatus(err) == http.StatusMovedPermanently:
+		return "the bucket is in another region; set Region or DetectRegion"
+	case code == "InvalidAccessKeyId" || code == "SignatureDoesNotMatch" || code == "ExpiredToken":
+		return "the credentials are invalid or expired"
+	case httpStatus(err) == http.StatusForbidden:
+		return "access denied; check the credentials and the " + permission + " permission"
+	case errors.Is(err, context.DeadlineExceeded) || httpStatus(err) == 0:
+		return "the endpoint cannot be reached; check Endpoint, the network and proxy settings"
+	}
+	return ""
+}
+
+// ProbeError is returned by New when the checks of Config.Probe or
+// Config.ProbeWrite fail. It matches ErrProbeFailed.
+type ProbeError struct {
+	Bucket string // Probed bucket
+	Op     string // Failed request, such as "HeadBucket" or "PutObject"
+	Hint   string // Likely cause, if known
+	Err    error  // Error of the request
+}
+
+// Error implements the error interface.
+func (e *ProbeError) Error() string {
+	if e.Hint == "" {
+		return fmt.Sprintf("s3fs: probing bucket %s: %s: %v", e.Bucket, e.Op, e.Err)
+	}
+	return fmt.Sprintf("s3fs: probing bucket %s: %s: %s: %v", e.Bucket, e.Op, e.Hint, e.Err)
+}
+
+// Unwrap returns the error of the request.
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrProbeFailed or, like for an S3Error,
+// fs.ErrNotExist for a missing bucket or fs.ErrPermission for a denied
+// request.
+func (e *ProbeError) Is(target error) bool {
+	return target == ErrProbeFailed || (&S3Error{Op: e.Op, Err: e.Err}).Is(target)
+}
@@ -0,0 +1,85 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// probeClient fails HeadBucket or PutObject with the given errors and
+// records the keys written and the prefixes listed.
+type probeClient struct {
+	*s3fstest.Client
+	head, put error
+	puts      []string
+	lists     []string
+}
+
+func (c *probeClient) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if c.head != nil {
+		return nil, c.head
+	}
+	return c.Client.HeadBucket(ctx, params, optFns...)
+}
+
+func (c *probeClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if c.put != nil {
+		return nil, c.put
+	}
+	c.puts = append(c.puts, aws.ToString(params.Key))
+	return c.Client.PutObject(ctx, params, optFns...)
+}
+
+func (c *probeClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.lists = append(c.lists, aws.ToString(params.Prefix))
+	return c.Client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func TestNew_Probe(t *testing.T) {
+	client := s3fstest.NewClient()
+	if _, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, ProbeWrite: true}); err != nil {
+		t.Fatalf("New() with ProbeWrite error = %v", err)
+	}
+	if keys := client.Keys("bucket"); len(keys) != 0 {
+		t.Errorf("ProbeWrite left keys %v", keys)
+	}
+
+	denied := &s3fstest.Error{StatusCode: 403, Code: "AccessDenied", Message: "Access Denied"}
+	c := &probeClient{Client: client, put: denied}
+	if _, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: c, Probe: true}); err != nil {
+		t.Errorf("New() with Probe error = %v, want no write test", err)
+	}
+	_, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: c, ProbeWrite: true})
+	var pe *s3fs.ProbeError
+	if !errors.As(err, &pe) || !errors.Is(err, s3fs.ErrProbeFailed) || !errors.Is(err, denied) {
+		t.Fatalf("New() with a denied write error = %v, want a *ProbeError", err)
+	}
+	if pe.Op != "PutObject" || !strings.Contains(pe.Hint, "s3:PutObject") {
+		t.Errorf("ProbeError = %+v, want a PutObject permission hint", pe)
+	}
+
+	c = &probeClient{Client: client, head: &s3fstest.Error{StatusCode: 404, Code: "NotFound", Message: "Not Found"}}
+	_, err = s3fs.New(&s3fs.Config{Bucket: "missing", Client: c, Probe: true})
+	if !errors.As(err, &pe) || pe.Op != "HeadBucket" || !strings.Contains(err.Error(), "bucket does not exist") {
+		t.Errorf("New() of a missing bucket error = %v", err)
+	}
+}
+
+func TestNew_ProbeWriteKey(t *testing.T) {
+	c := &probeClient{Client: s3fstest.NewClient()}
+	if _, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: c, ProbeWrite: true, SystemPrefix: "sys"}); err != nil {
+		t.Fatalf("New() with ProbeWrite error = %v", err)
+	}
+	if len(c.puts) != 1 || !strings.HasPrefix(c.puts[0], "sys/"+s3fs.TempKeyMarker) {
+		t.Errorf("ProbeWrite wrote %v, want one temp key below the system prefix", c.puts)
+	}
+	if keys := c.Keys("bucket"); len(keys) != 0 {
+		t.Errorf("ProbeWrite left keys %v", keys)
+	}
+}
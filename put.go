@@ -0,0 +1,110 @@
+package s3fs
+
+import (
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// PutOptions sets attributes of the objects written by PutReader and
+// NewWriter.
+type PutOptions struct {
+	ContentType  string            // MIME type of the content
+	Metadata     map[string]string // User metadata, without the x-amz-meta- prefix
+	Tags         map[string]string // Object tags
+	StorageClass string            // Storage class, such as "STANDARD_IA"
+
+	// PartSize is the part size of multipart uploads (default
+	// DefaultPartSize, at least MinPartSize). Each part is buffered in
+	// memory while it is uploaded.
+	PartSize int64
+}
+
+// PutReader writes the content of r to the named file without buffering it
+// in a File. If size is known (not negative) and at most the part size, r is
+// streamed into a single PutObject request; otherwise it is uploaded with a
+// multipart upload, one part at a time, and the upload is aborted if r or a
+// part fails. opts may be nil.
+func (fs *FileSystem) PutReader(name string, r io.Reader, size int64, opts *PutOptions) error {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+	defer fs.stats.invalidate(key)
+
+	partSize := int64(DefaultPartSize)
+	if opts != nil && opts.PartSize > 0 {
+		partSize = max(opts.PartSize, MinPartSize)
+	}
+
+	if size >= 0 && size <= partSize {
+		input, err := fs.putInput(name, opts)
+		if err != nil {
+			return fs.wrapError("PutReader", name, err)
+		}
+		input.Body = io.LimitReader(r, size)
+		input.ContentLength = aws.Int64(size)
+		if _, err := fs.client.PutObject(fs.ctx, input); err != nil {
+			return fs.wrapError("PutReader", name, err)
+		}
+		fs.packs.forget(name)
+		return fs.manifestPut(key, size, false)
+	}
+
+	mu, err := fs.newMultipartUpload(name, opts)
+	if err != nil {
+		return fs.wrapError("PutReader", name, err)
+	}
+	if size >= 0 {
+		r = io.LimitReader(r, size)
+	}
+	n, err := mu.ReadFrom(r)
+	if err == nil && size >= 0 && n != size {
+		err = fs.wrapError("PutReader", name, io.ErrUnexpectedEOF)
+	}
+	if err == nil {
+		err = mu.Complete()
+	}
+	if err != nil {
+		mu.Abort()
+		return err
+	}
+	fs.packs.forget(name)
+	return fs.manifestPut(key, n, false)
+}
+
+// putInput returns a PutObjectInput for the named file with the attributes
+// of opts, which may be nil.
+func (fs *FileSystem) putInput(name string, opts *PutOptions) (*s3.PutObjectInput, error) {
+	metadata, err := fs.nameMetadata(name)
+	if err != nil {
+		return nil, err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(fs.bucket),
+		Key:      aws.String(fs.objectKey(name)),
+		Metadata: metadata,
+	}
+	if opts == nil {
+		return input, nil
+	}
+
+	if len(opts.Metadata) > 0 {
+		input.Metadata = make(map[string]string, len(opts.Metadata)+len(metadata))
+		for k, v := range opts.Metadata {
+			input.Metadata[k] = v
+		}
+		for k, v := range metadata {
+			input.Metadata[k] = v
+		}
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.Tags != nil {
+		input.Tagging = aws.String(encodeTags(opts.Tags))
+	}
+	input.StorageClass = types.StorageClass(opts.StorageClass)
+	return input, nil
+}
@@ -0,0 +1,78 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestFileSystem_PutReader(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &s3fs.PutOptions{
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"owner": "alice"},
+		Tags:        map[string]string{"project": "x"},
+		PartSize:    s3fs.MinPartSize,
+	}
+
+	large := strings.Repeat("0123456789", s3fs.MinPartSize/10+100)
+	tests := []struct {
+		name    string
+		content string
+		size    int64
+	}{
+		{"small.txt", "hello", 5},
+		{"unknown.txt", "hello", -1},
+		{"large.txt", large, int64(len(large))},
+		{"large-unknown.txt", large, -1},
+	}
+	for _, tt := range tests {
+		r := iotest.OneByteReader(strings.NewReader(tt.content))
+		if err := fs.PutReader(tt.name, r, tt.size, opts); err != nil {
+			t.Fatalf("PutReader(%s) error = %v", tt.name, err)
+		}
+		data, err := fs.ReadFile(tt.name)
+		if err != nil || string(data) != tt.content {
+			t.Errorf("ReadFile(%s) = %d bytes, %v; want %d bytes", tt.name, len(data), err, len(tt.content))
+		}
+		info, err := fs.StatExtended(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.ContentType != "text/plain" || info.Metadata["owner"] != "alice" {
+			t.Errorf("%s content type %q, metadata %v", tt.name, info.ContentType, info.Metadata)
+		}
+		tags, err := client.GetObjectTagging(context.Background(), &s3.GetObjectTaggingInput{Bucket: aws.String("bucket"), Key: aws.String(tt.name)})
+		if err != nil || len(tags.TagSet) != 1 || aws.ToString(tags.TagSet[0].Value) != "x" {
+			t.Errorf("%s tags = %v, %v", tt.name, tags, err)
+		}
+	}
+
+	// Failed uploads are aborted
+	r := io.MultiReader(strings.NewReader(large), iotest.ErrReader(errors.New("broken")))
+	if err := fs.PutReader("broken.txt", r, -1, opts); err == nil {
+		t.Error("PutReader() of a failing reader succeeded")
+	}
+	if err := fs.PutReader("short.txt", strings.NewReader(large), int64(len(large))+1, opts); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("PutReader() of a short reader error = %v, want ErrUnexpectedEOF", err)
+	}
+	uploads, err := client.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{Bucket: aws.String("bucket")})
+	if err != nil || len(uploads.Uploads) != 0 {
+		t.Errorf("ListMultipartUploads() = %d uploads, %v; want none", len(uploads.Uploads), err)
+	}
+	if ok, _ := fs.Exists("broken.txt"); ok {
+		t.Error("broken.txt exists after a failed PutReader")
+	}
+}
@@ -0,0 +1,244 @@
+package s3fs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DefaultReadAtBlockSize is the block size of the ReadAt cache when
+// Config.ReadAtBlockSize is not set.
+const DefaultReadAtBlockSize = 1024 * 1024
+
+// DefaultReaderAtBlocks is the number of blocks cached by a ReaderAt when
+// Config.ReadAtCacheBlocks is not set.
+const DefaultReaderAtBlocks = 16
+
+// ReaderAt reads an object at arbitrary offsets through a block cache. It
+// implements io.ReaderAt, io.ReadSeekCloser and the Size method expected by
+// archive readers, so it can be passed to zip.NewReader(r, r.Size()).
+// ReadAt may be called concurrently.
+type ReaderAt struct {
+	f    *File
+	size int64
+}
+
+// OpenReaderAt opens the named file for random access reads. The size is
+// determined when opening, and all reads are pinned to the version of the
+// object seen then where it is known, so reads of an object that was
+// replaced fail instead of mixing content. Blocks are cached as configured
+// by Config.ReadAtCacheBlocks and ReadAtBlockSize, with at least
+// DefaultReaderAtBlocks blocks.
+func (fs *FileSystem) OpenReaderAt(name string) (*ReaderAt, error) {
+	file, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	f := file.(*File)
+	if f.stat == nil {
+		// Skipped by Config.LazyOpen
+		if f.stat, err = fs.Stat(f.name); err != nil {
+			return nil, err
+		}
+	}
+	if f.stat.IsDir() {
+		return nil, fs.wrapError("OpenReaderAt", f.name, syscall.EISDIR)
+	}
+
+	size := f.stat.Size()
+	f.blocks = newBlockCache(fs.readAtBlockSize, max(fs.readAtCacheBlocks, DefaultReaderAtBlocks))
+	f.blocks.size = size
+	if obj, ok := f.stat.Sys().(*ObjectInfo); ok {
+		f.blocks.etag = obj.ETag
+	}
+	return &ReaderAt{f: f, size: size}, nil
+}
+
+// ReadAt reads len(b) bytes at off, like io.ReaderAt.
+func (r *ReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	return r.f.ReadAt(b, off)
+}
+
+// Read reads sequentially from the current offset.
+func (r *ReaderAt) Read(b []byte) (int, error) {
+	return r.f.Read(b)
+}
+
+// Seek sets the offset for the next Read. It does not affect ReadAt.
+func (r *ReaderAt) Seek(offset int64, whence int) (int64, error) {
+	return r.f.Seek(offset, whence)
+}
+
+// Size returns the size of the object when it was opened.
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+// Name returns the name of the file.
+func (r *ReaderAt) Name() string {
+	return r.f.name
+}
+
+// Close closes the response stream of Read, if any.
+func (r *ReaderAt) Close() error {
+	return r.f.Close()
+}
+
+// blockCache keeps the most recently used aligned blocks of an object for
+// ReadAt. Missing blocks needed by a call are fetched with a single Range
+// request, pinned to the ETag of the first response.
+type blockCache struct {
+	blockSize int64
+	max       int
+
+	mu     sync.Mutex
+	blocks map[int64][]byte // Block data by block index
+	order  []int64          // Block indexes, least recently used first
+	etag   string
+	size   int64 // Object size, -1 until the first response
+}
+
+func newBlockCache(blockSize int64, max int) *blockCache {
+	if blockSize <= 0 {
+		blockSize = DefaultReadAtBlockSize
+	}
+	return &blockCache{blockSize: blockSize, max: max, blocks: make(map[int64][]byte), size: -1}
+}
+
+// readAt implements ReadAt of f from the cached blocks.
+func (c *blockCache) readAt(f *File, b []byte, off int64) (int, error) {
+	end := off + int64(len(b))
+	c.mu.Lock()
+	size := c.size
+	c.mu.Unlock()
+	if size >= 0 {
+		if off >= size {
+			return 0, io.EOF
+		}
+		if end > size {
+			end = size
+		}
+	}
+	if end <= off {
+		return 0, nil
+	}
+
+	first, last := off/c.blockSize, (end-1)/c.blockSize
+	blocks, err := c.load(f, first, last)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for i, block := range blocks {
+		start := off + int64(n) - (first+int64(i))*c.blockSize
+		if start >= int64(len(block)) {
+			break
+		}
+		n += copy(b[n:], block[start:])
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// load returns the blocks first to last, fetching the missing ones. Blocks
+// past the end of the object are nil.
+func (c *blockCache) load(f *File, first, last int64) ([][]byte, error) {
+	blocks := make([][]byte, last-first+1)
+	missFirst, missLast := int64(-1), int64(-1)
+	c.mu.Lock()
+	for i := first; i <= last; i++ {
+		if block, ok := c.get(i); ok {
+			blocks[i-first] = block
+			continue
+		}
+		if missFirst < 0 {
+			missFirst = i
+		}
+		missLast = i
+	}
+	etag := c.etag
+	c.mu.Unlock()
+	if missFirst < 0 {
+		return blocks, nil
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(f.fs.bucket),
+		Key:    aws.String(f.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", missFirst*c.blockSize, (missLast+1)*c.blockSize-1)),
+	}
+	if f.version != "" {
+		input.VersionId = aws.String(f.version)
+	}
+	if etag != "" {
+		input.IfMatch = aws.String(etag)
+	}
+	output, err := f.fs.client.GetObject(f.fs.ctx, input)
+	if err != nil {
+		if httpStatus(err) == 416 {
+			return blocks[:missFirst-first], nil
+		}
+		return nil, preconditionError(err)
+	}
+	defer output.Body.Close()
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.etag = aws.ToString(output.ETag)
+	if size, ok := contentRangeSize(aws.ToString(output.ContentRange)); ok {
+		c.size = size
+	}
+	for i := missFirst; i <= missLast && len(data) > 0; i++ {
+		block := data[:min(c.blockSize, int64(len(data)))]
+		data = data[len(block):]
+		blocks[i-first] = block
+		c.put(i, block)
+	}
+	return blocks, nil
+}
+
+// get returns a cached block and marks it as most recently used.
+func (c *blockCache) get(i int64) ([]byte, bool) {
+	block, ok := c.blocks[i]
+	if ok {
+		c.touch(i)
+	}
+	return block, ok
+}
+
+// put caches a block, evicting the least recently used one if the cache is
+// full.
+func (c *blockCache) put(i int64, block []byte) {
+	if _, ok := c.blocks[i]; ok {
+		c.blocks[i] = block
+		c.touch(i)
+		return
+	}
+	c.blocks[i] = block
+	c.order = append(c.order, i)
+	if len(c.order) > c.max {
+		delete(c.blocks, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+func (c *blockCache) touch(i int64) {
+	for j, k := range c.order {
+		if k == i {
+			c.order = append(append(c.order[:j:j], c.order[j+1:]...), i)
+			return
+		}
+	}
+}
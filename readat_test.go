@@ -0,0 +1,123 @@
+package s3fs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_ReadAtCache(t *testing.T) {
+	client := &rangeClient{Client: s3fstest.NewClient()}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, ReadAtCacheBlocks: 2, ReadAtBlockSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "0123456789abcdefghij"
+	writeFile(t, fs, "file.txt", content)
+
+	f, err := fs.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		off    int64
+		n      int
+		want   string
+		eof    bool
+		ranges []string
+	}{
+		{0, 2, "01", false, []string{"bytes=0-3"}},
+		{2, 2, "23", false, nil},
+		{3, 6, "345678", false, []string{"bytes=4-11"}},
+		{6, 2, "67", false, nil},
+		{15, 10, "fghij", true, []string{"bytes=12-19"}},
+		{0, 1, "0", false, []string{"bytes=0-3"}}, // Evicted
+		{25, 1, "", true, nil},
+	}
+	for _, tt := range tests {
+		client.ranges = nil
+		b := make([]byte, tt.n)
+		n, err := f.ReadAt(b, tt.off)
+		if string(b[:n]) != tt.want || (err == io.EOF) != tt.eof || (err != nil && err != io.EOF) {
+			t.Errorf("ReadAt(%d bytes, %d) = %q, %v; want %q, EOF %v", tt.n, tt.off, b[:n], err, tt.want, tt.eof)
+		}
+		if strings.Join(client.ranges, ",") != strings.Join(tt.ranges, ",") {
+			t.Errorf("ReadAt(%d bytes, %d) requested %q, want %q", tt.n, tt.off, client.ranges, tt.ranges)
+		}
+	}
+}
+
+func TestFileSystem_OpenReaderAt(t *testing.T) {
+	fs := s3fstest.New("bucket")
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"a.txt", "b/c.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("content of " + name))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "archive.zip", buf.String())
+
+	r, err := fs.OpenReaderAt("archive.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Size() != int64(buf.Len()) {
+		t.Errorf("Size() = %d, want %d", r.Size(), buf.Len())
+	}
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(data) != "content of "+zf.Name {
+			t.Errorf("%s = %q, %v", zf.Name, data, err)
+		}
+	}
+
+	if pos, err := r.Seek(-4, io.SeekEnd); err != nil || pos != r.Size()-4 {
+		t.Errorf("Seek(-4, SeekEnd) = %d, %v", pos, err)
+	}
+
+	// Reads are pinned to the object seen when opening
+	r, err = fs.OpenReaderAt("archive.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	writeFile(t, fs, "archive.zip", "replaced")
+	if _, err := r.ReadAt(make([]byte, 4), 0); !errors.Is(err, s3fs.ErrPreconditionFailed) {
+		t.Errorf("ReadAt() of a replaced object error = %v, want ErrPreconditionFailed", err)
+	}
+
+	fs.Mkdir("dir", 0755)
+	if _, err := fs.OpenReaderAt("dir"); !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("OpenReaderAt(dir) error = %v, want EISDIR", err)
+	}
+	if _, err := fs.OpenReaderAt("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenReaderAt(missing) error = %v, want ErrNotExist", err)
+	}
+}
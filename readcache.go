@@ -0,0 +1,131 @@
+package s3fs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// readCache stores downloaded objects in a local directory, keyed by bucket
+// and key, next to the ETag they were downloaded with. A nil *readCache is
+// valid and disables caching.
+type readCache struct {
+	dir string
+}
+
+// newReadCache creates dir if necessary and returns a cache rooted there,
+// or nil if dir is empty.
+func newReadCache(dir string) (*readCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &readCache{dir: dir}, nil
+}
+
+// paths returns the locations of the cached content and ETag of key.
+func (c *readCache) paths(bucket, key string) (data, etag string) {
+	sum := sha256.Sum256([]byte(bucket + "/" + key))
+	base := filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+	return base + ".data", base + ".etag"
+}
+
+// cachedGet returns the content of key, served from the read cache when S3
+// confirms with a conditional GET that the cached ETag is still current.
+// Otherwise the object is downloaded and stored in the cache as it is read.
+func (fs *FileSystem) cachedGet(key string) (io.ReadCloser, error) {
+	dataPath, etagPath := fs.readCache.paths(fs.bucket, key)
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	}
+	if etag, err := os.ReadFile(etagPath); err == nil {
+		input.IfNoneMatch = aws.String(string(etag))
+	}
+
+	output, err := fs.client.GetObject(fs.ctx, input)
+	if err != nil && input.IfNoneMatch != nil && httpStatus(err) == 304 {
+		if f, ferr := os.Open(dataPath); ferr == nil {
+			return f, nil
+		}
+		// The cached content disappeared; fetch it again
+		input.IfNoneMatch = nil
+		output, err = fs.client.GetObject(fs.ctx, input)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Caching is best effort: serve the body directly if the cache is unusable
+	tmp, err := os.CreateTemp(fs.readCache.dir, "tmp-*")
+	if err != nil {
+		return output.Body, nil
+	}
+	return &cachingReader{
+		body:     output.Body,
+		tmp:      tmp,
+		dataPath: dataPath,
+		etagPath: etagPath,
+		etag:     aws.ToString(output.ETag),
+	}, nil
+}
+
+// cachingReader copies an object body into a temporary file while it is read
+// and moves the file into the read cache once the body is fully consumed.
+type cachingReader struct {
+	body     io.ReadCloser
+	tmp      *os.File
+	dataPath string
+	etagPath string
+	etag     string
+}
+
+func (r *cachingReader) Read(b []byte) (int, error) {
+	n, err := r.body.Read(b)
+	if n > 0 && r.tmp != nil {
+		if _, werr := r.tmp.Write(b[:n]); werr != nil {
+			r.discard()
+		}
+	}
+	if err == io.EOF && r.tmp != nil {
+		r.commit()
+	}
+	return n, err
+}
+
+func (r *cachingReader) Close() error {
+	r.discard()
+	return r.body.Close()
+}
+
+// commit moves the downloaded content into place and records its ETag.
+func (r *cachingReader) commit() {
+	tmp := r.tmp.Name()
+	r.tmp.Close()
+	r.tmp = nil
+
+	os.Remove(r.etagPath)
+	if r.etag == "" || os.Rename(tmp, r.dataPath) != nil {
+		os.Remove(tmp)
+		return
+	}
+	os.WriteFile(r.etagPath, []byte(r.etag), 0600)
+}
+
+// discard drops a partially downloaded temporary file.
+func (r *cachingReader) discard() {
+	if r.tmp == nil {
+		return
+	}
+	r.tmp.Close()
+	os.Remove(r.tmp.Name())
+	r.tmp = nil
+}
@@ -0,0 +1,81 @@
+package s3fs
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewReadCache_Disabled(t *testing.T) {
+	c, err := newReadCache("")
+	if c != nil || err != nil {
+		t.Errorf("newReadCache(\"\") = %v, %v, want nil, nil", c, err)
+	}
+}
+
+func TestReadCache_Paths(t *testing.T) {
+	c, err := newReadCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data1, etag1 := c.paths("bucket", "a.txt")
+	data2, _ := c.paths("bucket", "b.txt")
+	data3, _ := c.paths("other", "a.txt")
+	if data1 == data2 || data1 == data3 {
+		t.Errorf("paths() collide: %q %q %q", data1, data2, data3)
+	}
+	if !strings.HasSuffix(data1, ".data") || !strings.HasSuffix(etag1, ".etag") {
+		t.Errorf("paths() = %q, %q", data1, etag1)
+	}
+}
+
+func TestCachingReader(t *testing.T) {
+	c, err := newReadCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataPath, etagPath := c.paths("bucket", "a.txt")
+
+	newReader := func(content string) *cachingReader {
+		tmp, err := os.CreateTemp(c.dir, "tmp-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &cachingReader{
+			body:     io.NopCloser(strings.NewReader(content)),
+			tmp:      tmp,
+			dataPath: dataPath,
+			etagPath: etagPath,
+			etag:     `"abc"`,
+		}
+	}
+
+	// A partially read body is not cached
+	r := newReader("hello")
+	r.Read(make([]byte, 2))
+	r.Close()
+	if _, err := os.Stat(dataPath); !os.IsNotExist(err) {
+		t.Errorf("partial read was cached")
+	}
+
+	// A fully read body is moved into the cache
+	r = newReader("hello")
+	if data, err := io.ReadAll(r); err != nil || string(data) != "hello" {
+		t.Fatalf("ReadAll() = %q, %v", data, err)
+	}
+	r.Close()
+
+	if data, err := os.ReadFile(dataPath); err != nil || string(data) != "hello" {
+		t.Errorf("cached data = %q, %v", data, err)
+	}
+	if etag, err := os.ReadFile(etagPath); err != nil || string(etag) != `"abc"` {
+		t.Errorf("cached etag = %q, %v", etag, err)
+	}
+
+	entries, _ := os.ReadDir(c.dir)
+	if len(entries) != 2 {
+		t.Errorf("cache dir has %d entries, want 2 (no leftover temp files)", len(entries))
+	}
+}
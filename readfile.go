@@ -0,0 +1,145 @@
+package s3fs
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ReadFile reads the named file and returns its contents, like os.ReadFile.
+// The object is fetched with a single GetObject request (or served from a
+// mirror, the read cache, a pack or an inlined manifest entry as configured).
+// Like Read, it resumes an interrupted download with a Range request.
+func (fs *FileSystem) ReadFile(name string) ([]byte, error) {
+	name = strings.TrimPrefix(name, "/")
+
+	f := &File{fs: fs, name: name, key: fs.objectKey(name)}
+	if e, ok := fs.packs.lookup(name); ok {
+		f.key, f.packed = e.Pack, &e
+	}
+
+	body, err := f.openBody()
+	if err != nil {
+		return nil, fs.wrapError("ReadFile", name, err)
+	}
+	f.body = body
+	defer func() {
+		if f.body != nil {
+			f.body.Close()
+		}
+	}()
+
+	data, err := io.ReadAll(bodyReader{f})
+	if err != nil {
+		return nil, fs.wrapError("ReadFile", name, err)
+	}
+	return data, nil
+}
+
+// WriteFile writes data to the named file with a single PutObject request,
+// replacing any existing object, like os.WriteFile. perm is ignored, as S3
+// objects have no POSIX permissions.
+func (fs *FileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+
+	metadata, err := fs.nameMetadata(name)
+	if err != nil {
+		return fs.wrapError("WriteFile", name, err)
+	}
+	_, err = fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(fs.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		Metadata:      metadata,
+	})
+	fs.stats.invalidate(key)
+	if err != nil {
+		return fs.wrapError("WriteFile", name, err)
+	}
+	fs.packs.forget(name)
+
+	if fs.inlineThreshold > 0 && int64(len(data)) <= fs.inlineThreshold {
+		return fs.manifestPutInline(key, data)
+	}
+	return fs.manifestPut(key, int64(len(data)), false)
+}
+
+// WriteFileAtomic writes data to the named file like WriteFile, but so that
+// readers never observe partially written content: data is uploaded to a
+// temporary sibling key (see NewTempKey) with a Content-MD5 that S3 verifies,
+// the returned ETag is checked against the local MD5 (except for encrypted
+// objects, whose ETag is not an MD5), and the temporary object is then copied
+// to name with a server-side copy conditioned on that ETag and deleted. A
+// mismatch fails with a *ChecksumError and leaves name unchanged.
+func (fs *FileSystem) WriteFileAtomic(name string, data []byte, perm os.FileMode) error {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+
+	metadata, err := fs.nameMetadata(name)
+	if err != nil {
+		return fs.wrapError("WriteFileAtomic", name, err)
+	}
+
+	sum := md5.Sum(data)
+	tmpName := NewTempKey(path.Dir(name))
+	tmpKey := fs.objectKey(tmpName)
+	tmpMetadata, err := fs.nameMetadata(tmpName)
+	if err != nil {
+		return fs.wrapError("WriteFileAtomic", name, err)
+	}
+	output, err := fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(fs.bucket),
+		Key:           aws.String(tmpKey),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentMD5:    aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+		Metadata:      tmpMetadata,
+	})
+	if err != nil {
+		return fs.wrapError("WriteFileAtomic", name, err)
+	}
+	defer fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(tmpKey),
+	})
+
+	etag := aws.ToString(output.ETag)
+	if output.ServerSideEncryption != types.ServerSideEncryptionAwsKms && output.SSECustomerAlgorithm == nil {
+		if want := hex.EncodeToString(sum[:]); strings.Trim(etag, `"`) != want {
+			return fs.wrapError("WriteFileAtomic", name, &ChecksumError{Key: tmpKey, Expected: want, Actual: etag})
+		}
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(fs.bucket),
+		CopySource:        aws.String(fs.copySource(tmpKey)),
+		CopySourceIfMatch: aws.String(etag),
+		Key:               aws.String(key),
+	}
+	if metadata != nil {
+		input.Metadata = metadata
+		input.MetadataDirective = types.MetadataDirectiveReplace
+	}
+	_, err = fs.client.CopyObject(fs.ctx, input)
+	fs.stats.invalidate(key)
+	if err != nil {
+		return fs.wrapError("WriteFileAtomic", name, err)
+	}
+	fs.packs.forget(name)
+
+	if fs.inlineThreshold > 0 && int64(len(data)) <= fs.inlineThreshold {
+		return fs.manifestPutInline(key, data)
+	}
+	return fs.manifestPut(key, int64(len(data)), false)
+}
@@ -0,0 +1,47 @@
+package s3fs_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_ReadWriteFile(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	if err := fs.WriteFile("/notes/a.txt", []byte("alpha"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	data, err := fs.ReadFile("notes/a.txt")
+	if err != nil || string(data) != "alpha" {
+		t.Fatalf("ReadFile() = %q, %v", data, err)
+	}
+	if _, err := fs.ReadFile("notes/missing.txt"); err == nil {
+		t.Errorf("ReadFile() of a missing file should fail")
+	}
+}
+
+func TestFileSystem_WriteFileAtomic(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "dir/a.txt", "old")
+
+	if err := fs.WriteFileAtomic("dir/a.txt", []byte("new content"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	if data, err := fs.ReadFile("dir/a.txt"); err != nil || string(data) != "new content" {
+		t.Errorf("ReadFile() = %q, %v", data, err)
+	}
+	if err := fs.WriteFileAtomic("b.txt", nil, 0644); err != nil {
+		t.Fatalf("WriteFileAtomic() of an empty file error = %v", err)
+	}
+
+	var walked []string
+	fs.Walk("", func(path string, info os.FileInfo, err error) error {
+		walked = append(walked, path)
+		return err
+	})
+	if got := strings.Join(walked, " "); got != "b.txt dir/ dir/a.txt" {
+		t.Errorf("Walk() after WriteFileAtomic = %s, want no temp keys", got)
+	}
+}
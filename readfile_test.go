@@ -0,0 +1,47 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// corruptingClient stores uploads with a wrong ETag and records copies.
+type corruptingClient struct {
+	Client
+	copies, deletes int
+}
+
+func (c *corruptingClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return &s3.PutObjectOutput{ETag: aws.String(`"0123456789abcdef0123456789abcdef"`)}, nil
+}
+
+func (c *corruptingClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	c.copies++
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (c *corruptingClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.deletes++
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestWriteFileAtomic_Mismatch(t *testing.T) {
+	client := &corruptingClient{}
+	fs := &FileSystem{bucket: "bucket", client: client, ctx: context.Background()}
+
+	err := fs.WriteFileAtomic("dir/a.txt", []byte("hello"), 0644)
+	var checksumErr *ChecksumError
+	if !errors.As(err, &checksumErr) || !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("WriteFileAtomic() error = %v, want *ChecksumError", err)
+	}
+	if client.copies != 0 {
+		t.Errorf("WriteFileAtomic() copied a corrupt upload to the destination")
+	}
+	if client.deletes != 1 {
+		t.Errorf("WriteFileAtomic() deleted %d temp keys, want 1", client.deletes)
+	}
+}
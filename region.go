@@ -0,0 +1,43 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// bucketLocator is implemented by *s3.Client.
+type bucketLocator interface {
+	GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+}
+
+// bucketRegion returns the region of bucket. The region of an access point
+// is taken from its ARN; Multi-Region Access Points have none, so "" is
+// returned for them.
+func bucketRegion(ctx context.Context, client bucketLocator, bucket string) (string, error) {
+	if a, err := arn.Parse(bucket); err == nil {
+		return a.Region, nil
+	}
+	output, err := client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return "", fmt.Errorf("s3fs: detecting the region of bucket %s: %w", bucket, err)
+	}
+	return locationRegion(output.LocationConstraint), nil
+}
+
+// locationRegion converts a bucket location constraint to a region name.
+// Buckets in us-east-1 have no location constraint, and old buckets in
+// eu-west-1 report "EU".
+func locationRegion(location types.BucketLocationConstraint) string {
+	switch location {
+	case "":
+		return "us-east-1"
+	case types.BucketLocationConstraintEu:
+		return "eu-west-1"
+	}
+	return string(location)
+}
@@ -0,0 +1,53 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// locationStub answers GetBucketLocation with a fixed location.
+type locationStub struct {
+	location types.BucketLocationConstraint
+	err      error
+}
+
+func (l locationStub) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	return &s3.GetBucketLocationOutput{LocationConstraint: l.location}, nil
+}
+
+func TestBucketRegion(t *testing.T) {
+	tests := []struct {
+		location types.BucketLocationConstraint
+		want     string
+	}{
+		{"", "us-east-1"},
+		{"EU", "eu-west-1"},
+		{"ap-southeast-2", "ap-southeast-2"},
+	}
+	for _, tt := range tests {
+		if got, err := bucketRegion(context.Background(), locationStub{location: tt.location}, "bucket"); err != nil || got != tt.want {
+			t.Errorf("bucketRegion(%q) = %q, %v; want %q", tt.location, got, err, tt.want)
+		}
+	}
+
+	// Access points carry their region, which GetBucketLocation does not report
+	failing := locationStub{err: errors.New("unsupported")}
+	if got, err := bucketRegion(context.Background(), failing, "arn:aws:s3:eu-central-1:123456789012:accesspoint/ap"); err != nil || got != "eu-central-1" {
+		t.Errorf("bucketRegion(access point) = %q, %v; want eu-central-1", got, err)
+	}
+	if got, err := bucketRegion(context.Background(), failing, "arn:aws:s3::123456789012:accesspoint/x.mrap"); err != nil || got != "" {
+		t.Errorf("bucketRegion(MRAP) = %q, %v; want no region", got, err)
+	}
+
+	denied := errors.New("AccessDenied")
+	if _, err := bucketRegion(context.Background(), locationStub{err: denied}, "bucket"); !errors.Is(err, denied) {
+		t.Errorf("bucketRegion() error = %v, want %v", err, denied)
+	}
+}
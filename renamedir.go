@@ -0,0 +1,117 @@
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxDeleteBatch is the largest number of keys a DeleteObjects request accepts.
+const maxDeleteBatch = 1000
+
+// RenameDir moves every object below the directory oldpath to the same
+// relative name below newpath. The move runs as a transaction (see Begin):
+// the objects are copied and then the originals deleted, with every step
+// recorded in a journal, so a RenameDir interrupted by a crash can be
+// finished with ResumeTxn or undone with RollbackTxn. If an operation
+// fails, the transaction is rolled back, restoring the originals and the
+// objects that already existed below newpath. progress, if not nil,
+// receives the number of bytes copied so far.
+//
+// Rename calls RenameDir for directories.
+func (fs *FileSystem) RenameDir(oldpath, newpath string, progress ProgressFunc) error {
+	src := strings.Trim(oldpath, "/") + "/"
+	dst := strings.Trim(newpath, "/") + "/"
+	if src == "/" || dst == "/" {
+		return fs.wrapError("Rename", oldpath, ErrRootPrefix)
+	}
+	if strings.HasPrefix(src, dst) || strings.HasPrefix(dst, src) {
+		return fs.wrapError("Rename", oldpath, ErrPrefixOverlap)
+	}
+
+	jobs, err := fs.listCopyJobs(src, dst)
+	if err != nil {
+		return fs.wrapError("Rename", oldpath, err)
+	}
+	if len(jobs) == 0 {
+		return fs.wrapError("Rename", oldpath, ErrNotExist)
+	}
+
+	txn := fs.Begin()
+	sizes := make(map[string]int64, len(jobs))
+	for _, job := range jobs {
+		txn.copyKey(job.srcKey, job.dstName)
+		sizes[fs.objectKey(job.dstName)] = job.size
+	}
+	for _, job := range jobs {
+		txn.deleteKey(job.srcKey)
+	}
+	if progress != nil {
+		var copied int64
+		txn.afterOp = func(op txnOp) {
+			if op.Op == "copy" {
+				copied += sizes[op.Key]
+				progress(copied)
+			}
+		}
+	}
+
+	err = txn.commit()
+	fs.dirs.invalidatePrefix(fs.objectKey(src))
+	if err != nil {
+		return fs.wrapError("Rename", oldpath, err)
+	}
+	fs.moveInode(src, dst)
+	return nil
+}
+
+// renameDir moves a directory for Rename and its variants. With
+// If-None-Match in cond it fails if anything exists at newpath.
+func (fs *FileSystem) renameDir(oldpath, newpath string, cond writeCondition) error {
+	if cond.ifNoneMatch != "" {
+		_, err := fs.Stat(strings.Trim(newpath, "/"))
+		if err == nil {
+			return fs.wrapError("Rename", newpath, os.ErrExist)
+		}
+		if !errors.Is(err, iofs.ErrNotExist) {
+			return err
+		}
+	}
+	return fs.RenameDir(oldpath, newpath, nil)
+}
+
+// deleteKeys deletes keys with DeleteObjects requests of up to
+// maxDeleteBatch keys each.
+func (fs *FileSystem) deleteKeys(keys []string) error {
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > maxDeleteBatch {
+			n = maxDeleteBatch
+		}
+
+		objects := make([]types.ObjectIdentifier, n)
+		for i, key := range keys[:n] {
+			objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+		output, err := fs.client.DeleteObjects(fs.ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(fs.bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.Errors) > 0 {
+			e := output.Errors[0]
+			return fmt.Errorf("s3fs: delete %s: %s: %s", aws.ToString(e.Key), aws.ToString(e.Code), aws.ToString(e.Message))
+		}
+
+		keys = keys[n:]
+	}
+	return nil
+}
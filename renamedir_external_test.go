@@ -0,0 +1,82 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// failingCopyClient fails the copies to one destination key.
+type failingCopyClient struct {
+	*s3fstest.Client
+	failKey string
+}
+
+var errCopyFailed = errors.New("copy failed")
+
+func (c *failingCopyClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if aws.ToString(params.Key) == c.failKey {
+		return nil, errCopyFailed
+	}
+	return c.Client.CopyObject(ctx, params, optFns...)
+}
+
+func TestRenameDir_RollbackKeepsExisting(t *testing.T) {
+	client := &failingCopyClient{Client: s3fstest.NewClient(), failKey: "dst/b.txt"}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "src/a.txt", "new a")
+	writeFile(t, fs, "src/b.txt", "new b")
+	writeFile(t, fs, "src/c.txt", "new c")
+	writeFile(t, fs, "dst/a.txt", "old a")
+
+	if err := fs.RenameDir("src", "dst", nil); !errors.Is(err, errCopyFailed) {
+		t.Fatalf("RenameDir() error = %v, want the copy error", err)
+	}
+
+	if got := readFile(t, fs, "dst/a.txt"); got != "old a" {
+		t.Errorf("dst/a.txt after rollback = %q, want the existing content restored", got)
+	}
+	if ok, err := fs.Exists("dst/c.txt"); ok || err != nil {
+		t.Errorf("Exists(dst/c.txt) after rollback = %v, %v, want the copy removed", ok, err)
+	}
+	for _, name := range []string{"src/a.txt", "src/b.txt", "src/c.txt"} {
+		if ok, err := fs.Exists(name); !ok || err != nil {
+			t.Errorf("Exists(%s) after rollback = %v, %v, want the original kept", name, ok, err)
+		}
+	}
+}
+
+func TestRenameDir_CrashRecovery(t *testing.T) {
+	for _, resume := range []bool{true, false} {
+		client := &crashingClient{Client: s3fstest.NewClient(), crashAfter: -1}
+		fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, fs, "src/a.txt", "a")
+		writeFile(t, fs, "src/b.txt", "b")
+
+		client.crashAfter = 3 // the backups of both sources and the first copy
+		if err := fs.RenameDir("src", "dst", nil); err == nil {
+			t.Fatal("RenameDir() during a crash succeeded")
+		}
+		client.crashAfter = -1
+
+		recoverTxn(t, fs, resume)
+		want := "src/a.txt=a src/b.txt=b"
+		if resume {
+			want = "dst/a.txt=a dst/b.txt=b"
+		}
+		if got := treeContents(fs); got != want {
+			t.Errorf("after recovery (resume %v) = %s, want %s", resume, got, want)
+		}
+	}
+}
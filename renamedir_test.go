@@ -0,0 +1,28 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRenameDir_InvalidPrefixes(t *testing.T) {
+	fs := &FileSystem{ctx: context.Background()}
+
+	if err := fs.RenameDir("/", "b", nil); !errors.Is(err, ErrRootPrefix) {
+		t.Errorf("RenameDir() of the root error = %v, want ErrRootPrefix", err)
+	}
+	if err := fs.RenameDir("a", "a/b", nil); !errors.Is(err, ErrPrefixOverlap) {
+		t.Errorf("RenameDir() into itself error = %v, want ErrPrefixOverlap", err)
+	}
+	if err := fs.Rename("a/", "a/b/"); !errors.Is(err, ErrPrefixOverlap) {
+		t.Errorf("Rename() of a directory into itself error = %v, want ErrPrefixOverlap", err)
+	}
+}
+
+func TestDeleteKeys_Empty(t *testing.T) {
+	fs := &FileSystem{}
+	if err := fs.deleteKeys(nil); err != nil {
+		t.Errorf("deleteKeys(nil) error = %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MirrorOptions controls Mirror. A nil *MirrorOptions mirrors every object
+// with the defaults.
+type MirrorOptions struct {
+	// Include and Exclude are path.Match patterns matched against the name
+	// of each object relative to the mirrored prefix. Patterns without a
+	// slash also match the base name, so "*.log" excludes logs at any depth.
+	// An object is mirrored if it matches any Include pattern (or Include is
+	// empty) and no Exclude pattern.
+	Include []string
+	Exclude []string
+
+	// ServerSideCopy asserts that the destination client can read the source
+	// bucket (same S3 service and sufficient credentials), so objects are
+	// copied server-side instead of streamed through the client. It is
+	// implied when both FileSystems share a client. If a server-side copy is
+	// refused, Mirror falls back to streaming.
+	ServerSideCopy bool
+
+	Concurrency int          // Parallel transfers (default DefaultCopyConcurrency)
+	Progress    ProgressFunc // Receives the number of bytes mirrored so far
+}
+
+// Mirror replicates every object below the directory prefix of fs to the
+// same name in dst, which may use another bucket, region or endpoint.
+// Objects are copied server-side when possible (see
+// MirrorOptions.ServerSideCopy) and streamed through the client otherwise.
+// Transfers stop at the first error. It returns the number of objects
+// mirrored.
+func (fs *FileSystem) Mirror(dst *FileSystem, prefix string, opts *MirrorOptions) (int, error) {
+	if opts == nil {
+		opts = &MirrorOptions{}
+	}
+	src := syncPrefix(prefix)
+	if fs.baseClient() == dst.baseClient() && fs.bucket == dst.bucket {
+		return 0, fs.wrapError("Mirror", prefix, ErrPrefixOverlap)
+	}
+
+	jobs, err := fs.listCopyJobs(src, src)
+	if err != nil {
+		return 0, fs.wrapError("Mirror", prefix, err)
+	}
+	n := 0
+	for _, job := range jobs {
+		if opts.matches(strings.TrimPrefix(job.dstName, src)) {
+			jobs[n] = job
+			n++
+		}
+	}
+	jobs = jobs[:n]
+
+	m := &mirror{
+		src:        fs,
+		dst:        dst,
+		serverSide: opts.ServerSideCopy || fs.baseClient() == dst.baseClient(),
+	}
+	mirrored, err := m.run(jobs, opts)
+	if err != nil {
+		return mirrored, fs.wrapError("Mirror", prefix, err)
+	}
+	return mirrored, nil
+}
+
+// matches reports whether the relative name passes the filters.
+func (o *MirrorOptions) matches(name string) bool {
+	if len(o.Include) > 0 && !matchAny(o.Include, name) {
+		return false
+	}
+	return !matchAny(o.Exclude, name)
+}
+
+// matchAny reports whether name, or its base name for patterns without a
+// slash, matches one of patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		target := name
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mirror is a single Mirror run.
+type mirror struct {
+	src, dst *FileSystem
+
+	mu         sync.Mutex
+	serverSide bool
+	bytes      int64
+}
+
+// run transfers jobs with opts.Concurrency workers, stopping at the first
+// error, and returns the number of objects transferred.
+func (m *mirror) run(jobs []copyJob, opts *MirrorOptions) (int, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultCopyConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(m.src.ctx)
+	defer cancel()
+
+	work := make(chan copyJob)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	var mirrored int
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range work {
+				if err := m.transfer(ctx, job); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+
+				m.mu.Lock()
+				mirrored++
+				m.bytes += job.size
+				if opts.Progress != nil {
+					opts.Progress(m.bytes)
+				}
+				m.mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, job := range jobs {
+		select {
+		case work <- job:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = m.src.ctx.Err()
+	}
+	return mirrored, firstErr
+}
+
+// transfer mirrors a single object, falling back to streaming for the rest
+// of the run once the destination refuses a server-side copy.
+func (m *mirror) transfer(ctx context.Context, job copyJob) error {
+	dst := m.dst.WithContext(ctx)
+	key := dst.objectKey(job.dstName)
+	defer dst.stats.invalidate(key)
+
+	m.mu.Lock()
+	serverSide := m.serverSide
+	m.mu.Unlock()
+
+	if serverSide {
+		err := dst.copyObject(ctx, m.src.copySource(job.srcKey), job.dstName, job.size)
+		switch httpStatus(err) {
+		case http.StatusForbidden, http.StatusNotFound, http.StatusNotImplemented:
+			m.mu.Lock()
+			m.serverSide = false
+			m.mu.Unlock()
+		default:
+			if err != nil {
+				return err
+			}
+			return dst.manifestPut(key, job.size, strings.HasSuffix(key, "/"))
+		}
+	}
+
+	if err := m.stream(ctx, dst, job); err != nil {
+		return err
+	}
+	return dst.manifestPut(key, job.size, strings.HasSuffix(key, "/"))
+}
+
+// stream downloads the source object and uploads it to dst.
+func (m *mirror) stream(ctx context.Context, dst *FileSystem, job copyJob) error {
+	output, err := m.src.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(m.src.bucket),
+		Key:    aws.String(job.srcKey),
+	})
+	if err != nil {
+		return err
+	}
+	defer output.Body.Close()
+
+	return dst.putReader(ctx, job.dstName, output.Body, job.size)
+}
+
+// putReader uploads size bytes from r as name. Content larger than
+// DefaultPartSize is uploaded with a multipart upload; smaller content is
+// buffered in memory so the request body can be retried.
+func (fs *FileSystem) putReader(ctx context.Context, name string, r io.Reader, size int64) error {
+	if size > DefaultPartSize {
+		mu, err := fs.WithContext(ctx).NewMultipartUpload(name)
+		if err != nil {
+			return err
+		}
+		if err := mu.UploadFromReader(r); err != nil {
+			mu.Abort()
+			return err
+		}
+		return mu.Complete()
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	metadata, err := fs.nameMetadata(name)
+	if err != nil {
+		return err
+	}
+	_, err = fs.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(fs.bucket),
+		Key:           aws.String(fs.objectKey(name)),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		Metadata:      metadata,
+	})
+	return err
+}
@@ -0,0 +1,38 @@
+package s3fs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMirrorOptions_Matches(t *testing.T) {
+	tests := []struct {
+		name string
+		opts MirrorOptions
+		path string
+		want bool
+	}{
+		{"NoFilters", MirrorOptions{}, "a/b.txt", true},
+		{"IncludeBase", MirrorOptions{Include: []string{"*.txt"}}, "a/b.txt", true},
+		{"IncludeMiss", MirrorOptions{Include: []string{"*.txt"}}, "a/b.log", false},
+		{"IncludePath", MirrorOptions{Include: []string{"a/*"}}, "a/b.log", true},
+		{"IncludePathDepth", MirrorOptions{Include: []string{"a/*"}}, "a/c/b.log", false},
+		{"ExcludeBase", MirrorOptions{Exclude: []string{"*.log"}}, "x/y/z.log", false},
+		{"IncludeThenExclude", MirrorOptions{Include: []string{"*.txt"}, Exclude: []string{"tmp/*"}}, "tmp/b.txt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.matches(tt.path); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirror_SameBucket(t *testing.T) {
+	fs := &FileSystem{bucket: "bucket"}
+	_, err := fs.Mirror(fs.WithContext(fs.ctx), "data", nil)
+	if !errors.Is(err, ErrPrefixOverlap) {
+		t.Errorf("Mirror() to the same bucket error = %v, want ErrPrefixOverlap", err)
+	}
+}
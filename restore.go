@@ -0,0 +1,111 @@
+package s3fs
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// RestoreTier selects the speed and cost of a restore from an archive
+// storage class.
+type RestoreTier string
+
+const (
+	RestoreExpedited RestoreTier = "Expedited" // Minutes; not available for DEEP_ARCHIVE
+	RestoreStandard  RestoreTier = "Standard"  // Hours
+	RestoreBulk      RestoreTier = "Bulk"      // Cheapest, up to two days
+)
+
+// RestoreInfo describes the archive state of an object, as reported by
+// RestoreStatus.
+type RestoreInfo struct {
+	StorageClass string    // Storage class of the object
+	Archived     bool      // Whether the object is in GLACIER or DEEP_ARCHIVE
+	Ongoing      bool      // Whether a restore is in progress
+	Restored     bool      // Whether a restored copy can be read
+	Expiry       time.Time // When the restored copy expires
+}
+
+// isArchiveClass reports whether objects of the storage class must be
+// restored before they can be read.
+func isArchiveClass(class types.StorageClass) bool {
+	return class == types.StorageClassGlacier || class == types.StorageClassDeepArchive
+}
+
+// Restore starts restoring the archived object name, making a copy readable
+// for days days once the restore completes. Reads of an archived object fail
+// with an error matching ErrObjectArchived until then; use RestoreStatus to
+// monitor progress. Requesting a restore that is already in progress is not
+// an error.
+func (fs *FileSystem) Restore(name string, days int, tier RestoreTier) error {
+	name = strings.TrimPrefix(name, "/")
+	if days < 1 {
+		return fs.wrapError("Restore", name, ErrInvalidRestore)
+	}
+	if tier == "" {
+		tier = RestoreStandard
+	}
+
+	_, err := fs.client.RestoreObject(fs.ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.objectKey(name)),
+		RestoreRequest: &types.RestoreRequest{
+			Days:                 aws.Int32(int32(days)),
+			GlacierJobParameters: &types.GlacierJobParameters{Tier: types.Tier(tier)},
+		},
+	})
+	if err != nil && errorCode(err) != "RestoreAlreadyInProgress" && httpStatus(err) != http.StatusConflict {
+		return fs.wrapError("Restore", name, err)
+	}
+	return nil
+}
+
+// RestoreStatus reports whether the object name is archived and the state of
+// its restore.
+func (fs *FileSystem) RestoreStatus(name string) (*RestoreInfo, error) {
+	name = strings.TrimPrefix(name, "/")
+
+	output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.objectKey(name)),
+	})
+	if err != nil {
+		return nil, fs.wrapError("RestoreStatus", name, err)
+	}
+
+	info := &RestoreInfo{
+		StorageClass: string(output.StorageClass),
+		Archived:     isArchiveClass(output.StorageClass),
+	}
+	if info.StorageClass == "" {
+		info.StorageClass = string(types.StorageClassStandard)
+	}
+	info.Ongoing, info.Expiry = parseRestore(aws.ToString(output.Restore))
+	info.Restored = !info.Ongoing && !info.Expiry.IsZero()
+	return info, nil
+}
+
+// parseRestore parses the x-amz-restore header, such as
+// `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`.
+func parseRestore(header string) (ongoing bool, expiry time.Time) {
+	for header != "" {
+		var field string
+		field, header, _ = strings.Cut(header, `",`)
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		switch key {
+		case "ongoing-request":
+			ongoing = value == "true"
+		case "expiry-date":
+			expiry, _ = http.ParseTime(value)
+		}
+	}
+	return ongoing, expiry
+}
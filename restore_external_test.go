@@ -0,0 +1,61 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestFileSystem_Restore(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:       aws.String("bucket"),
+		Key:          aws.String("cold.txt"),
+		Body:         strings.NewReader("frozen"),
+		StorageClass: types.StorageClassGlacier,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.ReadFile("cold.txt"); !errors.Is(err, s3fs.ErrObjectArchived) {
+		t.Errorf("ReadFile() of an archived object error = %v, want ErrObjectArchived", err)
+	}
+	status, err := fs.RestoreStatus("cold.txt")
+	if err != nil {
+		t.Fatalf("RestoreStatus() error = %v", err)
+	}
+	if !status.Archived || status.Restored || status.StorageClass != "GLACIER" {
+		t.Errorf("RestoreStatus() before Restore = %+v", status)
+	}
+
+	if err := fs.Restore("cold.txt", 2, s3fs.RestoreBulk); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	status, err = fs.RestoreStatus("cold.txt")
+	if err != nil || !status.Restored || status.Expiry.IsZero() {
+		t.Errorf("RestoreStatus() after Restore = %+v, %v", status, err)
+	}
+	if data, err := fs.ReadFile("cold.txt"); err != nil || string(data) != "frozen" {
+		t.Errorf("ReadFile() after Restore = %q, %v", data, err)
+	}
+
+	writeFile(t, fs, "warm.txt", "data")
+	if status, err := fs.RestoreStatus("warm.txt"); err != nil || status.Archived {
+		t.Errorf("RestoreStatus() of a STANDARD object = %+v, %v", status, err)
+	}
+	if err := fs.Restore("warm.txt", 1, ""); !errors.Is(err, s3fs.ErrObjectArchived) {
+		t.Errorf("Restore() of a STANDARD object error = %v, want InvalidObjectState", err)
+	}
+}
@@ -0,0 +1,25 @@
+package s3fs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRestore(t *testing.T) {
+	tests := []struct {
+		header  string
+		ongoing bool
+		expiry  time.Time
+	}{
+		{"", false, time.Time{}},
+		{`ongoing-request="true"`, true, time.Time{}},
+		{`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`, false, time.Date(2012, 12, 21, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		ongoing, expiry := parseRestore(tt.header)
+		if ongoing != tt.ongoing || !expiry.Equal(tt.expiry) {
+			t.Errorf("parseRestore(%q) = %v, %v; want %v, %v", tt.header, ongoing, expiry, tt.ongoing, tt.expiry)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package s3afero adapts an s3fs.FileSystem to afero.Fs, so tools built on
+// afero, such as viper or test helpers, can work on S3 buckets:
+//
+//	fs, err := s3fs.New(&s3fs.Config{Bucket: "my-bucket"})
+//	if err != nil {
+//		return err
+//	}
+//	afs := s3afero.New(fs)
+//	afero.WriteFile(afs, "config/app.yaml", data, 0644)
+//
+// Reading a regular file as a directory fails with ENOTDIR, as
+// afero.Walk expects. Errors matching fs.ErrNotExist, fs.ErrExist
+// and fs.ErrPermission are returned as *os.PathError values, which
+// os.IsNotExist and friends recognize.
+//
+// The package is a module of its own, so s3fs users who don't need it don't
+// depend on afero.
+package s3afero
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/s3fs"
+	"github.com/spf13/afero"
+)
+
+// Fs is an afero.Fs backed by an s3fs.FileSystem.
+type Fs struct {
+	fs *s3fs.FileSystem
+}
+
+var _ afero.Fs = (*Fs)(nil)
+
+// New returns an afero.Fs that stores files in fs.
+func New(fs *s3fs.FileSystem) *Fs {
+	return &Fs{fs: fs}
+}
+
+// FileSystem returns the s3fs.FileSystem behind f.
+func (f *Fs) FileSystem() *s3fs.FileSystem {
+	return f.fs
+}
+
+// Name returns the name of the filesystem.
+func (f *Fs) Name() string { return "S3FS" }
+
+// Create creates or truncates the named file for writing.
+func (f *Fs) Create(name string) (afero.File, error) {
+	return f.wrap("open", name)(f.fs.Create(name))
+}
+
+// Open opens the named file or directory for reading.
+func (f *Fs) Open(name string) (afero.File, error) {
+	return f.wrap("open", name)(f.fs.Open(name))
+}
+
+// OpenFile opens the named file with the os.O_* flags s3fs supports.
+func (f *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	return f.wrap("open", name)(f.fs.OpenFile(name, flag, perm))
+}
+
+// Mkdir creates a directory.
+func (f *Fs) Mkdir(name string, perm os.FileMode) error {
+	return osError("mkdir", name, f.fs.Mkdir(name, perm))
+}
+
+// MkdirAll creates a directory and its parents.
+func (f *Fs) MkdirAll(path string, perm os.FileMode) error {
+	return osError("mkdir", path, f.fs.MkdirAll(path, perm))
+}
+
+// Remove removes a file or an empty directory.
+func (f *Fs) Remove(name string) error {
+	return osError("remove", name, f.fs.Remove(name))
+}
+
+// RemoveAll removes a file or a directory with its contents.
+func (f *Fs) RemoveAll(path string) error {
+	return osError("remove", path, f.fs.RemoveAll(path))
+}
+
+// Rename moves a file.
+func (f *Fs) Rename(oldname, newname string) error {
+	return osError("rename", oldname, f.fs.Rename(oldname, newname))
+}
+
+// Stat returns the FileInfo of the named file or directory.
+func (f *Fs) Stat(name string) (os.FileInfo, error) {
+	info, err := f.fs.Stat(name)
+	return info, osError("stat", name, err)
+}
+
+// Chmod changes the mode of a file, see s3fs.Config.POSIXMetadata.
+func (f *Fs) Chmod(name string, mode os.FileMode) error {
+	return osError("chmod", name, f.fs.Chmod(name, mode))
+}
+
+// Chown changes the owner of a file, see s3fs.Config.POSIXMetadata.
+func (f *Fs) Chown(name string, uid, gid int) error {
+	return osError("chown", name, f.fs.Chown(name, uid, gid))
+}
+
+// Chtimes is not supported by S3 and always fails.
+func (f *Fs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return f.fs.Chtimes(name, atime, mtime)
+}
+
+// wrap returns a function that returns an s3fs file as an afero.File.
+func (f *Fs) wrap(op, name string) func(absfs.File, error) (afero.File, error) {
+	return func(file absfs.File, err error) (afero.File, error) {
+		if err != nil {
+			return nil, osError(op, name, err)
+		}
+		return &File{File: file, fs: f.fs}, nil
+	}
+}
+
+// osError returns err as an *os.PathError with the os sentinel it matches,
+// since afero-based code tests errors with os.IsNotExist and os.IsExist,
+// which do not look into the error chain of s3fs errors.
+func osError(op, name string, err error) error {
+	for _, target := range []error{os.ErrNotExist, os.ErrExist, os.ErrPermission} {
+		if errors.Is(err, target) {
+			return &os.PathError{Op: op, Path: name, Err: target}
+		}
+	}
+	return err
+}
+
+// File is an afero.File backed by an s3fs file.
+type File struct {
+	absfs.File
+	fs *s3fs.FileSystem
+
+	listing bool // Whether Readdir checked that the file is a directory
+}
+
+var _ afero.File = (*File)(nil)
+
+// Readdir returns up to n entries of the directory, following the contract
+// of os.File.Readdir. Entry names are base names.
+func (f *File) Readdir(n int) ([]os.FileInfo, error) {
+	if !f.listing {
+		info, err := f.File.Stat()
+		if err != nil {
+			return nil, osError("readdirent", f.Name(), err)
+		}
+		if !info.IsDir() {
+			return nil, &os.PathError{Op: "readdirent", Path: f.Name(), Err: syscall.ENOTDIR}
+		}
+		f.listing = true
+	}
+
+	infos, err := f.File.Readdir(n)
+	if err != nil && err != io.EOF {
+		return nil, osError("readdirent", f.Name(), err)
+	}
+	return infos, err
+}
+
+// Readdirnames returns up to n names of entries of the directory, following
+// the contract of Readdir.
+func (f *File) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, nil
+}
@@ -0,0 +1,84 @@
+package s3afero_test
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs/s3afero"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFs(t *testing.T) {
+	afs := s3afero.New(s3fstest.New("bucket"))
+	for _, name := range []string{"dir/a.txt", "dir/b.txt", "dir/sub/c.txt"} {
+		f, err := afs.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%q) error = %v", name, err)
+		}
+		if _, err := f.WriteString(name); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := afs.Open("dir/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "dir/a.txt" {
+		t.Errorf("ReadAll() = %q, %v", data, err)
+	}
+	if _, err := f.Readdir(-1); err == nil {
+		t.Errorf("Readdir() of a file succeeded")
+	}
+
+	d, err := afs.Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	var names []string
+	var dirs []string
+	for {
+		infos, err := d.Readdir(2)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, info := range infos {
+			names = append(names, info.Name())
+			if info.IsDir() {
+				dirs = append(dirs, info.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, " "); got != "a.txt b.txt sub" {
+		t.Errorf("Readdir() names = %q, want direct entries with base names", got)
+	}
+	if len(dirs) != 1 || dirs[0] != "sub" {
+		t.Errorf("Readdir() directories = %q, want sub", dirs)
+	}
+
+	if err := afs.Rename("dir/a.txt", "dir/moved.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := afs.Stat("dir/a.txt"); !os.IsNotExist(err) {
+		t.Errorf("Stat() after Rename = %v, want not exist", err)
+	}
+	if err := afs.RemoveAll("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := afs.Stat("dir/moved.txt"); !os.IsNotExist(err) {
+		t.Errorf("Stat() after RemoveAll = %v, want not exist", err)
+	}
+}
@@ -2,15 +2,24 @@ package s3fs
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	iofs "io/fs"
 	"os"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+// DefaultReadRetries is the number of times Read resumes an interrupted
+// download when Config.ReadRetries is not set.
+const DefaultReadRetries = 3
+
 // File represents a file in S3.
 // It implements the absfs.File interface for S3 object operations.
 // Files are opened in either read or write mode. Write mode uses an in-memory
@@ -23,6 +32,20 @@ type File struct {
 	buffer  []byte
 	offset  int64
 	body    io.ReadCloser
+	packed  *packEntry
+	version string
+	dir     *dirReader
+	stat    os.FileInfo // Result of the Stat of OpenFile, nil if skipped
+
+	blocks   *blockCache // Blocks cached for ReadAt, nil if disabled
+	bodyETag string      // ETag of the object the body was fetched from
+	resumes  int         // Resumes of the body since the last successful Read
+
+	spill     *os.File
+	spillSize int64
+
+	cond writeCondition
+	etag string
 }
 
 // Name returns the name of the file.
@@ -30,68 +53,243 @@ func (f *File) Name() string {
 	return f.name
 }
 
-// Read reads from the S3 object.
+// Read reads from the S3 object at the current offset.
 // On the first call, it fetches the object from S3 and reads from the response body.
-// Subsequent calls continue reading from the same response stream.
+// Subsequent calls continue reading from the same response stream until Seek
+// moves the offset, after which the object is fetched again with a Range request.
+// If the stream fails, for example because the connection was reset, Read
+// resumes from the current offset with a Range request for the same object
+// version, up to Config.ReadRetries times in a row.
 func (f *File) Read(b []byte) (int, error) {
 	if f.writing {
 		return 0, ErrReadOnWriteFile
 	}
 
+	n, err := f.readBody(b)
+	if err != nil && err != io.EOF {
+		return n, f.fs.wrapError("Read", f.name, err)
+	}
+	return n, err
+}
+
+// readBody reads from the response stream at the current offset, fetching
+// it first if needed and resuming it if it fails.
+func (f *File) readBody(b []byte) (int, error) {
 	// Lazy load the object body
 	if f.body == nil {
-		output, err := f.fs.client.GetObject(f.fs.ctx, &s3.GetObjectInput{
-			Bucket: aws.String(f.fs.bucket),
-			Key:    aws.String(f.key),
-		})
+		if f.packed != nil && f.offset >= f.packed.Size {
+			return 0, io.EOF
+		}
+		body, err := f.openBody()
 		if err != nil {
-			return 0, wrapError("Read", f.name, err)
+			if httpStatus(err) == 416 {
+				return 0, io.EOF
+			}
+			return 0, err
 		}
-		f.body = output.Body
+		f.body = body
 	}
 
 	n, err := f.body.Read(b)
-	if err != nil && err != io.EOF {
-		return n, wrapError("Read", f.name, err)
+	f.offset += int64(n)
+	if n > 0 {
+		f.resumes = 0
+	}
+	if err != nil && err != io.EOF && f.resumable(err) {
+		f.body.Close()
+		f.body = nil
+		f.resumes++
+		if n > 0 {
+			return n, nil
+		}
+		return f.readBody(b)
 	}
 	return n, err
 }
 
+// bodyReader reads the object of a File with readBody.
+type bodyReader struct{ f *File }
+
+func (r bodyReader) Read(b []byte) (int, error) { return r.f.readBody(b) }
+
+// resumable reports whether a failed body stream can be resumed with a new
+// request. Cancellation and checksum mismatches are not transient.
+func (f *File) resumable(err error) bool {
+	if f.resumes >= f.fs.readRetries {
+		return false
+	}
+	var checksum *ChecksumError
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+		!errors.As(err, &checksum) && f.fs.ctx.Err() == nil
+}
+
+// openBody fetches the object body for sequential reads, consulting the
+// configured HTTP mirrors before or after S3.
+// Mirrors and the read cache are only used when reading from the start.
+func (f *File) openBody() (io.ReadCloser, error) {
+	if f.packed == nil && f.version == "" {
+		if data, ok := f.fs.inlineData(f.key); ok {
+			if f.offset >= int64(len(data)) {
+				return io.NopCloser(bytes.NewReader(nil)), nil
+			}
+			return io.NopCloser(bytes.NewReader(data[f.offset:])), nil
+		}
+	}
+
+	if f.offset > 0 {
+		return f.getBody()
+	}
+
+	useMirrors := len(f.fs.mirrors) > 0 && f.packed == nil && f.version == ""
+	if useMirrors && f.fs.mirrorFirst {
+		if body, err := f.fs.mirrorGet(f.key); err == nil {
+			return body, nil
+		}
+	}
+
+	var body io.ReadCloser
+	var err error
+	if f.fs.readCache != nil && f.packed == nil && f.version == "" {
+		body, err = f.fs.cachedGet(f.key)
+	} else {
+		body, err = f.getBody()
+	}
+
+	if err != nil && useMirrors && !f.fs.mirrorFirst {
+		if mbody, merr := f.fs.mirrorGet(f.key); merr == nil {
+			return mbody, nil
+		}
+	}
+	return body, err
+}
+
+// getBody issues the GetObject request for sequential reads starting at the
+// current offset. When resuming an interrupted stream, the request is
+// conditional on the ETag of the object the stream was reading, so a
+// replaced object fails with a precondition error instead of mixing content.
+func (f *File) getBody() (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(f.fs.bucket),
+		Key:    aws.String(f.key),
+	}
+	if f.version != "" {
+		input.VersionId = aws.String(f.version)
+	}
+	if f.packed != nil {
+		input.Range = aws.String(packRange(f.packed, f.offset, f.packed.Size-f.offset))
+	} else if f.offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", f.offset))
+	}
+	if f.resumes > 0 && f.bodyETag != "" {
+		input.IfMatch = aws.String(f.bodyETag)
+	}
+	if f.fs.prefetch > 0 && f.packed == nil {
+		return f.prefetchBody(input)
+	}
+
+	output, err := f.fs.client.GetObject(f.fs.ctx, input)
+	if err != nil {
+		return nil, preconditionError(err)
+	}
+	f.bodyETag = aws.ToString(output.ETag)
+	return output.Body, nil
+}
+
 // ReadAt reads from the S3 object at a specific offset.
 // It uses S3's Range header to read only the requested bytes.
-// Each call makes a separate request to S3.
+// Each call makes a separate request to S3, unless Config.ReadAtCacheBlocks
+// is set: then the object is fetched in aligned blocks that are cached per
+// file, so that nearby calls are served without new requests.
 func (f *File) ReadAt(b []byte, off int64) (int, error) {
 	if f.writing {
 		return 0, ErrReadOnWriteFile
 	}
 
+	if f.packed == nil && f.version == "" {
+		if data, ok := f.fs.inlineData(f.key); ok {
+			if off >= int64(len(data)) {
+				return 0, io.EOF
+			}
+			n := copy(b, data[off:])
+			if n < len(b) {
+				return n, io.EOF
+			}
+			return n, nil
+		}
+	}
+
+	if f.blocks != nil && f.packed == nil {
+		n, err := f.blocks.readAt(f, b, off)
+		if err != nil && err != io.EOF {
+			return n, f.fs.wrapError("ReadAt", f.name, err)
+		}
+		return n, err
+	}
+
 	// S3 supports range reads
 	rangeStr := fmt.Sprintf("bytes=%d-%d", off, off+int64(len(b))-1)
-	output, err := f.fs.client.GetObject(f.fs.ctx, &s3.GetObjectInput{
+	short := false
+	if f.packed != nil {
+		if off >= f.packed.Size {
+			return 0, io.EOF
+		}
+		if len(b) == 0 {
+			return 0, nil
+		}
+		if rest := f.packed.Size - off; int64(len(b)) > rest {
+			b = b[:rest]
+			short = true
+		}
+		rangeStr = packRange(f.packed, off, int64(len(b)))
+	}
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(f.fs.bucket),
 		Key:    aws.String(f.key),
 		Range:  aws.String(rangeStr),
-	})
+	}
+	if f.version != "" {
+		input.VersionId = aws.String(f.version)
+	}
+	output, err := f.fs.client.GetObject(f.fs.ctx, input)
 	if err != nil {
-		return 0, wrapError("ReadAt", f.name, err)
+		return 0, f.fs.wrapError("ReadAt", f.name, err)
 	}
 	defer output.Body.Close()
 
 	n, err := io.ReadFull(output.Body, b)
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-		return n, wrapError("ReadAt", f.name, err)
+		return n, f.fs.wrapError("ReadAt", f.name, err)
+	}
+	if err == nil && short {
+		err = io.EOF
 	}
 	return n, err
 }
 
 // Write writes to the file buffer (will be uploaded on Close).
 // Data is buffered in memory until Close() is called, which uploads the entire
-// buffer to S3 in a single operation.
+// buffer to S3 in a single operation. Buffers growing beyond
+// Config.SpillThreshold are moved to a temporary file on local disk.
 func (f *File) Write(b []byte) (int, error) {
 	if !f.writing {
 		return 0, ErrWriteOnReadFile
 	}
 
+	if err := f.maybeSpill(f.size() + int64(len(b))); err != nil {
+		return 0, err
+	}
+	if f.offset != f.size() {
+		// Overwriting preloaded content, or after a Seek
+		n, err := f.WriteAt(b, f.offset)
+		f.offset += int64(n)
+		return n, err
+	}
+	if f.spill != nil {
+		n, err := f.spillWriteAt(b, f.spillSize)
+		f.offset += int64(n)
+		return n, err
+	}
+
 	f.buffer = append(f.buffer, b...)
 	f.offset += int64(len(b))
 	return len(b), nil
@@ -104,6 +302,15 @@ func (f *File) WriteAt(b []byte, off int64) (int, error) {
 		return 0, ErrWriteOnReadFile
 	}
 
+	if end := off + int64(len(b)); end > f.size() {
+		if err := f.maybeSpill(end); err != nil {
+			return 0, err
+		}
+	}
+	if f.spill != nil {
+		return f.spillWriteAt(b, off)
+	}
+
 	// Extend buffer if necessary
 	if int(off)+len(b) > len(f.buffer) {
 		newBuf := make([]byte, int(off)+len(b))
@@ -130,34 +337,81 @@ func (f *File) Close() error {
 
 	if f.writing {
 		// Upload the buffer to S3
-		_, err := f.fs.client.PutObject(f.fs.ctx, &s3.PutObjectInput{
-			Bucket: aws.String(f.fs.bucket),
-			Key:    aws.String(f.key),
-			Body:   bytes.NewReader(f.buffer),
-		})
+		size := f.size()
+		inline := f.fs.inlineThreshold > 0 && f.spill == nil && size <= f.fs.inlineThreshold
+		err := f.uploadBuffer()
+		f.fs.stats.invalidate(f.key)
 		if err != nil {
-			return wrapError("Close", f.name, err)
+			return f.fs.wrapError("Close", f.name, err)
+		}
+		f.fs.packs.forget(f.name)
+		if inline {
+			return f.fs.manifestPutInline(f.key, f.buffer)
 		}
+		return f.fs.manifestPut(f.key, size, false)
 	}
 
 	return nil
 }
 
-// Seek seeks within the file.
-// Note: This is a simplified implementation. For S3, seeking is limited and
-// io.SeekEnd is not supported as it would require knowing the file size.
+// Seek sets the offset for the next Read and returns the new offset.
+// Seeking does not issue a request by itself; if the offset changes, the
+// current response stream is discarded and the next Read fetches the object
+// from the new offset with a Range request. io.SeekEnd needs the object size,
+// which is looked up with a HeadObject request for read mode files.
 func (f *File) Seek(offset int64, whence int) (int64, error) {
-	// For S3, seeking is limited. This is a simplified implementation.
+	var abs int64
 	switch whence {
 	case io.SeekStart:
-		f.offset = offset
+		abs = offset
 	case io.SeekCurrent:
-		f.offset += offset
+		abs = f.offset + offset
 	case io.SeekEnd:
-		// Would need to know file size
-		return 0, ErrInvalidSeek
+		size, err := f.objectSize()
+		if err != nil {
+			return f.offset, err
+		}
+		abs = size + offset
+	default:
+		return f.offset, ErrInvalidSeek
+	}
+	if abs < 0 {
+		return f.offset, ErrInvalidSeek
+	}
+
+	if abs != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
 	}
-	return f.offset, nil
+	f.offset = abs
+	return abs, nil
+}
+
+// objectSize returns the size of the file: the buffer size in write mode and
+// the object size in read mode.
+func (f *File) objectSize() (int64, error) {
+	if f.writing {
+		return f.size(), nil
+	}
+	if f.packed != nil {
+		return f.packed.Size, nil
+	}
+	if f.stat != nil && !f.stat.IsDir() {
+		return f.stat.Size(), nil
+	}
+
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(f.fs.bucket),
+		Key:    aws.String(f.key),
+	}
+	if f.version != "" {
+		input.VersionId = aws.String(f.version)
+	}
+	output, err := f.fs.client.HeadObject(f.fs.ctx, input)
+	if err != nil {
+		return 0, f.fs.wrapError("Seek", f.name, err)
+	}
+	return aws.ToInt64(output.ContentLength), nil
 }
 
 // Stat returns file info.
@@ -179,6 +433,17 @@ func (f *File) Truncate(size int64) error {
 		return ErrWriteOnReadFile
 	}
 
+	if err := f.maybeSpill(size); err != nil {
+		return err
+	}
+	if f.spill != nil {
+		if err := f.spill.Truncate(size); err != nil {
+			return f.fs.wrapError("Truncate", f.name, err)
+		}
+		f.spillSize = size
+		return nil
+	}
+
 	if size < int64(len(f.buffer)) {
 		f.buffer = f.buffer[:size]
 	} else {
@@ -189,42 +454,143 @@ func (f *File) Truncate(size int64) error {
 	return nil
 }
 
-// Readdir reads directory entries (lists objects with prefix).
+// Readdir reads the direct entries of a directory, with base names.
 // In S3, "directories" are represented by objects with keys that have the directory
-// as a prefix. If n > 0, at most n entries are returned.
+// as a prefix; subdirectories are reported from the common prefixes of a
+// delimiter listing. It follows the os.File.Readdir contract: if n > 0, at most n entries
+// are returned and successive calls continue where the previous one stopped,
+// returning io.EOF once the directory is exhausted. If n <= 0, all remaining
+// entries are returned in a single slice with a nil error.
+//
+// Entries are sorted by name, directories and files interleaved, so the
+// first call lists the whole directory. With Config.UnsortedReaddir they are
+// returned in listing order instead, one page of the listing at a time.
 func (f *File) Readdir(n int) ([]os.FileInfo, error) {
+	if f.dir == nil {
+		f.dir = &dirReader{}
+		if f.fs.manifests {
+			infos, err := f.fs.manifestReaddir(f.key)
+			if err != nil {
+				f.dir = nil
+				return nil, err
+			}
+			if infos != nil {
+				f.dir.pending = infos
+				f.dir.done = true
+			}
+		}
+		// Packed files take precedence over objects with the same name
+		for _, info := range f.fs.packedInfos(f.name) {
+			if f.dir.packed == nil {
+				f.dir.packed = make(map[string]bool)
+			}
+			f.dir.packed[info.Name()] = true
+			f.dir.pending = append(f.dir.pending, info)
+		}
+		if !f.fs.unsortedReaddir {
+			for !f.dir.done {
+				if err := f.readdirPage(); err != nil {
+					f.dir = nil
+					return nil, err
+				}
+			}
+			sort.SliceStable(f.dir.pending, func(i, j int) bool {
+				return f.dir.pending[i].Name() < f.dir.pending[j].Name()
+			})
+		}
+	}
+
+	for !f.dir.done && (n <= 0 || len(f.dir.pending) < n) {
+		if err := f.readdirPage(); err != nil {
+			return nil, err
+		}
+	}
+
+	if n <= 0 {
+		infos := f.dir.pending
+		f.dir.pending = nil
+		if infos == nil {
+			infos = []os.FileInfo{}
+		}
+		return infos, nil
+	}
+
+	if len(f.dir.pending) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(f.dir.pending) {
+		n = len(f.dir.pending)
+	}
+	infos := f.dir.pending[:n:n]
+	f.dir.pending = f.dir.pending[n:]
+	return infos, nil
+}
+
+// dirReader tracks the progress of successive Readdir calls.
+type dirReader struct {
+	pending []os.FileInfo
+	token   *string
+	done    bool
+	packed  map[string]bool // Names of entries from loaded packs
+}
+
+// readdirPage fetches the next page of the directory listing into f.dir.
+// The listing uses the delimiter "/", so files come from the objects of the
+// page and subdirectories from its common prefixes, all with base names.
+func (f *File) readdirPage() error {
 	prefix := f.key
-	if !strings.HasSuffix(prefix, "/") {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
 		prefix += "/"
 	}
 
 	output, err := f.fs.client.ListObjectsV2(f.fs.ctx, &s3.ListObjectsV2Input{
-		Bucket: aws.String(f.fs.bucket),
-		Prefix: aws.String(prefix),
+		Bucket:            aws.String(f.fs.bucket),
+		Prefix:            aws.String(prefix),
+		Delimiter:         aws.String("/"),
+		ContinuationToken: f.dir.token,
 	})
 	if err != nil {
-		return nil, wrapError("Readdir", f.name, err)
+		return f.fs.wrapError("Readdir", f.name, err)
 	}
 
-	var infos []os.FileInfo
 	for _, obj := range output.Contents {
-		infos = append(infos, &fileInfo{
-			name:    aws.ToString(obj.Key),
-			size:    *obj.Size,
-			modTime: *obj.LastModified,
-			isDir:   strings.HasSuffix(aws.ToString(obj.Key), "/"),
-		})
-
-		if n > 0 && len(infos) >= n {
-			break
+		key := aws.ToString(obj.Key)
+		if key == prefix || f.fs.isSystemKey(key) {
+			continue // marker of the directory itself
+		}
+		name, err := f.fs.logicalName(key)
+		if err != nil {
+			return f.fs.wrapError("Readdir", f.name, err)
+		}
+		if f.dir.packed[path.Base(name)] {
+			continue
 		}
+		f.dir.pending = append(f.dir.pending, f.fs.listedInfo(path.Base(name), false, obj))
+	}
+	for _, cp := range output.CommonPrefixes {
+		dirKey := aws.ToString(cp.Prefix)
+		if f.fs.isSystemKey(dirKey) {
+			continue
+		}
+		name, err := f.fs.logicalName(dirKey)
+		if err != nil {
+			return f.fs.wrapError("Readdir", f.name, err)
+		}
+		base := path.Base(strings.TrimSuffix(name, "/"))
+		if f.dir.packed[base] {
+			continue
+		}
+		f.dir.pending = append(f.dir.pending, &fileInfo{name: base, isDir: true})
 	}
 
-	return infos, nil
+	f.dir.token = output.NextContinuationToken
+	f.dir.done = !aws.ToBool(output.IsTruncated)
+	return nil
 }
 
 // Readdirnames reads directory entry names.
-// It returns the names of up to n entries in the directory.
+// It returns the names of up to n entries in the directory, following the
+// same contract as Readdir.
 func (f *File) Readdirnames(n int) ([]string, error) {
 	infos, err := f.Readdir(n)
 	if err != nil {
@@ -237,3 +603,19 @@ func (f *File) Readdirnames(n int) ([]string, error) {
 	}
 	return names, nil
 }
+
+// ReadDir reads directory entries and returns them as fs.DirEntry values,
+// following the same contract as Readdir. Together with Stat, Read and Close
+// this makes File an fs.ReadDirFile.
+func (f *File) ReadDir(n int) ([]iofs.DirEntry, error) {
+	infos, err := f.Readdir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]iofs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = iofs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
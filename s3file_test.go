@@ -0,0 +1,270 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/iotest"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// resettingClient returns GetObject bodies that fail with a connection reset
+// after every chunk bytes, for the first failures requests.
+type resettingClient struct {
+	*s3fstest.Client
+	chunk    int
+	failures int
+	ranges   []string
+}
+
+func (c *resettingClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.ranges = append(c.ranges, aws.ToString(params.Range))
+	output, err := c.Client.GetObject(ctx, params, optFns...)
+	if err != nil || c.failures == 0 {
+		return output, err
+	}
+	c.failures--
+	output.Body = io.NopCloser(io.MultiReader(
+		io.LimitReader(output.Body, int64(c.chunk)),
+		iotest.ErrReader(syscall.ECONNRESET),
+	))
+	return output, nil
+}
+
+func TestFileSystem_ReadResumes(t *testing.T) {
+	client := &resettingClient{Client: s3fstest.NewClient(), chunk: 4}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "file.txt", "0123456789")
+
+	client.failures = 2
+	data, err := fs.ReadFile("file.txt")
+	if err != nil || string(data) != "0123456789" {
+		t.Fatalf("ReadFile() with resets = %q, %v; want 0123456789", data, err)
+	}
+	if want := []string{"", "bytes=4-", "bytes=8-"}; strings.Join(client.ranges, ",") != strings.Join(want, ",") {
+		t.Errorf("GetObject ranges = %q, want %q", client.ranges, want)
+	}
+
+	// Resuming does not mix content from a replaced object
+	client.failures = 1
+	f, err := fs.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "file.txt", "abcdefghij")
+	if _, err := io.ReadAll(f); err == nil {
+		t.Error("ReadAll() after the object was replaced succeeded, want a precondition error")
+	}
+
+	// The resumes in a row are bounded
+	client.failures = s3fs.DefaultReadRetries + 1
+	client.chunk = 0
+	if _, err := fs.ReadFile("file.txt"); !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("ReadFile() with persistent resets error = %v, want ECONNRESET", err)
+	}
+}
+
+// reversingClient returns the objects of each listing page in reverse order.
+type reversingClient struct {
+	*s3fstest.Client
+}
+
+func (c *reversingClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out, err := c.Client.ListObjectsV2(ctx, params, optFns...)
+	if err == nil {
+		for i, j := 0, len(out.Contents)-1; i < j; i, j = i+1, j-1 {
+			out.Contents[i], out.Contents[j] = out.Contents[j], out.Contents[i]
+		}
+	}
+	return out, err
+}
+
+func TestFile_ReaddirSorted(t *testing.T) {
+	client := &reversingClient{Client: s3fstest.NewClient()}
+	readdir := func(unsorted bool) []string {
+		t.Helper()
+		fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, UnsortedReaddir: unsorted})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"dir/b.txt", "dir/a.txt", "dir/c/d.txt"} {
+			if err := fs.WriteFile(name, []byte("x"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, err := fs.Open("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var names []string
+		for {
+			batch, err := f.Readdirnames(1)
+			if err == io.EOF {
+				return names
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, batch...)
+		}
+	}
+
+	if names := readdir(false); strings.Join(names, " ") != "a.txt b.txt c" {
+		t.Errorf("Readdirnames() = %v, want [a.txt b.txt c]", names)
+	}
+	if names := readdir(true); sort.StringsAreSorted(names) {
+		t.Errorf("Readdirnames() with UnsortedReaddir = %v, want listing order", names)
+	}
+}
+
+func TestFile_ReaddirDirMarkers(t *testing.T) {
+	client := s3fstest.NewClient()
+	list := func(show bool) ([]os.FileInfo, map[string]os.FileInfo) {
+		t.Helper()
+		fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, ShowDirMarkers: show})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.MkdirAll("dir/empty", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.WriteFile("dir/a.txt", []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := fs.Open("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		infos, err := f.Readdir(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walked := map[string]os.FileInfo{}
+		err = fs.Walk("dir", func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			walked[path] = info
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return infos, walked
+	}
+
+	infos, walked := list(false)
+	if len(infos) != 2 {
+		t.Fatalf("Readdir() = %d entries, want 2", len(infos))
+	}
+	for _, info := range infos {
+		if info.Name() == "dir/" {
+			t.Errorf("Readdir() returned the marker of the directory itself")
+		}
+		if info.IsDir() && info.Sys() != nil {
+			t.Errorf("Readdir() entry %q carries marker details %v", info.Name(), info.Sys())
+		}
+	}
+	if info, ok := walked["dir/empty/"]; !ok || !info.IsDir() || info.Sys() != nil {
+		t.Errorf("Walk() entry for dir/empty/ = %v, want a plain directory", info)
+	}
+
+	infos, walked = list(true)
+	if len(infos) != 2 {
+		t.Errorf("Readdir() with ShowDirMarkers = %d entries, want 2", len(infos))
+	}
+	if info, ok := walked["dir/empty/"]; !ok || info.Sys() == nil {
+		t.Errorf("Walk() entry for dir/empty/ with ShowDirMarkers = %v, want marker details", info)
+	}
+}
+
+func TestFile_Readdir_Contract(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	for _, name := range []string{"dir/a", "dir/b", "dir/c"} {
+		writeFile(t, fs, name, "x")
+	}
+	open := func() *s3fs.File {
+		t.Helper()
+		f, err := fs.Open("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f.(*s3fs.File)
+	}
+
+	f := open()
+	infos, err := f.Readdir(2)
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("Readdir(2) = %d entries, %v", len(infos), err)
+	}
+	infos, err = f.Readdir(2)
+	if err != nil || len(infos) != 1 || infos[0].Name() != "c" {
+		t.Fatalf("second Readdir(2) = %d entries, %v", len(infos), err)
+	}
+	if _, err = f.Readdir(2); err != io.EOF {
+		t.Errorf("Readdir(2) after exhaustion error = %v, want io.EOF", err)
+	}
+
+	f = open()
+	infos, err = f.Readdir(0)
+	if err != nil || len(infos) != 3 {
+		t.Fatalf("Readdir(0) = %d entries, %v", len(infos), err)
+	}
+	infos, err = f.Readdir(-1)
+	if err != nil || infos == nil || len(infos) != 0 {
+		t.Errorf("Readdir(-1) after exhaustion = %v, %v, want empty slice and nil", infos, err)
+	}
+}
+
+func TestFile_ReadDir(t *testing.T) {
+	var _ iofs.ReadDirFile = (*s3fs.File)(nil)
+
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "dir/a.txt", "abc")
+	writeFile(t, fs, "dir/sub/b.txt", "x")
+	writeFile(t, fs, "dir/sub/deeper/c.txt", "x")
+	f, err := fs.Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	entries, err := f.(*s3fs.File).ReadDir(-1)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ReadDir(-1) = %d entries, %v, want 2", len(entries), err)
+	}
+	if entries[0].Name() != "a.txt" || entries[0].IsDir() {
+		t.Errorf("entries[0] = %q dir=%v, want file a.txt", entries[0].Name(), entries[0].IsDir())
+	}
+	if entries[1].Name() != "sub" || !entries[1].IsDir() {
+		t.Errorf("entries[1] = %q dir=%v, want directory sub", entries[1].Name(), entries[1].IsDir())
+	}
+	if info, err := entries[0].Info(); err != nil || info.Size() != 3 {
+		t.Errorf("entries[0].Info() = %v, %v", info, err)
+	}
+
+	if _, err := f.(*s3fs.File).ReadDir(1); err != io.EOF {
+		t.Errorf("ReadDir(1) after exhaustion error = %v, want io.EOF", err)
+	}
+}
@@ -4,6 +4,11 @@ package s3fs
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"net/http"
 	"os"
 	"path"
 	"strings"
@@ -17,70 +22,603 @@ import (
 
 // FileSystem implements absfs.Filer for S3 object storage.
 type FileSystem struct {
-	client *s3.Client
+	client Client
 	bucket string
 	ctx    context.Context
+	packs  *packTable
+
+	manifests       bool
+	inlineThreshold int64
+	manifestCache   *manifestCache
+
+	mirrors      []string
+	mirrorFirst  bool
+	mirrorClient *http.Client
+
+	downloadPartSize    int64
+	downloadConcurrency int
+	prefetch            int
+	readAtCacheBlocks   int
+	readAtBlockSize     int64
+
+	pathErrors bool
+
+	stats     *statCache
+	dirs      *statCache
+	heads     *flightGroup
+	readCache *readCache
+
+	spillThreshold int64
+	spillDir       string
+
+	implicitDirs    bool
+	unsortedReaddir bool
+	showDirMarkers  bool
+	systemPrefix    string
+	inodes          *inodeTable // nil unless Config.Inodes is set
+
+	codec NameCodec
+	names *nameTable
+
+	normalizer Normalizer
+	aliases    *aliasTable
+
+	strictPaths     bool
+	strictSemantics bool
+	lazyOpen        bool
+	followSymlinks  bool
+	trash           string // Key prefix of the trash, empty if disabled
+	posixMetadata   bool
+	readRetries     int
+
+	metrics  *metrics
+	pricing  Pricing
+	shutdown *shutdown
+
+	root string // Logical name prefix of a Sub filesystem, with trailing slash
 }
 
 // Config contains the configuration for connecting to S3.
 type Config struct {
-	Bucket string      // S3 bucket name
+	Bucket string      // S3 bucket name, or access point or Multi-Region Access Point ARN
 	Region string      // AWS region
 	Config *aws.Config // Optional AWS config (if nil, uses default config loading)
+
+	// DetectRegion makes New ask S3 for the region of the bucket with
+	// GetBucketLocation and use it instead of Region, avoiding
+	// PermanentRedirect errors when Region does not match the bucket. It
+	// needs the s3:GetBucketLocation permission and costs one request.
+	DetectRegion bool
+
+	// Probe makes New check the bucket before returning: HeadBucket must
+	// find it and a one-key listing must succeed, so a wrong bucket,
+	// region, endpoint or missing permissions fail New with a *ProbeError
+	// instead of failing the first operation later. ProbeWrite also writes
+	// and deletes an empty object under a NewTempKey key to check write
+	// permissions, and implies Probe.
+	Probe      bool
+	ProbeWrite bool
+
+	// Profile selects a profile of the shared config and credentials files,
+	// like AWS_PROFILE, and SharedConfigFiles and SharedCredentialsFiles
+	// replace the default ~/.aws/config and ~/.aws/credentials. They apply
+	// to the default config loading, not to Config.
+	Profile                string
+	SharedConfigFiles      []string
+	SharedCredentialsFiles []string
+
+	// Client, if set, is used instead of a client created from Config,
+	// Region, Endpoint and UsePathStyle, for example the in-memory fake of
+	// package s3fstest. Presigning requires an *s3.Client.
+	Client Client
+
+	// Endpoint overrides the S3 endpoint URL, for S3-compatible services
+	// such as MinIO ("http://localhost:9000"). Most of them also need
+	// UsePathStyle.
+	Endpoint     string
+	UsePathStyle bool // Address buckets as endpoint/bucket instead of bucket.endpoint
+
+	// HTTPClient, if set, sends the requests of the client New creates.
+	// Otherwise ProxyURL, CABundle and TLSConfig, if any is set, configure
+	// a copy of http.DefaultTransport: ProxyURL replaces the proxy taken
+	// from the HTTPS_PROXY environment variables, CABundle adds PEM encoded
+	// certificates to the system roots, as needed behind TLS-intercepting
+	// proxies and for private endpoints, and TLSConfig sets other TLS
+	// options such as client certificates.
+	HTTPClient *http.Client
+	ProxyURL   string
+	CABundle   []byte
+	TLSConfig  *tls.Config
+
+	// Credentials, if set, provides the credentials of the client New
+	// creates instead of the default credential chain, for example
+	// credentials.NewStaticCredentialsProvider or a per-tenant provider.
+	Credentials aws.CredentialsProvider
+
+	// RoleARN makes the client assume an IAM role: with AssumeRole, signed
+	// with Credentials or the default chain and passing ExternalID if set,
+	// or with AssumeRoleWithWebIdentity using the token in
+	// WebIdentityTokenFile if set. RoleSessionName defaults to
+	// DefaultRoleSessionName. The temporary credentials are cached and
+	// refreshed before they expire. Like Credentials, they only apply to
+	// the client New creates, not to Client.
+	RoleARN              string
+	ExternalID           string
+	RoleSessionName      string
+	WebIdentityTokenFile string
+
+	// Manifests enables per-directory manifest objects that are updated on
+	// write and delete, so Readdir and Stat can be answered with a single GET.
+	// Only enable it for prefixes that are modified exclusively through s3fs.
+	Manifests bool
+
+	// InlineThreshold copies the content of files up to this many bytes into
+	// their directory manifest when Manifests is enabled, so reads of small,
+	// frequently read files are served from the manifest. The object itself
+	// is still written. With StatCacheTTL set, loaded manifests are cached
+	// for that long, letting a single GET serve a whole directory.
+	InlineThreshold int64
+
+	// Mirrors lists read-only HTTP(S) mirrors of the bucket. Reads of whole
+	// objects are served from the first mirror that has the key, either
+	// before S3 (MirrorFirst) or as a fallback when the S3 GET fails.
+	// Mirror content is verified against the S3 ETag when it is an MD5 digest.
+	// Writes always go to S3.
+	Mirrors      []string
+	MirrorFirst  bool         // Try mirrors before S3 instead of after
+	MirrorClient *http.Client // HTTP client for mirrors (default http.DefaultClient)
+
+	DownloadPartSize    int64 // Range size used by Download (default DefaultPartSize)
+	DownloadConcurrency int   // Parallel ranges used by Download (default DefaultDownloadConcurrency)
+
+	// Prefetch makes Read of read mode files fetch the object in ranges of
+	// DownloadPartSize bytes, requesting up to Prefetch ranges ahead of the
+	// one being read in the background. This speeds up sequential reads
+	// over high-latency links at the cost of up to Prefetch+1 ranges of
+	// memory per open file. Ranged reads are not verified against
+	// ChecksumAlgorithm checksums. Zero disables prefetching.
+	Prefetch int
+
+	// ReadAtCacheBlocks enables a cache of the most recently used blocks of
+	// ReadAtBlockSize bytes (default DefaultReadAtBlockSize) per read mode
+	// file. ReadAt fetches the blocks it needs, missing adjacent blocks
+	// with a single request, and serves later calls in the same blocks
+	// from memory, which helps readers such as archive/zip that issue many
+	// small ReadAt calls. Zero disables the cache.
+	ReadAtCacheBlocks int
+	ReadAtBlockSize   int64
+
+	// PathErrors wraps every returned S3Error in an *os.PathError with the
+	// lowercase operation names used by the os package ("open", "stat", ...).
+	PathErrors bool
+
+	// StatCacheTTL enables an in-process cache of Stat (and Exists) results
+	// that expire after the given duration. Entries are invalidated when the
+	// object is written, removed or renamed through this FileSystem, but
+	// changes made by other clients are only seen after expiry.
+	StatCacheTTL  time.Duration
+	StatCacheSize int // Maximum cached entries (default DefaultStatCacheSize)
+
+	// NegativeStatCacheTTL caches not-found results of Stat (and Exists)
+	// for the given duration, usually much shorter than StatCacheTTL, so
+	// repeated checks for missing files do not each cost a request. Writes
+	// through this FileSystem invalidate them, and InvalidateStat drops
+	// them on request. Concurrent Stats of the same file always share a
+	// single request.
+	NegativeStatCacheTTL time.Duration
+
+	// ReadCacheDir enables a local read-through cache of object content in
+	// the given directory. Cached objects are revalidated with a conditional
+	// GET (If-None-Match) on every open and only downloaded again if changed.
+	ReadCacheDir string
+
+	// DirCacheTTL controls how long MkdirAll remembers directories it has
+	// seen or created (default DefaultDirCacheTTL, negative disables).
+	// Directories removed with RemoveAll through this FileSystem are
+	// forgotten immediately.
+	DirCacheTTL time.Duration
+
+	// SpillThreshold moves the write buffer of a file to a temporary file on
+	// local disk once it grows beyond this many bytes, so files larger than
+	// available memory can be written (0 keeps everything in memory).
+	// Spilled files larger than DefaultPartSize are uploaded with a multipart
+	// upload on Close.
+	SpillThreshold int64
+	SpillDir       string // Directory for spill files (default os.TempDir())
+
+	// ImplicitDirs stops Mkdir and MkdirAll from writing directory marker
+	// objects. They only check that no file is in the way; Stat reports a
+	// directory for any name that is a prefix of existing keys either way.
+	ImplicitDirs bool
+
+	// UnsortedReaddir makes Readdir, Readdirnames and ReadDir of File
+	// return entries in listing order, one page of the listing at a time,
+	// instead of listing the whole directory on the first call and sorting
+	// the entries by name. It saves time and memory for large directories
+	// read in batches.
+	UnsortedReaddir bool
+
+	// ShowDirMarkers makes Walk report directory marker objects as they are
+	// stored, with their size, modification time and object details. By
+	// default their prefixes are reported as plain directories. Readdir
+	// never returns the marker of the listed directory itself.
+	ShowDirMarkers bool
+
+	// SystemPrefix is the key prefix reserved for internal objects such as
+	// journals, trash and locks (default DefaultSystemPrefix). Keys below it
+	// are hidden from Readdir and Walk.
+	SystemPrefix string
+
+	// Inodes assigns stable inode numbers to files and directories, for
+	// adapters such as FUSE and NFS servers that need file handles to
+	// survive renames and restarts. The numbers are kept in an index object
+	// below SystemPrefix, read on first use and saved by SyncInodes and
+	// Close; Stat and Lstat report them in ObjectInfo.Inode, see Inode.
+	Inodes bool
+
+	// NameCodec stores objects under encoded keys instead of their names,
+	// for example NewHMACCodec to keep personal data out of key names.
+	// Listings recover names from object metadata, which costs a HeadObject
+	// request per object not created or seen through this FileSystem.
+	// Manifests are disabled when a NameCodec is set. NewPercentCodec
+	// stores arbitrary local file names under keys S3 tools handle safely.
+	NameCodec NameCodec
+
+	// Pricing sets the prices Costs and WithBudget estimate with (default
+	// DefaultPricing).
+	Pricing *Pricing
+
+	// Normalizer maps names to one Unicode normalization form, such as
+	// norm.NFC from golang.org/x/text/unicode/norm, so a name matches the
+	// same object whether it was written in composed form (as by most tools)
+	// or decomposed (as by macOS). Names are normalized before they become
+	// keys, and listings report normalized names. Objects stored in another
+	// form are found by the listings they show up in; a Stat that misses
+	// lists the parent directory for them.
+	Normalizer Normalizer
+
+	// ValidateKeys makes writes fail with a *KeyError, before any request,
+	// when the object key breaks one of the constraints ValidateKey checks.
+	// Without it only keys longer than MaxKeyLength are refused up front.
+	ValidateKeys bool
+
+	// StrictPaths makes Stat and OpenFile (in read mode) fail with an
+	// *AmbiguousPathError when a name exists both as a file and as a
+	// directory, instead of resolving it to the file. It costs an extra
+	// listing request per call.
+	StrictPaths bool
+
+	// StrictSemantics makes the FileSystem report errors where the os
+	// package would, at the cost of extra requests: Mkdir fails with
+	// fs.ErrExist if the name exists, Remove fails with fs.ErrNotExist for
+	// missing names and with syscall.ENOTEMPTY for directories that are not
+	// empty (and removes empty ones), and OpenFile in read mode fails with
+	// fs.ErrNotExist right away even with LazyOpen.
+	StrictSemantics bool
+
+	// LazyOpen skips the Stat that OpenFile does in read mode, saving a
+	// HeadObject request per open for latency-sensitive readers. Opening a
+	// missing file then succeeds and the first Read fails instead, and Seek
+	// relative to io.SeekEnd costs a HeadObject request. StrictSemantics
+	// overrides it.
+	LazyOpen bool
+
+	// FollowSymlinks makes OpenFile (in read mode) and Walk follow symbolic
+	// links created with Symlink, as os.Open and filepath.Walk with -L would.
+	// Opening a file then costs a HeadObject request, and Walk a HeadObject
+	// request for each object small enough to be a link. Stat always
+	// follows links.
+	FollowSymlinks bool
+
+	// Trash makes Remove and RemoveAll move objects below TrashPrefix
+	// (default "trash/" below SystemPrefix) instead of deleting them. Trashed
+	// objects can be listed with ListTrash, put back with RestoreTrash and
+	// deleted for good with EmptyTrash. Moving an object costs a HeadObject,
+	// a CopyObject and a DeleteObject request.
+	Trash       bool
+	TrashPrefix string
+
+	// POSIXMetadata makes Chmod and Chown store the mode, owner and group
+	// in the metadata of the object (see ModeMetadataKey), so permissions
+	// survive a backup and restore through s3fs. Stat reports the stored
+	// mode, and ObjectInfo.Owner the stored ids; listings such as Readdir
+	// do not return metadata and report the default modes. Stat of a
+	// directory costs a HeadObject request for its marker object.
+	POSIXMetadata bool
+
+	// ChecksumAlgorithm enables S3 additional checksums: uploads carry a
+	// checksum of the given algorithm that S3 verifies, and whole-object
+	// downloads are verified against the stored checksum, failing with a
+	// *ChecksumError on mismatch. Objects uploaded without a checksum are
+	// read unverified.
+	ChecksumAlgorithm ChecksumAlgorithm
+
+	// Timeouts, if set, bounds every S3 request by its class: metadata
+	// requests, small transfers and large transfers have separate timeouts,
+	// so a hung multi-gigabyte download does not share the timeout of a
+	// Stat. Nil means no timeouts besides those of the contexts of
+	// operations and the HTTP client.
+	Timeouts *Timeouts
+
+	// RetryMaxAttempts, RetryMaxBackoff and RetryAdaptive configure how the
+	// SDK retries throttled and failed requests: the maximum number of
+	// attempts per request including the first, the maximum delay between
+	// attempts, and adaptive mode, which also rate limits requests on the
+	// client while S3 throttles them. Zero values keep the SDK defaults.
+	// They only apply to the client New creates, not to Config.Client.
+	RetryMaxAttempts int
+	RetryMaxBackoff  time.Duration
+	RetryAdaptive    bool
+
+	// ReadRetries is the number of times in a row Read resumes a download
+	// whose response stream failed mid-way, such as on a connection reset,
+	// with a Range request from the last offset (default
+	// DefaultReadRetries, negative disables). Resumed downloads are not
+	// verified against ChecksumAlgorithm checksums.
+	ReadRetries int
+
+	// BreakerThreshold enables a circuit breaker: after this many
+	// consecutive requests failed with throttling or server errors, requests
+	// fail immediately with ErrCircuitOpen for BreakerCooldown (default
+	// DefaultBreakerCooldown), giving S3 time to recover. The first request
+	// after the cooldown probes whether it has.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// MaxConcurrentRequests caps the number of S3 requests in flight, and
+	// MaxRequestsPerSecond the rate at which requests start, for all
+	// operations of the FileSystem and those derived from it with
+	// WithContext and Sub. Requests beyond the limits wait, so bulk
+	// operations such as Walk, RemoveAll and SyncUp stay below S3's
+	// throttling thresholds and local socket limits. A GetObject request
+	// holds its slot until the body is closed. Zero means no limit.
+	MaxConcurrentRequests int
+	MaxRequestsPerSecond  float64
 }
 
 // New creates a new S3 filesystem with the given configuration.
 func New(cfg *Config) (*FileSystem, error) {
 	ctx := context.Background()
 
-	var awsConfig aws.Config
-	var err error
-
-	if cfg.Config != nil {
-		awsConfig = *cfg.Config
-	} else {
-		// Load default AWS config
-		awsConfig, err = config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	client := cfg.Client
+	if client == nil {
+		var awsConfig aws.Config
+		if cfg.Config != nil {
+			awsConfig = *cfg.Config
+		} else {
+			// Load default AWS config
+			var err error
+			awsConfig, err = config.LoadDefaultConfig(ctx, cfg.loadOptions()...)
+			if err != nil {
+				return nil, err
+			}
+		}
+		httpClient, err := cfg.httpClient()
 		if err != nil {
 			return nil, err
 		}
+		if httpClient != nil {
+			awsConfig.HTTPClient = httpClient
+		}
+		cfg.applyCredentials(&awsConfig)
+
+		options := func(o *s3.Options) {
+			if cfg.Endpoint != "" {
+				o.BaseEndpoint = aws.String(cfg.Endpoint)
+			}
+			o.UsePathStyle = cfg.UsePathStyle
+			if r := cfg.retryer(); r != nil {
+				o.Retryer = r
+			}
+		}
+		s3Client := s3.NewFromConfig(awsConfig, options)
+		if cfg.DetectRegion {
+			region, err := bucketRegion(ctx, s3Client, cfg.Bucket)
+			if err != nil {
+				return nil, err
+			}
+			if region != "" && region != awsConfig.Region {
+				awsConfig.Region = region
+				s3Client = s3.NewFromConfig(awsConfig, options)
+			}
+		}
+		client = s3Client
+	}
+
+	readCache, err := newReadCache(cfg.ReadCacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirCacheTTL := cfg.DirCacheTTL
+	if dirCacheTTL == 0 {
+		dirCacheTTL = DefaultDirCacheTTL
+	}
+
+	readRetries := cfg.ReadRetries
+	if readRetries == 0 {
+		readRetries = DefaultReadRetries
+	}
+
+	mirrorClient := cfg.MirrorClient
+	if mirrorClient == nil {
+		mirrorClient = http.DefaultClient
+	}
+
+	switch cfg.ChecksumAlgorithm {
+	case "":
+	case ChecksumCRC32C, ChecksumSHA256:
+		client = &checksumClient{Client: client, algorithm: cfg.ChecksumAlgorithm}
+	default:
+		return nil, ErrInvalidChecksumAlgorithm
+	}
+
+	if cfg.Timeouts != nil {
+		client = &timeoutClient{Client: client, t: cfg.Timeouts.withDefaults()}
+	}
+
+	m := newMetrics()
+	client = &metricsClient{Client: client, m: m}
+	// Outside the metrics, so rejected and waiting calls are not counted
+	// as requests
+	guarded := &guardedClient{Client: client, l: newLimiter(cfg.MaxConcurrentRequests, cfg.MaxRequestsPerSecond)}
+	if cfg.BreakerThreshold > 0 {
+		guarded.b = newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
 	}
+	if guarded.b != nil || guarded.l != nil {
+		client = guarded
+	}
+
+	// Outermost, so rejected writes make no request at all
+	client = &keyCheckClient{Client: client, strict: cfg.ValidateKeys}
+
+	pricing := DefaultPricing
+	if cfg.Pricing != nil {
+		pricing = *cfg.Pricing
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	fs := &FileSystem{
+		client:   client,
+		metrics:  m,
+		pricing:  pricing,
+		shutdown: newShutdown(cancel),
+		bucket:   cfg.Bucket,
+		ctx:      ctx,
+		packs:    newPackTable(),
+
+		manifests:       cfg.Manifests && cfg.NameCodec == nil,
+		inlineThreshold: cfg.InlineThreshold,
+		manifestCache:   newManifestCache(cfg.StatCacheTTL),
+
+		mirrors:      cfg.Mirrors,
+		mirrorFirst:  cfg.MirrorFirst,
+		mirrorClient: mirrorClient,
+
+		downloadPartSize:    cfg.DownloadPartSize,
+		downloadConcurrency: cfg.DownloadConcurrency,
+		prefetch:            cfg.Prefetch,
+		readAtCacheBlocks:   cfg.ReadAtCacheBlocks,
+		readAtBlockSize:     cfg.ReadAtBlockSize,
+
+		pathErrors: cfg.PathErrors,
+
+		stats:     newNegativeStatCache(cfg.StatCacheTTL, cfg.NegativeStatCacheTTL, cfg.StatCacheSize),
+		heads:     newFlightGroup(),
+		dirs:      newStatCache(dirCacheTTL, 0),
+		readCache: readCache,
+
+		spillThreshold: cfg.SpillThreshold,
+		spillDir:       cfg.SpillDir,
+
+		implicitDirs:    cfg.ImplicitDirs,
+		unsortedReaddir: cfg.UnsortedReaddir,
+		showDirMarkers:  cfg.ShowDirMarkers,
+		systemPrefix:    systemPrefix(cfg.SystemPrefix),
+		inodes:          newInodeTable(cfg.Inodes),
+
+		codec: cfg.NameCodec,
+		names: &nameTable{},
 
-	client := s3.NewFromConfig(awsConfig)
+		normalizer: cfg.Normalizer,
+		aliases:    &aliasTable{},
 
-	return &FileSystem{
-		client: client,
-		bucket: cfg.Bucket,
-		ctx:    ctx,
-	}, nil
+		strictPaths:     cfg.StrictPaths,
+		strictSemantics: cfg.StrictSemantics,
+		lazyOpen:        cfg.LazyOpen,
+		followSymlinks:  cfg.FollowSymlinks,
+		trash:           trashPrefix(cfg),
+		posixMetadata:   cfg.POSIXMetadata,
+		readRetries:     readRetries,
+	}
+	if cfg.Probe || cfg.ProbeWrite {
+		if err := fs.probe(cfg.ProbeWrite); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	return fs, nil
 }
 
 // OpenFile opens a file in S3.
 // Note: S3 doesn't support traditional file flags, so this is a simplified implementation.
-// Files opened with O_WRONLY, O_RDWR, or O_CREATE are opened in write mode and buffer
-// data in memory until Close(). Files opened with O_RDONLY are opened in read mode and
-// stream data from S3.
+// Files opened with O_WRONLY or O_RDWR are opened in write mode and buffer
+// data in memory until Close(). O_CREATE, O_EXCL, O_TRUNC and O_APPEND have
+// the meaning of os.OpenFile; without O_TRUNC the existing content is
+// preloaded into the buffer. Files opened with O_RDONLY are opened in read
+// mode and stream data from S3. Opening a missing file for reading fails
+// with fs.ErrNotExist, unless Config.LazyOpen is set.
+//
+// Since a file is either read or written, O_RDWR is only supported together
+// with O_TRUNC, and combinations that s3fs cannot honor fail with a
+// *FlagError. See OpenMode for the recommended flag combinations.
 func (fs *FileSystem) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
 	name = strings.TrimPrefix(name, "/")
+	if err := checkFlag(flag); err != nil {
+		return nil, fs.wrapError("OpenFile", name, err)
+	}
 
 	// For write operations
-	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE) != 0 {
-		return &File{
+	if flag&accessModes != os.O_RDONLY {
+		f := &File{
 			fs:      fs,
 			name:    name,
-			key:     name,
+			key:     fs.objectKey(name),
 			writing: true,
 			buffer:  []byte{},
+		}
+		if err := f.openWrite(flag); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	// Files from loaded packs are read from their pack object
+	if e, ok := fs.packs.lookup(name); ok {
+		return &File{
+			fs:      fs,
+			name:    name,
+			key:     e.Pack,
+			writing: false,
+			packed:  &e,
 		}, nil
 	}
 
-	// For read operations, get the object
-	return &File{
+	key := fs.objectKey(name)
+	if fs.followSymlinks {
+		target, err := fs.resolveLinks("OpenFile", name)
+		if err != nil {
+			return nil, err
+		}
+		key = fs.objectKey(target)
+	}
+
+	// For read operations, check that the object exists
+	f := &File{
 		fs:      fs,
 		name:    name,
-		key:     name,
+		key:     key,
 		writing: false,
-	}, nil
+	}
+	if fs.readAtCacheBlocks > 0 {
+		f.blocks = newBlockCache(fs.readAtBlockSize, fs.readAtCacheBlocks)
+	}
+	check := !fs.lazyOpen || fs.strictSemantics
+	if check || fs.strictPaths {
+		var ambiguous *AmbiguousPathError
+		info, err := fs.Stat(name)
+		switch {
+		case errors.As(err, &ambiguous):
+			return nil, fs.wrapError("OpenFile", name, ambiguous)
+		case err != nil && check:
+			if errors.Is(err, iofs.ErrNotExist) {
+				return nil, fs.wrapError("OpenFile", name, ErrNotExist)
+			}
+			return nil, err
+		}
+		f.stat = info
+	}
+	return f, nil
 }
 
 // Mkdir creates a "directory" in S3 (creates a zero-byte object with trailing slash).
@@ -92,86 +630,287 @@ func (fs *FileSystem) Mkdir(name string, perm os.FileMode) error {
 		name += "/"
 	}
 
-	_, err := fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
-		Bucket: aws.String(fs.bucket),
-		Key:    aws.String(name),
-		Body:   strings.NewReader(""),
+	key := fs.objectKey(name)
+
+	if fs.strictSemantics {
+		if err := fs.checkMkdirStrict(name); err != nil {
+			return err
+		}
+	}
+	if fs.implicitDirs {
+		return fs.checkImplicitDir(name, key)
+	}
+
+	metadata, err := fs.nameMetadata(name)
+	if err != nil {
+		return fs.wrapError("Mkdir", name, err)
+	}
+	_, err = fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:   aws.String(fs.bucket),
+		Key:      aws.String(key),
+		Body:     strings.NewReader(""),
+		Metadata: metadata,
 	})
 	if err != nil {
-		return wrapError("Mkdir", name, err)
+		return fs.wrapError("Mkdir", name, err)
+	}
+	fs.stats.invalidate(key)
+	fs.dirs.put(key, nil)
+
+	if fs.manifests {
+		m, err := fs.loadManifest(key)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			if err := fs.saveManifest(key, &manifest{Entries: map[string]manifestEntry{}}); err != nil {
+				return err
+			}
+		}
+		return fs.manifestPut(key, 0, true)
 	}
 	return nil
 }
 
 // Remove removes a file from S3.
-// This deletes the S3 object with the given key.
+// This deletes the S3 object with the given key. Like DeleteObject, it
+// succeeds if there is no such object, unless Config.StrictSemantics is set.
 func (fs *FileSystem) Remove(name string) error {
 	name = strings.TrimPrefix(name, "/")
+	var err error
+	if fs.strictSemantics {
+		err = fs.removeStrict(name)
+	} else {
+		err = fs.remove(name)
+	}
+	if _, ok := fs.packs.lookup(name); ok {
+		fs.packs.forget(name)
+		if errors.Is(err, ErrNotExist) {
+			err = nil // Only stored in a pack
+		}
+	}
+	if err == nil {
+		fs.forgetInode(name)
+	}
+	return err
+}
 
+// remove deletes or trashes the object stored for name.
+func (fs *FileSystem) remove(name string) error {
+	if fs.trash != "" {
+		return fs.trashKey(name, fs.objectKey(name))
+	}
+	return fs.removeKey(name, fs.objectKey(name))
+}
+
+// removeKey deletes the object stored under key for Remove.
+func (fs *FileSystem) removeKey(name, key string) error {
 	_, err := fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(fs.bucket),
-		Key:    aws.String(name),
+		Key:    aws.String(key),
 	})
+	fs.stats.invalidate(key)
+	fs.dirs.invalidate(key)
 	if err != nil {
-		return wrapError("Remove", name, err)
+		return fs.wrapError("Remove", name, err)
 	}
-	return nil
+	return fs.manifestDelete(key)
 }
 
 // Rename renames (moves) a file in S3 by copying and deleting.
 // Since S3 doesn't support atomic rename, this operation copies the object to the
 // new location and then deletes the original. This is not atomic and may fail
-// partway through.
+// partway through. Directories are moved with RenameDir.
+//
+// The copy keeps the metadata, tags, storage class and encryption settings
+// of the original; see RenameWithOptions to change them. It is checked
+// against the size of the original before the original is deleted, and
+// deleted again if the check or the deletion of the original fails, in
+// which case a file replaced at newpath is lost. A missing oldpath fails
+// with an error matching fs.ErrNotExist.
 func (fs *FileSystem) Rename(oldpath, newpath string) error {
+	return fs.RenameWithOptions(oldpath, newpath, nil)
+}
+
+// RenameWithOptions renames a file like Rename, with the attributes set in
+// opts (if not nil) replacing those of the original object. opts does not
+// apply to directories, which are moved with RenameDir.
+func (fs *FileSystem) RenameWithOptions(oldpath, newpath string, opts *CopyOptions) error {
+	return fs.rename(oldpath, newpath, opts, writeCondition{})
+}
+
+// RenameNoReplace renames a file like Rename, but fails with an error
+// matching fs.ErrExist instead of replacing an existing file at newpath, like
+// renameat2 with RENAME_NOREPLACE. For files the copy is conditional
+// (If-None-Match), so a file created concurrently at newpath is not
+// replaced either; the S3 service or S3-compatible store must support
+// conditional writes. Directories are only checked before they are moved.
+func (fs *FileSystem) RenameNoReplace(oldpath, newpath string) error {
+	return fs.rename(oldpath, newpath, nil, writeCondition{ifNoneMatch: "*"})
+}
+
+// rename implements Rename and its variants. cond applies to the copy of a
+// file; with If-None-Match an existing directory at newpath also fails.
+func (fs *FileSystem) rename(oldpath, newpath string, opts *CopyOptions, cond writeCondition) error {
 	oldpath = strings.TrimPrefix(oldpath, "/")
 	newpath = strings.TrimPrefix(newpath, "/")
+	if strings.HasSuffix(oldpath, "/") {
+		return fs.renameDir(oldpath, newpath, cond)
+	}
+	oldkey, newkey := fs.objectKey(oldpath), fs.objectKey(newpath)
+
+	defer fs.stats.invalidate(oldkey, newkey)
+	defer fs.dirs.invalidate(oldkey)
+
+	// Copy object to new location; the size decides between a single
+	// CopyObject and a multipart copy
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(oldkey),
+	})
+	if err != nil {
+		if httpStatus(err) == 404 {
+			// Nothing stored under oldpath itself: it may be a directory
+			isDir, derr := fs.isDirectory(oldkey + "/")
+			if derr != nil {
+				return fs.wrapError("Rename", oldpath, derr)
+			}
+			if isDir {
+				return fs.renameDir(oldpath, newpath, cond)
+			}
+			return fs.wrapError("Rename", oldpath, ErrNotExist)
+		}
+		return fs.wrapError("Rename", oldpath, err)
+	}
+	source := head.Metadata
+	if opts != nil && opts.Metadata != nil {
+		source = opts.Metadata
+	}
+	metadata, err := fs.copyMetadata(newpath, source)
+	if err != nil {
+		return fs.wrapError("Rename", oldpath, err)
+	}
+	if err := fs.copyKeyIf(fs.ctx, oldkey, newkey, head, metadata, opts, cond); err != nil {
+		if err := preconditionError(err); errors.Is(err, ErrPreconditionFailed) && cond.ifNoneMatch != "" {
+			return fs.wrapError("Rename", newpath, fmt.Errorf("%w: %w", os.ErrExist, err))
+		}
+		return fs.wrapError("Rename", oldpath, err)
+	}
 
-	// Copy object to new location
-	_, err := fs.client.CopyObject(fs.ctx, &s3.CopyObjectInput{
-		Bucket:     aws.String(fs.bucket),
-		CopySource: aws.String(path.Join(fs.bucket, oldpath)),
-		Key:        aws.String(newpath),
+	// Check the copy before giving up the original, and delete it again if
+	// the original cannot be deleted, so the file is not left in both places
+	rollback := func(err error) error {
+		fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(fs.bucket),
+			Key:    aws.String(newkey),
+		})
+		return fs.wrapError("Rename", oldpath, err)
+	}
+	copied, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(newkey),
 	})
 	if err != nil {
-		return wrapError("Rename", oldpath, err)
+		return rollback(err)
+	}
+	if aws.ToInt64(copied.ContentLength) != aws.ToInt64(head.ContentLength) {
+		return rollback(fmt.Errorf("%w: %s", ErrIncompleteCopy, newpath))
 	}
 
 	// Delete old object
 	_, err = fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(fs.bucket),
-		Key:    aws.String(oldpath),
+		Key:    aws.String(oldkey),
 	})
 	if err != nil {
-		return wrapError("Rename", oldpath, err)
+		return rollback(err)
 	}
-	return nil
+	fs.packs.forget(newpath)
+	fs.moveInode(oldpath, newpath)
+	return fs.manifestMove(oldkey, newkey)
 }
 
 // Stat returns file info for an S3 object.
 // It uses HeadObject to retrieve metadata without downloading the object content.
 func (fs *FileSystem) Stat(name string) (os.FileInfo, error) {
+	info, err := fs.stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.withInode(name, info)
+}
+
+// stat implements Stat.
+func (fs *FileSystem) stat(name string) (os.FileInfo, error) {
 	name = strings.TrimPrefix(name, "/")
 
-	output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(fs.bucket),
-		Key:    aws.String(name),
+	if e, ok := fs.packs.lookup(name); ok {
+		return &fileInfo{
+			name:    path.Base(name),
+			size:    e.Size,
+			modTime: e.ModTime,
+		}, nil
+	}
+
+	key := fs.objectKey(name)
+	if info, ok := fs.stats.get(key); ok {
+		if info == nil {
+			return nil, fs.wrapError("Stat", name, ErrNotExist)
+		}
+		return info, nil
+	}
+
+	if fs.manifests && !strings.HasSuffix(name, "/") {
+		if info, ok, err := fs.manifestStat(key); ok {
+			return info, err
+		}
+	}
+
+	// The bucket root always exists; the root of a Sub filesystem exists
+	// while anything is stored below it
+	if name == "" || fs.implicitDirs && strings.HasSuffix(name, "/") {
+		return fs.statImplicitDir(name, key, nil)
+	}
+
+	// Concurrent Stats of the same key share one request
+	output, err := fs.heads.do(fs.ctx, key, func(ctx context.Context) (*s3.HeadObjectOutput, error) {
+		return fs.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(fs.bucket),
+			Key:    aws.String(key),
+		})
 	})
 	if err != nil {
-		return nil, wrapError("Stat", name, err)
+		// Directories may exist without a marker object, as prefixes of keys
+		if httpStatus(err) == 404 {
+			// The name may be stored in another normalization form
+			if fs.normalizer != nil && fs.findAlias(name) && fs.objectKey(name) != key {
+				return fs.stat(name)
+			}
+			info, err := fs.statImplicitDir(name, key, err)
+			if errors.Is(err, iofs.ErrNotExist) {
+				fs.stats.putMissing(key)
+			}
+			return info, err
+		}
+		return nil, fs.wrapError("Stat", name, err)
+	}
+
+	if err := fs.checkAmbiguous("Stat", name, key); err != nil {
+		return nil, err
+	}
+	if isSymlink(output.Metadata) {
+		return fs.statLink(name)
 	}
 
-	return &fileInfo{
+	info := &fileInfo{
 		name:    path.Base(name),
 		size:    *output.ContentLength,
 		modTime: *output.LastModified,
 		isDir:   strings.HasSuffix(name, "/"),
-	}, nil
-}
-
-// Chmod is not supported for S3.
-// S3 doesn't have POSIX file permissions, so this always returns ErrNotImplemented.
-func (fs *FileSystem) Chmod(name string, mode os.FileMode) error {
-	return absfs.ErrNotImplemented
+		obj:     headObjectInfo(key, output),
+	}
+	fs.stats.put(key, info)
+	return info, nil
 }
 
 // Chtimes is not supported for S3.
@@ -181,23 +920,69 @@ func (fs *FileSystem) Chtimes(name string, atime time.Time, mtime time.Time) err
 	return absfs.ErrNotImplemented
 }
 
-// Chown is not supported for S3.
-// S3 doesn't have POSIX ownership, so this always returns ErrNotImplemented.
-func (fs *FileSystem) Chown(name string, uid, gid int) error {
-	return absfs.ErrNotImplemented
+// Separator returns '/', the separator used in S3 keys.
+func (fs *FileSystem) Separator() uint8 { return '/' }
+
+// ListSeparator returns ':', the separator of path lists.
+func (fs *FileSystem) ListSeparator() uint8 { return ':' }
+
+// Getwd returns "/". FileSystem has no working directory; all names are
+// resolved from the root. Wrap it with absfs.ExtendFiler for one.
+func (fs *FileSystem) Getwd() (string, error) { return "/", nil }
+
+// Chdir only accepts the root directory, see Getwd.
+func (fs *FileSystem) Chdir(dir string) error {
+	if path.Clean("/"+dir) != "/" {
+		return absfs.ErrNotImplemented
+	}
+	return nil
 }
 
+// TempDir returns "/tmp", the directory for temporary files in this
+// filesystem.
+func (fs *FileSystem) TempDir() string { return "/tmp" }
+
 // fileInfo implements os.FileInfo for S3 objects.
 type fileInfo struct {
 	name    string
 	size    int64
 	modTime time.Time
 	isDir   bool
+	link    bool        // Symbolic link, as reported by Lstat
+	obj     *ObjectInfo // S3 attributes, nil if unknown
 }
 
 func (fi *fileInfo) Name() string       { return fi.name }
 func (fi *fileInfo) Size() int64        { return fi.size }
-func (fi *fileInfo) Mode() os.FileMode  { return 0644 }
 func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
 func (fi *fileInfo) IsDir() bool        { return fi.isDir }
-func (fi *fileInfo) Sys() interface{}   { return nil }
+
+// Mode returns 0644 for files, os.ModeDir|0755 for directories and
+// os.ModeSymlink|0777 for symbolic links, with the permission bits replaced
+// by those set with Chmod, if known.
+func (fi *fileInfo) Mode() os.FileMode {
+	if fi.link {
+		return os.ModeSymlink | 0777
+	}
+	perm := os.FileMode(0644)
+	if fi.isDir {
+		perm = 0755
+	}
+	if fi.obj != nil {
+		if mode, ok := parseMode(fi.obj.Metadata[ModeMetadataKey]); ok {
+			perm = mode
+		}
+	}
+	if fi.isDir {
+		return os.ModeDir | perm
+	}
+	return perm
+}
+
+// Sys returns the *ObjectInfo of the object, or nil if it is not known.
+func (fi *fileInfo) Sys() interface{} {
+	if fi.obj == nil {
+		return nil
+	}
+	return fi.obj
+}
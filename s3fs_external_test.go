@@ -0,0 +1,199 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func writeFile(t *testing.T, fs *s3fs.FileSystem, name, data string) {
+	t.Helper()
+	f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile(%q) error = %v", name, err)
+	}
+	if _, err := f.Write([]byte(data)); err != nil {
+		t.Fatalf("Write(%q) error = %v", name, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close(%q) error = %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, fs *s3fs.FileSystem, name string) string {
+	t.Helper()
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(%q) error = %v", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll(%q) error = %v", name, err)
+	}
+	return string(data)
+}
+
+func TestFileSystem_StatDirectories(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "x/y/z.txt", "data")
+
+	for _, name := range []string{"/", "", "x", "x/y", "/x/y/"} {
+		info, err := fs.Stat(name)
+		if err != nil {
+			t.Errorf("Stat(%q) error = %v", name, err)
+			continue
+		}
+		if !info.IsDir() || !info.Mode().IsDir() || info.Mode().Perm() != 0755 {
+			t.Errorf("Stat(%q) = dir %v mode %v, want a directory", name, info.IsDir(), info.Mode())
+		}
+	}
+
+	if info, err := fs.Stat("x/y/z.txt"); err != nil || info.Mode().IsDir() {
+		t.Errorf("Stat() of a file = %v, %v", info, err)
+	}
+	if _, err := fs.Stat("x/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() of a missing name error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestFileSystem_RenameKeepsAttributes(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(key string) {
+		t.Helper()
+		_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket:               aws.String("bucket"),
+			Key:                  aws.String(key),
+			Body:                 strings.NewReader("data"),
+			ContentType:          aws.String("text/csv"),
+			Metadata:             map[string]string{"owner": "ops"},
+			Tagging:              aws.String("team=data"),
+			StorageClass:         types.StorageClassStandardIa,
+			ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+			SSEKMSKeyId:          aws.String("key-1"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(key, class, kmsKeyID, tags string) {
+		t.Helper()
+		head, err := client.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key)})
+		if err != nil {
+			t.Fatalf("HeadObject(%s) error = %v", key, err)
+		}
+		if string(head.StorageClass) != class || aws.ToString(head.SSEKMSKeyId) != kmsKeyID ||
+			aws.ToString(head.ContentType) != "text/csv" || head.Metadata["owner"] != "ops" {
+			t.Errorf("%s: class %q, KMS key %q, type %q, metadata %v; want %s, %s, text/csv and the original metadata",
+				key, head.StorageClass, aws.ToString(head.SSEKMSKeyId), aws.ToString(head.ContentType), head.Metadata, class, kmsKeyID)
+		}
+		tagging, err := client.GetObjectTagging(context.Background(), &s3.GetObjectTaggingInput{Bucket: aws.String("bucket"), Key: aws.String(key)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, tag := range tagging.TagSet {
+			got = append(got, aws.ToString(tag.Key)+"="+aws.ToString(tag.Value))
+		}
+		if strings.Join(got, "&") != tags {
+			t.Errorf("%s: tags %v, want %s", key, got, tags)
+		}
+	}
+
+	put("a.csv")
+	if err := fs.Rename("a.csv", "b.csv"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	check("b.csv", "STANDARD_IA", "key-1", "team=data")
+
+	err = fs.RenameWithOptions("b.csv", "c.csv", &s3fs.CopyOptions{
+		StorageClass: "GLACIER_IR",
+		SSEKMSKeyID:  "key-2",
+		Tags:         map[string]string{"team": "archive"},
+	})
+	if err != nil {
+		t.Fatalf("RenameWithOptions() error = %v", err)
+	}
+	check("c.csv", "GLACIER_IR", "key-2", "team=archive")
+
+	put("dir/x.csv")
+	if err := fs.Rename("dir", "moved"); err != nil {
+		t.Fatalf("Rename(dir) error = %v", err)
+	}
+	check("moved/x.csv", "STANDARD_IA", "key-1", "team=data")
+}
+
+// failDeleteClient fails DeleteObject requests for one key.
+type failDeleteClient struct {
+	*s3fstest.Client
+	key string
+}
+
+func (c *failDeleteClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if aws.ToString(params.Key) == c.key {
+		return nil, errors.New("delete failed")
+	}
+	return c.Client.DeleteObject(ctx, params, optFns...)
+}
+
+func TestFileSystem_RenameSemantics(t *testing.T) {
+	client := &failDeleteClient{Client: s3fstest.NewClient(), key: "stuck.txt"}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "a.txt", "a")
+	writeFile(t, fs, "b.txt", "b")
+	writeFile(t, fs, "stuck.txt", "stuck")
+	writeFile(t, fs, "dir/x.txt", "x")
+	writeFile(t, fs, "other/y.txt", "y")
+
+	err = fs.Rename("missing.txt", "c.txt")
+	if !errors.Is(err, os.ErrNotExist) || !errors.Is(err, s3fs.ErrNotExist) {
+		t.Errorf("Rename(missing) error = %v, want ErrNotExist", err)
+	}
+
+	// The copy is removed again when the original cannot be deleted
+	if err := fs.Rename("stuck.txt", "moved.txt"); err == nil {
+		t.Errorf("Rename() with a failing delete succeeded")
+	}
+	if ok, _ := fs.Exists("moved.txt"); ok {
+		t.Errorf("Rename() with a failing delete left the copy behind")
+	}
+	if ok, _ := fs.Exists("stuck.txt"); !ok {
+		t.Errorf("Rename() with a failing delete lost the original")
+	}
+
+	if err := fs.RenameNoReplace("a.txt", "b.txt"); !errors.Is(err, os.ErrExist) {
+		t.Errorf("RenameNoReplace() onto a file error = %v, want ErrExist", err)
+	}
+	if data, _ := fs.ReadFile("b.txt"); string(data) != "b" {
+		t.Errorf("RenameNoReplace() replaced b.txt with %q", data)
+	}
+	if err := fs.RenameNoReplace("a.txt", "c.txt"); err != nil {
+		t.Fatalf("RenameNoReplace() error = %v", err)
+	}
+	if data, _ := fs.ReadFile("c.txt"); string(data) != "a" {
+		t.Errorf("RenameNoReplace() = %q at c.txt, want a", data)
+	}
+
+	if err := fs.RenameNoReplace("dir", "other"); !errors.Is(err, os.ErrExist) {
+		t.Errorf("RenameNoReplace() onto a directory error = %v, want ErrExist", err)
+	}
+	if err := fs.RenameNoReplace("dir", "fresh"); err != nil {
+		t.Errorf("RenameNoReplace(dir) error = %v", err)
+	}
+}
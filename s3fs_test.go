@@ -1,9 +1,13 @@
 package s3fs
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 )
 
 func TestConfig(t *testing.T) {
@@ -57,6 +61,11 @@ func TestFileInfo(t *testing.T) {
 	if fi.Sys() != nil {
 		t.Errorf("Sys() = %v, want nil", fi.Sys())
 	}
+
+	dir := &fileInfo{name: "dir", isDir: true}
+	if !dir.Mode().IsDir() || dir.Mode().Perm() != 0755 {
+		t.Errorf("directory Mode() = %v, want drwxr-xr-x", dir.Mode())
+	}
 }
 
 func TestAwsStringHelper(t *testing.T) {
@@ -66,3 +75,54 @@ func TestAwsStringHelper(t *testing.T) {
 		t.Errorf("aws.String() failed")
 	}
 }
+
+// endpointServer is an S3-compatible endpoint that answers every request
+// with 404 Not Found and records the paths it was asked for.
+type endpointServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	paths []string
+}
+
+func newEndpointServer(t *testing.T) *endpointServer {
+	s := &endpointServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.paths = append(s.paths, r.URL.Path)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// firstPath returns the path of the first request the server received.
+func (s *endpointServer) firstPath() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.paths) == 0 {
+		return ""
+	}
+	return s.paths[0]
+}
+
+func TestNew_Endpoint(t *testing.T) {
+	server := newEndpointServer(t)
+	fs, err := New(&Config{
+		Bucket: "bucket",
+		Config: &aws.Config{
+			Region:      "us-east-1",
+			Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		},
+		Endpoint:     server.URL,
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs.Stat("a.txt")
+	if got := server.firstPath(); got != "/bucket/a.txt" {
+		t.Errorf("request path = %q, want /bucket/a.txt on the configured endpoint", got)
+	}
+}
@@ -0,0 +1,814 @@
+package s3fstest
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// defaultMaxKeys is the page size of listings when MaxKeys is not set.
+const defaultMaxKeys = 1000
+
+// Client is an in-memory implementation of s3fs.Client. It is safe for
+// concurrent use. The zero value is not usable; call NewClient.
+type Client struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	uploads  map[string]*upload
+	uploadID int
+
+	// Now returns the time recorded as LastModified of new objects
+	// (default time.Now).
+	Now func() time.Time
+}
+
+var _ s3fs.Client = (*Client)(nil)
+
+type bucket struct {
+	objects   map[string]*object
+	lifecycle []types.LifecycleRule
+}
+
+type object struct {
+	data         []byte
+	etag         string
+	lastModified time.Time
+	contentType  string
+	metadata     map[string]string
+	storageClass types.StorageClass
+	restored     time.Time // Expiry of the restored copy of an archived object
+	tags         map[string]string
+
+	sse      types.ServerSideEncryption
+	kmsKeyID string
+
+	checksumCRC32C, checksumSHA256 string // Additional checksums, base64
+
+	lockMode    types.ObjectLockRetentionMode // Object Lock retention mode, if any
+	retainUntil time.Time                     // End of the retention period
+	legalHold   bool
+}
+
+// locked reports whether Object Lock protects obj from deletion at now.
+func (obj *object) locked(now time.Time) bool {
+	return obj.legalHold || (obj.lockMode != "" && obj.retainUntil.After(now))
+}
+
+// archived reports whether the content of obj is in an archive storage class
+// and has no restored copy at now.
+func (obj *object) archived(now time.Time) bool {
+	switch obj.storageClass {
+	case types.StorageClassGlacier, types.StorageClassDeepArchive:
+		return !obj.restored.After(now)
+	}
+	return false
+}
+
+// class returns the storage class of obj, as reported in listings.
+func (obj *object) class() types.StorageClass {
+	if obj.storageClass == "" {
+		return types.StorageClassStandard
+	}
+	return obj.storageClass
+}
+
+type upload struct {
+	bucket, key  string
+	initiated    time.Time
+	contentType  string
+	metadata     map[string]string
+	storageClass types.StorageClass
+	tags         map[string]string
+	sse          types.ServerSideEncryption
+	kmsKeyID     string
+	parts        map[int32][]byte
+}
+
+// NewClient returns an empty in-memory S3 backend.
+func NewClient() *Client {
+	return &Client{
+		buckets: make(map[string]*bucket),
+		uploads: make(map[string]*upload),
+	}
+}
+
+// Keys returns the keys stored in bucket, sorted.
+func (c *Client) Keys(bucketName string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bucket(bucketName).sortedKeys()
+}
+
+// Object returns a copy of the content of key in bucket and whether it
+// exists.
+func (c *Client) Object(bucketName, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	obj, ok := c.bucket(bucketName).objects[key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), obj.data...), true
+}
+
+// bucket returns the named bucket, creating it on first use. c.mu must be
+// held.
+func (c *Client) bucket(name string) *bucket {
+	b, ok := c.buckets[name]
+	if !ok {
+		b = &bucket{objects: make(map[string]*object)}
+		c.buckets[name] = b
+	}
+	return b
+}
+
+func (b *bucket) sortedKeys() []string {
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Client) now() time.Time {
+	if c.Now != nil {
+		return c.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// newObject stores data under key. c.mu must be held.
+func (c *Client) newObject(bucketName, key string, data []byte, etag, contentType string, metadata map[string]string) *object {
+	if etag == "" {
+		sum := md5.Sum(data)
+		etag = `"` + hex.EncodeToString(sum[:]) + `"`
+	}
+	obj := &object{
+		data:         data,
+		etag:         etag,
+		lastModified: c.now(),
+		contentType:  contentType,
+		metadata:     lowerKeys(metadata),
+	}
+	if obj.contentType == "" {
+		obj.contentType = "binary/octet-stream"
+	}
+	c.bucket(bucketName).objects[key] = obj
+	return obj
+}
+
+func lowerKeys(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// GetObject returns the content of an object, honoring Range, If-Match and
+// If-None-Match.
+func (c *Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	obj, ok := c.bucket(aws.ToString(params.Bucket)).objects[key]
+	if !ok {
+		return nil, errNoSuchKey(key)
+	}
+	if params.IfMatch != nil && !etagMatch(aws.ToString(params.IfMatch), obj.etag) {
+		return nil, errPreconditionFailed()
+	}
+	if params.IfNoneMatch != nil && etagMatch(aws.ToString(params.IfNoneMatch), obj.etag) {
+		return nil, errNotModified()
+	}
+	if obj.archived(c.now()) {
+		return nil, errInvalidObjectState()
+	}
+
+	data := obj.data
+	output := &s3.GetObjectOutput{
+		ContentType:  aws.String(obj.contentType),
+		ETag:         aws.String(obj.etag),
+		LastModified: aws.Time(obj.lastModified),
+		Metadata:     copyMap(obj.metadata),
+		AcceptRanges: aws.String("bytes"),
+	}
+	if params.Range != nil {
+		first, last, err := parseRange(aws.ToString(params.Range), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		output.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", first, last, len(data)))
+		data = data[first : last+1]
+	} else if params.ChecksumMode == types.ChecksumModeEnabled {
+		output.ChecksumCRC32C = optional(obj.checksumCRC32C)
+		output.ChecksumSHA256 = optional(obj.checksumSHA256)
+	}
+	output.ContentLength = aws.Int64(int64(len(data)))
+	output.Body = io.NopCloser(bytes.NewReader(data))
+	return output, nil
+}
+
+// parseRange parses a single "bytes=first-last", "bytes=first-" or
+// "bytes=-suffix" range against an object of the given size.
+func parseRange(spec string, size int64) (first, last int64, err error) {
+	r, ok := strings.CutPrefix(spec, "bytes=")
+	if !ok || strings.Contains(r, ",") {
+		return 0, 0, errInvalidRequest("unsupported range: " + spec)
+	}
+	start, end, _ := strings.Cut(r, "-")
+
+	if start == "" {
+		n, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, errInvalidRange()
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, nil
+	}
+
+	first, err = strconv.ParseInt(start, 10, 64)
+	if err != nil || first >= size {
+		return 0, 0, errInvalidRange()
+	}
+	last = size - 1
+	if end != "" {
+		last, err = strconv.ParseInt(end, 10, 64)
+		if err != nil || last < first {
+			return 0, 0, errInvalidRange()
+		}
+		if last >= size {
+			last = size - 1
+		}
+	}
+	return first, last, nil
+}
+
+// checkWriteConditions enforces the If-Match and If-None-Match headers that
+// optFns add to a write or delete of key, as s3fs does for conditional
+// writes and locks. c.mu must be held.
+func (c *Client) checkWriteConditions(ctx context.Context, bucketName, key string, optFns []func(*s3.Options)) error {
+	header, err := requestHeaders(ctx, optFns)
+	if err != nil {
+		return err
+	}
+	obj, exists := c.bucket(bucketName).objects[key]
+	if header.Get("If-None-Match") != "" && exists {
+		return errPreconditionFailed()
+	}
+	if ifMatch := header.Get("If-Match"); ifMatch != "" {
+		if !exists {
+			return errNoSuchKey(key)
+		}
+		if !etagMatch(ifMatch, obj.etag) {
+			return errPreconditionFailed()
+		}
+	}
+	return nil
+}
+
+// requestHeaders returns the HTTP headers that the API options of optFns
+// add to a request, by running them on a request that is never sent.
+func requestHeaders(ctx context.Context, optFns []func(*s3.Options)) (http.Header, error) {
+	var o s3.Options
+	for _, fn := range optFns {
+		fn(&o)
+	}
+	if len(o.APIOptions) == 0 {
+		return http.Header{}, nil
+	}
+
+	stack := middleware.NewStack("s3fstest", smithyhttp.NewStackRequest)
+	for _, fn := range o.APIOptions {
+		if err := fn(stack); err != nil {
+			return nil, err
+		}
+	}
+	var header http.Header
+	capture := middleware.HandlerFunc(func(ctx context.Context, in interface{}) (interface{}, middleware.Metadata, error) {
+		header = in.(*smithyhttp.Request).Header
+		return nil, middleware.Metadata{}, nil
+	})
+	if _, _, err := middleware.DecorateHandler(capture, stack).Handle(ctx, nil); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func etagMatch(condition, etag string) bool {
+	if condition == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(condition, ",") {
+		if strings.Trim(strings.TrimSpace(tag), `"`) == strings.Trim(etag, `"`) {
+			return true
+		}
+	}
+	return false
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// HeadObject returns the metadata of an object.
+func (c *Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	obj, ok := c.bucket(aws.ToString(params.Bucket)).objects[key]
+	if !ok {
+		// HEAD responses have no body, so S3 reports a bare NotFound
+		return nil, &Error{http.StatusNotFound, "NotFound", "Not Found"}
+	}
+	if params.IfMatch != nil && !etagMatch(aws.ToString(params.IfMatch), obj.etag) {
+		return nil, errPreconditionFailed()
+	}
+	if params.IfNoneMatch != nil && etagMatch(aws.ToString(params.IfNoneMatch), obj.etag) {
+		return nil, errNotModified()
+	}
+	output := &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(obj.data))),
+		ContentType:   aws.String(obj.contentType),
+		ETag:          aws.String(obj.etag),
+		LastModified:  aws.Time(obj.lastModified),
+		Metadata:      copyMap(obj.metadata),
+	}
+	if obj.sse != "" {
+		output.ServerSideEncryption = obj.sse
+		if obj.kmsKeyID != "" {
+			output.SSEKMSKeyId = aws.String(obj.kmsKeyID)
+		}
+	}
+	// Like S3, the header is omitted for the default class
+	if obj.class() != types.StorageClassStandard {
+		output.StorageClass = obj.storageClass
+	}
+	if obj.lockMode != "" {
+		output.ObjectLockMode = types.ObjectLockMode(obj.lockMode)
+		output.ObjectLockRetainUntilDate = aws.Time(obj.retainUntil)
+	}
+	if obj.legalHold {
+		output.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+	}
+	if !obj.restored.IsZero() {
+		output.Restore = aws.String(fmt.Sprintf(`ongoing-request="false", expiry-date="%s"`, obj.restored.UTC().Format(http.TimeFormat)))
+	}
+	return output, nil
+}
+
+// PutObject stores an object.
+func (c *Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWriteConditions(ctx, aws.ToString(params.Bucket), aws.ToString(params.Key), optFns); err != nil {
+		return nil, err
+	}
+	crc, sha, err := verifyChecksums(data, params.ChecksumCRC32C, params.ChecksumSHA256)
+	if err != nil {
+		return nil, err
+	}
+	if params.ContentMD5 != nil {
+		sum := md5.Sum(data)
+		if base64.StdEncoding.EncodeToString(sum[:]) != *params.ContentMD5 {
+			return nil, errBadDigest("Content-MD5")
+		}
+	}
+	tags, err := parseTagging(params.Tagging)
+	if err != nil {
+		return nil, err
+	}
+	obj := c.newObject(aws.ToString(params.Bucket), aws.ToString(params.Key), data, "", aws.ToString(params.ContentType), params.Metadata)
+	obj.storageClass = params.StorageClass
+	obj.tags = tags
+	obj.sse, obj.kmsKeyID = params.ServerSideEncryption, aws.ToString(params.SSEKMSKeyId)
+	obj.checksumCRC32C, obj.checksumSHA256 = crc, sha
+	return &s3.PutObjectOutput{ETag: aws.String(obj.etag)}, nil
+}
+
+// CopyObject copies an object, replacing its metadata if MetadataDirective
+// is REPLACE and its tags if TaggingDirective is REPLACE. Like S3, the copy
+// has the storage class and encryption given in the request, not those of
+// the source.
+func (c *Client) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkWriteConditions(ctx, aws.ToString(params.Bucket), aws.ToString(params.Key), optFns); err != nil {
+		return nil, err
+	}
+	src, err := c.copySource(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, err
+	}
+	if params.CopySourceIfMatch != nil && !etagMatch(aws.ToString(params.CopySourceIfMatch), src.etag) {
+		return nil, errPreconditionFailed()
+	}
+
+	contentType, metadata := src.contentType, src.metadata
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		contentType, metadata = aws.ToString(params.ContentType), params.Metadata
+	}
+	tags := copyMap(src.tags)
+	if params.TaggingDirective == types.TaggingDirectiveReplace {
+		if tags, err = parseTagging(params.Tagging); err != nil {
+			return nil, err
+		}
+	}
+	data := append([]byte(nil), src.data...)
+	obj := c.newObject(aws.ToString(params.Bucket), aws.ToString(params.Key), data, src.etag, contentType, metadata)
+	obj.storageClass = params.StorageClass
+	obj.tags = tags
+	obj.sse, obj.kmsKeyID = params.ServerSideEncryption, aws.ToString(params.SSEKMSKeyId)
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{
+			ETag:         aws.String(obj.etag),
+			LastModified: aws.Time(obj.lastModified),
+		},
+	}, nil
+}
+
+// copySource resolves a "bucket/key" CopySource value. c.mu must be held.
+func (c *Client) copySource(source string) (*object, error) {
+	source, _, _ = strings.Cut(source, "?versionId=")
+	if unescaped, err := url.PathUnescape(source); err == nil {
+		source = unescaped
+	}
+	bucketName, key, ok := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	if !ok {
+		return nil, errInvalidRequest("invalid copy source: " + source)
+	}
+	obj, ok := c.bucket(bucketName).objects[key]
+	if !ok {
+		return nil, errNoSuchKey(key)
+	}
+	return obj, nil
+}
+
+// DeleteObject removes an object. Deleting a missing key succeeds, as in S3.
+func (c *Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWriteConditions(ctx, aws.ToString(params.Bucket), aws.ToString(params.Key), optFns); err != nil {
+		return nil, err
+	}
+	b := c.bucket(aws.ToString(params.Bucket))
+	key := aws.ToString(params.Key)
+	if obj, ok := b.objects[key]; ok && obj.locked(c.now()) {
+		return nil, errAccessDenied("The object is protected by Object Lock: " + key)
+	}
+	delete(b.objects, key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// DeleteObjects removes up to 1000 objects.
+func (c *Client) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params.Delete == nil || len(params.Delete.Objects) > 1000 {
+		return nil, &Error{http.StatusBadRequest, "MalformedXML", "expected 1 to 1000 keys"}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.bucket(aws.ToString(params.Bucket))
+	output := &s3.DeleteObjectsOutput{}
+	now := c.now()
+	for _, id := range params.Delete.Objects {
+		if obj, ok := b.objects[aws.ToString(id.Key)]; ok && obj.locked(now) {
+			output.Errors = append(output.Errors, types.Error{
+				Key:     id.Key,
+				Code:    aws.String("AccessDenied"),
+				Message: aws.String("The object is protected by Object Lock"),
+			})
+			continue
+		}
+		delete(b.objects, aws.ToString(id.Key))
+		if !aws.ToBool(params.Delete.Quiet) {
+			output.Deleted = append(output.Deleted, types.DeletedObject{Key: id.Key})
+		}
+	}
+	return output, nil
+}
+
+// ListObjectsV2 lists objects in key order with S3's prefix, delimiter,
+// StartAfter, MaxKeys and continuation semantics.
+func (c *Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.bucket(aws.ToString(params.Bucket))
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+	maxKeys := int(aws.ToInt32(params.MaxKeys))
+	if params.MaxKeys == nil {
+		maxKeys = defaultMaxKeys
+	}
+	after := aws.ToString(params.StartAfter)
+	if params.ContinuationToken != nil {
+		after = aws.ToString(params.ContinuationToken)
+	}
+
+	output := &s3.ListObjectsV2Output{
+		Prefix:      params.Prefix,
+		Delimiter:   params.Delimiter,
+		MaxKeys:     aws.Int32(int32(maxKeys)),
+		IsTruncated: aws.Bool(false),
+	}
+	seen := make(map[string]bool)
+	count := 0
+	last := ""
+	for _, key := range b.sortedKeys() {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+
+		var common string
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common = key[:len(prefix)+i+len(delimiter)]
+				if seen[common] || common <= after {
+					continue
+				}
+			}
+		}
+
+		if count == maxKeys {
+			output.IsTruncated = aws.Bool(true)
+			output.NextContinuationToken = aws.String(last)
+			break
+		}
+		if common != "" {
+			seen[common] = true
+			output.CommonPrefixes = append(output.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(common)})
+			// Continue after every key of the common prefix
+			last = common + "\xff"
+		} else {
+			obj := b.objects[key]
+			output.Contents = append(output.Contents, types.Object{
+				Key:          aws.String(key),
+				Size:         aws.Int64(int64(len(obj.data))),
+				ETag:         aws.String(obj.etag),
+				LastModified: aws.Time(obj.lastModified),
+				StorageClass: types.ObjectStorageClass(obj.class()),
+			})
+			last = key
+		}
+		count++
+	}
+	output.KeyCount = aws.Int32(int32(count))
+	return output, nil
+}
+
+// ListObjectVersions lists the current objects as their only versions, as
+// the fake does not keep history.
+func (c *Client) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.bucket(aws.ToString(params.Bucket))
+	prefix := aws.ToString(params.Prefix)
+	output := &s3.ListObjectVersionsOutput{IsTruncated: aws.Bool(false)}
+	for _, key := range b.sortedKeys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		obj := b.objects[key]
+		output.Versions = append(output.Versions, types.ObjectVersion{
+			Key:          aws.String(key),
+			VersionId:    aws.String("null"),
+			IsLatest:     aws.Bool(true),
+			Size:         aws.Int64(int64(len(obj.data))),
+			ETag:         aws.String(obj.etag),
+			LastModified: aws.Time(obj.lastModified),
+		})
+	}
+	return output, nil
+}
+
+// RestoreObject restores a copy of an archived object for the requested
+// number of days. The fake completes restores immediately.
+func (c *Client) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	obj, ok := c.bucket(aws.ToString(params.Bucket)).objects[key]
+	if !ok {
+		return nil, errNoSuchKey(key)
+	}
+	switch obj.storageClass {
+	case types.StorageClassGlacier, types.StorageClassDeepArchive:
+	default:
+		return nil, errInvalidObjectState()
+	}
+
+	days := int32(1)
+	if params.RestoreRequest != nil && params.RestoreRequest.Days != nil {
+		days = *params.RestoreRequest.Days
+	}
+	obj.restored = c.now().Add(time.Duration(days) * 24 * time.Hour)
+	return &s3.RestoreObjectOutput{}, nil
+}
+
+// verifyChecksums checks the additional checksums sent with data and
+// returns them for storage.
+func verifyChecksums(data []byte, crc32c, sha *string) (string, string, error) {
+	if crc32c != nil {
+		sum := crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], sum)
+		if base64.StdEncoding.EncodeToString(b[:]) != *crc32c {
+			return "", "", errBadDigest("CRC32C")
+		}
+	}
+	if sha != nil {
+		sum := sha256.Sum256(data)
+		if base64.StdEncoding.EncodeToString(sum[:]) != *sha {
+			return "", "", errBadDigest("SHA256")
+		}
+	}
+	return aws.ToString(crc32c), aws.ToString(sha), nil
+}
+
+// optional returns nil for an empty string.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// HeadBucket succeeds for every bucket, since buckets are created on first
+// use.
+func (c *Client) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bucket(aws.ToString(params.Bucket))
+	return &s3.HeadBucketOutput{}, nil
+}
+
+// GetObjectTagging returns the tags of an object, sorted by key.
+func (c *Client) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	obj, ok := c.bucket(aws.ToString(params.Bucket)).objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, errNoSuchKey(aws.ToString(params.Key))
+	}
+	output := &s3.GetObjectTaggingOutput{TagSet: []types.Tag{}}
+	for k, v := range obj.tags {
+		output.TagSet = append(output.TagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	sort.Slice(output.TagSet, func(i, j int) bool {
+		return aws.ToString(output.TagSet[i].Key) < aws.ToString(output.TagSet[j].Key)
+	})
+	return output, nil
+}
+
+// PutObjectRetention sets or, with BypassGovernanceRetention, removes the
+// retention period of an object. Like S3, an active compliance mode
+// retention can only be extended, and a governance mode retention only
+// without bypass.
+func (c *Client) PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	obj, ok := c.bucket(aws.ToString(params.Bucket)).objects[key]
+	if !ok {
+		return nil, errNoSuchKey(key)
+	}
+	var mode types.ObjectLockRetentionMode
+	var until time.Time
+	if params.Retention != nil {
+		mode, until = params.Retention.Mode, aws.ToTime(params.Retention.RetainUntilDate)
+	}
+	if (mode == "") != until.IsZero() {
+		return nil, &Error{http.StatusBadRequest, "MalformedXML", "retention needs both a mode and a date"}
+	}
+	if obj.lockMode != "" && obj.retainUntil.After(c.now()) {
+		extends := mode == obj.lockMode && !until.Before(obj.retainUntil)
+		bypass := obj.lockMode == types.ObjectLockRetentionModeGovernance && aws.ToBool(params.BypassGovernanceRetention)
+		if !extends && !bypass {
+			return nil, errAccessDenied("The retention period of the object cannot be shortened: " + key)
+		}
+	}
+	obj.lockMode, obj.retainUntil = mode, until
+	return &s3.PutObjectRetentionOutput{}, nil
+}
+
+// PutObjectLegalHold places or removes the legal hold of an object.
+func (c *Client) PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	obj, ok := c.bucket(aws.ToString(params.Bucket)).objects[key]
+	if !ok {
+		return nil, errNoSuchKey(key)
+	}
+	if params.LegalHold == nil {
+		return nil, &Error{http.StatusBadRequest, "MalformedXML", "missing legal hold"}
+	}
+	obj.legalHold = params.LegalHold.Status == types.ObjectLockLegalHoldStatusOn
+	return &s3.PutObjectLegalHoldOutput{}, nil
+}
+
+// parseTagging parses the URL-encoded tag set of a Tagging header.
+func parseTagging(tagging *string) (map[string]string, error) {
+	if tagging == nil || *tagging == "" {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(*tagging)
+	if err != nil {
+		return nil, errInvalidRequest("invalid tagging: " + *tagging)
+	}
+	tags := make(map[string]string, len(values))
+	for k, v := range values {
+		tags[k] = v[0]
+	}
+	return tags, nil
+}
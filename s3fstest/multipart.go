@@ -0,0 +1,256 @@
+package s3fstest
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CreateMultipartUpload starts a multipart upload.
+func (c *Client) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tags, err := parseTagging(params.Tagging)
+	if err != nil {
+		return nil, err
+	}
+	c.uploadID++
+	id := strconv.Itoa(c.uploadID)
+	c.uploads[id] = &upload{
+		bucket:       aws.ToString(params.Bucket),
+		key:          aws.ToString(params.Key),
+		initiated:    c.now(),
+		contentType:  aws.ToString(params.ContentType),
+		metadata:     params.Metadata,
+		storageClass: params.StorageClass,
+		tags:         tags,
+		sse:          params.ServerSideEncryption,
+		kmsKeyID:     aws.ToString(params.SSEKMSKeyId),
+		parts:        make(map[int32][]byte),
+	}
+	return &s3.CreateMultipartUploadOutput{
+		Bucket:   params.Bucket,
+		Key:      params.Key,
+		UploadId: aws.String(id),
+	}, nil
+}
+
+// upload returns the upload with the given ID. c.mu must be held.
+func (c *Client) upload(id *string) (*upload, error) {
+	u, ok := c.uploads[aws.ToString(id)]
+	if !ok {
+		return nil, errNoSuchUpload(aws.ToString(id))
+	}
+	return u, nil
+}
+
+func partETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// UploadPart stores a part of a multipart upload.
+func (c *Client) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+	if _, _, err := verifyChecksums(data, params.ChecksumCRC32C, params.ChecksumSHA256); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, err := c.upload(params.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	u.parts[aws.ToInt32(params.PartNumber)] = data
+	return &s3.UploadPartOutput{ETag: aws.String(partETag(data))}, nil
+}
+
+// UploadPartCopy stores a byte range of an existing object as a part.
+func (c *Client) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u, err := c.upload(params.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	src, err := c.copySource(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, err
+	}
+	data := src.data
+	if params.CopySourceRange != nil {
+		first, last, err := parseRange(aws.ToString(params.CopySourceRange), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		data = data[first : last+1]
+	}
+	data = append([]byte(nil), data...)
+	u.parts[aws.ToInt32(params.PartNumber)] = data
+	return &s3.UploadPartCopyOutput{
+		CopyPartResult: &types.CopyPartResult{ETag: aws.String(partETag(data))},
+	}, nil
+}
+
+// CompleteMultipartUpload assembles the listed parts into the object. The
+// ETag has the "<md5 of part md5s>-<part count>" form of S3.
+func (c *Client) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u, err := c.upload(params.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkWriteConditions(ctx, u.bucket, u.key, optFns); err != nil {
+		return nil, err
+	}
+	if params.MultipartUpload == nil || len(params.MultipartUpload.Parts) == 0 {
+		return nil, &Error{http.StatusBadRequest, "MalformedXML", "no parts"}
+	}
+
+	var data []byte
+	sums := md5.New()
+	prev := int32(0)
+	for _, part := range params.MultipartUpload.Parts {
+		n := aws.ToInt32(part.PartNumber)
+		content, ok := u.parts[n]
+		if !ok || n <= prev || strings.Trim(aws.ToString(part.ETag), `"`) != strings.Trim(partETag(content), `"`) {
+			return nil, &Error{http.StatusBadRequest, "InvalidPart", fmt.Sprintf("invalid part %d", n)}
+		}
+		prev = n
+		data = append(data, content...)
+		sum := md5.Sum(content)
+		sums.Write(sum[:])
+	}
+	etag := fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sums.Sum(nil)), len(params.MultipartUpload.Parts))
+
+	obj := c.newObject(u.bucket, u.key, data, etag, u.contentType, u.metadata)
+	obj.storageClass, obj.tags = u.storageClass, u.tags
+	obj.sse, obj.kmsKeyID = u.sse, u.kmsKeyID
+	delete(c.uploads, aws.ToString(params.UploadId))
+	return &s3.CompleteMultipartUploadOutput{
+		Key:  aws.String(u.key),
+		ETag: aws.String(obj.etag),
+	}, nil
+}
+
+// AbortMultipartUpload discards an upload and its parts.
+func (c *Client) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.upload(params.UploadId); err != nil {
+		return nil, err
+	}
+	delete(c.uploads, aws.ToString(params.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// ListMultipartUploads lists the uploads in progress in key order.
+func (c *Client) ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bucketName := aws.ToString(params.Bucket)
+	prefix := aws.ToString(params.Prefix)
+	output := &s3.ListMultipartUploadsOutput{IsTruncated: aws.Bool(false)}
+	for id, u := range c.uploads {
+		if u.bucket != bucketName || !strings.HasPrefix(u.key, prefix) {
+			continue
+		}
+		output.Uploads = append(output.Uploads, types.MultipartUpload{
+			Key:       aws.String(u.key),
+			UploadId:  aws.String(id),
+			Initiated: aws.Time(u.initiated),
+		})
+	}
+	sort.Slice(output.Uploads, func(i, j int) bool {
+		a, b := output.Uploads[i], output.Uploads[j]
+		if aws.ToString(a.Key) != aws.ToString(b.Key) {
+			return aws.ToString(a.Key) < aws.ToString(b.Key)
+		}
+		return aws.ToTime(a.Initiated).Before(aws.ToTime(b.Initiated))
+	})
+	return output, nil
+}
+
+// GetBucketLifecycleConfiguration returns the lifecycle rules of a bucket.
+func (c *Client) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rules := c.bucket(aws.ToString(params.Bucket)).lifecycle
+	if len(rules) == 0 {
+		return nil, &Error{http.StatusNotFound, "NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist"}
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: append([]types.LifecycleRule(nil), rules...)}, nil
+}
+
+// PutBucketLifecycleConfiguration replaces the lifecycle rules of a bucket.
+// Rules are stored but never applied.
+func (c *Client) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var rules []types.LifecycleRule
+	if params.LifecycleConfiguration != nil {
+		rules = append(rules, params.LifecycleConfiguration.Rules...)
+	}
+	c.bucket(aws.ToString(params.Bucket)).lifecycle = rules
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+// DeleteBucketLifecycle removes the lifecycle rules of a bucket.
+func (c *Client) DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bucket(aws.ToString(params.Bucket)).lifecycle = nil
+	return &s3.DeleteBucketLifecycleOutput{}, nil
+}
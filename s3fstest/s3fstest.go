@@ -0,0 +1,107 @@
+// Package s3fstest provides an in-memory S3 backend for testing code that
+// uses s3fs without AWS or MinIO:
+//
+//	fs := s3fstest.New("bucket")
+//	// or, to share the backend or set other options:
+//	client := s3fstest.NewClient()
+//	fs, _ := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+//
+// The fake implements the operations s3fs uses with the semantics of S3 that
+// s3fs relies on: sorted listings with prefixes, delimiters and pagination,
+// ranged and conditional GETs (Range, If-Match, If-None-Match), server-side
+// copies, multipart uploads, conditional writes, copies and deletes (If-Match
+// and If-None-Match headers added with s3.WithAPIOptions), bucket lifecycle
+// rules, object tags, Object Lock retention periods and legal holds (which
+// make deletes fail with AccessDenied), the server-side encryption settings
+// of requests (recorded, not applied), CRC32C and SHA256 additional
+// checksums (verified on upload, returned with ChecksumMode), and archive
+// storage classes (objects put with GLACIER or DEEP_ARCHIVE cannot be read
+// until RestoreObject, which completes immediately). Buckets are created on
+// first use. Objects are not versioned, so ListObjectVersions reports the
+// current objects only. Presigning needs a real *s3.Client and is not
+// supported.
+package s3fstest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/absfs/s3fs"
+	"github.com/aws/smithy-go"
+)
+
+// New returns a FileSystem for bucket backed by a new in-memory Client.
+func New(bucket string) *s3fs.FileSystem {
+	fs, err := s3fs.New(&s3fs.Config{Bucket: bucket, Client: NewClient()})
+	if err != nil {
+		panic(err) // New only fails when creating the real client or caches
+	}
+	return fs
+}
+
+// Error is returned by Client for failed requests. Like the errors of the
+// AWS SDK it carries an HTTP status code and implements smithy.APIError.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+var _ smithy.APIError = (*Error)(nil)
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api error %s: %s", e.Code, e.Message)
+}
+
+// HTTPStatusCode returns the HTTP status code of the simulated response.
+func (e *Error) HTTPStatusCode() int { return e.StatusCode }
+
+// ErrorCode returns the S3 error code, such as "NoSuchKey".
+func (e *Error) ErrorCode() string { return e.Code }
+
+// ErrorMessage returns the error message.
+func (e *Error) ErrorMessage() string { return e.Message }
+
+// ErrorFault reports a client fault for 4xx codes and a server fault otherwise.
+func (e *Error) ErrorFault() smithy.ErrorFault {
+	if e.StatusCode < 500 {
+		return smithy.FaultClient
+	}
+	return smithy.FaultServer
+}
+
+func errAccessDenied(msg string) error {
+	return &Error{http.StatusForbidden, "AccessDenied", msg}
+}
+
+func errBadDigest(algorithm string) error {
+	return &Error{http.StatusBadRequest, "BadDigest", "The " + algorithm + " you specified did not match the calculated checksum"}
+}
+
+func errInvalidObjectState() error {
+	return &Error{http.StatusForbidden, "InvalidObjectState", "The operation is not valid for the object's storage class"}
+}
+
+func errNoSuchKey(key string) error {
+	return &Error{http.StatusNotFound, "NoSuchKey", "The specified key does not exist: " + key}
+}
+
+func errNoSuchUpload(id string) error {
+	return &Error{http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist: " + id}
+}
+
+func errPreconditionFailed() error {
+	return &Error{http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the preconditions you specified did not hold"}
+}
+
+func errNotModified() error {
+	return &Error{http.StatusNotModified, "NotModified", "Not Modified"}
+}
+
+func errInvalidRange() error {
+	return &Error{http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable"}
+}
+
+func errInvalidRequest(msg string) error {
+	return &Error{http.StatusBadRequest, "InvalidRequest", msg}
+}
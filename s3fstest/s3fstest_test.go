@@ -0,0 +1,225 @@
+package s3fstest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func writeFile(t *testing.T, fs *s3fs.FileSystem, name, data string) {
+	t.Helper()
+	f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile(%q) error = %v", name, err)
+	}
+	if _, err := f.Write([]byte(data)); err != nil {
+		t.Fatalf("Write(%q) error = %v", name, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close(%q) error = %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, fs *s3fs.FileSystem, name string) string {
+	t.Helper()
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(%q) error = %v", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll(%q) error = %v", name, err)
+	}
+	return string(data)
+}
+
+func TestFileSystem_ReadWrite(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "/docs/hello.txt", "hello, world")
+
+	if got := readFile(t, fs, "docs/hello.txt"); got != "hello, world" {
+		t.Errorf("read %q, want %q", got, "hello, world")
+	}
+	info, err := fs.Stat("docs/hello.txt")
+	if err != nil || info.Size() != 12 || info.IsDir() {
+		t.Fatalf("Stat() = %v, %v", info, err)
+	}
+
+	f, _ := fs.OpenFile("docs/hello.txt", os.O_RDONLY, 0)
+	defer f.Close()
+	if _, err := f.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, _ := io.ReadAll(f)
+	if string(rest) != "world" {
+		t.Errorf("read after Seek = %q, want world", rest)
+	}
+}
+
+func TestFileSystem_RenameRemove(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "a.txt", "a")
+	writeFile(t, fs, "dir/x.txt", "x")
+	writeFile(t, fs, "dir/sub/y.txt", "y")
+
+	if err := fs.Rename("a.txt", "b.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if exists, _ := fs.Exists("a.txt"); exists {
+		t.Errorf("a.txt still exists after Rename")
+	}
+	if got := readFile(t, fs, "b.txt"); got != "a" {
+		t.Errorf("b.txt = %q, want a", got)
+	}
+
+	if err := fs.Rename("dir", "moved"); err != nil {
+		t.Fatalf("Rename() of a directory error = %v", err)
+	}
+	if got := readFile(t, fs, "moved/sub/y.txt"); got != "y" {
+		t.Errorf("moved/sub/y.txt = %q, want y", got)
+	}
+
+	if err := fs.RemoveAll("moved"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if err := fs.Remove("b.txt"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+}
+
+func TestFileSystem_Walk(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	for _, name := range []string{"w/b.txt", "w/a/1.txt", "w/a/2.txt"} {
+		writeFile(t, fs, name, name)
+	}
+
+	var visited []string
+	err := fs.Walk("w", func(path string, info os.FileInfo, err error) error {
+		visited = append(visited, path)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	want := "w/,w/a/,w/a/1.txt,w/a/2.txt,w/b.txt"
+	if strings.Join(visited, ",") != want {
+		t.Errorf("Walk() visited %v, want %s", visited, want)
+	}
+}
+
+func TestFileSystem_Multipart(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu, err := fs.NewMultipartUpload("big.bin")
+	if err != nil {
+		t.Fatalf("NewMultipartUpload() error = %v", err)
+	}
+	data := bytes.Repeat([]byte("0123456789"), 1<<20+1)
+	if err := mu.UploadFromReader(bytes.NewReader(data)); err != nil {
+		t.Fatalf("UploadFromReader() error = %v", err)
+	}
+	if err := mu.Complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	got, ok := client.Object("bucket", "big.bin")
+	if !ok || !bytes.Equal(got, data) {
+		t.Errorf("object has %d bytes, want %d", len(got), len(data))
+	}
+	etag, _ := fs.ETag("big.bin")
+	if !strings.Contains(etag, "-") {
+		t.Errorf("ETag() = %q, want a multipart ETag", etag)
+	}
+}
+
+func TestClient_ListPagination(t *testing.T) {
+	client := s3fstest.NewClient()
+	ctx := context.Background()
+	for _, key := range []string{"a/1", "a/2", "b", "c/1", "d"} {
+		client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("b"), Key: aws.String(key), Body: strings.NewReader(key)})
+	}
+
+	var got []string
+	var token *string
+	for {
+		output, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String("b"),
+			Delimiter:         aws.String("/"),
+			MaxKeys:           aws.Int32(2),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, cp := range output.CommonPrefixes {
+			got = append(got, aws.ToString(cp.Prefix))
+		}
+		for _, obj := range output.Contents {
+			got = append(got, aws.ToString(obj.Key))
+		}
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		token = output.NextContinuationToken
+	}
+	if strings.Join(got, ",") != "a/,b,c/,d" {
+		t.Errorf("paginated listing = %v, want [a/ b c/ d]", got)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	client := s3fstest.NewClient()
+	ctx := context.Background()
+	client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("b"), Key: aws.String("k"), Body: strings.NewReader("data")})
+
+	tests := []struct {
+		name   string
+		input  *s3.GetObjectInput
+		status int
+	}{
+		{"Missing", &s3.GetObjectInput{Key: aws.String("missing")}, 404},
+		{"BadRange", &s3.GetObjectInput{Key: aws.String("k"), Range: aws.String("bytes=10-")}, 416},
+		{"IfMatch", &s3.GetObjectInput{Key: aws.String("k"), IfMatch: aws.String(`"nope"`)}, 412},
+		{"IfNoneMatch", &s3.GetObjectInput{Key: aws.String("k"), IfNoneMatch: aws.String("*")}, 304},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.Bucket = aws.String("b")
+			_, err := client.GetObject(ctx, tt.input)
+			var apiErr *s3fstest.Error
+			if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode() != tt.status {
+				t.Errorf("GetObject() error = %v, want status %d", err, tt.status)
+			}
+		})
+	}
+
+	output, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("b"), Key: aws.String("k"), Range: aws.String("bytes=-2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(output.Body)
+	if string(data) != "ta" || aws.ToString(output.ContentRange) != "bytes 2-3/4" {
+		t.Errorf("suffix range = %q (%s)", data, aws.ToString(output.ContentRange))
+	}
+}
+
+func TestPresignUnsupported(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	if _, err := fs.PresignGet("a.txt", 0); !errors.Is(err, s3fs.ErrPresignUnsupported) {
+		t.Errorf("PresignGet() error = %v, want ErrPresignUnsupported", err)
+	}
+}
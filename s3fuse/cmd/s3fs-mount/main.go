@@ -0,0 +1,95 @@
+//go:build linux || darwin
+
+// Command s3fs-mount mounts an S3 bucket as a local directory with FUSE:
+//
+//	s3fs-mount -bucket my-bucket -cache-blocks 256 /mnt/bucket
+//
+// It serves the mount until it is interrupted or unmounted with umount(8)
+// or fusermount -u. Reads are cached in memory, in up to -cache-blocks
+// blocks of -block-size bytes per open file, and on disk in -cache-dir if
+// set. Writes stream to S3 and must be sequential.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fuse"
+)
+
+func main() {
+	var (
+		bucket      = flag.String("bucket", "", "S3 bucket (required)")
+		prefix      = flag.String("prefix", "", "mount only the keys below this prefix")
+		region      = flag.String("region", "", "AWS region (default from the environment)")
+		endpoint    = flag.String("endpoint", "", "S3 endpoint URL for S3-compatible services")
+		pathStyle   = flag.Bool("path-style", false, "use path-style bucket addressing")
+		readOnly    = flag.Bool("read-only", false, "mount read-only")
+		cacheBlocks = flag.Int("cache-blocks", 64, "blocks of read cache in memory")
+		blockSize   = flag.Int64("block-size", 1<<20, "size of a read cache block in bytes")
+		cacheDir    = flag.String("cache-dir", "", "directory for a local read cache")
+		allowOther  = flag.Bool("allow-other", false, "let other users access the mount")
+		inodes      = flag.Bool("inodes", false, "keep inode numbers stable across mounts in an index in the bucket")
+		debug       = flag.Bool("debug", false, "log FUSE requests")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: s3fs-mount -bucket name [flags] mountpoint\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *bucket == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fs, err := s3fs.New(&s3fs.Config{
+		Bucket:            *bucket,
+		Region:            *region,
+		DetectRegion:      *region == "",
+		Endpoint:          *endpoint,
+		UsePathStyle:      *pathStyle,
+		ReadAtCacheBlocks: *cacheBlocks,
+		ReadAtBlockSize:   *blockSize,
+		ReadCacheDir:      *cacheDir,
+		Inodes:            *inodes,
+		Probe:             true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		// Saves the inode index of -inodes
+		if err := fs.Close(); err != nil {
+			log.Printf("close: %v", err)
+		}
+	}()
+	if *prefix != "" {
+		fs = fs.Sub(*prefix)
+	}
+
+	server, err := s3fuse.Mount(flag.Arg(0), fs, &s3fuse.Options{
+		ReadOnly:   *readOnly,
+		AllowOther: *allowOther,
+		Debug:      *debug,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		if err := server.Unmount(); err != nil {
+			log.Printf("unmount: %v", err)
+		}
+	}()
+
+	log.Printf("serving s3://%s at %s", *bucket, flag.Arg(0))
+	server.Wait()
+}
@@ -0,0 +1,31 @@
+package s3fuse
+
+import (
+	"errors"
+	iofs "io/fs"
+	"syscall"
+
+	"github.com/absfs/s3fs"
+)
+
+// errno returns the errno a FUSE request that failed with err answers with.
+func errno(err error) syscall.Errno {
+	var en syscall.Errno
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &en):
+		return en
+	case errors.Is(err, iofs.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, iofs.ErrExist):
+		return syscall.EEXIST
+	case errors.Is(err, iofs.ErrPermission):
+		return syscall.EACCES
+	case errors.Is(err, s3fs.ErrInvalidKey):
+		return syscall.EINVAL
+	case errors.Is(err, s3fs.ErrUnsupportedFlags):
+		return syscall.ENOTSUP
+	}
+	return syscall.EIO
+}
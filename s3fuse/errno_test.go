@@ -0,0 +1,31 @@
+package s3fuse
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/s3fs"
+)
+
+func TestErrno(t *testing.T) {
+	tests := []struct {
+		err  error
+		want syscall.Errno
+	}{
+		{nil, 0},
+		{&s3fs.S3Error{Op: "Stat", Path: "a", Err: s3fs.ErrNotExist}, syscall.ENOENT},
+		{&os.PathError{Op: "mkdir", Path: "a", Err: os.ErrExist}, syscall.EEXIST},
+		{fmt.Errorf("remove: %w", syscall.ENOTEMPTY), syscall.ENOTEMPTY},
+		{&s3fs.KeyError{Key: "a", Reason: "too long"}, syscall.EINVAL},
+		{&s3fs.FlagError{Reason: "O_RDWR"}, syscall.ENOTSUP},
+		{errors.New("connection reset"), syscall.EIO},
+	}
+	for _, tt := range tests {
+		if got := errno(tt.err); got != tt.want {
+			t.Errorf("errno(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
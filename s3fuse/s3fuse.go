@@ -0,0 +1,479 @@
+//go:build linux || darwin
+
+// Package s3fuse mounts an s3fs.FileSystem as a local directory with FUSE,
+// for programs that need operating-system level access to a bucket:
+//
+//	fs, err := s3fs.New(&s3fs.Config{
+//		Bucket:            "my-bucket",
+//		ReadAtCacheBlocks: 64,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	server, err := s3fuse.Mount("/mnt/bucket", fs, nil)
+//	if err != nil {
+//		return err
+//	}
+//	server.Wait()
+//
+// Reads go through File.ReadAt, so they use the block cache of
+// Config.ReadAtCacheBlocks and the local cache of Config.ReadCacheDir.
+// Writes stream to S3 with a Writer and must be sequential, as with cp or
+// shell redirection; a file is visible in the bucket once the writing
+// program closes it. Files cannot be opened for appending or random writes.
+//
+// Inode numbers are made up by go-fuse for each mount, unless the
+// FileSystem keeps stable numbers with s3fs.Config.Inodes, which NFS
+// exports of the mount need; they are saved when the FileSystem is closed.
+//
+// The package is a module of its own, so s3fs users who don't need it don't
+// depend on a FUSE library. The command s3fs-mount in cmd/s3fs-mount mounts
+// a bucket from the command line.
+package s3fuse
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// Options configures a mount. The zero value mounts the filesystem
+// writable, with files and directories owned by the mounting user.
+type Options struct {
+	ReadOnly bool // Refuse all changes with EROFS
+
+	UID, GID uint32      // Owner of all files (default the current user)
+	FileMode os.FileMode // Permissions of files (default 0644)
+	DirMode  os.FileMode // Permissions of directories (default 0755)
+
+	// AttrTimeout is how long the kernel caches attributes and names
+	// (default one second). Changes made to the bucket by others show up
+	// after it expires.
+	AttrTimeout time.Duration
+
+	AllowOther bool // Let other users access the mount
+	Debug      bool // Log FUSE requests
+}
+
+// withDefaults returns a copy of o with zero fields set to their defaults.
+func (o *Options) withDefaults() Options {
+	var c Options
+	if o != nil {
+		c = *o
+	}
+	if c.UID == 0 && c.GID == 0 {
+		c.UID, c.GID = uint32(os.Getuid()), uint32(os.Getgid())
+	}
+	if c.FileMode == 0 {
+		c.FileMode = 0o644
+	}
+	if c.DirMode == 0 {
+		c.DirMode = 0o755
+	}
+	if c.AttrTimeout == 0 {
+		c.AttrTimeout = time.Second
+	}
+	return c
+}
+
+// Mount mounts fsys at dir and serves it until the returned server is
+// unmounted, with Server.Unmount or umount(8). opts may be nil.
+func Mount(dir string, fsys *s3fs.FileSystem, opts *Options) (*fuse.Server, error) {
+	o := opts.withDefaults()
+	mountOpts := fuse.MountOptions{
+		AllowOther: o.AllowOther,
+		FsName:     "s3fs",
+		Name:       "s3fs",
+		Debug:      o.Debug,
+	}
+	if o.ReadOnly {
+		mountOpts.Options = append(mountOpts.Options, "ro")
+	}
+	return fs.Mount(dir, Root(fsys, opts), &fs.Options{
+		MountOptions: mountOpts,
+		EntryTimeout: &o.AttrTimeout,
+		AttrTimeout:  &o.AttrTimeout,
+	})
+}
+
+// Root returns the root node of fsys, for use with fs.Mount when more
+// control over the mount is needed than Mount offers.
+func Root(fsys *s3fs.FileSystem, opts *Options) fs.InodeEmbedder {
+	return &node{fsys: fsys, opts: opts.withDefaults()}
+}
+
+// node is a file or directory. Its name is the path of its inode, so it
+// follows renames.
+type node struct {
+	fs.Inode
+	fsys *s3fs.FileSystem
+	opts Options
+
+	mu      sync.Mutex
+	writing *writeHandle // Open writer of a file not yet stored
+}
+
+var (
+	_ fs.NodeLookuper  = (*node)(nil)
+	_ fs.NodeReaddirer = (*node)(nil)
+	_ fs.NodeGetattrer = (*node)(nil)
+	_ fs.NodeSetattrer = (*node)(nil)
+	_ fs.NodeOpener    = (*node)(nil)
+	_ fs.NodeCreater   = (*node)(nil)
+	_ fs.NodeMkdirer   = (*node)(nil)
+	_ fs.NodeUnlinker  = (*node)(nil)
+	_ fs.NodeRmdirer   = (*node)(nil)
+	_ fs.NodeRenamer   = (*node)(nil)
+)
+
+// name returns the name of n in fsys, "" for the root.
+func (n *node) name() string {
+	return n.Path(n.Root())
+}
+
+// child returns a new node for the named file or directory below n.
+func (n *node) child(name string, dir bool) (*node, fs.StableAttr) {
+	mode := uint32(fuse.S_IFREG)
+	if dir {
+		mode = fuse.S_IFDIR
+	}
+	return &node{fsys: n.fsys, opts: n.opts}, fs.StableAttr{Mode: mode, Ino: n.ino(name)}
+}
+
+// ino returns the inode number fsys assigns to name, or 0 to let go-fuse
+// number the node if s3fs.Config.Inodes is not set.
+func (n *node) ino(name string) uint64 {
+	ino, err := n.fsys.Inode(name)
+	if err != nil {
+		return 0
+	}
+	return ino
+}
+
+// setAttr fills out from info.
+func (n *node) setAttr(out *fuse.Attr, info os.FileInfo) {
+	mode := n.opts.FileMode | fuse.S_IFREG
+	if info.IsDir() {
+		mode = n.opts.DirMode | fuse.S_IFDIR
+	}
+	out.Mode = uint32(mode)
+	if obj, ok := info.Sys().(*s3fs.ObjectInfo); ok {
+		out.Ino = obj.Inode
+	}
+	out.Size = uint64(info.Size())
+	out.Blocks = (out.Size + 511) / 512
+	out.Uid, out.Gid = n.opts.UID, n.opts.GID
+	mtime := info.ModTime()
+	out.SetTimes(&mtime, &mtime, &mtime)
+}
+
+// setWritingAttr fills out for a file that is being written.
+func (n *node) setWritingAttr(out *fuse.Attr, h *writeHandle) {
+	now := time.Now()
+	out.Mode = uint32(n.opts.FileMode) | fuse.S_IFREG
+	out.Size = uint64(h.size())
+	out.Blocks = (out.Size + 511) / 512
+	out.Uid, out.Gid = n.opts.UID, n.opts.GID
+	out.SetTimes(&now, &now, &now)
+}
+
+// Lookup finds the file or directory name in n.
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	childName := path.Join(n.name(), name)
+	info, err := n.fsys.WithContext(ctx).Stat(childName)
+	if err != nil {
+		return nil, errno(err)
+	}
+	n.setAttr(&out.Attr, info)
+	child, attr := n.child(childName, info.IsDir())
+	return n.NewInode(ctx, child, attr), 0
+}
+
+// Readdir lists the direct entries of the directory n.
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	fsys := n.fsys.WithContext(ctx)
+	dir := n.name()
+	var entries []fuse.DirEntry
+	token := ""
+	for {
+		infos, next, err := fsys.ListPage(dir, token, 0)
+		if err != nil {
+			return nil, errno(err)
+		}
+		for _, info := range infos {
+			mode := uint32(fuse.S_IFREG)
+			if info.IsDir() {
+				mode = fuse.S_IFDIR
+			}
+			ino := n.ino(path.Join(dir, info.Name()))
+			entries = append(entries, fuse.DirEntry{Name: info.Name(), Mode: mode, Ino: ino})
+		}
+		if next == "" {
+			return fs.NewListDirStream(entries), 0
+		}
+		token = next
+	}
+}
+
+// Getattr reports the attributes of n.
+func (n *node) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	n.mu.Lock()
+	h := n.writing
+	n.mu.Unlock()
+	if h != nil {
+		n.setWritingAttr(&out.Attr, h)
+		return 0
+	}
+
+	info, err := n.fsys.WithContext(ctx).Stat(n.name())
+	if err != nil {
+		return errno(err)
+	}
+	n.setAttr(&out.Attr, info)
+	return 0
+}
+
+// Setattr supports truncating a file to zero length, which replaces it with
+// an empty object. Modes, owners and times are fixed by Options.
+func (n *node) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if size, ok := in.GetSize(); ok {
+		if n.opts.ReadOnly {
+			return syscall.EROFS
+		}
+		if size != 0 {
+			return syscall.ENOTSUP
+		}
+		n.mu.Lock()
+		h := n.writing
+		n.mu.Unlock()
+		if h != nil {
+			// Being replaced by a writer already
+			if h.size() != 0 {
+				return syscall.ENOTSUP
+			}
+		} else if err := n.fsys.WithContext(ctx).WriteFile(n.name(), nil, 0); err != nil {
+			return errno(err)
+		}
+	}
+	return n.Getattr(ctx, f, out)
+}
+
+// Open opens the file n for reading, or for writing with O_TRUNC, which
+// replaces its content when the file is closed.
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&syscall.O_ACCMODE == syscall.O_RDONLY {
+		f, err := n.fsys.Open(n.name())
+		if err != nil {
+			return nil, 0, errno(err)
+		}
+		return &readHandle{f: f}, 0, 0
+	}
+
+	if n.opts.ReadOnly {
+		return nil, 0, syscall.EROFS
+	}
+	if flags&syscall.O_TRUNC == 0 || flags&syscall.O_APPEND != 0 || flags&syscall.O_ACCMODE == syscall.O_RDWR {
+		return nil, 0, syscall.ENOTSUP
+	}
+	return n.startWriting(), fuse.FOPEN_DIRECT_IO, 0
+}
+
+// startWriting returns a handle that streams a new content of n to S3.
+func (n *node) startWriting() *writeHandle {
+	h := &writeHandle{node: n, w: n.fsys.NewWriter(n.name(), nil)}
+	n.mu.Lock()
+	n.writing = h
+	n.mu.Unlock()
+	return h
+}
+
+// Create creates the file name in n and opens it for writing.
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	if n.opts.ReadOnly {
+		return nil, nil, 0, syscall.EROFS
+	}
+	if flags&syscall.O_ACCMODE == syscall.O_RDWR {
+		return nil, nil, 0, syscall.ENOTSUP
+	}
+	child, attr := n.child(path.Join(n.name(), name), false)
+	inode := n.NewInode(ctx, child, attr)
+	h := child.startWriting()
+	child.setWritingAttr(&out.Attr, h)
+	return inode, h, fuse.FOPEN_DIRECT_IO, 0
+}
+
+// Mkdir creates the directory name in n.
+func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if n.opts.ReadOnly {
+		return nil, syscall.EROFS
+	}
+	fsys := n.fsys.WithContext(ctx)
+	dir := path.Join(n.name(), name)
+	if err := fsys.Mkdir(dir, os.FileMode(mode)); err != nil {
+		return nil, errno(err)
+	}
+	info, err := fsys.Stat(dir)
+	if err != nil {
+		return nil, errno(err)
+	}
+	n.setAttr(&out.Attr, info)
+	child, attr := n.child(dir, true)
+	return n.NewInode(ctx, child, attr), 0
+}
+
+// Unlink removes the file name from n.
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	if n.opts.ReadOnly {
+		return syscall.EROFS
+	}
+	return errno(n.fsys.WithContext(ctx).Remove(path.Join(n.name(), name)))
+}
+
+// Rmdir removes the empty directory name from n.
+func (n *node) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if n.opts.ReadOnly {
+		return syscall.EROFS
+	}
+	fsys := n.fsys.WithContext(ctx)
+	dir := path.Join(n.name(), name)
+	infos, _, err := fsys.ListPage(dir, "", 1)
+	if err != nil {
+		return errno(err)
+	}
+	if len(infos) > 0 {
+		return syscall.ENOTEMPTY
+	}
+	return errno(fsys.Remove(dir + "/"))
+}
+
+// Rename moves the file or directory name in n to newName in newParent.
+func (n *node) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if n.opts.ReadOnly {
+		return syscall.EROFS
+	}
+	parent, ok := newParent.(*node)
+	if !ok {
+		return syscall.EXDEV
+	}
+	fsys := n.fsys.WithContext(ctx)
+	oldpath, newpath := path.Join(n.name(), name), path.Join(parent.name(), newName)
+
+	const renameNoReplace = 1 // RENAME_NOREPLACE of renameat2
+	switch flags {
+	case 0:
+		return errno(fsys.Rename(oldpath, newpath))
+	case renameNoReplace:
+		return errno(fsys.RenameNoReplace(oldpath, newpath))
+	}
+	return syscall.ENOTSUP
+}
+
+// readHandle reads an open file.
+type readHandle struct {
+	mu sync.Mutex
+	f  interface {
+		io.ReaderAt
+		io.Closer
+	}
+}
+
+var (
+	_ fs.FileReader   = (*readHandle)(nil)
+	_ fs.FileReleaser = (*readHandle)(nil)
+)
+
+// Read reads from the file at off.
+func (h *readHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n, err := h.f.ReadAt(dest, off)
+	if err != nil && err != io.EOF {
+		return nil, errno(err)
+	}
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+// Release closes the file.
+func (h *readHandle) Release(ctx context.Context) syscall.Errno {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return errno(h.f.Close())
+}
+
+// writeHandle streams sequential writes of a file to S3.
+type writeHandle struct {
+	node *node
+
+	mu     sync.Mutex
+	w      *s3fs.Writer
+	off    int64
+	closed bool
+	err    error
+}
+
+var (
+	_ fs.FileWriter   = (*writeHandle)(nil)
+	_ fs.FileFlusher  = (*writeHandle)(nil)
+	_ fs.FileReleaser = (*writeHandle)(nil)
+)
+
+// size returns the number of bytes written so far.
+func (h *writeHandle) size() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.off
+}
+
+// Write appends data, which must start where the previous write ended.
+func (h *writeHandle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return 0, syscall.EBADF
+	}
+	if off != h.off {
+		return 0, syscall.ENOTSUP
+	}
+	n, err := h.w.Write(data)
+	h.off += int64(n)
+	if err != nil {
+		return uint32(n), errno(err)
+	}
+	return uint32(n), 0
+}
+
+// Flush stores the file when the writing program closes it. Later writes
+// through the same handle fail.
+func (h *writeHandle) Flush(ctx context.Context) syscall.Errno {
+	return errno(h.close())
+}
+
+// Release stores the file if Flush did not.
+func (h *writeHandle) Release(ctx context.Context) syscall.Errno {
+	return errno(h.close())
+}
+
+// close completes the upload once and returns its result.
+func (h *writeHandle) close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		h.err = h.w.Close()
+
+		h.node.mu.Lock()
+		if h.node.writing == h {
+			h.node.writing = nil
+		}
+		h.node.mu.Unlock()
+	}
+	return h.err
+}
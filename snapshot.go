@@ -0,0 +1,173 @@
+package s3fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Snapshot pins the current version of every object below a prefix. On a
+// bucket with versioning enabled, the pinned versions stay readable with
+// OpenVersion after the objects are overwritten or deleted.
+type Snapshot struct {
+	Prefix  string                   `json:"prefix"`
+	Created time.Time                `json:"created"`
+	Entries map[string]SnapshotEntry `json:"entries"` // Keyed by name relative to Prefix
+}
+
+// SnapshotEntry is the pinned version of one object in a Snapshot.
+type SnapshotEntry struct {
+	VersionID string    `json:"version_id"`
+	ETag      string    `json:"etag"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+}
+
+// SnapshotChange describes one object that differs between two snapshots.
+type SnapshotChange struct {
+	Name  string         // Name relative to the snapshot prefix
+	Old   *SnapshotEntry // nil if the object was added
+	New   *SnapshotEntry // nil if the object was removed
+	Delta int64          // Size difference in bytes (new - old)
+}
+
+// SnapshotDiff lists the changes between two snapshots, each sorted by name.
+type SnapshotDiff struct {
+	Added    []SnapshotChange
+	Removed  []SnapshotChange
+	Modified []SnapshotChange
+	Delta    int64 // Total size difference in bytes
+}
+
+// Snapshot records the latest version of every object below the directory
+// prefix. Use SaveSnapshot to keep it in the bucket.
+func (fs *FileSystem) Snapshot(prefix string) (*Snapshot, error) {
+	prefix = strings.Trim(prefix, "/")
+	listPrefix := ""
+	if prefix != "" {
+		listPrefix = prefix + "/"
+	}
+
+	snap := &Snapshot{
+		Prefix:  prefix,
+		Created: time.Now().UTC(),
+		Entries: make(map[string]SnapshotEntry),
+	}
+
+	var keyMarker, versionIDMarker *string
+	for {
+		output, err := fs.client.ListObjectVersions(fs.ctx, &s3.ListObjectVersionsInput{
+			Bucket:          aws.String(fs.bucket),
+			Prefix:          aws.String(fs.objectKey(listPrefix)),
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIDMarker,
+		})
+		if err != nil {
+			return nil, fs.wrapError("Snapshot", prefix, err)
+		}
+
+		for _, v := range output.Versions {
+			key := aws.ToString(v.Key)
+			if !aws.ToBool(v.IsLatest) || strings.HasSuffix(key, "/") || fs.isSystemKey(key) {
+				continue
+			}
+			name, err := fs.logicalName(key)
+			if err != nil {
+				return nil, fs.wrapError("Snapshot", key, err)
+			}
+			snap.Entries[strings.TrimPrefix(name, listPrefix)] = SnapshotEntry{
+				VersionID: aws.ToString(v.VersionId),
+				ETag:      aws.ToString(v.ETag),
+				Size:      aws.ToInt64(v.Size),
+				ModTime:   aws.ToTime(v.LastModified),
+			}
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		keyMarker = output.NextKeyMarker
+		versionIDMarker = output.NextVersionIdMarker
+	}
+
+	return snap, nil
+}
+
+// snapshotKey returns the key under which SaveSnapshot stores a snapshot.
+func (fs *FileSystem) snapshotKey(name string) string {
+	return fs.systemKey("snapshots", name+".json")
+}
+
+// SaveSnapshot stores snap under the given name below the system prefix.
+func (fs *FileSystem) SaveSnapshot(name string, snap *Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fs.wrapError("SaveSnapshot", name, err)
+	}
+
+	_, err = fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.snapshotKey(name)),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fs.wrapError("SaveSnapshot", name, err)
+	}
+	return nil
+}
+
+// LoadSnapshot fetches the snapshot stored by SaveSnapshot under name.
+func (fs *FileSystem) LoadSnapshot(name string) (*Snapshot, error) {
+	output, err := fs.client.GetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.snapshotKey(name)),
+	})
+	if err != nil {
+		return nil, fs.wrapError("LoadSnapshot", name, err)
+	}
+	defer output.Body.Close()
+
+	var snap Snapshot
+	if err := json.NewDecoder(output.Body).Decode(&snap); err != nil {
+		return nil, fs.wrapError("LoadSnapshot", name, err)
+	}
+	return &snap, nil
+}
+
+// DiffSnapshots compares snapshot a with the later snapshot b. An object is
+// modified if its ETag or size changed; a new version with identical content
+// is not reported.
+func DiffSnapshots(a, b *Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{}
+
+	for name, old := range a.Entries {
+		old := old
+		cur, ok := b.Entries[name]
+		if !ok {
+			diff.Removed = append(diff.Removed, SnapshotChange{Name: name, Old: &old, Delta: -old.Size})
+			continue
+		}
+		if cur.ETag != old.ETag || cur.Size != old.Size {
+			diff.Modified = append(diff.Modified, SnapshotChange{Name: name, Old: &old, New: &cur, Delta: cur.Size - old.Size})
+		}
+	}
+	for name, cur := range b.Entries {
+		cur := cur
+		if _, ok := a.Entries[name]; !ok {
+			diff.Added = append(diff.Added, SnapshotChange{Name: name, New: &cur, Delta: cur.Size})
+		}
+	}
+
+	for _, changes := range [][]SnapshotChange{diff.Added, diff.Removed, diff.Modified} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+		for _, c := range changes {
+			diff.Delta += c.Delta
+		}
+	}
+	return diff
+}
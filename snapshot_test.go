@@ -0,0 +1,37 @@
+package s3fs
+
+import "testing"
+
+func TestDiffSnapshots(t *testing.T) {
+	a := &Snapshot{Entries: map[string]SnapshotEntry{
+		"same.csv":    {VersionID: "1", ETag: `"a"`, Size: 10},
+		"changed.csv": {VersionID: "1", ETag: `"b"`, Size: 20},
+		"gone.csv":    {VersionID: "1", ETag: `"c"`, Size: 30},
+		"rewrite.csv": {VersionID: "1", ETag: `"d"`, Size: 5},
+	}}
+	b := &Snapshot{Entries: map[string]SnapshotEntry{
+		"same.csv":    {VersionID: "1", ETag: `"a"`, Size: 10},
+		"changed.csv": {VersionID: "2", ETag: `"e"`, Size: 25},
+		"rewrite.csv": {VersionID: "2", ETag: `"d"`, Size: 5},
+		"new/b.csv":   {VersionID: "1", ETag: `"f"`, Size: 7},
+		"new/a.csv":   {VersionID: "1", ETag: `"g"`, Size: 3},
+	}}
+
+	diff := DiffSnapshots(a, b)
+
+	if len(diff.Added) != 2 || diff.Added[0].Name != "new/a.csv" || diff.Added[1].Name != "new/b.csv" {
+		t.Errorf("Added = %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "gone.csv" || diff.Removed[0].Delta != -30 {
+		t.Errorf("Removed = %+v", diff.Removed)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0].Name != "changed.csv" || diff.Modified[0].Delta != 5 {
+		t.Errorf("Modified = %+v", diff.Modified)
+	}
+	if diff.Delta != 10-30+5 {
+		t.Errorf("Delta = %d, want %d", diff.Delta, 10-30+5)
+	}
+	if diff.Modified[0].Old.VersionID != "1" || diff.Modified[0].New.VersionID != "2" {
+		t.Errorf("Modified entry versions = %s -> %s", diff.Modified[0].Old.VersionID, diff.Modified[0].New.VersionID)
+	}
+}
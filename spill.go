@@ -0,0 +1,119 @@
+package s3fs
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// size returns the current size of the write buffer.
+func (f *File) size() int64 {
+	if f.spill != nil {
+		return f.spillSize
+	}
+	return int64(len(f.buffer))
+}
+
+// maybeSpill moves the in-memory write buffer to a temporary file once
+// growing it to newSize would exceed Config.SpillThreshold.
+func (f *File) maybeSpill(newSize int64) error {
+	if f.spill != nil || f.fs == nil || f.fs.spillThreshold <= 0 || newSize <= f.fs.spillThreshold {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(f.fs.spillDir, "s3fs-spill-*")
+	if err != nil {
+		return f.fs.wrapError("Write", f.name, err)
+	}
+	if _, err := tmp.Write(f.buffer); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return f.fs.wrapError("Write", f.name, err)
+	}
+
+	f.spill = tmp
+	f.spillSize = int64(len(f.buffer))
+	f.buffer = nil
+	return nil
+}
+
+// spillWriteAt writes b at off in the spill file, growing it as needed.
+func (f *File) spillWriteAt(b []byte, off int64) (int, error) {
+	n, err := f.spill.WriteAt(b, off)
+	if end := off + int64(n); end > f.spillSize {
+		f.spillSize = end
+	}
+	if err != nil {
+		return n, f.fs.wrapError("Write", f.name, err)
+	}
+	return n, nil
+}
+
+// uploadBuffer uploads the write buffer to S3 and records the new ETag.
+// Spilled buffers larger than a single part are uploaded with a multipart upload.
+func (f *File) uploadBuffer() error {
+	metadata, err := f.fs.nameMetadata(f.name)
+	if err != nil {
+		return err
+	}
+
+	if f.spill == nil {
+		output, err := f.fs.client.PutObject(f.fs.ctx, &s3.PutObjectInput{
+			Bucket:   aws.String(f.fs.bucket),
+			Key:      aws.String(f.key),
+			Body:     bytes.NewReader(f.buffer),
+			Metadata: metadata,
+		}, f.cond.options()...)
+		if err != nil {
+			return preconditionError(err)
+		}
+		f.etag = aws.ToString(output.ETag)
+		return nil
+	}
+
+	defer f.removeSpill()
+	body := io.NewSectionReader(f.spill, 0, f.spillSize)
+
+	if f.spillSize <= DefaultPartSize {
+		output, err := f.fs.client.PutObject(f.fs.ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(f.fs.bucket),
+			Key:           aws.String(f.key),
+			Body:          body,
+			ContentLength: aws.Int64(f.spillSize),
+			Metadata:      metadata,
+		}, f.cond.options()...)
+		if err != nil {
+			return preconditionError(err)
+		}
+		f.etag = aws.ToString(output.ETag)
+		return nil
+	}
+
+	mu, err := f.fs.NewMultipartUpload(f.name)
+	if err != nil {
+		return err
+	}
+	mu.cond = f.cond
+	if err := mu.UploadFromReader(body); err != nil {
+		mu.Abort()
+		return err
+	}
+	if err := mu.Complete(); err != nil {
+		return err
+	}
+	f.etag = mu.etag
+	return nil
+}
+
+// removeSpill closes and deletes the spill file.
+func (f *File) removeSpill() {
+	if f.spill == nil {
+		return
+	}
+	f.spill.Close()
+	os.Remove(f.spill.Name())
+	f.spill = nil
+}
@@ -0,0 +1,57 @@
+package s3fs
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestFile_Spill(t *testing.T) {
+	fs := &FileSystem{spillThreshold: 8, spillDir: t.TempDir()}
+	f := &File{fs: fs, writing: true, buffer: []byte{}}
+
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if f.spill != nil {
+		t.Fatalf("buffer spilled below the threshold")
+	}
+
+	if _, err := f.Write([]byte(" world")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if f.spill == nil {
+		t.Fatalf("buffer not spilled above the threshold")
+	}
+	if f.buffer != nil || f.size() != 11 {
+		t.Errorf("size() = %d, buffer = %q after spill", f.size(), f.buffer)
+	}
+
+	if _, err := f.WriteAt([]byte("W"), 6); err != nil {
+		t.Fatalf("WriteAt() error = %v", err)
+	}
+	if err := f.Truncate(9); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(f.spill, 0, f.size()))
+	if err != nil || string(data) != "hello Wor" {
+		t.Errorf("spilled content = %q, %v, want \"hello Wor\"", data, err)
+	}
+
+	name := f.spill.Name()
+	f.removeSpill()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("spill file not removed")
+	}
+}
+
+func TestFile_SpillDisabled(t *testing.T) {
+	f := &File{fs: &FileSystem{}, writing: true, buffer: []byte{}}
+	if _, err := f.Write(make([]byte, 1<<20)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if f.spill != nil {
+		t.Errorf("buffer spilled with spilling disabled")
+	}
+}
@@ -0,0 +1,263 @@
+package s3fs
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// DefaultStatCacheSize is the number of entries kept by the stat cache when
+	// Config.StatCacheTTL is set and Config.StatCacheSize is not.
+	DefaultStatCacheSize = 10000
+
+	// DefaultDirCacheTTL is how long MkdirAll remembers existing directories
+	// when Config.DirCacheTTL is zero.
+	DefaultDirCacheTTL = 5 * time.Minute
+)
+
+// statCache is a bounded LRU cache of Stat results with a fixed TTL, and of
+// not-found results with a separate, usually shorter one. A nil *statCache
+// is valid and caches nothing.
+type statCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	negativeTTL time.Duration
+	size        int
+	lru         *list.List
+	entries     map[string]*list.Element
+}
+
+type statCacheEntry struct {
+	key     string
+	info    os.FileInfo
+	missing bool // Not-found result, see putMissing
+	expires time.Time
+}
+
+// newStatCache returns a cache for the given TTL and size, or nil if ttl is zero.
+func newStatCache(ttl time.Duration, size int) *statCache {
+	return newNegativeStatCache(ttl, 0, size)
+}
+
+// newNegativeStatCache returns a cache that also keeps not-found results
+// for negativeTTL, or nil if both TTLs are zero.
+func newNegativeStatCache(ttl, negativeTTL time.Duration, size int) *statCache {
+	if ttl <= 0 && negativeTTL <= 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = DefaultStatCacheSize
+	}
+	return &statCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		size:        size,
+		lru:         list.New(),
+		entries:     make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached info for key if it has not expired. The info of a
+// not-found result is nil.
+func (c *statCache) get(key string) (os.FileInfo, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*statCacheEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.info, true
+}
+
+// put stores info for key, evicting the least recently used entry if full.
+func (c *statCache) put(key string, info os.FileInfo) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	c.store(key, info, false, c.ttl)
+}
+
+// putMissing records that nothing exists at key, if not-found results are
+// cached.
+func (c *statCache) putMissing(key string) {
+	if c == nil || c.negativeTTL <= 0 {
+		return
+	}
+	c.store(key, nil, true, c.negativeTTL)
+}
+
+func (c *statCache) store(key string, info os.FileInfo, missing bool, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*statCacheEntry)
+		entry.info = info
+		entry.missing = missing
+		entry.expires = expires
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	for c.lru.Len() >= c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*statCacheEntry).key)
+	}
+	c.entries[key] = c.lru.PushFront(&statCacheEntry{key: key, info: info, missing: missing, expires: expires})
+}
+
+// invalidate drops the entries for the given keys, and the not-found
+// results of their parent directories, which exist once a key below them
+// is written.
+func (c *statCache) invalidate(keys ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+		for i := strings.IndexByte(key, '/'); i >= 0 && c.negativeTTL > 0; i = nextSlash(key, i) {
+			for _, dir := range []string{key[:i], key[:i+1]} {
+				if elem, ok := c.entries[dir]; ok && elem.Value.(*statCacheEntry).missing {
+					c.lru.Remove(elem)
+					delete(c.entries, dir)
+				}
+			}
+		}
+	}
+}
+
+// nextSlash returns the index of the first slash in s after index i, or -1.
+func nextSlash(s string, i int) int {
+	j := strings.IndexByte(s[i+1:], '/')
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+// invalidatePrefix drops every entry whose key starts with prefix.
+func (c *statCache) invalidatePrefix(prefix string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// len returns the number of cached entries, including expired ones.
+func (c *statCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// flightGroup deduplicates concurrent HeadObject requests for the same key,
+// so that many concurrent Stats of a hot key cost a single request. A nil
+// *flightGroup does not deduplicate.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done   chan struct{}
+	output *s3.HeadObjectOutput
+	err    error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// do returns the result of head for key, sharing the request of a
+// concurrent call for the same key. A caller whose shared request failed
+// because the context of the first caller ended makes its own request
+// under ctx.
+func (g *flightGroup) do(ctx context.Context, key string, head func(ctx context.Context) (*s3.HeadObjectOutput, error)) (*s3.HeadObjectOutput, error) {
+	if g == nil {
+		return head(ctx)
+	}
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if isContextError(call.err) && ctx.Err() == nil {
+			return head(ctx)
+		}
+		return call.output, call.err
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.output, call.err = head(ctx)
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+	return call.output, call.err
+}
+
+// isContextError reports whether err is due to a canceled or expired
+// context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// InvalidateStat drops the cached Stat results of the named files,
+// including not-found results (see Config.NegativeStatCacheTTL), so that
+// changes made by other clients are seen before the entries expire. A name
+// ending in a slash drops the results of everything below that directory.
+func (fs *FileSystem) InvalidateStat(names ...string) {
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "/")
+		key := fs.objectKey(name)
+		if strings.HasSuffix(name, "/") || name == "" {
+			fs.stats.invalidatePrefix(key)
+			fs.dirs.invalidatePrefix(key)
+			key = strings.TrimSuffix(key, "/")
+		}
+		fs.stats.invalidate(key, key+"/")
+		fs.dirs.invalidate(key, key+"/")
+	}
+}
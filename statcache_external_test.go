@@ -0,0 +1,57 @@
+package s3fs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_NegativeStatCache(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, NegativeStatCacheTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	heads := func() int64 { return fs.Stats().Operations["HeadObject"].Requests }
+
+	for i := 0; i < 3; i++ {
+		if ok, err := fs.Exists("missing.txt"); ok || err != nil {
+			t.Fatalf("Exists() = %v, %v; want false", ok, err)
+		}
+	}
+	if n := heads(); n != 1 {
+		t.Errorf("HeadObject requests = %d, want 1", n)
+	}
+
+	// Writes through the FileSystem drop the not-found results of the file
+	// and of its directories
+	if _, err := fs.Stat("dir"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat(dir) error = %v, want not exist", err)
+	}
+	if err := fs.WriteFile("dir/missing.txt", []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := fs.Stat("dir"); err != nil || !info.IsDir() {
+		t.Errorf("Stat(dir) after a write below it = %v, %v", info, err)
+	}
+
+	// Files created by other clients are seen after InvalidateStat
+	other, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.WriteFile("missing.txt", []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := fs.Exists("missing.txt"); ok {
+		t.Errorf("Exists() = true before InvalidateStat, want the cached result")
+	}
+	fs.InvalidateStat("/missing.txt")
+	if ok, err := fs.Exists("missing.txt"); !ok || err != nil {
+		t.Errorf("Exists() after InvalidateStat = %v, %v; want true", ok, err)
+	}
+}
@@ -0,0 +1,164 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestStatCache_Disabled(t *testing.T) {
+	c := newStatCache(0, 10)
+	if c != nil {
+		t.Fatalf("newStatCache(0) = %v, want nil", c)
+	}
+
+	c.put("a", &fileInfo{name: "a"})
+	if _, ok := c.get("a"); ok {
+		t.Errorf("get() on disabled cache should miss")
+	}
+	c.invalidate("a")
+}
+
+func TestStatCache_GetPut(t *testing.T) {
+	c := newStatCache(time.Minute, 2)
+
+	c.put("a", &fileInfo{name: "a"})
+	c.put("b", &fileInfo{name: "b"})
+	if info, ok := c.get("a"); !ok || info.Name() != "a" {
+		t.Fatalf("get(a) = %v, %v", info, ok)
+	}
+
+	// "b" is now the least recently used entry and is evicted
+	c.put("c", &fileInfo{name: "c"})
+	if _, ok := c.get("b"); ok {
+		t.Errorf("get(b) should miss after eviction")
+	}
+	if c.len() != 2 {
+		t.Errorf("len() = %d, want 2", c.len())
+	}
+
+	c.invalidate("a", "c")
+	if c.len() != 0 {
+		t.Errorf("len() after invalidate = %d, want 0", c.len())
+	}
+}
+
+func TestStatCache_Expiry(t *testing.T) {
+	c := newStatCache(time.Millisecond, 0)
+	if c.size != DefaultStatCacheSize {
+		t.Errorf("size = %d, want DefaultStatCacheSize", c.size)
+	}
+
+	c.put("a", &fileInfo{name: "a"})
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.get("a"); ok {
+		t.Errorf("get() should miss after TTL")
+	}
+}
+
+func TestStatCache_InvalidatePrefix(t *testing.T) {
+	c := newStatCache(time.Minute, 0)
+	for _, key := range []string{"a/", "a/b/", "a/b/c/", "ab/"} {
+		c.put(key, nil)
+	}
+
+	c.invalidatePrefix("a/b/")
+	for key, want := range map[string]bool{"a/": true, "a/b/": false, "a/b/c/": false, "ab/": true} {
+		if _, ok := c.get(key); ok != want {
+			t.Errorf("get(%q) = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestStatCache_Missing(t *testing.T) {
+	c := newNegativeStatCache(0, time.Minute, 0)
+	c.put("a", &fileInfo{name: "a"})
+	if _, ok := c.get("a"); ok {
+		t.Errorf("get() of a positive result hit without StatCacheTTL")
+	}
+
+	for _, key := range []string{"a", "a/", "a/b", "c"} {
+		c.putMissing(key)
+	}
+	if info, ok := c.get("a/b"); !ok || info != nil {
+		t.Fatalf("get(a/b) = %v, %v; want a not-found result", info, ok)
+	}
+
+	// Writing a/b/c creates the directories a and a/b
+	c.invalidate("a/b/c")
+	for key, want := range map[string]bool{"a": false, "a/": false, "a/b": false, "c": true} {
+		if _, ok := c.get(key); ok != want {
+			t.Errorf("get(%q) = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestFlightGroup(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	head := func(ctx context.Context) (*s3.HeadObjectOutput, error) {
+		calls.Add(1)
+		<-release
+		return &s3.HeadObjectOutput{ETag: aws.String(`"x"`)}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if out, err := g.do(context.Background(), "key", head); err != nil || aws.ToString(out.ETag) != `"x"` {
+				t.Errorf("do() = %v, %v", out, err)
+			}
+		}()
+	}
+	// Wait for the first call to start before releasing it
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("head called %d times, want 1", n)
+	}
+}
+
+func TestFlightGroup_CanceledLeader(t *testing.T) {
+	g := newFlightGroup()
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := g.do(ctx, "key", func(ctx context.Context) (*s3.HeadObjectOutput, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		done <- err
+	}()
+	<-started
+
+	follower := make(chan error)
+	go func() {
+		_, err := g.do(context.Background(), "key", func(ctx context.Context) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{}, nil
+		})
+		follower <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader error = %v, want context.Canceled", err)
+	}
+	if err := <-follower; err != nil {
+		t.Errorf("follower error = %v, want its own request to succeed", err)
+	}
+}
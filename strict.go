@@ -0,0 +1,76 @@
+package s3fs
+
+import (
+	"errors"
+	iofs "io/fs"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// checkMkdirStrict fails with os.ErrExist if a file or directory exists at
+// the directory name, for Mkdir with Config.StrictSemantics.
+func (fs *FileSystem) checkMkdirStrict(name string) error {
+	_, err := fs.Stat(strings.TrimSuffix(name, "/"))
+	if err == nil {
+		return fs.wrapError("Mkdir", name, os.ErrExist)
+	}
+	if !errors.Is(err, iofs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// removeStrict implements Remove with Config.StrictSemantics: a file is
+// removed, a directory only if it is empty, and a missing name is an error.
+func (fs *FileSystem) removeStrict(name string) error {
+	file := strings.TrimSuffix(name, "/")
+	if file != name || file == "" {
+		return fs.removeDirStrict(name)
+	}
+
+	_, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.objectKey(name)),
+	})
+	if err == nil {
+		return fs.remove(name)
+	}
+	if httpStatus(err) != 404 {
+		return fs.wrapError("Remove", name, err)
+	}
+	return fs.removeDirStrict(name + "/")
+}
+
+// removeDirStrict removes the marker object of the directory name (with
+// trailing slash) if nothing else is stored below it.
+func (fs *FileSystem) removeDirStrict(name string) error {
+	dir := fs.objectKey(name)
+	if dir == "/" || dir == fs.objectKey("") {
+		return fs.wrapError("Remove", name, ErrRootPrefix)
+	}
+
+	// Two keys are enough to tell the marker from the contents
+	output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(fs.bucket),
+		Prefix:  aws.String(dir),
+		MaxKeys: aws.Int32(2),
+	})
+	if err != nil {
+		return fs.wrapError("Remove", name, err)
+	}
+	marker := false
+	for _, obj := range output.Contents {
+		if aws.ToString(obj.Key) != dir {
+			return fs.wrapError("Remove", name, syscall.ENOTEMPTY)
+		}
+		marker = true
+	}
+	if !marker {
+		return fs.wrapError("Remove", name, ErrNotExist)
+	}
+	return fs.remove(name)
+}
@@ -0,0 +1,65 @@
+package s3fs_test
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_StrictSemantics(t *testing.T) {
+	for _, implicit := range []bool{false, true} {
+		fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: s3fstest.NewClient(), StrictSemantics: true, ImplicitDirs: implicit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, fs, "dir/file.txt", "data")
+
+		if err := fs.Mkdir("dir", 0755); !errors.Is(err, os.ErrExist) {
+			t.Errorf("Mkdir(existing dir) error = %v, want ErrExist", err)
+		}
+		if err := fs.Mkdir("dir/file.txt", 0755); !errors.Is(err, os.ErrExist) {
+			t.Errorf("Mkdir(existing file) error = %v, want ErrExist", err)
+		}
+		if err := fs.MkdirAll("dir", 0755); err != nil {
+			t.Errorf("MkdirAll(existing dir) error = %v", err)
+		}
+
+		if _, err := fs.Open("missing.txt"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Open(missing) error = %v, want ErrNotExist", err)
+		}
+		if f, err := fs.Open("dir/file.txt"); err != nil {
+			t.Errorf("Open() error = %v", err)
+		} else {
+			f.Close()
+		}
+
+		if err := fs.Remove("missing.txt"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Remove(missing) error = %v, want ErrNotExist", err)
+		}
+		if err := fs.Remove("dir"); !errors.Is(err, syscall.ENOTEMPTY) {
+			t.Errorf("Remove(non-empty dir) error = %v, want ENOTEMPTY", err)
+		}
+		if err := fs.Remove("dir/file.txt"); err != nil {
+			t.Fatalf("Remove(file) error = %v", err)
+		}
+		if err := fs.RemoveAll("dir/file.txt"); err != nil {
+			t.Errorf("RemoveAll(missing) error = %v", err)
+		}
+
+		if !implicit {
+			if err := fs.Mkdir("empty", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := fs.Remove("empty"); err != nil {
+				t.Errorf("Remove(empty dir) error = %v", err)
+			}
+			if ok, _ := fs.Exists("empty"); ok {
+				t.Errorf("Remove(empty dir) left the directory")
+			}
+		}
+	}
+}
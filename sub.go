@@ -0,0 +1,85 @@
+package s3fs
+
+import (
+	iofs "io/fs"
+	"os"
+	"strings"
+)
+
+// Sub returns a FileSystem rooted at the directory prefix, so that parts of
+// an application can be handed an isolated directory of a shared bucket.
+// Names passed to the returned FileSystem are relative to prefix, and names
+// it returns (from Readdir, Walk and the like) are relative to prefix too.
+// Sub of a Sub nests. The client, configuration and caches are shared with
+// fs; loaded packs are not. Internal objects (see Config.SystemPrefix) are
+// still stored below the system prefix of the bucket.
+func (fs *FileSystem) Sub(prefix string) *FileSystem {
+	c := *fs
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		c.root = fs.normalize(fs.root + prefix + "/")
+		c.packs = newPackTable()
+	}
+	return &c
+}
+
+// Root returns the prefix fs is rooted at (see Sub), with a trailing slash,
+// or "" for the bucket root.
+func (fs *FileSystem) Root() string {
+	return fs.root
+}
+
+// FS returns a read-only io/fs view of fs. It implements fs.SubFS with
+// FileSystem.Sub, so fs.Sub on it does not wrap every call.
+func (fs *FileSystem) FS() iofs.FS {
+	return ioFS{fs}
+}
+
+// ioFS adapts a FileSystem to io/fs.
+type ioFS struct {
+	fs *FileSystem
+}
+
+var (
+	_ iofs.FS     = ioFS{}
+	_ iofs.SubFS  = ioFS{}
+	_ iofs.StatFS = ioFS{}
+)
+
+// Open opens the named file or directory for reading.
+func (f ioFS) Open(name string) (iofs.File, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrInvalid}
+	}
+	if name == "." {
+		name = ""
+	}
+
+	file, err := f.fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return file.(*File), nil
+}
+
+// Stat returns file info for the named file or directory.
+func (f ioFS) Stat(name string) (iofs.FileInfo, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &iofs.PathError{Op: "stat", Path: name, Err: iofs.ErrInvalid}
+	}
+	if name == "." {
+		name = ""
+	}
+	return f.fs.Stat(name)
+}
+
+// Sub returns an io/fs view of the directory dir.
+func (f ioFS) Sub(dir string) (iofs.FS, error) {
+	if !iofs.ValidPath(dir) {
+		return nil, &iofs.PathError{Op: "sub", Path: dir, Err: iofs.ErrInvalid}
+	}
+	if dir == "." {
+		return f, nil
+	}
+	return ioFS{f.fs.Sub(dir)}, nil
+}
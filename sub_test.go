@@ -0,0 +1,72 @@
+package s3fs
+
+import (
+	"errors"
+	iofs "io/fs"
+	"testing"
+)
+
+func TestSub(t *testing.T) {
+	fs := &FileSystem{packs: newPackTable()}
+	app := fs.Sub("/apps/")
+	users := app.Sub("users")
+
+	if app.Root() != "apps/" || users.Root() != "apps/users/" || fs.Root() != "" {
+		t.Errorf("Root() = %q, %q, %q", fs.Root(), app.Root(), users.Root())
+	}
+	if got := users.objectKey("/alice.json"); got != "apps/users/alice.json" {
+		t.Errorf("objectKey() = %q, want apps/users/alice.json", got)
+	}
+	if got, _ := users.logicalName("apps/users/alice.json"); got != "alice.json" {
+		t.Errorf("logicalName() = %q, want alice.json", got)
+	}
+	if fs.Sub("").root != "" {
+		t.Errorf("Sub(\"\") should keep the root")
+	}
+	if users.packs == fs.packs {
+		t.Errorf("Sub() should not share loaded packs")
+	}
+}
+
+func TestSub_Codec(t *testing.T) {
+	codec, _ := NewHMACCodec([]byte("k"), make([]byte, 32))
+	fs := &FileSystem{codec: codec, names: &nameTable{}, packs: newPackTable()}
+	sub := fs.Sub("private")
+
+	key := sub.objectKey("a.txt")
+	if key != fs.objectKey("private/a.txt") {
+		t.Errorf("Sub objectKey() = %q, want the parent's key for private/a.txt", key)
+	}
+	if name, err := sub.logicalName(key); err != nil || name != "a.txt" {
+		t.Errorf("Sub logicalName() = %q, %v", name, err)
+	}
+	if name, err := fs.logicalName(key); err != nil || name != "private/a.txt" {
+		t.Errorf("parent logicalName() = %q, %v", name, err)
+	}
+
+	metadata, _ := sub.nameMetadata("a.txt")
+	if name, _ := codec.OpenName(metadata[NameMetadataKey]); name != "private/a.txt" {
+		t.Errorf("nameMetadata() sealed %q, want the bucket-relative name", name)
+	}
+}
+
+func TestIOFS_Sub(t *testing.T) {
+	fsys := (&FileSystem{packs: newPackTable()}).FS()
+
+	sub, err := iofs.Sub(fsys, "a/b")
+	if err != nil {
+		t.Fatalf("fs.Sub() error = %v", err)
+	}
+	if root := sub.(ioFS).fs.Root(); root != "a/b/" {
+		t.Errorf("fs.Sub() root = %q, want a/b/", root)
+	}
+
+	for _, name := range []string{"../x", "/abs", "a//b"} {
+		if _, err := iofs.Sub(fsys, name); !errors.Is(err, iofs.ErrInvalid) {
+			t.Errorf("fs.Sub(%q) error = %v, want fs.ErrInvalid", name, err)
+		}
+		if _, err := fsys.Open(name); !errors.Is(err, iofs.ErrInvalid) {
+			t.Errorf("Open(%q) error = %v, want fs.ErrInvalid", name, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package s3fs
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/absfs/absfs"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// SymlinkMetadataKey is the user metadata key that marks an object as a
+// symbolic link. The body of a link object is the path of its target.
+const SymlinkMetadataKey = "s3fs-symlink"
+
+const (
+	// maxSymlinkHops is the number of links followed before giving up with
+	// ErrSymlinkLoop, as Linux does with ELOOP.
+	maxSymlinkHops = 40
+
+	// maxSymlinkSize bounds the size of link objects. Larger objects are
+	// never links, so Walk does not check them.
+	maxSymlinkSize = 4096
+)
+
+var _ absfs.SymlinkFileSystem = (*FileSystem)(nil)
+
+// isSymlink reports whether the object metadata marks a symbolic link.
+func isSymlink(metadata map[string]string) bool {
+	return metadata[SymlinkMetadataKey] != ""
+}
+
+// Symlink creates newname as a symbolic link to oldname. S3 has no links, so
+// the link is an object whose body is oldname and whose metadata carries
+// SymlinkMetadataKey. Relative targets are resolved from the directory of the
+// link. Stat follows links; Lstat and Readlink describe the link itself.
+// Listings do not return metadata, so Readdir reports links as small regular
+// files; see Config.FollowSymlinks for OpenFile and Walk.
+func (fs *FileSystem) Symlink(oldname, newname string) error {
+	newname = strings.TrimPrefix(newname, "/")
+	if oldname == "" || newname == "" || strings.HasSuffix(newname, "/") {
+		return fs.wrapError("Symlink", newname, os.ErrInvalid)
+	}
+	if len(oldname) > maxSymlinkSize {
+		return fs.wrapError("Symlink", newname, os.ErrInvalid)
+	}
+	key := fs.objectKey(newname)
+
+	_, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err == nil {
+		return fs.wrapError("Symlink", newname, os.ErrExist)
+	}
+	if httpStatus(err) != 404 {
+		return fs.wrapError("Symlink", newname, err)
+	}
+
+	metadata, err := fs.nameMetadata(newname)
+	if err != nil {
+		return fs.wrapError("Symlink", newname, err)
+	}
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata[SymlinkMetadataKey] = "true"
+
+	_, err = fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(fs.bucket),
+		Key:           aws.String(key),
+		Body:          strings.NewReader(oldname),
+		ContentLength: aws.Int64(int64(len(oldname))),
+		Metadata:      metadata,
+	})
+	fs.stats.invalidate(key)
+	if err != nil {
+		return fs.wrapError("Symlink", newname, err)
+	}
+	return fs.manifestPut(key, int64(len(oldname)), false)
+}
+
+// Readlink returns the target of the symbolic link name. It fails with
+// os.ErrInvalid if name is not a link.
+func (fs *FileSystem) Readlink(name string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+
+	output, err := fs.client.GetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.objectKey(name)),
+	})
+	if err != nil {
+		return "", fs.wrapError("Readlink", name, err)
+	}
+	defer output.Body.Close()
+
+	if !isSymlink(output.Metadata) {
+		return "", fs.wrapError("Readlink", name, os.ErrInvalid)
+	}
+	target, err := io.ReadAll(io.LimitReader(output.Body, maxSymlinkSize))
+	if err != nil {
+		return "", fs.wrapError("Readlink", name, err)
+	}
+	return string(target), nil
+}
+
+// Lchown is not supported for S3, like Chown.
+func (fs *FileSystem) Lchown(name string, uid, gid int) error {
+	return absfs.ErrNotImplemented
+}
+
+// linkTarget returns the name that the link name pointing to target refers to.
+func linkTarget(name, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/")
+	}
+	return strings.TrimPrefix(path.Join(path.Dir("/"+name), target), "/")
+}
+
+// resolveLinks follows the chain of links starting at name and returns the
+// first name that is not a link. Names that do not exist as objects, such as
+// directories, end the chain.
+func (fs *FileSystem) resolveLinks(op, name string) (string, error) {
+	for hops := 0; hops < maxSymlinkHops; hops++ {
+		output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(fs.bucket),
+			Key:    aws.String(fs.objectKey(name)),
+		})
+		if err != nil {
+			if httpStatus(err) == 404 {
+				return name, nil
+			}
+			return "", fs.wrapError(op, name, err)
+		}
+		if !isSymlink(output.Metadata) {
+			return name, nil
+		}
+
+		target, err := fs.Readlink(name)
+		if err != nil {
+			return "", err
+		}
+		name = linkTarget(name, target)
+	}
+	return "", fs.wrapError(op, name, ErrSymlinkLoop)
+}
+
+// statLink returns the FileInfo of the target of the link name, under the
+// base name of the link, as os.Stat does.
+func (fs *FileSystem) statLink(name string) (os.FileInfo, error) {
+	target, err := fs.resolveLinks("Stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo(info, path.Base(name)), nil
+}
+
+// renamedInfo returns info with its name replaced.
+func renamedInfo(info os.FileInfo, name string) os.FileInfo {
+	fi, ok := info.(*fileInfo)
+	if !ok {
+		return info
+	}
+	renamed := *fi
+	renamed.name = name
+	return &renamed
+}
+
+// followLinks wraps fn for Walk with Config.FollowSymlinks. Links are passed
+// to fn with the FileInfo of their target, and links to directories are
+// walked as if the target's tree was stored below the link. visited holds
+// the directories entered through the current chain of links, so that
+// cycles are reported as links instead of being followed.
+func (fs *FileSystem) followLinks(fn filepath.WalkFunc, visited map[string]bool) filepath.WalkFunc {
+	return func(p string, info os.FileInfo, err error) error {
+		if err != nil || info == nil || info.IsDir() || info.Size() > maxSymlinkSize {
+			return fn(p, info, err)
+		}
+
+		linfo, lerr := fs.Lstat(p)
+		if lerr != nil || linfo.Mode()&os.ModeSymlink == 0 {
+			return fn(p, info, nil)
+		}
+
+		// Broken links and loops are reported as links, like filepath.Walk
+		target, terr := fs.resolveLinks("Walk", p)
+		if terr != nil {
+			return fn(p, linfo, nil)
+		}
+		tinfo, terr := fs.Stat(target)
+		if terr != nil {
+			return fn(p, linfo, nil)
+		}
+		tinfo = renamedInfo(tinfo, path.Base(p))
+		if !tinfo.IsDir() {
+			return fn(p, tinfo, nil)
+		}
+
+		dir := strings.TrimSuffix(target, "/") + "/"
+		if visited[dir] {
+			return fn(p, linfo, nil)
+		}
+		if err := fn(p+"/", tinfo, nil); err != nil {
+			if err == filepath.SkipDir {
+				return nil
+			}
+			return err
+		}
+
+		chain := make(map[string]bool, len(visited)+1)
+		for d := range visited {
+			chain[d] = true
+		}
+		chain[dir] = true
+		err = fs.walk(dir, func(q string, info os.FileInfo, err error) error {
+			if q == dir {
+				return nil
+			}
+			return fn(p+"/"+strings.TrimPrefix(q, dir), info, err)
+		}, chain)
+		if err == filepath.SkipDir {
+			return nil
+		}
+		return err
+	}
+}
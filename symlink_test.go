@@ -0,0 +1,78 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_Symlink(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client, FollowSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "data/a.txt", "hello")
+
+	if err := fs.Symlink("data/a.txt", "link.txt"); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	if err := fs.Symlink("../data", "links/dir"); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	if err := fs.Symlink("elsewhere", "link.txt"); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Symlink() over an existing name error = %v, want os.ErrExist", err)
+	}
+
+	if target, err := fs.Readlink("link.txt"); err != nil || target != "data/a.txt" {
+		t.Errorf("Readlink() = %q, %v", target, err)
+	}
+	if _, err := fs.Readlink("data/a.txt"); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Readlink() of a file error = %v, want os.ErrInvalid", err)
+	}
+
+	info, err := fs.Lstat("link.txt")
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat() = %v, %v; want a symlink", info, err)
+	}
+	info, err = fs.Stat("link.txt")
+	if err != nil || info.Size() != 5 || info.Name() != "link.txt" {
+		t.Errorf("Stat() = %v, %v; want the target's size under the link's name", info, err)
+	}
+	if info, err := fs.Stat("links/dir"); err != nil || !info.IsDir() {
+		t.Errorf("Stat() of a directory link = %v, %v", info, err)
+	}
+
+	f, err := fs.Open("link.txt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "hello" {
+		t.Errorf("reading through the link = %q, want hello", data)
+	}
+
+	var walked []string
+	err = fs.Walk("links", func(p string, info os.FileInfo, err error) error {
+		walked = append(walked, p)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if got := strings.Join(walked, ","); got != "links/,links/dir/,links/dir/a.txt" {
+		t.Errorf("Walk() = %s", got)
+	}
+
+	fs.Symlink("loop2", "loop1")
+	fs.Symlink("loop1", "loop2")
+	if _, err := fs.Stat("loop1"); !errors.Is(err, s3fs.ErrSymlinkLoop) {
+		t.Errorf("Stat() of a link loop error = %v, want ErrSymlinkLoop", err)
+	}
+}
@@ -0,0 +1,432 @@
+package s3fs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DefaultSyncConcurrency is the number of files SyncUp and SyncDown transfer
+// in parallel when SyncOptions.Concurrency is zero.
+const DefaultSyncConcurrency = 8
+
+// SyncOptions controls SyncUp and SyncDown. A nil *SyncOptions uses the
+// defaults.
+type SyncOptions struct {
+	// Delete removes files from the destination that do not exist in the
+	// source.
+	Delete bool
+
+	// Checksum compares the MD5 of local files with the S3 ETag instead of
+	// comparing modification times. Objects uploaded with multipart uploads
+	// have no MD5 ETag and fall back to modification times.
+	Checksum bool
+
+	// DryRun reports what would be transferred and deleted without changing
+	// anything.
+	DryRun bool
+
+	Concurrency int // Parallel transfers (default DefaultSyncConcurrency)
+}
+
+// SyncSummary reports the outcome of SyncUp or SyncDown.
+type SyncSummary struct {
+	Transferred []string // Names transferred, relative to the synced directories
+	Deleted     []string // Names deleted from the destination
+	Skipped     int      // Files already up to date
+	Bytes       int64    // Bytes transferred
+}
+
+// syncEntry is a file on either side of a sync, keyed by its relative name.
+type syncEntry struct {
+	size    int64
+	modTime time.Time
+	etag    string // S3 only
+	key     string // S3 only
+}
+
+// SyncUp makes the directory prefix in the bucket match the local directory
+// localDir, like "aws s3 sync": files that are missing remotely, differ in
+// size or are newer locally (or differ in MD5 with SyncOptions.Checksum) are
+// uploaded with bounded concurrency. The uploads are staged below the system
+// prefix and then moved into place, together with the deletions of
+// SyncOptions.Delete, as a transaction (see Begin), so a SyncUp interrupted
+// by a crash can be finished with ResumeTxn or undone with RollbackTxn.
+// Transfers stop at the first error, and then nothing is changed.
+func (fs *FileSystem) SyncUp(localDir, prefix string, opts *SyncOptions) (*SyncSummary, error) {
+	opts = syncDefaults(opts)
+	prefix = syncPrefix(prefix)
+
+	local, err := listLocal(localDir)
+	if err != nil {
+		return nil, fs.wrapError("SyncUp", localDir, err)
+	}
+	remote, err := fs.listRemote(prefix)
+	if err != nil {
+		return nil, fs.wrapError("SyncUp", prefix, err)
+	}
+
+	summary := &SyncSummary{}
+	var names []string
+	for name, l := range local {
+		r, ok := remote[name]
+		if ok && !syncNeeded(l, r, filepath.Join(localDir, filepath.FromSlash(name)), opts, true) {
+			summary.Skipped++
+			continue
+		}
+		names = append(names, name)
+	}
+
+	txn := fs.Begin()
+	var mu sync.Mutex
+	err = fs.syncTransfer(names, local, summary, opts, func(name string) error {
+		mu.Lock()
+		key := txn.putStaged(prefix + name)
+		mu.Unlock()
+		return fs.uploadLocal(filepath.Join(localDir, filepath.FromSlash(name)), prefix+name, key)
+	})
+	if err != nil {
+		txn.discard()
+		return summary, fs.wrapError("SyncUp", prefix, err)
+	}
+
+	if opts.Delete {
+		for name, r := range remote {
+			if _, ok := local[name]; !ok {
+				txn.deleteKey(r.key)
+				summary.Deleted = append(summary.Deleted, name)
+			}
+		}
+	}
+	if opts.DryRun {
+		return summary, nil
+	}
+	if err := txn.commit(); err != nil {
+		return summary, fs.wrapError("SyncUp", prefix, err)
+	}
+	return summary, nil
+}
+
+// SyncDown makes the local directory localDir match the directory prefix in
+// the bucket. Files that are missing locally, differ in size or are newer
+// remotely (or differ in MD5 with SyncOptions.Checksum) are downloaded, and
+// the modification time of each downloaded file is set to that of its object.
+func (fs *FileSystem) SyncDown(prefix, localDir string, opts *SyncOptions) (*SyncSummary, error) {
+	opts = syncDefaults(opts)
+	prefix = syncPrefix(prefix)
+
+	remote, err := fs.listRemote(prefix)
+	if err != nil {
+		return nil, fs.wrapError("SyncDown", prefix, err)
+	}
+	local, err := listLocal(localDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fs.wrapError("SyncDown", localDir, err)
+	}
+
+	summary := &SyncSummary{}
+	var names []string
+	for name, r := range remote {
+		l, ok := local[name]
+		if ok && !syncNeeded(l, r, filepath.Join(localDir, filepath.FromSlash(name)), opts, false) {
+			summary.Skipped++
+			continue
+		}
+		names = append(names, name)
+	}
+
+	err = fs.syncTransfer(names, remote, summary, opts, func(name string) error {
+		r := remote[name]
+		return fs.downloadLocal(r.key, filepath.Join(localDir, filepath.FromSlash(name)), r.modTime)
+	})
+	if err != nil {
+		return summary, fs.wrapError("SyncDown", prefix, err)
+	}
+
+	if opts.Delete {
+		for name := range local {
+			if _, ok := remote[name]; ok {
+				continue
+			}
+			summary.Deleted = append(summary.Deleted, name)
+			if opts.DryRun {
+				continue
+			}
+			if err := os.Remove(filepath.Join(localDir, filepath.FromSlash(name))); err != nil {
+				return summary, fs.wrapError("SyncDown", name, err)
+			}
+		}
+	}
+	return summary, nil
+}
+
+// syncDefaults fills in the defaults for opts.
+func syncDefaults(opts *SyncOptions) *SyncOptions {
+	o := SyncOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultSyncConcurrency
+	}
+	return &o
+}
+
+// syncPrefix normalizes a sync prefix to a directory prefix.
+func syncPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// syncNeeded reports whether src must be transferred over dst. up is true
+// when the local file (at localPath) is the source.
+func syncNeeded(local, remote syncEntry, localPath string, opts *SyncOptions, up bool) bool {
+	if local.size != remote.size {
+		return true
+	}
+	if opts.Checksum {
+		if sum := etagMD5(remote.etag); sum != "" {
+			localSum, err := fileMD5(localPath)
+			return err != nil || localSum != sum
+		}
+	}
+	if up {
+		return local.modTime.After(remote.modTime)
+	}
+	return remote.modTime.After(local.modTime)
+}
+
+// syncTransfer runs transfer for names with opts.Concurrency workers and
+// records the results in summary.
+func (fs *FileSystem) syncTransfer(names []string, src map[string]syncEntry, summary *SyncSummary, opts *SyncOptions, transfer func(name string) error) error {
+	if opts.DryRun {
+		for _, name := range names {
+			summary.Transferred = append(summary.Transferred, name)
+			summary.Bytes += src[name].size
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(fs.ctx)
+	defer cancel()
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				err := transfer(name)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				} else if err == nil {
+					summary.Transferred = append(summary.Transferred, name)
+					summary.Bytes += src[name].size
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, name := range names {
+		select {
+		case work <- name:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = fs.ctx.Err()
+	}
+	return firstErr
+}
+
+// listLocal returns the regular files below dir keyed by their slash
+// separated relative names.
+func listLocal(dir string) (map[string]syncEntry, error) {
+	entries := make(map[string]syncEntry)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		entries[filepath.ToSlash(rel)] = syncEntry{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return entries, err
+}
+
+// listRemote returns the objects below the directory prefix keyed by their
+// names relative to prefix. Directory markers and system objects are skipped.
+func (fs *FileSystem) listRemote(prefix string) (map[string]syncEntry, error) {
+	entries := make(map[string]syncEntry)
+	var continuationToken *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(fs.objectKey(prefix)),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			if strings.HasSuffix(key, "/") || fs.isSystemKey(key) {
+				continue
+			}
+			name, err := fs.logicalName(key)
+			if err != nil {
+				return nil, err
+			}
+			entries[strings.TrimPrefix(name, prefix)] = syncEntry{
+				size:    aws.ToInt64(obj.Size),
+				modTime: aws.ToTime(obj.LastModified),
+				etag:    aws.ToString(obj.ETag),
+				key:     key,
+			}
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+	return entries, nil
+}
+
+// uploadLocal uploads the local file at localPath under key with the
+// attributes of the named file. Files larger than DefaultPartSize are
+// uploaded with a multipart upload.
+func (fs *FileSystem) uploadLocal(localPath, name, key string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > DefaultPartSize {
+		mu, err := fs.newMultipartUploadTo(key, name, nil)
+		if err != nil {
+			return err
+		}
+		if err := mu.UploadFromReader(file); err != nil {
+			mu.Abort()
+			return err
+		}
+		return mu.Complete()
+	}
+
+	metadata, err := fs.nameMetadata(name)
+	if err != nil {
+		return err
+	}
+	_, err = fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(fs.bucket),
+		Key:           aws.String(key),
+		Body:          file,
+		ContentLength: aws.Int64(info.Size()),
+		Metadata:      metadata,
+	})
+	return err
+}
+
+// downloadLocal downloads the object key to localPath through a temporary
+// file in the same directory and sets its modification time to modTime.
+func (fs *FileSystem) downloadLocal(key, localPath string, modTime time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return err
+	}
+
+	output, err := fs.client.GetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	defer output.Body.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), "."+path.Base(filepath.ToSlash(localPath))+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, output.Body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), modTime, modTime); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), localPath)
+}
+
+// etagMD5 returns the hex MD5 contained in an S3 ETag, or "" for multipart
+// ETags, which are not MD5 digests of the content.
+func etagMD5(etag string) string {
+	etag = strings.Trim(etag, `"`)
+	if len(etag) != md5.Size*2 || strings.Contains(etag, "-") {
+		return ""
+	}
+	return etag
+}
+
+// fileMD5 returns the hex MD5 of the local file at p.
+func fileMD5(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
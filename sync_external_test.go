@@ -0,0 +1,60 @@
+package s3fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestSyncUp_CrashRecovery(t *testing.T) {
+	local := t.TempDir()
+	for name, data := range map[string]string{"a.txt": "a", "b.txt": "b"} {
+		if err := os.WriteFile(filepath.Join(local, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, resume := range []bool{true, false} {
+		client := &crashingClient{Client: s3fstest.NewClient(), crashAfter: -1}
+		fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, fs, "dir/old.txt", "old")
+
+		client.crashAfter = 2 // the backup of old.txt and the first put
+		if _, err := fs.SyncUp(local, "dir", &s3fs.SyncOptions{Delete: true}); err == nil {
+			t.Fatal("SyncUp() during a crash succeeded")
+		}
+		client.crashAfter = -1
+
+		recoverTxn(t, fs, resume)
+		want := "dir/old.txt=old"
+		if resume {
+			want = "dir/a.txt=a dir/b.txt=b"
+		}
+		if got := treeContents(fs); got != want {
+			t.Errorf("after recovery (resume %v) = %s, want %s", resume, got, want)
+		}
+	}
+
+	// Without a crash the staged uploads and deletions take effect
+	fs := s3fstest.New("bucket")
+	writeFile(t, fs, "dir/old.txt", "old")
+	summary, err := fs.SyncUp(local, "dir", &s3fs.SyncOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("SyncUp() error = %v", err)
+	}
+	if len(summary.Transferred) != 2 || len(summary.Deleted) != 1 {
+		t.Errorf("SyncUp() summary = %+v, want 2 transferred and 1 deleted", summary)
+	}
+	if got := treeContents(fs); got != "dir/a.txt=a dir/b.txt=b" {
+		t.Errorf("after SyncUp() = %s", got)
+	}
+	if ids, _ := fs.PendingTxns(); len(ids) != 0 {
+		t.Errorf("PendingTxns() after SyncUp() = %v", ids)
+	}
+}
@@ -0,0 +1,97 @@
+package s3fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEtagMD5(t *testing.T) {
+	tests := []struct {
+		etag string
+		want string
+	}{
+		{`"9e107d9d372bb6826bd81d3542a419d6"`, "9e107d9d372bb6826bd81d3542a419d6"},
+		{`"9e107d9d372bb6826bd81d3542a419d6-3"`, ""},
+		{`"abc"`, ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := etagMD5(tt.etag); got != tt.want {
+			t.Errorf("etagMD5(%q) = %q, want %q", tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestSyncNeeded(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(p, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	helloMD5 := `"5d41402abc4b2a76b9719d911017c592"`
+
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := old.Add(time.Hour)
+	opts := syncDefaults(nil)
+	checksum := syncDefaults(&SyncOptions{Checksum: true})
+
+	tests := []struct {
+		name   string
+		local  syncEntry
+		remote syncEntry
+		opts   *SyncOptions
+		up     bool
+		want   bool
+	}{
+		{"SizeDiffers", syncEntry{size: 5, modTime: old}, syncEntry{size: 4, modTime: old}, opts, true, true},
+		{"SameUp", syncEntry{size: 5, modTime: old}, syncEntry{size: 5, modTime: old}, opts, true, false},
+		{"LocalNewerUp", syncEntry{size: 5, modTime: newer}, syncEntry{size: 5, modTime: old}, opts, true, true},
+		{"LocalNewerDown", syncEntry{size: 5, modTime: newer}, syncEntry{size: 5, modTime: old}, opts, false, false},
+		{"RemoteNewerDown", syncEntry{size: 5, modTime: old}, syncEntry{size: 5, modTime: newer}, opts, false, true},
+		{"ChecksumMatch", syncEntry{size: 5, modTime: newer}, syncEntry{size: 5, modTime: old, etag: helloMD5}, checksum, true, false},
+		{"ChecksumDiffers", syncEntry{size: 5, modTime: old}, syncEntry{size: 5, modTime: old, etag: `"00000000000000000000000000000000"`}, checksum, true, true},
+		{"ChecksumMultipart", syncEntry{size: 5, modTime: newer}, syncEntry{size: 5, modTime: old, etag: `"abc-2"`}, checksum, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncNeeded(tt.local, tt.remote, p, tt.opts, tt.up); got != tt.want {
+				t.Errorf("syncNeeded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListLocal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"top.txt": "1", "a/b/deep.txt": "123"} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := listLocal(dir)
+	if err != nil {
+		t.Fatalf("listLocal() error = %v", err)
+	}
+	if len(entries) != 2 || entries["top.txt"].size != 1 || entries["a/b/deep.txt"].size != 3 {
+		t.Errorf("listLocal() = %v", entries)
+	}
+}
+
+func TestSyncDefaults(t *testing.T) {
+	if got := syncDefaults(nil).Concurrency; got != DefaultSyncConcurrency {
+		t.Errorf("Concurrency = %d, want %d", got, DefaultSyncConcurrency)
+	}
+	opts := &SyncOptions{Concurrency: 2}
+	if got := syncDefaults(opts); got == opts || got.Concurrency != 2 {
+		t.Errorf("syncDefaults() should copy the options and keep Concurrency")
+	}
+	if got := syncPrefix("/backups/"); got != "backups/" {
+		t.Errorf("syncPrefix() = %q, want backups/", got)
+	}
+}
@@ -0,0 +1,45 @@
+package s3fs
+
+import (
+	"path"
+	"strings"
+)
+
+// DefaultSystemPrefix is the key prefix reserved for objects that s3fs
+// creates for its own bookkeeping.
+const DefaultSystemPrefix = ".s3fs/"
+
+// systemPrefix normalizes a configured system prefix to a key prefix without
+// a leading and with a trailing slash.
+func systemPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return DefaultSystemPrefix
+	}
+	return prefix + "/"
+}
+
+// reservedPrefix returns the system prefix, falling back to the default for
+// a FileSystem that was not created by New.
+func (fs *FileSystem) reservedPrefix() string {
+	if fs.systemPrefix == "" {
+		return DefaultSystemPrefix
+	}
+	return fs.systemPrefix
+}
+
+// systemKey returns the key of an internal object below the system prefix.
+func (fs *FileSystem) systemKey(elem ...string) string {
+	return fs.reservedPrefix() + strings.TrimPrefix(path.Join(elem...), "/")
+}
+
+// isSystemKey reports whether key belongs to s3fs itself and must be hidden
+// from directory listings: keys below the system prefix and directory
+// manifests, which live next to the entries they describe, and the trash.
+func (fs *FileSystem) isSystemKey(key string) bool {
+	if isManifestKey(key) || fs.isTrashKey(key) {
+		return true
+	}
+	prefix := fs.reservedPrefix()
+	return strings.HasPrefix(key, prefix) || key == strings.TrimSuffix(prefix, "/")
+}
@@ -0,0 +1,45 @@
+package s3fs
+
+import "testing"
+
+func TestSystemPrefix(t *testing.T) {
+	tests := []struct {
+		prefix, want string
+	}{
+		{"", DefaultSystemPrefix},
+		{"/", DefaultSystemPrefix},
+		{"_internal", "_internal/"},
+		{"/a/b/", "a/b/"},
+	}
+	for _, tt := range tests {
+		if got := systemPrefix(tt.prefix); got != tt.want {
+			t.Errorf("systemPrefix(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestIsSystemKey(t *testing.T) {
+	fs := &FileSystem{systemPrefix: DefaultSystemPrefix}
+
+	if got := fs.systemKey("trash", "/a/b.txt"); got != ".s3fs/trash/a/b.txt" {
+		t.Errorf("systemKey() = %q", got)
+	}
+
+	hidden := []string{".s3fs", ".s3fs/", ".s3fs/locks/a", "dir/" + ManifestName}
+	for _, key := range hidden {
+		if !fs.isSystemKey(key) {
+			t.Errorf("isSystemKey(%q) = false, want true", key)
+		}
+	}
+	visible := []string{"a.txt", ".s3fsx/a", "dir/.s3fs/a"}
+	for _, key := range visible {
+		if fs.isSystemKey(key) {
+			t.Errorf("isSystemKey(%q) = true, want false", key)
+		}
+	}
+
+	// A FileSystem built without New falls back to the default prefix.
+	if !(&FileSystem{}).isSystemKey(".s3fs/x") {
+		t.Errorf("isSystemKey() without prefix should use DefaultSystemPrefix")
+	}
+}
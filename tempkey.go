@@ -0,0 +1,90 @@
+package s3fs
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// TempKeyMarker starts the base name of every key returned by NewTempKey.
+const TempKeyMarker = ".s3fs-tmp-"
+
+// NewTempKey returns a fresh, collision-resistant key inside the directory
+// prefix, for uploading content that is later copied to its final key.
+// The base name is TempKeyMarker followed by the creation time and 64 random
+// bits, so concurrent writers on different hosts never pick the same key and
+// CleanupTempKeys can tell how old an abandoned temp key is.
+func NewTempKey(prefix string) string {
+	base := fmt.Sprintf("%s%016x-%s", TempKeyMarker, time.Now().UnixNano(), randomID())
+	return path.Join(strings.Trim(prefix, "/"), base)
+}
+
+// parseTempKey reports whether key was returned by NewTempKey and, if so,
+// when it was created.
+func parseTempKey(key string) (time.Time, bool) {
+	base := path.Base(key)
+	if !strings.HasPrefix(base, TempKeyMarker) {
+		return time.Time{}, false
+	}
+
+	stamp, _, ok := strings.Cut(strings.TrimPrefix(base, TempKeyMarker), "-")
+	if !ok || len(stamp) != 16 {
+		return time.Time{}, false
+	}
+	nanos, err := strconv.ParseInt(stamp, 16, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// CleanupTempKeys deletes temp keys created by NewTempKey anywhere under the
+// directory prefix that are older than olderThan. Writers normally delete
+// their temp keys themselves; the sweep removes the ones left behind by
+// crashed processes. Choose olderThan comfortably above the longest upload
+// you expect, so in-progress writes are not removed. It returns the number of
+// keys deleted.
+func (fs *FileSystem) CleanupTempKeys(prefix string, olderThan time.Duration) (int, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	cutoff := time.Now().Add(-olderThan)
+	deleted := 0
+
+	var continuationToken *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(fs.objectKey(prefix)),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return deleted, fs.wrapError("CleanupTempKeys", prefix, err)
+		}
+
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			created, ok := parseTempKey(key)
+			if !ok || !created.Before(cutoff) {
+				continue
+			}
+			if err := fs.removeKey(key, key); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+
+	return deleted, nil
+}
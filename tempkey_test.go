@@ -0,0 +1,56 @@
+package s3fs
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTempKey(t *testing.T) {
+	before := time.Now()
+	key := NewTempKey("/uploads/")
+
+	if path.Dir(key) != "uploads" {
+		t.Errorf("NewTempKey() = %q, want a key in uploads", key)
+	}
+	if !strings.HasPrefix(path.Base(key), TempKeyMarker) {
+		t.Errorf("NewTempKey() = %q, want base name starting with %q", key, TempKeyMarker)
+	}
+
+	created, ok := parseTempKey(key)
+	if !ok {
+		t.Fatalf("parseTempKey(%q) failed", key)
+	}
+	if created.Before(before.Add(-time.Second)) || created.After(time.Now()) {
+		t.Errorf("parseTempKey() time = %v, want about %v", created, before)
+	}
+
+	if root := NewTempKey(""); strings.Contains(root, "/") {
+		t.Errorf("NewTempKey(\"\") = %q, want a key at the bucket root", root)
+	}
+}
+
+func TestNewTempKey_Unique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		key := NewTempKey("dir")
+		if seen[key] {
+			t.Fatalf("NewTempKey() returned duplicate %q", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestParseTempKey_Invalid(t *testing.T) {
+	for _, key := range []string{
+		"dir/file.txt",
+		"dir/" + TempKeyMarker,
+		"dir/" + TempKeyMarker + "zz-abc",
+		"dir/" + TempKeyMarker + "0000000000000001",
+	} {
+		if _, ok := parseTempKey(key); ok {
+			t.Errorf("parseTempKey(%q) succeeded", key)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// DefaultMetadataTimeout is Timeouts.Metadata when it is zero.
+	DefaultMetadataTimeout = 30 * time.Second
+
+	// DefaultSmallTransferTimeout is Timeouts.SmallTransfer when it is zero.
+	DefaultSmallTransferTimeout = 5 * time.Minute
+
+	// DefaultLargeTransferTimeout is Timeouts.LargeTransfer when it is zero.
+	DefaultLargeTransferTimeout = time.Hour
+
+	// DefaultLargeTransferSize is Timeouts.LargeTransferSize when it is zero
+	// (64MB).
+	DefaultLargeTransferSize = 64 * 1024 * 1024
+)
+
+// Timeouts bounds the S3 requests of a FileSystem by operation class, see
+// Config.Timeouts. Each request runs under a context derived from that of
+// the operation, so the SDK retries of a request share its timeout. Zero
+// fields take the defaults and negative ones disable the timeout of their
+// class.
+type Timeouts struct {
+	// Metadata bounds requests without object content, such as HeadObject,
+	// ListObjectsV2 and DeleteObject, and the wait for the response of a
+	// GetObject request.
+	Metadata time.Duration
+
+	// SmallTransfer bounds uploads of up to LargeTransferSize bytes with
+	// PutObject and UploadPart, and reading a GetObject response of up to
+	// LargeTransferSize bytes until its body is closed. LargeTransfer bounds
+	// larger ones, and server-side copies and the completion of multipart
+	// uploads, which take longer for large objects.
+	SmallTransfer     time.Duration
+	LargeTransfer     time.Duration
+	LargeTransferSize int64
+}
+
+// withDefaults returns t with the defaults for zero fields.
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Metadata == 0 {
+		t.Metadata = DefaultMetadataTimeout
+	}
+	if t.SmallTransfer == 0 {
+		t.SmallTransfer = DefaultSmallTransferTimeout
+	}
+	if t.LargeTransfer == 0 {
+		t.LargeTransfer = DefaultLargeTransferTimeout
+	}
+	if t.LargeTransferSize <= 0 {
+		t.LargeTransferSize = DefaultLargeTransferSize
+	}
+	return t
+}
+
+// transfer returns the timeout of a transfer of size bytes.
+func (t *Timeouts) transfer(size int64) time.Duration {
+	if size > t.LargeTransferSize {
+		return t.LargeTransfer
+	}
+	return t.SmallTransfer
+}
+
+// timeoutClient runs every call to Client under the timeout of its class.
+type timeoutClient struct {
+	Client
+	t Timeouts // With the defaults applied
+}
+
+func (c *timeoutClient) unwrap() Client { return c.Client }
+
+// timed calls fn with ctx bounded by timeout d, if it is positive.
+func timed[T any](ctx context.Context, d time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if d <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	return fn(ctx)
+}
+
+// deadline cancels a context when its timer expires. Unlike the deadline
+// of a context, the timer can be reset, so a download can get a longer
+// timeout once its size is known.
+type deadline struct {
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	expired bool
+}
+
+// reset restarts the timer with timeout d, or stops it if d is not
+// positive.
+func (d *deadline) reset(timeout time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if timeout > 0 && !d.expired {
+		d.timer = time.AfterFunc(timeout, d.expire)
+	}
+}
+
+func (d *deadline) expire() {
+	d.mu.Lock()
+	d.expired = true
+	d.mu.Unlock()
+	d.cancel()
+}
+
+// err returns err, marked as a context.DeadlineExceeded error if the timer
+// expired.
+func (d *deadline) err(err error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil || err == io.EOF || !d.expired {
+		return err
+	}
+	return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+}
+
+// timeoutBody applies the transfer timeout of a GetObject response until it
+// is closed.
+type timeoutBody struct {
+	io.ReadCloser
+	d *deadline
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	return n, b.d.err(err)
+}
+
+func (b *timeoutBody) Close() error {
+	b.d.reset(0)
+	b.d.cancel()
+	return b.ReadCloser.Close()
+}
+
+// GetObject waits up to the Metadata timeout for the response, then allows
+// the transfer timeout of its size for reading the body.
+func (c *timeoutClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	d := &deadline{cancel: cancel}
+	d.reset(c.t.Metadata)
+	out, err := c.Client.GetObject(ctx, params, optFns...)
+	if err != nil {
+		d.reset(0)
+		cancel()
+		return out, d.err(err)
+	}
+	d.reset(c.t.transfer(aws.ToInt64(out.ContentLength)))
+	out.Body = &timeoutBody{ReadCloser: out.Body, d: d}
+	return out, nil
+}
+
+func (c *timeoutClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return timed(ctx, c.t.transfer(bodySize(params.ContentLength, params.Body)), func(ctx context.Context) (*s3.PutObjectOutput, error) {
+		return c.Client.PutObject(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return timed(ctx, c.t.transfer(bodySize(params.ContentLength, params.Body)), func(ctx context.Context) (*s3.UploadPartOutput, error) {
+		return c.Client.UploadPart(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return timed(ctx, c.t.LargeTransfer, func(ctx context.Context) (*s3.CopyObjectOutput, error) {
+		return c.Client.CopyObject(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return timed(ctx, c.t.LargeTransfer, func(ctx context.Context) (*s3.UploadPartCopyOutput, error) {
+		return c.Client.UploadPartCopy(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return timed(ctx, c.t.LargeTransfer, func(ctx context.Context) (*s3.CompleteMultipartUploadOutput, error) {
+		return c.Client.CompleteMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.HeadObjectOutput, error) {
+		return c.Client.HeadObject(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.DeleteObjectOutput, error) {
+		return c.Client.DeleteObject(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.DeleteObjectsOutput, error) {
+		return c.Client.DeleteObjects(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.ListObjectsV2Output, error) {
+		return c.Client.ListObjectsV2(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.ListObjectVersionsOutput, error) {
+		return c.Client.ListObjectVersions(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.RestoreObjectOutput, error) {
+		return c.Client.RestoreObject(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.GetObjectTaggingOutput, error) {
+		return c.Client.GetObjectTagging(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.PutObjectRetentionOutput, error) {
+		return c.Client.PutObjectRetention(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.PutObjectLegalHoldOutput, error) {
+		return c.Client.PutObjectLegalHold(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.CreateMultipartUploadOutput, error) {
+		return c.Client.CreateMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.AbortMultipartUploadOutput, error) {
+		return c.Client.AbortMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.ListMultipartUploadsOutput, error) {
+		return c.Client.ListMultipartUploads(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+		return c.Client.GetBucketLifecycleConfiguration(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+		return c.Client.PutBucketLifecycleConfiguration(ctx, params, optFns...)
+	})
+}
+
+func (c *timeoutClient) DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	return timed(ctx, c.t.Metadata, func(ctx context.Context) (*s3.DeleteBucketLifecycleOutput, error) {
+		return c.Client.DeleteBucketLifecycle(ctx, params, optFns...)
+	})
+}
@@ -0,0 +1,75 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// hangingStub blocks every call, and every read of GetObject bodies, until
+// the context of the request is done.
+type hangingStub struct {
+	Client
+}
+
+func (c *hangingStub) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *hangingStub) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{ContentLength: aws.Int64(10), Body: hangingBody{ctx}}, nil
+}
+
+type hangingBody struct {
+	ctx context.Context
+}
+
+func (b hangingBody) Read(p []byte) (int, error) {
+	<-b.ctx.Done()
+	return 0, b.ctx.Err()
+}
+
+func (b hangingBody) Close() error { return nil }
+
+func TestTimeoutClient(t *testing.T) {
+	c := &timeoutClient{Client: &hangingStub{}, t: Timeouts{
+		Metadata:      10 * time.Millisecond,
+		SmallTransfer: 20 * time.Millisecond,
+		LargeTransfer: time.Hour,
+	}.withDefaults()}
+
+	start := time.Now()
+	if _, err := c.HeadObject(context.Background(), &s3.HeadObjectInput{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("HeadObject() error = %v, want context.DeadlineExceeded", err)
+	}
+	out, err := c.GetObject(context.Background(), &s3.GetObjectInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(out.Body); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("reading a hung body error = %v, want context.DeadlineExceeded", err)
+	}
+	out.Body.Close()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeouts took %v", elapsed)
+	}
+}
+
+func TestTimeouts_Transfer(t *testing.T) {
+	timeouts := Timeouts{SmallTransfer: time.Minute, LargeTransfer: -1}.withDefaults()
+	if got := timeouts.transfer(DefaultLargeTransferSize); got != time.Minute {
+		t.Errorf("transfer(DefaultLargeTransferSize) = %v, want 1m", got)
+	}
+	if got := timeouts.transfer(DefaultLargeTransferSize + 1); got != -1 {
+		t.Errorf("transfer() of a large transfer = %v, want disabled", got)
+	}
+	if timeouts.Metadata != DefaultMetadataTimeout {
+		t.Errorf("Metadata = %v, want DefaultMetadataTimeout", timeouts.Metadata)
+	}
+}
@@ -0,0 +1,82 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// copyBufferSize is the chunk size used by CopyContext and UploadContext.
+const copyBufferSize = 32 * 1024
+
+// ProgressFunc is called after each chunk of a transfer with the total number
+// of bytes transferred so far. It runs synchronously on the transfer path, so
+// a ProgressFunc that blocks slows the transfer down (see Throttle).
+type ProgressFunc func(transferred int64)
+
+// CopyContext copies from the s3fs file src to dst until EOF, ctx is cancelled
+// or an error occurs, reporting progress after every chunk. It returns the
+// number of bytes copied. Cancellation is checked between chunks; to also
+// abort an S3 request that is in flight, open src from fs.WithContext(ctx).
+func CopyContext(ctx context.Context, dst io.Writer, src absfs.File, progress ProgressFunc) (int64, error) {
+	return copyContext(ctx, dst, src, progress)
+}
+
+// UploadContext copies from src into the s3fs file dst until EOF, ctx is
+// cancelled or an error occurs, reporting progress after every chunk. The
+// upload itself happens when dst is closed, which is left to the caller.
+func UploadContext(ctx context.Context, dst absfs.File, src io.Reader, progress ProgressFunc) (int64, error) {
+	return copyContext(ctx, dst, src, progress)
+}
+
+func copyContext(ctx context.Context, dst io.Writer, src io.Reader, progress ProgressFunc) (int64, error) {
+	buf := make([]byte, copyBufferSize)
+	var written int64
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			m, werr := dst.Write(buf[:n])
+			written += int64(m)
+			if werr != nil {
+				return written, werr
+			}
+			if m != n {
+				return written, io.ErrShortWrite
+			}
+			if progress != nil {
+				progress(written)
+			}
+		}
+
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// Throttle returns a ProgressFunc that limits a transfer to roughly
+// bytesPerSecond by sleeping between chunks, then calls next (which may be nil).
+func Throttle(bytesPerSecond int64, next ProgressFunc) ProgressFunc {
+	start := time.Now()
+	return func(transferred int64) {
+		if bytesPerSecond > 0 {
+			expected := time.Duration(float64(transferred) / float64(bytesPerSecond) * float64(time.Second))
+			if wait := expected - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		if next != nil {
+			next(transferred)
+		}
+	}
+}
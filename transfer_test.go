@@ -0,0 +1,58 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadContext(t *testing.T) {
+	f := &File{writing: true, buffer: []byte{}}
+	data := strings.Repeat("x", copyBufferSize*2+10)
+
+	var calls int
+	var last int64
+	n, err := UploadContext(context.Background(), f, strings.NewReader(data), func(transferred int64) {
+		calls++
+		last = transferred
+	})
+	if err != nil {
+		t.Fatalf("UploadContext() error = %v", err)
+	}
+	if n != int64(len(data)) || last != n {
+		t.Errorf("UploadContext() = %d, last progress = %d, want %d", n, last, len(data))
+	}
+	if calls != 3 {
+		t.Errorf("progress called %d times, want 3", calls)
+	}
+	if string(f.buffer) != data {
+		t.Errorf("buffer does not match the uploaded data")
+	}
+}
+
+func TestCopyContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var dst bytes.Buffer
+	n, err := copyContext(ctx, &dst, strings.NewReader("data"), nil)
+	if err != context.Canceled || n != 0 {
+		t.Errorf("copyContext() = %d, %v, want 0, context.Canceled", n, err)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	var got int64
+	progress := Throttle(1000, func(transferred int64) { got = transferred })
+
+	start := time.Now()
+	progress(100)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Throttle() slept %v, want about 100ms", elapsed)
+	}
+	if got != 100 {
+		t.Errorf("next called with %d, want 100", got)
+	}
+}
@@ -0,0 +1,145 @@
+package s3fs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DirTransferOptions controls UploadDir and DownloadDir. A nil
+// *DirTransferOptions uses the defaults.
+type DirTransferOptions struct {
+	// Include and Exclude filter files by their slash-separated names
+	// relative to the transferred directories, with the patterns of Glob:
+	// "**" matches any number of directories. A file is transferred if it
+	// matches an Include pattern, or Include is empty, and no Exclude
+	// pattern.
+	Include []string
+	Exclude []string
+
+	Concurrency int // Files transferred in parallel (default DefaultSyncConcurrency)
+
+	// Progress is called after each transferred file, one call at a time.
+	Progress func(DirProgress)
+
+	// File controls the transfer of each file. Its Progress is not called.
+	File *TransferOptions
+}
+
+// DirProgress reports the progress of UploadDir and DownloadDir.
+type DirProgress struct {
+	Name       string // Relative name of the file just transferred
+	Files      int    // Files transferred so far
+	Bytes      int64  // Bytes transferred so far
+	TotalFiles int    // Files to transfer
+	TotalBytes int64  // Bytes to transfer
+}
+
+// UploadDir uploads the regular files below the local directory localDir to
+// the same relative names below the directory prefix with UploadFile,
+// transferring files in parallel. Existing objects are replaced. Transfers
+// stop at the first error; the summary lists the files transferred until
+// then, and counts the files excluded by the filters as skipped.
+func (fs *FileSystem) UploadDir(localDir, prefix string, opts *DirTransferOptions) (*SyncSummary, error) {
+	prefix = syncPrefix(prefix)
+	local, err := listLocal(localDir)
+	if err != nil {
+		return nil, fs.wrapError("UploadDir", localDir, err)
+	}
+	summary, err := fs.transferDir(local, opts, func(name string, file *TransferOptions) error {
+		return fs.UploadFile(filepath.Join(localDir, filepath.FromSlash(name)), prefix+name, file)
+	})
+	if err != nil {
+		return summary, fs.wrapError("UploadDir", prefix, err)
+	}
+	return summary, nil
+}
+
+// DownloadDir downloads the objects below the directory prefix to the same
+// relative names below the local directory localDir with DownloadFile,
+// transferring files in parallel and creating local directories as needed.
+// Existing local files are replaced. Transfers stop at the first error; the
+// summary lists the files transferred until then, and counts the files
+// excluded by the filters as skipped.
+func (fs *FileSystem) DownloadDir(prefix, localDir string, opts *DirTransferOptions) (*SyncSummary, error) {
+	prefix = syncPrefix(prefix)
+	remote, err := fs.listRemote(prefix)
+	if err != nil {
+		return nil, fs.wrapError("DownloadDir", prefix, err)
+	}
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return nil, fs.wrapError("DownloadDir", localDir, err)
+	}
+	summary, err := fs.transferDir(remote, opts, func(name string, file *TransferOptions) error {
+		return fs.DownloadFile(prefix+name, filepath.Join(localDir, filepath.FromSlash(name)), file)
+	})
+	if err != nil {
+		return summary, fs.wrapError("DownloadDir", prefix, err)
+	}
+	return summary, nil
+}
+
+// transferDir runs transfer for the entries that pass the filters of opts.
+func (fs *FileSystem) transferDir(entries map[string]syncEntry, opts *DirTransferOptions, transfer func(name string, file *TransferOptions) error) (*SyncSummary, error) {
+	if opts == nil {
+		opts = &DirTransferOptions{}
+	}
+	for _, pattern := range append(opts.Include[:len(opts.Include):len(opts.Include)], opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	file := &TransferOptions{}
+	if opts.File != nil {
+		*file = *opts.File
+	}
+	file.Progress = nil
+
+	summary := &SyncSummary{}
+	var names []string
+	var progress DirProgress
+	for name, e := range entries {
+		if !opts.included(name) {
+			summary.Skipped++
+			continue
+		}
+		names = append(names, name)
+		progress.TotalFiles++
+		progress.TotalBytes += e.size
+	}
+	sort.Strings(names)
+
+	var mu sync.Mutex
+	err := fs.syncTransfer(names, entries, summary, syncDefaults(&SyncOptions{Concurrency: opts.Concurrency}), func(name string) error {
+		if err := transfer(name, file); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			progress.Name = name
+			progress.Files++
+			progress.Bytes += entries[name].size
+			opts.Progress(progress)
+		}
+		return nil
+	})
+	return summary, err
+}
+
+// included reports whether the relative name passes the filters.
+func (opts *DirTransferOptions) included(name string) bool {
+	elems := strings.Split(name, "/")
+	match := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if matchElems(strings.Split(pattern, "/"), elems) {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(opts.Include) == 0 || match(opts.Include)) && !match(opts.Exclude)
+}
@@ -0,0 +1,100 @@
+package s3fs_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_UploadDownloadDir(t *testing.T) {
+	fs := s3fstest.New("bucket")
+	src := t.TempDir()
+	files := map[string]string{
+		"a.txt":          "a",
+		"b.log":          "bb",
+		"sub/c.txt":      "ccc",
+		"sub/deep/d.txt": "dddd",
+		"tmp/e.txt":      "eeeee",
+	}
+	for name, content := range files {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var last s3fs.DirProgress
+	calls := 0
+	opts := &s3fs.DirTransferOptions{
+		Include:     []string{"**/*.txt"},
+		Exclude:     []string{"tmp/**"},
+		Concurrency: 2,
+		Progress: func(p s3fs.DirProgress) {
+			calls++
+			last = p
+		},
+	}
+	summary, err := fs.UploadDir(src, "backup", opts)
+	if err != nil {
+		t.Fatalf("UploadDir() error = %v", err)
+	}
+	sort.Strings(summary.Transferred)
+	if got := strings.Join(summary.Transferred, ","); got != "a.txt,sub/c.txt,sub/deep/d.txt" || summary.Skipped != 2 || summary.Bytes != 8 {
+		t.Errorf("UploadDir() = %v, skipped %d, %d bytes", summary.Transferred, summary.Skipped, summary.Bytes)
+	}
+	if calls != 3 || last.Files != 3 || last.TotalFiles != 3 || last.Bytes != 8 || last.TotalBytes != 8 {
+		t.Errorf("UploadDir() progress: %d calls, last %+v", calls, last)
+	}
+	if ok, _ := fs.Exists("backup/sub/deep/d.txt"); !ok {
+		t.Error("backup/sub/deep/d.txt was not uploaded")
+	}
+	if ok, _ := fs.Exists("backup/b.log"); ok {
+		t.Error("backup/b.log was uploaded despite the filters")
+	}
+
+	dst := filepath.Join(t.TempDir(), "restore")
+	summary, err = fs.DownloadDir("backup", dst, &s3fs.DirTransferOptions{Exclude: []string{"sub/deep/*"}})
+	if err != nil {
+		t.Fatalf("DownloadDir() error = %v", err)
+	}
+	if len(summary.Transferred) != 2 || summary.Skipped != 1 {
+		t.Errorf("DownloadDir() = %v, skipped %d", summary.Transferred, summary.Skipped)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "sub", "c.txt")); err != nil || string(data) != "ccc" {
+		t.Errorf("downloaded sub/c.txt = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "sub", "deep")); !os.IsNotExist(err) {
+		t.Errorf("excluded directory sub/deep was created")
+	}
+
+	if _, err := fs.UploadDir(src, "x", &s3fs.DirTransferOptions{Include: []string{"["}}); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("UploadDir() with a bad pattern error = %v, want ErrBadPattern", err)
+	}
+}
+
+func TestFileSystem_DownloadDirSub(t *testing.T) {
+	fs := s3fstest.New("bucket").Sub("dir")
+	writeFile(t, fs, "backup/a.txt", "a")
+	writeFile(t, fs, "backup/sub/b.txt", "bb")
+
+	dst := t.TempDir()
+	summary, err := fs.DownloadDir("backup", dst, nil)
+	if err != nil {
+		t.Fatalf("DownloadDir() error = %v", err)
+	}
+	sort.Strings(summary.Transferred)
+	if got := strings.Join(summary.Transferred, ","); got != "a.txt,sub/b.txt" {
+		t.Errorf("DownloadDir() = %v, want a.txt,sub/b.txt", summary.Transferred)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "sub", "b.txt")); err != nil || string(data) != "bb" {
+		t.Errorf("downloaded sub/b.txt = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,265 @@
+package s3fs
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// TrashPathMetadataKey is the metadata key of trashed objects that holds
+	// the key the object was removed from.
+	TrashPathMetadataKey = "s3fs-trash-path"
+
+	// TrashTimeMetadataKey is the metadata key of trashed objects that holds
+	// the deletion time in RFC 3339 format.
+	TrashTimeMetadataKey = "s3fs-trash-time"
+)
+
+// trashTimeLayout formats the deletion time in trash keys. It has a fixed
+// width, so the trash entries of a key sort by deletion time.
+const trashTimeLayout = "20060102T150405.000000000Z"
+
+// TrashEntry is an object in the trash.
+type TrashEntry struct {
+	Name    string    // Name the object was removed from
+	Key     string    // Key of the object in the trash
+	Size    int64     // Size in bytes
+	Deleted time.Time // Deletion time
+}
+
+// trashPrefix returns the key prefix of the trash for cfg, or "" if the
+// trash is disabled.
+func trashPrefix(cfg *Config) string {
+	if !cfg.Trash {
+		return ""
+	}
+	if prefix := strings.Trim(cfg.TrashPrefix, "/"); prefix != "" {
+		return prefix + "/"
+	}
+	return systemPrefix(cfg.SystemPrefix) + "trash/"
+}
+
+// isTrashKey reports whether key is below the trash prefix.
+func (fs *FileSystem) isTrashKey(key string) bool {
+	return fs.trash != "" && (strings.HasPrefix(key, fs.trash) || key == strings.TrimSuffix(fs.trash, "/"))
+}
+
+// parseTrashKey returns the original key and deletion time of a trash key.
+func (fs *FileSystem) parseTrashKey(key string) (string, time.Time, bool) {
+	rest := strings.TrimPrefix(key, fs.trash)
+	i := strings.LastIndexByte(rest, '@')
+	if i < 0 || rest == key {
+		return "", time.Time{}, false
+	}
+	deleted, err := time.Parse(trashTimeLayout, rest[i+1:])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return rest[:i], deleted, true
+}
+
+// trashKey moves the object stored under key to the trash for Remove and
+// RemoveAll. Like DeleteObject, it succeeds if there is no such object.
+func (fs *FileSystem) trashKey(name, key string) error {
+	defer fs.stats.invalidate(key)
+	defer fs.dirs.invalidate(key)
+
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if httpStatus(err) == 404 {
+			return nil
+		}
+		return fs.wrapError("Remove", name, err)
+	}
+
+	now := time.Now().UTC()
+	metadata := make(map[string]string, len(head.Metadata)+2)
+	for k, v := range head.Metadata {
+		metadata[k] = v
+	}
+	metadata[TrashPathMetadataKey] = key
+	metadata[TrashTimeMetadataKey] = now.Format(time.RFC3339Nano)
+
+	dst := fs.trash + key + "@" + now.Format(trashTimeLayout)
+	if err := fs.copyKey(fs.ctx, key, dst, head, metadata, nil); err != nil {
+		return fs.wrapError("Remove", name, err)
+	}
+	return fs.removeKey(name, key)
+}
+
+// ListTrash returns the trashed objects that were removed from below the
+// directory prefix ("" for all), ordered by name and deletion time. An
+// object removed several times has an entry for each removal.
+func (fs *FileSystem) ListTrash(prefix string) ([]TrashEntry, error) {
+	if fs.trash == "" {
+		return nil, fs.wrapError("ListTrash", prefix, ErrTrashDisabled)
+	}
+	prefix = syncPrefix(prefix)
+
+	var entries []TrashEntry
+	err := fs.listTrash(fs.trash+fs.objectKey(prefix), func(e TrashEntry) error {
+		if strings.HasPrefix(e.Name, prefix) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fs.wrapError("ListTrash", prefix, err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Deleted.Before(entries[j].Deleted)
+	})
+	return entries, nil
+}
+
+// listTrash calls fn for each trash entry below the key prefix that belongs
+// to this FileSystem.
+func (fs *FileSystem) listTrash(prefix string, fn func(TrashEntry) error) error {
+	var continuationToken *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			orig, deleted, ok := fs.parseTrashKey(key)
+			if !ok {
+				continue
+			}
+
+			// With a NameCodec the name is in the metadata, which the
+			// trashed copy keeps
+			name := orig
+			if fs.codec != nil {
+				if name, err = fs.bucketName(key); err != nil {
+					return err
+				}
+			}
+			if !strings.HasPrefix(name, fs.root) {
+				continue // removed outside of this Sub filesystem
+			}
+
+			e := TrashEntry{
+				Name:    strings.TrimPrefix(name, fs.root),
+				Key:     key,
+				Size:    aws.ToInt64(obj.Size),
+				Deleted: deleted,
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			return nil
+		}
+		continuationToken = output.NextContinuationToken
+	}
+}
+
+// RestoreTrash puts the most recently trashed object removed from name back
+// in place. It fails with an error matching ErrNotExist if name is not in the
+// trash and ErrExist if a file was created under name since.
+func (fs *FileSystem) RestoreTrash(name string) error {
+	name = strings.TrimPrefix(name, "/")
+	if fs.trash == "" {
+		return fs.wrapError("RestoreTrash", name, ErrTrashDisabled)
+	}
+	key := fs.objectKey(name)
+	defer fs.stats.invalidate(key)
+
+	var latest string
+	err := fs.listTrash(fs.trash+key+"@", func(e TrashEntry) error {
+		if orig, _, _ := fs.parseTrashKey(e.Key); orig == key {
+			latest = e.Key // listings are sorted, so the last is the latest
+		}
+		return nil
+	})
+	if err != nil {
+		return fs.wrapError("RestoreTrash", name, err)
+	}
+	if latest == "" {
+		return fs.wrapError("RestoreTrash", name, ErrNotExist)
+	}
+
+	if _, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	}); err == nil {
+		return fs.wrapError("RestoreTrash", name, os.ErrExist)
+	} else if httpStatus(err) != 404 {
+		return fs.wrapError("RestoreTrash", name, err)
+	}
+
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(latest),
+	})
+	if err != nil {
+		return fs.wrapError("RestoreTrash", name, err)
+	}
+	metadata := make(map[string]string, len(head.Metadata))
+	for k, v := range head.Metadata {
+		if k != TrashPathMetadataKey && k != TrashTimeMetadataKey {
+			metadata[k] = v
+		}
+	}
+	if err := fs.copyKey(fs.ctx, latest, key, head, metadata, nil); err != nil {
+		return fs.wrapError("RestoreTrash", name, err)
+	}
+
+	if _, err := fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(latest),
+	}); err != nil {
+		return fs.wrapError("RestoreTrash", name, err)
+	}
+	return fs.manifestPut(key, aws.ToInt64(head.ContentLength), strings.HasSuffix(key, "/"))
+}
+
+// EmptyTrash permanently deletes the objects of this FileSystem that have
+// been in the trash for longer than olderThan; zero deletes all of them. It
+// returns the number of objects deleted.
+func (fs *FileSystem) EmptyTrash(olderThan time.Duration) (int, error) {
+	if fs.trash == "" {
+		return 0, fs.wrapError("EmptyTrash", "", ErrTrashDisabled)
+	}
+	cutoff := time.Now().Add(-olderThan)
+	deleted := 0
+
+	err := fs.listTrash(fs.trash+fs.objectKey(""), func(e TrashEntry) error {
+		if olderThan > 0 && !e.Deleted.Before(cutoff) {
+			return nil
+		}
+		if _, err := fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(fs.bucket),
+			Key:    aws.String(e.Key),
+		}); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	if err != nil {
+		return deleted, fs.wrapError("EmptyTrash", "", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,87 @@
+package s3fs_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+)
+
+func TestFileSystem_Trash(t *testing.T) {
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: s3fstest.NewClient(), Trash: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, "a.txt", "first")
+	writeFile(t, fs, "dir/b.txt", "b")
+	writeFile(t, fs, "dir/c.txt", "c")
+
+	if err := fs.Remove("a.txt"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	writeFile(t, fs, "a.txt", "second")
+	if err := fs.Remove("a.txt"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := fs.RemoveAll("dir"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if ok, _ := fs.Exists("a.txt"); ok {
+		t.Errorf("a.txt exists after Remove()")
+	}
+
+	entries, err := fs.ListTrash("")
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, " "); got != "a.txt a.txt dir/b.txt dir/c.txt" {
+		t.Errorf("ListTrash() = %s", got)
+	}
+	if entries, _ := fs.ListTrash("dir"); len(entries) != 2 {
+		t.Errorf("ListTrash(dir) = %d entries, want 2", len(entries))
+	}
+
+	var walked []string
+	fs.Walk("", func(path string, info os.FileInfo, err error) error {
+		walked = append(walked, path)
+		return err
+	})
+	if len(walked) != 0 {
+		t.Errorf("Walk() visits trash: %v", walked)
+	}
+
+	if err := fs.RestoreTrash("a.txt"); err != nil {
+		t.Fatalf("RestoreTrash() error = %v", err)
+	}
+	if data, err := fs.ReadFile("a.txt"); err != nil || string(data) != "second" {
+		t.Errorf("ReadFile() after RestoreTrash = %q, %v", data, err)
+	}
+	if err := fs.RestoreTrash("a.txt"); !errors.Is(err, os.ErrExist) {
+		t.Errorf("RestoreTrash() over a file error = %v, want ErrExist", err)
+	}
+	if err := fs.RestoreTrash("missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RestoreTrash() of a missing name error = %v, want ErrNotExist", err)
+	}
+
+	if n, err := fs.EmptyTrash(time.Hour); err != nil || n != 0 {
+		t.Errorf("EmptyTrash(1h) = %d, %v; want 0", n, err)
+	}
+	if n, err := fs.EmptyTrash(0); err != nil || n != 3 {
+		t.Errorf("EmptyTrash(0) = %d, %v; want 3", n, err)
+	}
+	if entries, _ := fs.ListTrash(""); len(entries) != 0 {
+		t.Errorf("ListTrash() after EmptyTrash = %d entries", len(entries))
+	}
+
+	if _, err := s3fstest.New("bucket").ListTrash(""); !errors.Is(err, s3fs.ErrTrashDisabled) {
+		t.Errorf("ListTrash() without Trash error = %v", err)
+	}
+}
@@ -0,0 +1,425 @@
+package s3fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Txn groups puts, copies and deletes of several objects so that they take
+// effect together. Commit records the operations and the prior state of every
+// object they touch in a journal object below the system prefix before
+// changing anything, so after a crash the transaction can be finished with
+// ResumeTxn or undone with RollbackTxn. Transactions do not isolate: other
+// writers may observe and change the objects while a transaction commits.
+type Txn struct {
+	fs   *FileSystem
+	id   string
+	ops  []txnOp
+	data map[int][]byte // Content of put operations by index
+	done bool
+
+	afterOp func(op txnOp) // Called after each operation Commit executes
+}
+
+// txnOp is an operation of a transaction, as recorded in its journal.
+type txnOp struct {
+	Op       string            `json:"op"` // "put", "copy" or "delete"
+	Key      string            `json:"key"`
+	Source   string            `json:"source,omitempty"`   // Source key of a copy, staged content of a put
+	Metadata map[string]string `json:"metadata,omitempty"` // Name metadata of the destination
+}
+
+// journal is the content of a transaction's journal object.
+type journal struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Ops     []txnOp   `json:"ops"`
+	Done    int       `json:"done"` // Number of operations executed
+
+	// Rollback is set once a rollback started, which must then be finished.
+	Rollback bool `json:"rollback,omitempty"`
+
+	// Backups maps the keys the transaction changes to copies of their
+	// prior content, or to "" for keys that did not exist.
+	Backups map[string]string `json:"backups"`
+}
+
+// Begin starts a transaction. Nothing is written until Commit.
+func (fs *FileSystem) Begin() *Txn {
+	return &Txn{
+		fs:   fs,
+		id:   fmt.Sprintf("%016x-%s", time.Now().UnixNano(), randomID()),
+		data: make(map[int][]byte),
+	}
+}
+
+// ID returns the identifier of the transaction, as reported by PendingTxns.
+func (t *Txn) ID() string {
+	return t.id
+}
+
+// Put adds writing data to the named file.
+func (t *Txn) Put(name string, data []byte) {
+	name = strings.TrimPrefix(name, "/")
+	t.data[len(t.ops)] = data
+	t.ops = append(t.ops, txnOp{Op: "put", Key: t.fs.objectKey(name), Metadata: t.nameMetadata(name)})
+}
+
+// Copy adds a server-side copy of the file src to dst.
+func (t *Txn) Copy(src, dst string) {
+	src, dst = strings.TrimPrefix(src, "/"), strings.TrimPrefix(dst, "/")
+	t.ops = append(t.ops, txnOp{Op: "copy", Key: t.fs.objectKey(dst), Source: t.fs.objectKey(src), Metadata: t.nameMetadata(dst)})
+}
+
+// Delete adds removing the named file.
+func (t *Txn) Delete(name string) {
+	name = strings.TrimPrefix(name, "/")
+	t.ops = append(t.ops, txnOp{Op: "delete", Key: t.fs.objectKey(name)})
+}
+
+// Rename adds moving the file oldpath to newpath, a copy followed by a
+// delete.
+func (t *Txn) Rename(oldpath, newpath string) {
+	t.Copy(oldpath, newpath)
+	t.Delete(oldpath)
+}
+
+// copyKey adds a server-side copy of the object under srcKey to the named
+// file dst.
+func (t *Txn) copyKey(srcKey, dst string) {
+	t.ops = append(t.ops, txnOp{Op: "copy", Key: t.fs.objectKey(dst), Source: srcKey, Metadata: t.nameMetadata(dst)})
+}
+
+// deleteKey adds removing the object under key.
+func (t *Txn) deleteKey(key string) {
+	t.ops = append(t.ops, txnOp{Op: "delete", Key: key})
+}
+
+// putStaged adds writing the named file with content the caller uploads
+// before Commit, and returns the key to upload it to.
+func (t *Txn) putStaged(name string) string {
+	key := t.fs.txnKey(t.id, "data", strconv.Itoa(len(t.ops)))
+	t.ops = append(t.ops, txnOp{Op: "put", Key: t.fs.objectKey(name), Source: key, Metadata: t.nameMetadata(name)})
+	return key
+}
+
+// discard abandons the transaction before Commit, deleting the content
+// staged for it.
+func (t *Txn) discard() error {
+	t.done = true
+	return t.fs.cleanupTxn(t.id)
+}
+
+// nameMetadata returns the name metadata for name, ignoring errors, which
+// only a failing NameCodec returns; the write then fails on Commit.
+func (t *Txn) nameMetadata(name string) map[string]string {
+	metadata, _ := t.fs.nameMetadata(name)
+	return metadata
+}
+
+// Commit executes the operations in order. Before the first change it
+// stages the content of puts, backs up every object the operations change
+// and writes the journal; after each operation it records the progress. If an
+// operation fails, the changes are rolled back and the error is returned.
+// Once all operations succeeded, the journal and backups are deleted.
+func (t *Txn) Commit() error {
+	return t.fs.wrapError("Commit", t.id, t.commit())
+}
+
+// commit implements Commit, for callers that wrap the error themselves.
+func (t *Txn) commit() error {
+	if t.done {
+		return ErrTxnDone
+	}
+	t.done = true
+	if len(t.ops) == 0 {
+		return nil
+	}
+	fs := t.fs
+
+	j := &journal{ID: t.id, Created: time.Now().UTC(), Ops: t.ops, Backups: make(map[string]string)}
+	for i := range j.Ops {
+		op := &j.Ops[i]
+		if data, ok := t.data[i]; ok {
+			op.Source = fs.txnKey(t.id, "data", strconv.Itoa(i))
+			if err := fs.putKey(op.Source, data, op.Metadata); err != nil {
+				fs.cleanupTxn(t.id)
+				return err
+			}
+		}
+		if _, ok := j.Backups[op.Key]; ok {
+			continue
+		}
+		backup, err := fs.backupKey(op.Key, fs.txnKey(t.id, "backup", strconv.Itoa(len(j.Backups))))
+		if err != nil {
+			fs.cleanupTxn(t.id)
+			return err
+		}
+		j.Backups[op.Key] = backup
+	}
+	if err := fs.saveJournal(j); err != nil {
+		fs.cleanupTxn(t.id)
+		return err
+	}
+
+	if err := fs.runJournal(j, t.afterOp); err != nil {
+		if rerr := fs.rollbackJournal(j); rerr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+		}
+		return err
+	}
+	return fs.cleanupTxn(t.id)
+}
+
+// PendingTxns returns the IDs of transactions that were not completed,
+// because the process committing them crashed or is still running. Call
+// ResumeTxn or RollbackTxn for each of them once no other process commits.
+func (fs *FileSystem) PendingTxns() ([]string, error) {
+	var ids []string
+	var continuationToken *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(fs.txnKey() + "/"),
+			Delimiter:         aws.String("/"),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, fs.wrapError("PendingTxns", "", err)
+		}
+		for _, p := range output.CommonPrefixes {
+			id := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(p.Prefix), fs.txnKey()+"/"), "/")
+			ids = append(ids, id)
+		}
+		if !aws.ToBool(output.IsTruncated) {
+			return ids, nil
+		}
+		continuationToken = output.NextContinuationToken
+	}
+}
+
+// ResumeTxn executes the remaining operations of a pending transaction and
+// completes it. A transaction that crashed before its journal was written
+// changed nothing; its staged objects are removed. A transaction that
+// crashed while rolling back is rolled back instead.
+func (fs *FileSystem) ResumeTxn(id string) error {
+	j, err := fs.loadJournal(id)
+	if err != nil {
+		if httpStatus(err) == 404 {
+			return fs.wrapError("ResumeTxn", id, fs.cleanupTxn(id))
+		}
+		return fs.wrapError("ResumeTxn", id, err)
+	}
+	if j.Rollback {
+		return fs.wrapError("ResumeTxn", id, fs.rollbackJournal(j))
+	}
+	if err := fs.runJournal(j, nil); err != nil {
+		return fs.wrapError("ResumeTxn", id, err)
+	}
+	return fs.wrapError("ResumeTxn", id, fs.cleanupTxn(id))
+}
+
+// RollbackTxn undoes a pending transaction, restoring every object it
+// touched to its state before the transaction.
+func (fs *FileSystem) RollbackTxn(id string) error {
+	j, err := fs.loadJournal(id)
+	if err != nil {
+		if httpStatus(err) == 404 {
+			return fs.wrapError("RollbackTxn", id, fs.cleanupTxn(id))
+		}
+		return fs.wrapError("RollbackTxn", id, err)
+	}
+	if err := fs.rollbackJournal(j); err != nil {
+		return fs.wrapError("RollbackTxn", id, err)
+	}
+	return nil
+}
+
+// txnKey returns the key of an object of a transaction below the system
+// prefix, or the prefix of all transactions without elem.
+func (fs *FileSystem) txnKey(elem ...string) string {
+	return fs.systemKey(append([]string{"txn"}, elem...)...)
+}
+
+// putKey uploads data under key.
+func (fs *FileSystem) putKey(key string, data []byte, metadata map[string]string) error {
+	_, err := fs.client.PutObject(fs.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(fs.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		Metadata:      metadata,
+	})
+	return err
+}
+
+// backupKey copies the object under key to backup and returns backup, or
+// returns "" if there is no object under key.
+func (fs *FileSystem) backupKey(key, backup string) (string, error) {
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if httpStatus(err) == 404 {
+			return "", nil
+		}
+		return "", err
+	}
+	if err := fs.copyKey(fs.ctx, key, backup, head, head.Metadata, nil); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
+
+// saveJournal writes the journal object of j.
+func (fs *FileSystem) saveJournal(j *journal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return fs.putKey(fs.txnKey(j.ID, "journal.json"), data, nil)
+}
+
+// loadJournal reads the journal object of the transaction id.
+func (fs *FileSystem) loadJournal(id string) (*journal, error) {
+	output, err := fs.client.GetObject(fs.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.txnKey(id, "journal.json")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, err
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// runJournal executes the operations of j not done yet, recording the
+// progress in the journal after each of them and then calling afterOp, if
+// not nil.
+func (fs *FileSystem) runJournal(j *journal, afterOp func(op txnOp)) error {
+	for j.Done < len(j.Ops) {
+		op := j.Ops[j.Done]
+		if err := fs.runOp(op); err != nil {
+			return err
+		}
+		j.Done++
+		if err := fs.saveJournal(j); err != nil {
+			return err
+		}
+		if afterOp != nil {
+			afterOp(op)
+		}
+	}
+	return nil
+}
+
+// runOp executes a single operation. Puts copy their staged content.
+func (fs *FileSystem) runOp(op txnOp) error {
+	defer fs.stats.invalidate(op.Key)
+
+	if op.Op == "delete" {
+		fs.dirs.invalidate(op.Key)
+		_, err := fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(fs.bucket),
+			Key:    aws.String(op.Key),
+		})
+		if err != nil {
+			return err
+		}
+		return fs.manifestDelete(op.Key)
+	}
+
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(op.Source),
+	})
+	if err != nil {
+		return err
+	}
+	metadata := make(map[string]string, len(head.Metadata))
+	for k, v := range head.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range op.Metadata {
+		metadata[k] = v
+	}
+	if err := fs.copyKey(fs.ctx, op.Source, op.Key, head, metadata, nil); err != nil {
+		return err
+	}
+	return fs.manifestPut(op.Key, aws.ToInt64(head.ContentLength), false)
+}
+
+// rollbackJournal restores every key touched by j from its backup, or
+// deletes it if it did not exist, and removes the transaction.
+func (fs *FileSystem) rollbackJournal(j *journal) error {
+	if !j.Rollback {
+		j.Rollback = true
+		if err := fs.saveJournal(j); err != nil {
+			return err
+		}
+	}
+	for key, backup := range j.Backups {
+		var err error
+		if backup == "" {
+			err = fs.runOp(txnOp{Op: "delete", Key: key})
+		} else {
+			err = fs.runOp(txnOp{Op: "copy", Key: key, Source: backup})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return fs.cleanupTxn(j.ID)
+}
+
+// cleanupTxn deletes the journal, staged content and backups of the
+// transaction id, the journal first, so an interrupted cleanup leaves no
+// journal referring to deleted backups.
+func (fs *FileSystem) cleanupTxn(id string) error {
+	journalKey := fs.txnKey(id, "journal.json")
+	if _, err := fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(journalKey),
+	}); err != nil {
+		return err
+	}
+
+	var keys []string
+	var continuationToken *string
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(fs.txnKey(id) + "/"),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return err
+		}
+		for _, obj := range output.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+	return fs.deleteKeys(keys)
+}
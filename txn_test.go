@@ -0,0 +1,168 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// crashingClient fails all writes after a number of copies, simulating a
+// process that died midway.
+type crashingClient struct {
+	*s3fstest.Client
+	crashAfter int // Number of copies before crashing; negative for never
+}
+
+var errCrashed = errors.New("crashed")
+
+func (c *crashingClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if c.crashAfter == 0 {
+		return nil, errCrashed
+	}
+	c.crashAfter--
+	return c.Client.CopyObject(ctx, params, optFns...)
+}
+
+func (c *crashingClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if c.crashAfter == 0 {
+		return nil, errCrashed
+	}
+	return c.Client.PutObject(ctx, params, optFns...)
+}
+
+func (c *crashingClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if c.crashAfter == 0 {
+		return nil, errCrashed
+	}
+	return c.Client.DeleteObject(ctx, params, optFns...)
+}
+
+// treeContents returns the files of fs as name=content pairs in lexical
+// order.
+func treeContents(fs *s3fs.FileSystem) string {
+	var files []string
+	fs.Walk("", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			data, _ := fs.ReadFile(path)
+			files = append(files, path+"="+string(data))
+		}
+		return err
+	})
+	return strings.Join(files, " ")
+}
+
+// recoverTxn checks that exactly one transaction is pending and resumes or
+// rolls it back.
+func recoverTxn(t *testing.T, fs *s3fs.FileSystem, resume bool) {
+	t.Helper()
+	ids, err := fs.PendingTxns()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("PendingTxns() = %v, %v; want one transaction", ids, err)
+	}
+	if resume {
+		err = fs.ResumeTxn(ids[0])
+	} else {
+		err = fs.RollbackTxn(ids[0])
+	}
+	if err != nil {
+		t.Fatalf("recovering %s (resume %v) error = %v", ids[0], resume, err)
+	}
+	if ids, _ := fs.PendingTxns(); len(ids) != 0 {
+		t.Errorf("PendingTxns() after recovery = %v", ids)
+	}
+}
+
+func TestFileSystem_Txn(t *testing.T) {
+	client := &crashingClient{Client: s3fstest.NewClient(), crashAfter: -1}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := func() string {
+		var files []string
+		fs.Walk("", func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				data, _ := fs.ReadFile(path)
+				files = append(files, path+"="+string(data))
+			}
+			return err
+		})
+		return strings.Join(files, " ")
+	}
+	writeFile(t, fs, "a.txt", "a")
+	writeFile(t, fs, "b.txt", "b")
+
+	txn := fs.Begin()
+	txn.Put("c.txt", []byte("c"))
+	txn.Rename("a.txt", "d.txt")
+	txn.Delete("b.txt")
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if got := contents(); got != "c.txt=c d.txt=a" {
+		t.Errorf("after Commit() = %s", got)
+	}
+	if err := txn.Commit(); !errors.Is(err, s3fs.ErrTxnDone) {
+		t.Errorf("second Commit() error = %v, want ErrTxnDone", err)
+	}
+
+	// A failing operation rolls back the earlier ones
+	txn = fs.Begin()
+	txn.Put("c.txt", []byte("changed"))
+	txn.Delete("d.txt")
+	txn.Copy("missing.txt", "e.txt")
+	if err := txn.Commit(); err == nil {
+		t.Fatalf("Commit() with a missing source succeeded")
+	}
+	if got := contents(); got != "c.txt=c d.txt=a" {
+		t.Errorf("after failed Commit() = %s", got)
+	}
+
+	// A crash leaves a journal to resume from or roll back
+	for _, resume := range []bool{true, false} {
+		txn = fs.Begin()
+		txn.Put("f.txt", []byte("f"))
+		txn.Rename("c.txt", "g.txt")
+		client.crashAfter = 2 // the backup of c.txt and the put, then during the rename
+		if err := txn.Commit(); err == nil {
+			t.Fatalf("Commit() during a crash succeeded")
+		}
+		client.crashAfter = -1
+
+		ids, err := fs.PendingTxns()
+		if err != nil || len(ids) != 1 || ids[0] != txn.ID() {
+			t.Fatalf("PendingTxns() = %v, %v; want [%s]", ids, err, txn.ID())
+		}
+		if resume {
+			if err := fs.ResumeTxn(ids[0]); err != nil {
+				t.Fatalf("ResumeTxn() error = %v", err)
+			}
+			if got := contents(); got != "d.txt=a f.txt=f g.txt=c" {
+				t.Errorf("after ResumeTxn() = %s", got)
+			}
+			// Undo for the rollback round
+			undo := fs.Begin()
+			undo.Rename("g.txt", "c.txt")
+			undo.Delete("f.txt")
+			if err := undo.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			if err := fs.RollbackTxn(ids[0]); err != nil {
+				t.Fatalf("RollbackTxn() error = %v", err)
+			}
+			if got := contents(); got != "c.txt=c d.txt=a" {
+				t.Errorf("after RollbackTxn() = %s", got)
+			}
+		}
+		if ids, _ := fs.PendingTxns(); len(ids) != 0 {
+			t.Errorf("PendingTxns() after recovery = %v", ids)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package s3fs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// NewFromV1Session creates a new S3 filesystem that reuses the region,
+// credentials, endpoint and HTTP client of an aws-sdk-go (v1) session, for
+// codebases that have not migrated to aws-sdk-go-v2 yet. cfg.Config is
+// ignored; cfg.Region and cfg.Endpoint, if set, override the session's.
+func NewFromV1Session(sess *session.Session, cfg *Config) (*FileSystem, error) {
+	c := *cfg
+	awsConfig := v1Config(sess.Config)
+	if c.Region != "" {
+		awsConfig.Region = c.Region
+	}
+	c.Config = &awsConfig
+
+	if c.Endpoint == "" {
+		c.Endpoint = v1Endpoint(sess.Config)
+	}
+	c.UsePathStyle = c.UsePathStyle || awsv1.BoolValue(sess.Config.S3ForcePathStyle)
+	return New(&c)
+}
+
+// v1Config converts the settings of a v1 configuration that have a direct
+// equivalent in aws.Config.
+func v1Config(cfg *awsv1.Config) aws.Config {
+	awsConfig := aws.Config{
+		Region: awsv1.StringValue(cfg.Region),
+	}
+	if cfg.Credentials != nil {
+		awsConfig.Credentials = aws.NewCredentialsCache(v1Credentials{cfg.Credentials})
+	}
+	if cfg.HTTPClient != nil {
+		awsConfig.HTTPClient = cfg.HTTPClient
+	}
+	return awsConfig
+}
+
+// v1Endpoint returns the custom endpoint of a v1 configuration as a URL.
+// v1 accepts endpoints without a scheme and picks one based on DisableSSL.
+func v1Endpoint(cfg *awsv1.Config) string {
+	endpoint := awsv1.StringValue(cfg.Endpoint)
+	if endpoint == "" || strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	if awsv1.BoolValue(cfg.DisableSSL) {
+		return "http://" + endpoint
+	}
+	return "https://" + endpoint
+}
+
+// v1Credentials adapts v1 credentials to aws.CredentialsProvider.
+type v1Credentials struct {
+	creds *credentials.Credentials
+}
+
+// Retrieve implements aws.CredentialsProvider.
+func (p v1Credentials) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	v, err := p.creds.GetWithContext(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	creds := aws.Credentials{
+		AccessKeyID:     v.AccessKeyID,
+		SecretAccessKey: v.SecretAccessKey,
+		SessionToken:    v.SessionToken,
+		Source:          v.ProviderName,
+	}
+	if expires, err := p.creds.ExpiresAt(); err == nil {
+		creds.CanExpire = true
+		creds.Expires = expires
+	}
+	return creds, nil
+}
@@ -0,0 +1,71 @@
+package s3fs
+
+import (
+	"context"
+	"testing"
+
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestV1Config(t *testing.T) {
+	cfg := v1Config(&awsv1.Config{
+		Region:      awsv1.String("eu-west-1"),
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", "TOKEN"),
+	})
+	if cfg.Region != "eu-west-1" {
+		t.Errorf("Region = %q, want eu-west-1", cfg.Region)
+	}
+
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if creds.AccessKeyID != "AKID" || creds.SecretAccessKey != "SECRET" || creds.SessionToken != "TOKEN" {
+		t.Errorf("Retrieve() = %+v", creds)
+	}
+
+	if v1Config(&awsv1.Config{}).Credentials != nil {
+		t.Errorf("Credentials should stay nil without v1 credentials")
+	}
+}
+
+func TestV1Endpoint(t *testing.T) {
+	tests := []struct {
+		cfg  *awsv1.Config
+		want string
+	}{
+		{&awsv1.Config{}, ""},
+		{&awsv1.Config{Endpoint: awsv1.String("http://localhost:9000")}, "http://localhost:9000"},
+		{&awsv1.Config{Endpoint: awsv1.String("minio:9000")}, "https://minio:9000"},
+		{&awsv1.Config{Endpoint: awsv1.String("minio:9000"), DisableSSL: awsv1.Bool(true)}, "http://minio:9000"},
+	}
+	for _, tt := range tests {
+		if got := v1Endpoint(tt.cfg); got != tt.want {
+			t.Errorf("v1Endpoint(%q) = %q, want %q", awsv1.StringValue(tt.cfg.Endpoint), got, tt.want)
+		}
+	}
+}
+
+func TestNewFromV1Session(t *testing.T) {
+	server := newEndpointServer(t)
+	sess, err := session.NewSession(&awsv1.Config{
+		Region:           awsv1.String("eu-west-1"),
+		Credentials:      credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:         awsv1.String(server.URL),
+		S3ForcePathStyle: awsv1.Bool(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := NewFromV1Session(sess, &Config{Bucket: "bucket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs.Stat("a.txt")
+	if got := server.firstPath(); got != "/bucket/a.txt" {
+		t.Errorf("request path = %q, want /bucket/a.txt on the session endpoint", got)
+	}
+}
@@ -0,0 +1,288 @@
+package s3fs
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// VersionInfo describes a single version of an object in a versioned bucket.
+type VersionInfo struct {
+	VersionID    string    // S3 version ID
+	Size         int64     // Size in bytes (zero for delete markers)
+	ModTime      time.Time // Time the version was created
+	ETag         string    // Entity tag of the version
+	IsLatest     bool      // Whether this is the current version
+	DeleteMarker bool      // Whether this version is a delete marker
+}
+
+// ListVersions returns all versions and delete markers of the named object,
+// newest first. The bucket must have versioning enabled (or suspended) for
+// more than one version to exist.
+func (fs *FileSystem) ListVersions(name string) ([]VersionInfo, error) {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+
+	var versions []VersionInfo
+	var keyMarker, versionIDMarker *string
+
+	for {
+		output, err := fs.client.ListObjectVersions(fs.ctx, &s3.ListObjectVersionsInput{
+			Bucket:          aws.String(fs.bucket),
+			Prefix:          aws.String(key),
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIDMarker,
+		})
+		if err != nil {
+			return nil, fs.wrapError("ListVersions", name, err)
+		}
+
+		for _, v := range output.Versions {
+			if aws.ToString(v.Key) != key {
+				continue
+			}
+			versions = append(versions, VersionInfo{
+				VersionID: aws.ToString(v.VersionId),
+				Size:      aws.ToInt64(v.Size),
+				ModTime:   aws.ToTime(v.LastModified),
+				ETag:      aws.ToString(v.ETag),
+				IsLatest:  aws.ToBool(v.IsLatest),
+			})
+		}
+		for _, m := range output.DeleteMarkers {
+			if aws.ToString(m.Key) != key {
+				continue
+			}
+			versions = append(versions, VersionInfo{
+				VersionID:    aws.ToString(m.VersionId),
+				ModTime:      aws.ToTime(m.LastModified),
+				IsLatest:     aws.ToBool(m.IsLatest),
+				DeleteMarker: true,
+			})
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		keyMarker = output.NextKeyMarker
+		versionIDMarker = output.NextVersionIdMarker
+	}
+
+	// LastModified has a resolution of a second: among versions of the same
+	// second, the latest and then delete markers are the newer ones
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := versions[i], versions[j]
+		if !a.ModTime.Equal(b.ModTime) {
+			return a.ModTime.After(b.ModTime)
+		}
+		if a.IsLatest != b.IsLatest {
+			return a.IsLatest
+		}
+		return a.DeleteMarker && !b.DeleteMarker
+	})
+	return versions, nil
+}
+
+// OpenVersion opens a specific version of the named object for reading.
+func (fs *FileSystem) OpenVersion(name, versionID string) (absfs.File, error) {
+	name = strings.TrimPrefix(name, "/")
+
+	return &File{
+		fs:      fs,
+		name:    name,
+		key:     fs.objectKey(name),
+		writing: false,
+		version: versionID,
+	}, nil
+}
+
+// RestoreVersion makes versionID the current version of the named object by
+// copying it over the latest version. The history is preserved: the restored
+// content becomes a new version.
+func (fs *FileSystem) RestoreVersion(name, versionID string) error {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+
+	head, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket:    aws.String(fs.bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return fs.wrapError("RestoreVersion", name, err)
+	}
+
+	source := fs.copySource(key) + "?versionId=" + versionID
+	err = fs.copyObject(fs.ctx, source, name, aws.ToInt64(head.ContentLength))
+	fs.stats.invalidate(key)
+	if err != nil {
+		return fs.wrapError("RestoreVersion", name, err)
+	}
+	return nil
+}
+
+// DeletedObject describes a removed object of a versioned bucket that can be
+// recovered with Undelete.
+type DeletedObject struct {
+	Name      string    // Name of the object
+	Deleted   time.Time // Time of the delete marker hiding the object
+	VersionID string    // Version that Undelete makes current again
+	Size      int64     // Size of that version
+}
+
+// ListDeleted returns the objects below the directory prefix ("" for all)
+// whose latest version is a delete marker, in name order. Deleted objects are
+// only recoverable in buckets with versioning enabled.
+func (fs *FileSystem) ListDeleted(prefix string) ([]DeletedObject, error) {
+	prefix = syncPrefix(prefix)
+
+	type state struct {
+		marker  *VersionInfo // latest delete marker
+		version *VersionInfo // newest version that is not a delete marker
+	}
+	keys := make(map[string]*state)
+	note := func(key string, v VersionInfo) {
+		s := keys[key]
+		if s == nil {
+			s = &state{}
+			keys[key] = s
+		}
+		switch {
+		case v.DeleteMarker && v.IsLatest:
+			s.marker = &v
+		case !v.DeleteMarker && (s.version == nil || v.ModTime.After(s.version.ModTime)):
+			s.version = &v
+		}
+	}
+
+	var keyMarker, versionIDMarker *string
+	for {
+		output, err := fs.client.ListObjectVersions(fs.ctx, &s3.ListObjectVersionsInput{
+			Bucket:          aws.String(fs.bucket),
+			Prefix:          aws.String(fs.objectKey(prefix)),
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIDMarker,
+		})
+		if err != nil {
+			return nil, fs.wrapError("ListDeleted", prefix, err)
+		}
+
+		for _, v := range output.Versions {
+			note(aws.ToString(v.Key), VersionInfo{
+				VersionID: aws.ToString(v.VersionId),
+				Size:      aws.ToInt64(v.Size),
+				ModTime:   aws.ToTime(v.LastModified),
+			})
+		}
+		for _, m := range output.DeleteMarkers {
+			note(aws.ToString(m.Key), VersionInfo{
+				VersionID:    aws.ToString(m.VersionId),
+				ModTime:      aws.ToTime(m.LastModified),
+				IsLatest:     aws.ToBool(m.IsLatest),
+				DeleteMarker: true,
+			})
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		keyMarker = output.NextKeyMarker
+		versionIDMarker = output.NextVersionIdMarker
+	}
+
+	var deleted []DeletedObject
+	for key, s := range keys {
+		if s.marker == nil || s.version == nil || fs.isSystemKey(key) {
+			continue
+		}
+		name, err := fs.versionName(key, s.version.VersionID)
+		if err != nil {
+			return nil, fs.wrapError("ListDeleted", key, err)
+		}
+		if !strings.HasPrefix(name, fs.root) {
+			continue
+		}
+		deleted = append(deleted, DeletedObject{
+			Name:      strings.TrimPrefix(name, fs.root),
+			Deleted:   s.marker.ModTime,
+			VersionID: s.version.VersionID,
+			Size:      s.version.Size,
+		})
+	}
+
+	sort.Slice(deleted, func(i, j int) bool {
+		return deleted[i].Name < deleted[j].Name
+	})
+	return deleted, nil
+}
+
+// versionName returns the name of a version of key relative to the bucket.
+// Deleted objects cannot be read without a version ID, so with a NameCodec
+// the name is read from the metadata of the given version.
+func (fs *FileSystem) versionName(key, versionID string) (string, error) {
+	if fs.codec == nil {
+		return key, nil
+	}
+	if d, ok := fs.codec.(KeyDecoder); ok {
+		return d.DecodeKey(key)
+	}
+	output, err := fs.client.HeadObject(fs.ctx, &s3.HeadObjectInput{
+		Bucket:    aws.String(fs.bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return "", err
+	}
+	sealed, ok := output.Metadata[NameMetadataKey]
+	if !ok {
+		return "", errors.New("s3fs: object has no name metadata: " + key)
+	}
+	return fs.codec.OpenName(sealed)
+}
+
+// Undelete reverses the removal of the named object in a versioned bucket by
+// deleting the delete markers above its newest version, which becomes the
+// current version again. It fails with ErrNotDeleted if the object exists and
+// with an error matching ErrNotExist if no earlier version is left.
+func (fs *FileSystem) Undelete(name string) error {
+	name = strings.TrimPrefix(name, "/")
+	key := fs.objectKey(name)
+
+	versions, err := fs.ListVersions(name)
+	if err != nil {
+		return err
+	}
+
+	// Versions are sorted newest first: the markers to delete lead
+	var markers []string
+	for _, v := range versions {
+		if !v.DeleteMarker {
+			break
+		}
+		markers = append(markers, v.VersionID)
+	}
+	if len(markers) == len(versions) {
+		return fs.wrapError("Undelete", name, ErrNotExist)
+	}
+	if len(markers) == 0 {
+		return fs.wrapError("Undelete", name, ErrNotDeleted)
+	}
+
+	defer fs.stats.invalidate(key)
+	for _, id := range markers {
+		if _, err := fs.client.DeleteObject(fs.ctx, &s3.DeleteObjectInput{
+			Bucket:    aws.String(fs.bucket),
+			Key:       aws.String(key),
+			VersionId: aws.String(id),
+		}); err != nil {
+			return fs.wrapError("Undelete", name, err)
+		}
+	}
+	return fs.manifestPut(key, versions[len(markers)].Size, strings.HasSuffix(key, "/"))
+}
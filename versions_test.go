@@ -0,0 +1,111 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// versionsStub serves a fixed version listing and records deleted versions.
+type versionsStub struct {
+	Client
+	output  *s3.ListObjectVersionsOutput
+	deleted []string
+}
+
+func (c *versionsStub) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return c.output, nil
+}
+
+func (c *versionsStub) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.deleted = append(c.deleted, aws.ToString(params.Key)+"@"+aws.ToString(params.VersionId))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestUndelete(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) *time.Time { return aws.Time(t0.Add(time.Duration(h) * time.Hour)) }
+	version := func(key, id string, h int, latest bool) types.ObjectVersion {
+		return types.ObjectVersion{Key: aws.String(key), VersionId: aws.String(id), LastModified: at(h), IsLatest: aws.Bool(latest), Size: aws.Int64(int64(h))}
+	}
+	marker := func(key, id string, h int, latest bool) types.DeleteMarkerEntry {
+		return types.DeleteMarkerEntry{Key: aws.String(key), VersionId: aws.String(id), LastModified: at(h), IsLatest: aws.Bool(latest)}
+	}
+
+	client := &versionsStub{output: &s3.ListObjectVersionsOutput{
+		IsTruncated: aws.Bool(false),
+		Versions: []types.ObjectVersion{
+			version("a.txt", "a1", 1, false),
+			version("a.txt", "a2", 2, false),
+			version("b.txt", "b1", 1, true),
+			version("dir/c.txt", "c1", 3, false),
+			version(".s3fs/journal", "j1", 1, false),
+		},
+		DeleteMarkers: []types.DeleteMarkerEntry{
+			marker("a.txt", "m1", 3, false),
+			marker("a.txt", "m2", 4, true),
+			marker("dir/c.txt", "m3", 5, true),
+			marker("gone.txt", "m4", 1, true),
+			marker(".s3fs/journal", "m5", 2, true),
+		},
+	}}
+	fs := &FileSystem{bucket: "bucket", client: client, ctx: context.Background()}
+
+	deleted, err := fs.ListDeleted("")
+	if err != nil {
+		t.Fatalf("ListDeleted() error = %v", err)
+	}
+	if len(deleted) != 2 || deleted[0].Name != "a.txt" || deleted[1].Name != "dir/c.txt" {
+		t.Fatalf("ListDeleted() = %+v, want a.txt and dir/c.txt", deleted)
+	}
+	if d := deleted[0]; d.VersionID != "a2" || d.Size != 2 || !d.Deleted.Equal(*at(4)) {
+		t.Errorf("ListDeleted()[0] = %+v, want version a2 deleted at 04:00", d)
+	}
+
+	if err := fs.Undelete("a.txt"); err != nil {
+		t.Fatalf("Undelete() error = %v", err)
+	}
+	if len(client.deleted) != 2 || client.deleted[0] != "a.txt@m2" || client.deleted[1] != "a.txt@m1" {
+		t.Errorf("Undelete() deleted %v, want both delete markers", client.deleted)
+	}
+
+	if err := fs.Undelete("b.txt"); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("Undelete() of an existing object error = %v, want ErrNotDeleted", err)
+	}
+	if err := fs.Undelete("gone.txt"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Undelete() without earlier versions error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestUndelete_SameSecond(t *testing.T) {
+	at := aws.Time(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := &versionsStub{output: &s3.ListObjectVersionsOutput{
+		IsTruncated: aws.Bool(false),
+		Versions: []types.ObjectVersion{
+			{Key: aws.String("a.txt"), VersionId: aws.String("v1"), LastModified: at, IsLatest: aws.Bool(false), Size: aws.Int64(1)},
+		},
+		DeleteMarkers: []types.DeleteMarkerEntry{
+			{Key: aws.String("a.txt"), VersionId: aws.String("m1"), LastModified: at, IsLatest: aws.Bool(true)},
+		},
+	}}
+	fs := &FileSystem{bucket: "bucket", client: client, ctx: context.Background()}
+
+	versions, err := fs.ListVersions("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].VersionID != "m1" || versions[1].VersionID != "v1" {
+		t.Errorf("ListVersions() = %+v, want the delete marker first", versions)
+	}
+	if err := fs.Undelete("a.txt"); err != nil {
+		t.Fatalf("Undelete() error = %v", err)
+	}
+	if len(client.deleted) != 1 || client.deleted[0] != "a.txt@m1" {
+		t.Errorf("Undelete() deleted %v, want the delete marker", client.deleted)
+	}
+}
@@ -0,0 +1,154 @@
+package s3fs
+
+import (
+	iofs "io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory, with the semantics of fs.WalkDir: paths are slash-separated
+// without trailing slash ("." for the bucket root), entries are visited in
+// lexical order, fn may return fs.SkipDir to skip a directory (or the rest of
+// a file's directory) and fs.SkipAll to stop the walk.
+//
+// Unlike Walk, each directory is listed separately with a delimiter only when
+// it is entered, so subtrees skipped with fs.SkipDir are never listed.
+func (fs *FileSystem) WalkDir(root string, fn iofs.WalkDirFunc) error {
+	root = strings.Trim(root, "/")
+	name := root
+	if name == "" || name == "." {
+		root, name = "", "."
+	}
+
+	var err error
+	if root == "" {
+		err = fs.walkDir(name, "", iofs.FileInfoToDirEntry(&fileInfo{name: ".", isDir: true}), fn)
+	} else {
+		info, serr := fs.Stat(root)
+		switch {
+		case serr == nil && !info.IsDir():
+			err = fn(name, iofs.FileInfoToDirEntry(info), nil)
+		case serr == nil:
+			err = fs.walkDir(name, root+"/", iofs.FileInfoToDirEntry(info), fn)
+		default:
+			// Directories without marker objects are found by listing
+			isDir, lerr := fs.isDirectory(fs.objectKey(root + "/"))
+			if lerr != nil || !isDir {
+				err = fn(name, nil, serr)
+			} else {
+				d := iofs.FileInfoToDirEntry(&fileInfo{name: path.Base(root), isDir: true})
+				err = fs.walkDir(name, root+"/", d, fn)
+			}
+		}
+	}
+
+	if err == iofs.SkipDir || err == iofs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir visits the directory name, stored under the prefix dir, and its
+// entries.
+func (fs *FileSystem) walkDir(name, dir string, d iofs.DirEntry, fn iofs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil {
+		if err == iofs.SkipDir {
+			return nil
+		}
+		return err
+	}
+
+	entries, err := fs.listDir(dir)
+	if err != nil {
+		// Second call for the directory, reporting the listing error
+		if err := fn(name, d, err); err != nil {
+			if err == iofs.SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		childName := path.Join(name, e.Name())
+		if e.IsDir() {
+			if err := fs.walkDir(childName, dir+e.Name()+"/", e, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(childName, e, nil); err != nil {
+			if err == iofs.SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// listDir returns the direct entries of the directory stored under the
+// prefix dir, sorted by name, using a delimiter listing.
+func (fs *FileSystem) listDir(dir string) ([]iofs.DirEntry, error) {
+	prefix := fs.objectKey(dir)
+	var entries []iofs.DirEntry
+	var continuationToken *string
+
+	for {
+		output, err := fs.client.ListObjectsV2(fs.ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(fs.bucket),
+			Prefix:            aws.String(prefix),
+			Delimiter:         aws.String("/"),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return entries, fs.wrapError("WalkDir", dir, err)
+		}
+
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			if key == prefix || fs.isSystemKey(key) {
+				continue
+			}
+			name, err := fs.logicalName(key)
+			if err != nil {
+				return entries, fs.wrapError("WalkDir", key, err)
+			}
+			entries = append(entries, iofs.FileInfoToDirEntry(&fileInfo{
+				name:    path.Base(name),
+				size:    aws.ToInt64(obj.Size),
+				modTime: aws.ToTime(obj.LastModified),
+				obj:     listObjectInfo(obj),
+			}))
+		}
+		for _, cp := range output.CommonPrefixes {
+			key := aws.ToString(cp.Prefix)
+			if fs.isSystemKey(key) {
+				continue
+			}
+			name, err := fs.logicalName(key)
+			if err != nil {
+				return entries, fs.wrapError("WalkDir", key, err)
+			}
+			entries = append(entries, iofs.FileInfoToDirEntry(&fileInfo{
+				name:  path.Base(strings.TrimSuffix(name, "/")),
+				isDir: true,
+			}))
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
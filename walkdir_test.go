@@ -0,0 +1,124 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	iofs "io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// listingClient records the prefixes listed.
+type listingClient struct {
+	*s3fstest.Client
+	prefixes []string
+}
+
+func (c *listingClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.prefixes = append(c.prefixes, aws.ToString(params.Prefix))
+	return c.Client.ListObjectsV2(ctx, params, optFns...)
+}
+
+// newWalkFS returns a filesystem holding a small tree and its client.
+func newWalkFS(t *testing.T) (*s3fs.FileSystem, *listingClient) {
+	t.Helper()
+	client := &listingClient{Client: s3fstest.NewClient()}
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "dir/z.txt", "skip/d.txt", "skip/deep/e.txt", "y.txt"} {
+		writeFile(t, fs, name, name)
+	}
+	client.prefixes = nil
+	return fs, client
+}
+
+// walkDir returns the paths WalkDir visits from root, with skip returning
+// the error fn returns for a path, if any.
+func walkDir(t *testing.T, fs *s3fs.FileSystem, root string, skip map[string]error) string {
+	t.Helper()
+	var visited []string
+	err := fs.WalkDir(root, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			t.Fatalf("WalkDir() visited %s with error %v", path, err)
+		}
+		visited = append(visited, path)
+		return skip[path]
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error = %v", err)
+	}
+	return strings.Join(visited, " ")
+}
+
+func TestWalkDir(t *testing.T) {
+	fs, _ := newWalkFS(t)
+	want := ". a.txt dir dir/b.txt dir/sub dir/sub/c.txt dir/z.txt skip skip/d.txt skip/deep skip/deep/e.txt y.txt"
+	if got := walkDir(t, fs, "", nil); got != want {
+		t.Errorf("WalkDir() = %s, want %s", got, want)
+	}
+	if got := walkDir(t, fs, "/dir/", nil); got != "dir dir/b.txt dir/sub dir/sub/c.txt dir/z.txt" {
+		t.Errorf("WalkDir(dir) = %s", got)
+	}
+	if got := walkDir(t, fs, "a.txt", nil); got != "a.txt" {
+		t.Errorf("WalkDir(a.txt) = %s, want a.txt", got)
+	}
+}
+
+func TestWalkDir_Skip(t *testing.T) {
+	tests := []struct {
+		name string
+		skip map[string]error
+		want string
+	}{
+		{"SkipDir on a directory", map[string]error{"skip": iofs.SkipDir},
+			". a.txt dir dir/b.txt dir/sub dir/sub/c.txt dir/z.txt skip y.txt"},
+		{"SkipDir on a file", map[string]error{"dir/b.txt": iofs.SkipDir},
+			". a.txt dir dir/b.txt skip skip/d.txt skip/deep skip/deep/e.txt y.txt"},
+		{"SkipAll", map[string]error{"dir/sub": iofs.SkipAll},
+			". a.txt dir dir/b.txt dir/sub"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, _ := newWalkFS(t)
+			if got := walkDir(t, fs, "", tt.skip); got != tt.want {
+				t.Errorf("WalkDir() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkDir_SkippedNotListed(t *testing.T) {
+	fs, client := newWalkFS(t)
+	walkDir(t, fs, "", map[string]error{"skip": iofs.SkipDir, "dir/sub": iofs.SkipDir})
+	if got := strings.Join(client.prefixes, " "); !strings.Contains(got, "dir/") {
+		t.Errorf("WalkDir() listed %q, want dir/ among them", got)
+	}
+	for _, prefix := range client.prefixes {
+		if strings.HasPrefix(prefix, "skip/") || strings.HasPrefix(prefix, "dir/sub/") {
+			t.Errorf("WalkDir() listed the skipped prefix %q", prefix)
+		}
+	}
+}
+
+func TestWalkDir_MissingRoot(t *testing.T) {
+	fs, _ := newWalkFS(t)
+	var calls int
+	err := fs.WalkDir("missing", func(path string, d iofs.DirEntry, err error) error {
+		calls++
+		if path != "missing" || d != nil || !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("WalkDir() called fn(%q, %v, %v), want the missing root and ErrNotExist", path, d, err)
+		}
+		return err
+	})
+	if calls != 1 || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("WalkDir() of a missing root = %v after %d calls, want ErrNotExist after one", err, calls)
+	}
+}
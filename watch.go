@@ -0,0 +1,302 @@
+package s3fs
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queue is a message queue receiving S3 event notifications, such as an SQS
+// queue that the bucket publishes to directly, through SNS, or through
+// EventBridge. Adapting an *sqs.Client takes a few lines, see the README.
+type Queue interface {
+	// Receive waits for the next messages. It may return no messages when
+	// a long poll times out.
+	Receive(ctx context.Context) ([]QueueMessage, error)
+
+	// Delete removes a processed message from the queue.
+	Delete(ctx context.Context, m QueueMessage) error
+}
+
+// QueueMessage is a message received from a Queue.
+type QueueMessage struct {
+	ID      string // Message ID
+	Receipt string // Handle used to delete the message, such as an SQS receipt handle
+	Body    string // JSON notification
+}
+
+// EventOp is the kind of change an Event reports.
+type EventOp int
+
+const (
+	// EventCreate reports an object that was written. S3 does not tell new
+	// objects from overwritten ones, so both are reported as EventCreate.
+	EventCreate EventOp = iota + 1
+
+	// EventRemove reports a deleted object, or a delete marker added in a
+	// versioned bucket.
+	EventRemove
+
+	// EventModify reports a change of an object that kept its content, such
+	// as new tags or ACLs, a completed restore or a storage class transition.
+	EventModify
+)
+
+func (op EventOp) String() string {
+	switch op {
+	case EventCreate:
+		return "CREATE"
+	case EventRemove:
+		return "REMOVE"
+	case EventModify:
+		return "MODIFY"
+	}
+	return "UNKNOWN"
+}
+
+// Event is a change of an object, delivered by a Watcher.
+type Event struct {
+	Name string    // Name of the object in the FileSystem
+	Op   EventOp   // Kind of change
+	Size int64     // Size of created objects
+	ETag string    // Entity tag of created objects
+	Time time.Time // Time of the change
+	Type string    // S3 event name, such as "ObjectCreated:Put"
+}
+
+// Watcher delivers the events of a Watch. Like fsnotify, events and errors
+// are delivered on channels that must be drained until Close.
+type Watcher struct {
+	Events <-chan Event
+	Errors <-chan error
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Close stops the watcher and closes its channels.
+func (w *Watcher) Close() error {
+	w.cancel()
+	w.wg.Wait()
+	return nil
+}
+
+// Watch delivers changes of objects below the directory prefix ("" for the
+// whole filesystem) by consuming the S3 event notifications in queue. The
+// bucket must be configured to publish notifications to the queue. Messages
+// are deleted from the queue once their events are delivered; notifications
+// for other buckets and prefixes are deleted too, so each queue should have
+// a single consumer. Events also invalidate the stat and directory caches.
+func (fs *FileSystem) Watch(prefix string, queue Queue) (*Watcher, error) {
+	if queue == nil {
+		return nil, fs.wrapError("Watch", prefix, ErrNoQueue)
+	}
+	prefix = syncPrefix(prefix)
+
+	ctx, cancel := context.WithCancel(fs.ctx)
+	events := make(chan Event)
+	errs := make(chan error)
+	w := &Watcher{Events: events, Errors: errs, cancel: cancel}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(events)
+		defer close(errs)
+		fs.watch(ctx, prefix, queue, events, errs)
+	}()
+	return w, nil
+}
+
+// watch receives messages until ctx is done.
+func (fs *FileSystem) watch(ctx context.Context, prefix string, queue Queue, events chan<- Event, errs chan<- error) {
+	report := func(err error) bool {
+		select {
+		case errs <- fs.wrapError("Watch", prefix, err):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for ctx.Err() == nil {
+		msgs, err := queue.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil || !report(err) {
+				return
+			}
+			// Do not spin on a persistent failure
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		for _, m := range msgs {
+			notifications, err := parseNotification(m.Body)
+			if err != nil {
+				// Leave malformed messages for the queue's redrive policy
+				if !report(err) {
+					return
+				}
+				continue
+			}
+			for _, n := range notifications {
+				e, ok := fs.notificationEvent(n, prefix)
+				if !ok {
+					continue
+				}
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err := queue.Delete(ctx, m); err != nil && !report(err) {
+				return
+			}
+		}
+	}
+}
+
+// notification is an S3 event in the form shared by direct, SNS and
+// EventBridge deliveries.
+type notification struct {
+	EventName string
+	Time      time.Time
+	Bucket    string
+	Key       string
+	Size      int64
+	ETag      string
+}
+
+// parseNotification parses the body of a queue message. Test events sent
+// when notifications are configured yield no notifications.
+func parseNotification(body string) ([]notification, error) {
+	var msg struct {
+		// SNS envelope
+		Type    string
+		Message string
+
+		// S3 notification
+		Records []struct {
+			EventName string    `json:"eventName"`
+			EventTime time.Time `json:"eventTime"`
+			S3        struct {
+				Bucket struct {
+					Name string `json:"name"`
+				} `json:"bucket"`
+				Object struct {
+					Key  string `json:"key"`
+					Size int64  `json:"size"`
+					ETag string `json:"eTag"`
+				} `json:"object"`
+			} `json:"s3"`
+		}
+
+		// EventBridge event
+		Source     string    `json:"source"`
+		DetailType string    `json:"detail-type"`
+		Time       time.Time `json:"time"`
+		Detail     struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+				ETag string `json:"etag"`
+			} `json:"object"`
+			Reason string `json:"reason"`
+		} `json:"detail"`
+	}
+	if err := json.Unmarshal([]byte(body), &msg); err != nil {
+		return nil, err
+	}
+
+	if msg.Type == "Notification" && msg.Message != "" {
+		return parseNotification(msg.Message)
+	}
+
+	if msg.Source == "aws.s3" {
+		return []notification{{
+			EventName: eventBridgeName(msg.DetailType, msg.Detail.Reason),
+			Time:      msg.Time,
+			Bucket:    msg.Detail.Bucket.Name,
+			Key:       msg.Detail.Object.Key,
+			Size:      msg.Detail.Object.Size,
+			ETag:      msg.Detail.Object.ETag,
+		}}, nil
+	}
+
+	var ns []notification
+	for _, r := range msg.Records {
+		// Keys in S3 notifications are URL-encoded, with + for spaces
+		key, err := url.QueryUnescape(r.S3.Object.Key)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, notification{
+			EventName: r.EventName,
+			Time:      r.EventTime,
+			Bucket:    r.S3.Bucket.Name,
+			Key:       key,
+			Size:      r.S3.Object.Size,
+			ETag:      r.S3.Object.ETag,
+		})
+	}
+	return ns, nil
+}
+
+// eventBridgeName maps an EventBridge detail type to the corresponding S3
+// event name prefix.
+func eventBridgeName(detailType, reason string) string {
+	switch detailType {
+	case "Object Created":
+		return "ObjectCreated:" + reason
+	case "Object Deleted":
+		return "ObjectRemoved:" + reason
+	}
+	return strings.ReplaceAll(detailType, " ", "")
+}
+
+// notificationEvent converts n into an Event for the filesystem. It reports
+// false for notifications of other buckets or outside prefix.
+func (fs *FileSystem) notificationEvent(n notification, prefix string) (Event, bool) {
+	if n.Bucket != fs.bucket || fs.isSystemKey(n.Key) {
+		return Event{}, false
+	}
+	// With a NameCodec the name is read from the object metadata, so the
+	// names of deleted objects are only known if s3fs saw them before
+	name, err := fs.bucketName(n.Key)
+	if err != nil || !strings.HasPrefix(name, fs.root) {
+		return Event{}, false
+	}
+	name = strings.TrimPrefix(name, fs.root)
+	if name == "" || !strings.HasPrefix(name, prefix) {
+		return Event{}, false
+	}
+
+	e := Event{Name: name, Time: n.Time, Type: n.EventName}
+	switch {
+	case strings.HasPrefix(n.EventName, "ObjectCreated:"):
+		e.Op, e.Size, e.ETag = EventCreate, n.Size, n.ETag
+	case strings.HasPrefix(n.EventName, "ObjectRemoved:"):
+		e.Op = EventRemove
+	default:
+		e.Op = EventModify
+	}
+
+	fs.stats.invalidate(n.Key)
+	if e.Op == EventRemove {
+		// The object may have been the last one of its directory
+		fs.dirs.invalidate(n.Key, path.Dir(strings.TrimSuffix(n.Key, "/"))+"/")
+	}
+	return e, true
+}
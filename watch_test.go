@@ -0,0 +1,121 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeQueue delivers canned messages once and records deletions.
+type fakeQueue struct {
+	mu      sync.Mutex
+	msgs    []QueueMessage
+	deleted []string
+}
+
+func (q *fakeQueue) Receive(ctx context.Context) ([]QueueMessage, error) {
+	q.mu.Lock()
+	msgs := q.msgs
+	q.msgs = nil
+	q.mu.Unlock()
+	if len(msgs) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return msgs, nil
+}
+
+func (q *fakeQueue) Delete(ctx context.Context, m QueueMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deleted = append(q.deleted, m.ID)
+	return nil
+}
+
+func TestParseNotification(t *testing.T) {
+	direct := `{"Records":[{"eventName":"ObjectCreated:Put","eventTime":"2024-01-02T03:04:05Z",
+		"s3":{"bucket":{"name":"b"},"object":{"key":"dir/a+b%21.txt","size":3,"eTag":"abc"}}}]}`
+	sns := `{"Type":"Notification","Message":"{\"Records\":[{\"eventName\":\"ObjectRemoved:Delete\",\"s3\":{\"bucket\":{\"name\":\"b\"},\"object\":{\"key\":\"x\"}}}]}"}`
+	eventBridge := `{"source":"aws.s3","detail-type":"Object Created","time":"2024-01-02T03:04:05Z",
+		"detail":{"bucket":{"name":"b"},"object":{"key":"dir/c.txt","size":7,"etag":"def"},"reason":"PutObject"}}`
+	test := `{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"b"}`
+
+	tests := []struct {
+		body  string
+		event string
+		key   string
+		n     int
+	}{
+		{direct, "ObjectCreated:Put", "dir/a b!.txt", 1},
+		{sns, "ObjectRemoved:Delete", "x", 1},
+		{eventBridge, "ObjectCreated:PutObject", "dir/c.txt", 1},
+		{test, "", "", 0},
+	}
+	for _, tt := range tests {
+		ns, err := parseNotification(tt.body)
+		if err != nil {
+			t.Fatalf("parseNotification(%s) error = %v", tt.body, err)
+		}
+		if len(ns) != tt.n {
+			t.Fatalf("parseNotification(%s) = %d notifications, want %d", tt.body, len(ns), tt.n)
+		}
+		if tt.n > 0 && (ns[0].EventName != tt.event || ns[0].Key != tt.key || ns[0].Bucket != "b") {
+			t.Errorf("parseNotification(%s) = %+v, want %s %s", tt.body, ns[0], tt.event, tt.key)
+		}
+	}
+
+	if _, err := parseNotification("not json"); err == nil {
+		t.Errorf("parseNotification() of a malformed body succeeded")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	fs := &FileSystem{bucket: "b", ctx: context.Background(), root: "sub/"}
+	if _, err := fs.Watch("", nil); !errors.Is(err, ErrNoQueue) {
+		t.Fatalf("Watch() without a queue error = %v, want ErrNoQueue", err)
+	}
+
+	record := func(event, bucket, key string) string {
+		return `{"Records":[{"eventName":"` + event + `","s3":{"bucket":{"name":"` + bucket + `"},"object":{"key":"` + key + `"}}}]}`
+	}
+	q := &fakeQueue{msgs: []QueueMessage{
+		{ID: "1", Body: record("ObjectCreated:Put", "b", "sub/dir/a.txt")},
+		{ID: "2", Body: record("ObjectCreated:Put", "other", "sub/dir/b.txt")},
+		{ID: "3", Body: record("ObjectCreated:Put", "b", "sub/elsewhere/c.txt")},
+		{ID: "4", Body: record("ObjectCreated:Put", "b", "sub/.s3fs/tmp")},
+		{ID: "5", Body: record("ObjectTagging:Put", "b", "sub/dir/a.txt")},
+		{ID: "6", Body: record("ObjectRemoved:Delete", "b", "sub/dir/a.txt")},
+	}}
+
+	w, err := fs.Watch("/dir", q)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	want := []Event{
+		{Name: "dir/a.txt", Op: EventCreate},
+		{Name: "dir/a.txt", Op: EventModify},
+		{Name: "dir/a.txt", Op: EventRemove},
+	}
+	for _, we := range want {
+		select {
+		case e := <-w.Events:
+			if e.Name != we.Name || e.Op != we.Op {
+				t.Errorf("event = %s %s, want %s %s", e.Op, e.Name, we.Op, we.Name)
+			}
+		case err := <-w.Errors:
+			t.Fatalf("Watch error = %v", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, ok := <-w.Events; ok {
+		t.Errorf("Events is open after Close()")
+	}
+	if len(q.deleted) != 6 {
+		t.Errorf("deleted %v, want all 6 messages", q.deleted)
+	}
+}
@@ -0,0 +1,144 @@
+package s3fs
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Writer streams data to an object as it is written, like the write end of a
+// pipe. Written data is buffered until a part is full; the first full part
+// starts a multipart upload, and each further part is uploaded while
+// writing, so memory use is bounded by the part size. Close completes the
+// upload, or writes small content with a single PutObject request, and
+// CloseWithError aborts it. Nothing is visible under the name before Close
+// succeeds. A Writer is not safe for concurrent use.
+type Writer struct {
+	fs   *FileSystem
+	name string
+	opts *PutOptions
+
+	partSize int64
+	buf      []byte
+	mu       *MultipartUpload // nil until the first part is full
+	size     int64
+	err      error // First failure, returned by later calls
+	closed   bool
+}
+
+// NewWriter returns a Writer for the named file with the attributes of opts,
+// which may be nil. No request is made until the first part is full or the
+// Writer is closed.
+func (fs *FileSystem) NewWriter(name string, opts *PutOptions) *Writer {
+	partSize := int64(DefaultPartSize)
+	if opts != nil && opts.PartSize > 0 {
+		partSize = max(opts.PartSize, MinPartSize)
+	}
+	return &Writer{fs: fs, name: strings.TrimPrefix(name, "/"), opts: opts, partSize: partSize}
+}
+
+// Write buffers p and uploads every full part. If an upload fails, the
+// error is returned by this and all later calls, and Close aborts the
+// upload.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, w.closedError()
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.size += int64(len(p))
+	if w.mu != nil {
+		if _, err := w.mu.Write(p); err != nil {
+			w.err = err
+		}
+		return len(p), w.err
+	}
+
+	w.buf = append(w.buf, p...)
+	if int64(len(w.buf)) <= w.partSize {
+		return len(p), nil
+	}
+	mu, err := w.fs.newMultipartUpload(w.name, w.opts)
+	if err != nil {
+		w.err = w.fs.wrapError("Write", w.name, err)
+		return len(p), w.err
+	}
+	mu.partSize = w.partSize
+	w.mu = mu
+	if _, err := mu.Write(w.buf); err != nil {
+		w.err = err
+	}
+	w.buf = nil
+	return len(p), w.err
+}
+
+// Close uploads the remaining data and completes the upload. If a Write
+// failed, the upload is aborted and the error of the Write returned.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.closedError()
+	}
+	w.closed = true
+	key := w.fs.objectKey(w.name)
+	defer w.fs.stats.invalidate(key)
+
+	if w.err != nil {
+		w.abort()
+		return w.err
+	}
+	if w.mu != nil {
+		if err := w.mu.Complete(); err != nil {
+			w.abort()
+			return err
+		}
+		return w.fs.manifestPut(key, w.size, false)
+	}
+
+	input, err := w.fs.putInput(w.name, w.opts)
+	if err != nil {
+		return w.fs.wrapError("Close", w.name, err)
+	}
+	input.Body = bytes.NewReader(w.buf)
+	input.ContentLength = aws.Int64(int64(len(w.buf)))
+	if _, err := w.fs.client.PutObject(w.fs.ctx, input); err != nil {
+		return w.fs.wrapError("Close", w.name, err)
+	}
+	w.buf = nil
+	return w.fs.manifestPut(key, w.size, false)
+}
+
+// CloseWithError aborts the upload, discarding all data written, so that
+// the named file is left unchanged. Later calls return err, or
+// ErrWriterClosed if it is nil. It returns the error of aborting the
+// multipart upload, if one was started.
+func (w *Writer) CloseWithError(err error) error {
+	if w.closed {
+		return w.closedError()
+	}
+	w.closed = true
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	w.buf = nil
+	return w.abort()
+}
+
+// closedError is the error of calls after the Writer was closed.
+func (w *Writer) closedError() error {
+	if w.err != nil {
+		return w.err
+	}
+	return ErrWriterClosed
+}
+
+// abort aborts the multipart upload, if one was started.
+func (w *Writer) abort() error {
+	if w.mu == nil {
+		return nil
+	}
+	mu := w.mu
+	w.mu = nil
+	return mu.Abort()
+}
@@ -0,0 +1,75 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/absfs/s3fs"
+	"github.com/absfs/s3fs/s3fstest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestFileSystem_NewWriter(t *testing.T) {
+	client := s3fstest.NewClient()
+	fs, err := s3fs.New(&s3fs.Config{Bucket: "bucket", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads := func() int {
+		t.Helper()
+		out, err := client.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{Bucket: aws.String("bucket")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(out.Uploads)
+	}
+	large := strings.Repeat("0123456789", s3fs.MinPartSize/10+100)
+
+	for _, content := range []string{"", "small", large + large} {
+		w := fs.NewWriter("file.txt", &s3fs.PutOptions{ContentType: "text/plain", PartSize: s3fs.MinPartSize})
+		if _, err := io.Copy(w, iotest.HalfReader(strings.NewReader(content))); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := fs.Exists("file.txt"); ok && content == large+large {
+			t.Error("file.txt is visible before Close")
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		data, err := fs.ReadFile("file.txt")
+		if err != nil || string(data) != content {
+			t.Errorf("ReadFile() = %d bytes, %v; want %d bytes", len(data), err, len(content))
+		}
+		if _, err := w.Write([]byte("x")); !errors.Is(err, s3fs.ErrWriterClosed) {
+			t.Errorf("Write() after Close error = %v, want ErrWriterClosed", err)
+		}
+		fs.Remove("file.txt")
+	}
+
+	// CloseWithError aborts the upload
+	w := fs.NewWriter("aborted.txt", nil)
+	if _, err := w.Write([]byte(strings.Repeat(large, 2))); err != nil {
+		t.Fatal(err)
+	}
+	if n := uploads(); n != 1 {
+		t.Errorf("%d multipart uploads in progress, want 1", n)
+	}
+	cause := errors.New("source failed")
+	if err := w.CloseWithError(cause); err != nil {
+		t.Fatalf("CloseWithError() error = %v", err)
+	}
+	if n := uploads(); n != 0 {
+		t.Errorf("%d multipart uploads after CloseWithError, want 0", n)
+	}
+	if ok, _ := fs.Exists("aborted.txt"); ok {
+		t.Error("aborted.txt exists after CloseWithError")
+	}
+	if err := w.Close(); err != cause {
+		t.Errorf("Close() after CloseWithError error = %v, want %v", err, cause)
+	}
+}